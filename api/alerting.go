@@ -0,0 +1,249 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// ListAlertRuleGroups lists the organization level or, under a cluster, the cluster level alert rule groups
+func ListAlertRuleGroups(c *gin.Context) {
+	organizationID, clusterID, ok := getAlertRuleScope(c)
+	if !ok {
+		return
+	}
+
+	groups, err := model.QueryAlertRuleGroups(organizationID, clusterID)
+	if err != nil {
+		respondWithAlertRuleQueryError(c, err)
+		return
+	}
+
+	response := make([]*pkgCluster.AlertRuleGroupResponse, 0, len(groups))
+	for _, group := range groups {
+		groupResponse, err := cluster.NewAlertRuleGroupResponse(group)
+		if err != nil {
+			respondWithAlertRuleQueryError(c, err)
+			return
+		}
+		response = append(response, groupResponse)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAlertRuleGroup returns an alert rule group of the organization or the cluster
+func GetAlertRuleGroup(c *gin.Context) {
+	group, ok := getAlertRuleGroupFromRequest(c)
+	if !ok {
+		return
+	}
+
+	response, err := cluster.NewAlertRuleGroupResponse(group)
+	if err != nil {
+		respondWithAlertRuleQueryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateAlertRuleGroup creates an alert rule group and syncs it into the Prometheus of the affected clusters
+func CreateAlertRuleGroup(c *gin.Context) {
+	organizationID, clusterID, ok := getAlertRuleScope(c)
+	if !ok {
+		return
+	}
+
+	var request pkgCluster.AlertRuleGroup
+	if !bindAlertRuleGroup(c, &request) {
+		return
+	}
+
+	if _, err := model.GetAlertRuleGroup(organizationID, clusterID, request.Name); err != gorm.ErrRecordNotFound {
+		if err != nil {
+			respondWithAlertRuleQueryError(c, err)
+			return
+		}
+		c.JSON(http.StatusConflict, pkgCommon.ErrorResponse{
+			Code:    http.StatusConflict,
+			Message: "Alert rule group already exists",
+			Error:   "alert rule group already exists",
+		})
+		return
+	}
+
+	group := &model.AlertRuleGroupModel{
+		OrganizationID: organizationID,
+		ClusterID:      clusterID,
+		CreatedBy:      auth.GetCurrentUser(c.Request).ID,
+	}
+	if !saveAlertRuleGroup(c, group, &request) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// UpdateAlertRuleGroup replaces the rules of an alert rule group and syncs them into the Prometheus of the affected clusters
+func UpdateAlertRuleGroup(c *gin.Context) {
+	group, ok := getAlertRuleGroupFromRequest(c)
+	if !ok {
+		return
+	}
+
+	var request pkgCluster.AlertRuleGroup
+	if !bindAlertRuleGroup(c, &request) {
+		return
+	}
+
+	if request.Name != group.Name {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   "alert rule groups can't be renamed",
+		})
+		return
+	}
+
+	if !saveAlertRuleGroup(c, group, &request) {
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// DeleteAlertRuleGroup deletes an alert rule group and removes its rules from the Prometheus of the affected clusters
+func DeleteAlertRuleGroup(c *gin.Context) {
+	group, ok := getAlertRuleGroupFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := cluster.DeleteAlertRuleGroup(group); err != nil {
+		log.Errorf("Error deleting alert rule group: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error deleting alert rule group",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ReceiveClusterAlerts is the Alertmanager webhook receiver of the cluster,
+// the alerts of the rules marked with notify are sent to the notification channels of Pipeline
+func ReceiveClusterAlerts(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	var message pkgCluster.AlertmanagerMessage
+	if err := c.BindJSON(&message); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, cluster.NotifyAlerts(commonCluster, &message))
+}
+
+// getAlertRuleScope returns the organization and the cluster of the alert rule groups,
+// the cluster id is zero on the organization level routes, this handles error messages directly
+func getAlertRuleScope(c *gin.Context) (uint, uint, bool) {
+	if len(c.Param("id")) == 0 {
+		return auth.GetCurrentOrganization(c.Request).ID, 0, true
+	}
+
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return 0, 0, false
+	}
+
+	return commonCluster.GetOrganizationId(), commonCluster.GetID(), true
+}
+
+// getAlertRuleGroupFromRequest looks up the alert rule group identified by the name path parameter,
+// this handles error messages directly
+func getAlertRuleGroupFromRequest(c *gin.Context) (*model.AlertRuleGroupModel, bool) {
+	organizationID, clusterID, ok := getAlertRuleScope(c)
+	if !ok {
+		return nil, false
+	}
+
+	group, err := model.GetAlertRuleGroup(organizationID, clusterID, c.Param("name"))
+	if err != nil {
+		respondWithAlertRuleQueryError(c, err)
+		return nil, false
+	}
+
+	return group, true
+}
+
+func bindAlertRuleGroup(c *gin.Context, request *pkgCluster.AlertRuleGroup) bool {
+	if err := c.BindJSON(request); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+func saveAlertRuleGroup(c *gin.Context, group *model.AlertRuleGroupModel, request *pkgCluster.AlertRuleGroup) bool {
+	if err := request.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid alert rule group",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	if err := cluster.SaveAlertRuleGroup(group, request); err != nil {
+		log.Errorf("Error saving alert rule group: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error saving alert rule group",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+func respondWithAlertRuleQueryError(c *gin.Context, err error) {
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Alert rule group not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	log.Errorf("Error querying alert rule groups: %s", err.Error())
+	c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "Error querying alert rule groups",
+		Error:   err.Error(),
+	})
+}
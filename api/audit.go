@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/banzaicloud/pipeline/audit"
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/database"
+	pkgAudit "github.com/banzaicloud/pipeline/pkg/audit"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Page sizes of the audit event listing
+const (
+	defaultAuditEventLimit = 100
+	maxAuditEventLimit     = 1000
+)
+
+// ListAuditEvents lists the audited API requests of the organization, filtered by user, method, path prefix,
+// status and time range. JSON responses are paginated, CSV and JSONL exports contain every matching event.
+// The audit resource is granted to organization admins and the custom roles including it.
+func ListAuditEvents(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	filter, err := parseAuditEventFilter(c, organization.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid audit event filter",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	switch format := c.DefaultQuery("format", pkgAudit.FormatJSON); format {
+	case pkgAudit.FormatJSON:
+		listAuditEvents(c, filter)
+	case pkgAudit.FormatCSV, pkgAudit.FormatJSONL:
+		exportAuditEvents(c, filter, format)
+	default:
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid format",
+			Error:   fmt.Sprintf("unsupported format %q", format),
+		})
+	}
+}
+
+// VerifyAuditChain walks the audit event chain of the organization and reports the first broken link,
+// i.e. the first event that has been modified, deleted or inserted since it was recorded
+func VerifyAuditChain(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	verification, err := audit.VerifyChain(organization.ID)
+	if err != nil {
+		log.Errorf("Error verifying audit chain: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error verifying audit chain",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, verification)
+}
+
+// listAuditEvents responds with a page of the audit events, the number of matching events is returned in the X-Total-Count header
+func listAuditEvents(c *gin.Context, filter *audit.EventFilter) {
+	limit, offset := defaultAuditEventLimit, 0
+	var err error
+	if value := c.Query("limit"); len(value) != 0 {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxAuditEventLimit {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid limit",
+				Error:   fmt.Sprintf("limit must be between 1 and %d", maxAuditEventLimit),
+			})
+			return
+		}
+	}
+	if value := c.Query("offset"); len(value) != 0 {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid offset",
+				Error:   "offset must be a non-negative integer",
+			})
+			return
+		}
+	}
+
+	total, err := audit.CountEvents(filter)
+	if err == nil {
+		var events []*audit.AuditEvent
+		if events, err = audit.QueryEvents(filter, limit, offset); err == nil {
+			response := make([]*pkgAudit.EventResponse, 0, len(events))
+			for _, event := range events {
+				response = append(response, event.ToResponse())
+			}
+
+			c.Header("X-Total-Count", strconv.Itoa(total))
+			c.JSON(http.StatusOK, response)
+			return
+		}
+	}
+
+	log.Errorf("Error querying audit events: %s", err.Error())
+	c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "Error querying audit events",
+		Error:   err.Error(),
+	})
+}
+
+// exportAuditEvents streams every matching audit event as CSV or JSON lines
+func exportAuditEvents(c *gin.Context, filter *audit.EventFilter, format string) {
+	contentType := "application/x-ndjson"
+	if format == pkgAudit.FormatCSV {
+		contentType = "text/csv"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=audit-%d.%s", filter.OrganizationID, format))
+	c.Status(http.StatusOK)
+
+	var err error
+	if format == pkgAudit.FormatCSV {
+		writer := csv.NewWriter(c.Writer)
+		if err = writer.Write(pkgAudit.CSVHeader); err == nil {
+			err = audit.EachEvent(filter, func(event *audit.AuditEvent) error {
+				return writer.Write(event.CSVRecord())
+			})
+		}
+		writer.Flush()
+	} else {
+		encoder := json.NewEncoder(c.Writer)
+		err = audit.EachEvent(filter, func(event *audit.AuditEvent) error {
+			return encoder.Encode(event.ToResponse())
+		})
+	}
+
+	// the status has been sent already, so errors can only be logged
+	if err != nil {
+		log.Errorf("Error exporting audit events: %s", err.Error())
+	}
+}
+
+// parseAuditEventFilter parses the filters of the audit event listing from the query parameters,
+// the user can be given by ID or login
+func parseAuditEventFilter(c *gin.Context, organizationID uint) (*audit.EventFilter, error) {
+	filter := &audit.EventFilter{
+		OrganizationID: organizationID,
+		Method:         c.Query("method"),
+		PathPrefix:     c.Query("path"),
+	}
+
+	if user := c.Query("user"); len(user) != 0 {
+		if id, err := strconv.ParseUint(user, 10, 32); err == nil {
+			userID := uint(id)
+			filter.UserID = &userID
+		} else {
+			var u auth.User
+			if err := database.GetDB().Where("login = ?", user).First(&u).Error; err != nil {
+				return nil, fmt.Errorf("user %q not found", user)
+			}
+			filter.UserID = &u.ID
+		}
+	}
+
+	var err error
+	if status := c.Query("status"); len(status) != 0 {
+		if filter.StatusFrom, filter.StatusTo, err = audit.ParseStatusFilter(status); err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.From, err = parseAuditTimeQuery(c, "from"); err != nil {
+		return nil, err
+	}
+	if filter.To, err = parseAuditTimeQuery(c, "to"); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// parseAuditTimeQuery parses an optional RFC3339 time query parameter
+func parseAuditTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s time: %s", name, err.Error())
+	}
+	return &t, nil
+}
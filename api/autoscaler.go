@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/banzaicloud/pipeline/cluster"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	pkgErrors "github.com/banzaicloud/pipeline/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// GetClusterAutoscalerStatus returns the autoscaler settings of the cluster with the status and events reported by the cluster-autoscaler
+func GetClusterAutoscalerStatus(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	if !pkgCluster.IsAutoscalerSupported(commonCluster.GetType()) {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: pkgErrors.ErrorAutoscalerNotSupported.Error(),
+			Error:   pkgErrors.ErrorAutoscalerNotSupported.Error(),
+		})
+		return
+	}
+
+	status, err := cluster.GetAutoscalerStatus(commonCluster)
+	if err != nil {
+		log.Errorf("Error during getting autoscaler status: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error during getting autoscaler status",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// isAutoscalerSettingsChanged returns true if the update request changes the autoscaler settings of the cluster
+func isAutoscalerSettingsChanged(commonCluster cluster.CommonCluster, updateRequest *pkgCluster.UpdateClusterRequest) bool {
+	return updateRequest.Autoscaler != nil && !reflect.DeepEqual(updateRequest.Autoscaler, commonCluster.GetModel().AutoscalerSettings)
+}
+
+// updateAutoscalerSettings stores the autoscaler settings of an update request which leaves the node pools unchanged
+// and redeploys the cluster-autoscaler (ASYNC)
+func updateAutoscalerSettings(c *gin.Context, commonCluster cluster.CommonCluster, settings *pkgCluster.AutoscalerSettings) {
+	err := settings.Validate()
+	if err == nil && !pkgCluster.IsAutoscalerSupported(commonCluster.GetType()) {
+		err = pkgErrors.ErrorAutoscalerNotSupported
+	}
+	if err != nil {
+		log.Errorf("Validation failed: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	commonCluster.GetModel().AutoscalerSettings = settings
+	if err := commonCluster.GetModel().Save(); err != nil {
+		log.Errorf("Error during cluster save %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error during saving autoscaler settings",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	go func() {
+		if err := cluster.DeployClusterAutoscaler(commonCluster); err != nil {
+			log.Errorf("Error during deploying autoscaler: %s", err.Error())
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, pkgCluster.UpdateClusterResponse{
+		Status: http.StatusAccepted,
+	})
+}
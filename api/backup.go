@@ -0,0 +1,434 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/backup"
+	"github.com/banzaicloud/pipeline/cluster"
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// CreateClusterBackup starts backing up the selected namespaces of the cluster into a managed bucket
+func CreateClusterBackup(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	var request pkgBackup.CreateBackupRequest
+	if err := c.BindJSON(&request); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clusterBackup, err := backup.CreateBackup(commonCluster, &request, auth.GetCurrentUser(c.Request).ID, 0)
+	if err != nil {
+		log.Errorf("Error creating backup: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error creating backup",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, clusterBackup.ToResponse())
+}
+
+// ListClusterBackups lists the backups of the cluster
+func ListClusterBackups(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	backups, err := backup.QueryBackups(commonCluster.GetOrganizationId(), commonCluster.GetID())
+	if err != nil {
+		log.Errorf("Error listing backups: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error listing backups",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	response := make([]*pkgBackup.BackupResponse, 0, len(backups))
+	for i := range backups {
+		response = append(response, backups[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetClusterBackup returns a backup of the cluster
+func GetClusterBackup(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	clusterBackup, ok := getClusterBackupFromRequest(c, commonCluster)
+	if ok != true {
+		return
+	}
+
+	c.JSON(http.StatusOK, clusterBackup.ToResponse())
+}
+
+// DeleteClusterBackup deletes a backup of the cluster together with its archive
+func DeleteClusterBackup(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	clusterBackup, ok := getClusterBackupFromRequest(c, commonCluster)
+	if ok != true {
+		return
+	}
+
+	if err := backup.DeleteBackup(clusterBackup); err != nil {
+		log.Errorf("Error deleting backup: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error deleting backup",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreClusterBackup starts re-applying a backup of the cluster into the same or a different cluster of the organization
+func RestoreClusterBackup(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	clusterBackup, ok := getClusterBackupFromRequest(c, commonCluster)
+	if ok != true {
+		return
+	}
+
+	var request pkgBackup.RestoreBackupRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&request); err != nil {
+			log.Errorf("Error parsing request: %s", err.Error())
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Error parsing request",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	targetCluster := commonCluster
+	if request.TargetClusterID != 0 && request.TargetClusterID != commonCluster.GetID() {
+		targetCluster, ok = GetCommonClusterFromFilter(c, map[string]interface{}{
+			"id":              request.TargetClusterID,
+			"organization_id": commonCluster.GetOrganizationId(),
+		})
+		if ok != true {
+			return
+		}
+	}
+
+	restore, err := backup.RestoreBackup(clusterBackup, targetCluster, auth.GetCurrentUser(c.Request).ID)
+	if err != nil {
+		log.Errorf("Error restoring backup: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error restoring backup",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, restore.ToResponse())
+}
+
+// ListClusterRestores lists the backup restores into the cluster
+func ListClusterRestores(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	restores, err := backup.QueryRestores(commonCluster.GetOrganizationId(), commonCluster.GetID())
+	if err != nil {
+		log.Errorf("Error listing restores: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error listing restores",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	response := make([]*pkgBackup.RestoreResponse, 0, len(restores))
+	for i := range restores {
+		response = append(response, restores[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateClusterBackupSchedule creates a periodic backup of the cluster
+func CreateClusterBackupSchedule(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	var request pkgBackup.CreateScheduleRequest
+	if err := c.BindJSON(&request); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	schedule, err := backup.CreateSchedule(commonCluster, &request, auth.GetCurrentUser(c.Request).ID)
+	if err != nil {
+		log.Errorf("Error creating backup schedule: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error creating backup schedule",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule.ToResponse())
+}
+
+// ListClusterBackupSchedules lists the backup schedules of the cluster
+func ListClusterBackupSchedules(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	schedules, err := backup.QuerySchedules(commonCluster.GetOrganizationId(), commonCluster.GetID())
+	if err != nil {
+		log.Errorf("Error listing backup schedules: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error listing backup schedules",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	response := make([]*pkgBackup.ScheduleResponse, 0, len(schedules))
+	for i := range schedules {
+		response = append(response, schedules[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteClusterBackupSchedule deletes a backup schedule of the cluster, the backups already taken are kept
+func DeleteClusterBackupSchedule(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	scheduleID, err := strconv.ParseUint(c.Param("scheduleid"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid schedule id",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	schedule, err := backup.GetSchedule(commonCluster.GetOrganizationId(), commonCluster.GetID(), uint(scheduleID))
+	if err != nil {
+		respondWithBackupQueryError(c, err, "Backup schedule not found")
+		return
+	}
+
+	if err := schedule.Delete(); err != nil {
+		log.Errorf("Error deleting backup schedule: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error deleting backup schedule",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListBackups lists the backups of the organization including the ones of deleted clusters,
+// they can be filtered by the clusterId query param
+func ListBackups(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	var clusterID uint64
+	if clusterIDParam := c.Query("clusterId"); clusterIDParam != "" {
+		var err error
+		clusterID, err = strconv.ParseUint(clusterIDParam, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid cluster id",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	backups, err := backup.QueryBackups(organization.ID, uint(clusterID))
+	if err != nil {
+		log.Errorf("Error listing backups: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error listing backups",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	response := make([]*pkgBackup.BackupResponse, 0, len(backups))
+	for i := range backups {
+		response = append(response, backups[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetBackup returns a backup of the organization
+func GetBackup(c *gin.Context) {
+	organizationBackup, ok := getBackupFromRequest(c, auth.GetCurrentOrganization(c.Request).ID)
+	if ok != true {
+		return
+	}
+
+	c.JSON(http.StatusOK, organizationBackup.ToResponse())
+}
+
+// RestoreBackup starts re-applying a backup of the organization into a cluster of the organization,
+// this way backups of deleted clusters can be restored as well
+func RestoreBackup(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	organizationBackup, ok := getBackupFromRequest(c, organization.ID)
+	if ok != true {
+		return
+	}
+
+	var request pkgBackup.RestoreBackupRequest
+	if err := c.BindJSON(&request); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	targetClusterID := request.TargetClusterID
+	if targetClusterID == 0 {
+		targetClusterID = organizationBackup.ClusterID
+	}
+
+	targetCluster, ok := GetCommonClusterFromFilter(c, map[string]interface{}{
+		"id":              targetClusterID,
+		"organization_id": organization.ID,
+	})
+	if ok != true {
+		return
+	}
+
+	restore, err := backup.RestoreBackup(organizationBackup, targetCluster, auth.GetCurrentUser(c.Request).ID)
+	if err != nil {
+		log.Errorf("Error restoring backup: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error restoring backup",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, restore.ToResponse())
+}
+
+// getBackupFromRequest looks up the backup of the organization identified by the backupid path parameter,
+// this handles error messages directly
+func getBackupFromRequest(c *gin.Context, organizationID uint) (*backup.ClusterBackupModel, bool) {
+	backupID, err := strconv.ParseUint(c.Param("backupid"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid backup id",
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+
+	organizationBackup, err := backup.GetBackup(organizationID, uint(backupID))
+	if err != nil {
+		respondWithBackupQueryError(c, err, "Backup not found")
+		return nil, false
+	}
+
+	return organizationBackup, true
+}
+
+// getClusterBackupFromRequest looks up the backup of the cluster identified by the backupid path parameter,
+// this handles error messages directly
+func getClusterBackupFromRequest(c *gin.Context, commonCluster cluster.CommonCluster) (*backup.ClusterBackupModel, bool) {
+	clusterBackup, ok := getBackupFromRequest(c, commonCluster.GetOrganizationId())
+	if ok != true {
+		return nil, false
+	}
+
+	if clusterBackup.ClusterID != commonCluster.GetID() {
+		respondWithBackupQueryError(c, gorm.ErrRecordNotFound, "Backup not found")
+		return nil, false
+	}
+
+	return clusterBackup, true
+}
+
+func respondWithBackupQueryError(c *gin.Context, err error, notFoundMessage string) {
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: notFoundMessage,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	log.Errorf("Error querying backups: %s", err.Error())
+	c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "Error querying backups",
+		Error:   err.Error(),
+	})
+}
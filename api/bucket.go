@@ -16,8 +16,10 @@ import (
 	"github.com/banzaicloud/pipeline/pkg/common"
 	pkgErrors "github.com/banzaicloud/pipeline/pkg/errors"
 	"github.com/banzaicloud/pipeline/pkg/storage"
+	pkgWebhook "github.com/banzaicloud/pipeline/pkg/webhook"
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/banzaicloud/pipeline/utils"
+	"github.com/banzaicloud/pipeline/webhook"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 	"google.golang.org/api/googleapi"
@@ -144,25 +146,57 @@ func CreateObjectStoreBuckets(c *gin.Context) {
 	c.JSON(http.StatusAccepted, storage.CreateBucketResponse{
 		Name: createBucketRequest.Name,
 	})
+	payload := pkgWebhook.BucketPayload{
+		Name:     createBucketRequest.Name,
+		Cloud:    cloudType,
+		SecretID: createBucketRequest.SecretId,
+	}
 	if cloudType == pkgCluster.Amazon {
 		objectStore.WithRegion(createBucketRequest.Properties.CreateAmazonObjectStoreBucketProperties.Location)
+		payload.Location = createBucketRequest.Properties.CreateAmazonObjectStoreBucketProperties.Location
 	}
 	if cloudType == pkgCluster.Google {
 		objectStore.WithRegion(createBucketRequest.Properties.CreateGoogleObjectStoreBucketProperties.Location)
+		payload.Location = createBucketRequest.Properties.CreateGoogleObjectStoreBucketProperties.Location
 	}
 	if cloudType == pkgCluster.Azure {
 		objectStore.WithRegion(createBucketRequest.Properties.CreateAzureObjectStoreBucketProperties.Location)
+		payload.Location = createBucketRequest.Properties.CreateAzureObjectStoreBucketProperties.Location
 		objectStore.WithResourceGroup(createBucketRequest.Properties.CreateAzureObjectStoreBucketProperties.ResourceGroup)
 		objectStore.WithStorageAccount(createBucketRequest.Properties.CreateAzureObjectStoreBucketProperties.StorageAccount)
 	}
 	if cloudType == pkgCluster.Oracle {
 		objectStore.WithRegion(createBucketRequest.Properties.CreateOracleObjectStoreBucketProperties.Location)
+		payload.Location = createBucketRequest.Properties.CreateOracleObjectStoreBucketProperties.Location
 	}
 
-	go objectStore.CreateBucket(createBucketRequest.Name)
+	actor := webhook.ActorFromUser(auth.GetCurrentUser(c.Request))
+	go func() {
+		objectStore.CreateBucket(createBucketRequest.Name)
+
+		// bucket creation doesn't report errors, so the bucket is checked before the event is sent
+		if err := objectStore.CheckBucket(createBucketRequest.Name); err == nil {
+			publishBucketWebhookEvent(organizationID, pkgWebhook.EventBucketCreated, actor, payload)
+		}
+	}()
 	return
 }
 
+// publishBucketWebhookEvent sends a lifecycle event of the bucket to the subscribed webhooks of the organization
+func publishBucketWebhookEvent(organizationID uint, eventType string, actor *pkgWebhook.Actor, payload pkgWebhook.BucketPayload) {
+	webhook.Publish(webhook.Event{
+		Type:           eventType,
+		OrganizationID: organizationID,
+		Resource: pkgWebhook.Resource{
+			Type: pkgWebhook.ResourceBucket,
+			ID:   payload.Cloud + "/" + payload.Name,
+			Name: payload.Name,
+		},
+		Actor:   actor,
+		Payload: payload,
+	})
+}
+
 // CheckObjectStoreBucket checks if the given there is a bucket exists with the given name
 func CheckObjectStoreBucket(c *gin.Context) {
 	cloudType := c.Query("cloudType")
@@ -317,6 +351,14 @@ func DeleteObjectStoreBucket(c *gin.Context) {
 
 	log.Infof("Object store bucket: organization id=%d, bucket=%s deleted", organizationID, name)
 
+	publishBucketWebhookEvent(organizationID, pkgWebhook.EventBucketDeleted, webhook.ActorFromUser(auth.GetCurrentUser(c.Request)),
+		pkgWebhook.BucketPayload{
+			Name:     name,
+			Cloud:    cloudType,
+			Location: c.Query("location"),
+			SecretID: secretId,
+		})
+
 }
 
 // errorResponseFrom translates the given error into a components.ErrorResponse
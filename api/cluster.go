@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/banzaicloud/pipeline/audit"
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/config"
@@ -17,11 +18,14 @@ import (
 	"github.com/banzaicloud/pipeline/model/defaults"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	pkgNotify "github.com/banzaicloud/pipeline/pkg/notify"
 	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	pkgWebhook "github.com/banzaicloud/pipeline/pkg/webhook"
 	"github.com/banzaicloud/pipeline/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -104,6 +108,9 @@ func GetCommonClusterFromFilter(c *gin.Context, filter map[string]interface{}) (
 		})
 		return nil, false
 	}
+
+	audit.SetResource(c, audit.ResourceCluster, fmt.Sprint(commonCLuster.GetID()))
+
 	return commonCLuster, true
 }
 
@@ -156,6 +163,8 @@ func CreateClusterRequest(c *gin.Context) {
 		return
 	}
 
+	audit.SetResource(c, audit.ResourceCluster, fmt.Sprint(commonCluster.GetID()))
+
 	c.JSON(http.StatusAccepted, pkgCluster.CreateClusterResponse{
 		Name:       commonCluster.GetName(),
 		ResourceID: commonCluster.GetID(),
@@ -194,7 +203,7 @@ func CreateCluster(createClusterRequest *pkgCluster.CreateClusterRequest, organi
 	postHooks []cluster.PostFunctioner) (cluster.CommonCluster, *pkgCommon.ErrorResponse) {
 	if len(createClusterRequest.ProfileName) != 0 {
 		log.Infof("Fill data from profile[%s]", createClusterRequest.ProfileName)
-		profile, err := defaults.GetProfile(createClusterRequest.Cloud, createClusterRequest.ProfileName)
+		profile, err := defaults.GetProfile(organizationID, createClusterRequest.Cloud, createClusterRequest.ProfileName)
 		if err != nil {
 			return nil, &pkgCommon.ErrorResponse{
 				Code:    http.StatusNotFound,
@@ -318,6 +327,7 @@ func postCreateCluster(commonCluster cluster.CommonCluster, postHooks []cluster.
 	if err != nil {
 		log.Errorf("Error during cluster creation: %s", err.Error())
 		commonCluster.UpdateStatus(pkgCluster.Error, err.Error())
+		cluster.PublishClusterEvent(commonCluster, pkgNotify.EventClusterFailed, "Cluster creation failed: "+err.Error())
 		return err
 	}
 
@@ -336,6 +346,9 @@ func postCreateCluster(commonCluster cluster.CommonCluster, postHooks []cluster.
 		return err
 	}
 
+	cluster.PublishClusterEvent(commonCluster, pkgNotify.EventClusterCreated, "Cluster created")
+	cluster.PublishClusterWebhookEvent(commonCluster, pkgWebhook.EventClusterCreated, commonCluster.GetModel().CreatedBy)
+
 	return nil
 }
 
@@ -361,14 +374,22 @@ func GetClusterStatus(c *gin.Context) {
 	return
 }
 
-// GetClusterConfig gets a cluster config
+// GetClusterConfig gets a cluster config, the admin config for organization admins and a config with
+// a short-lived credential of their own for the other users
 func GetClusterConfig(c *gin.Context) {
 	commonCluster, ok := GetCommonClusterFromRequest(c)
 	if ok != true {
 		return
 	}
-	config, err := commonCluster.GetK8sConfig()
-	if err != nil {
+	config, err := getUserKubeConfig(commonCluster, auth.GetCurrentUser(c.Request))
+	if err == cluster.ErrClusterRoleNotAllowed {
+		c.JSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "Error during getting config",
+			Error:   err.Error(),
+		})
+		return
+	} else if err != nil {
 		log.Errorf("Error during getting config: %s", err.Error())
 		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
 			Code:    http.StatusBadRequest,
@@ -492,6 +513,12 @@ func UpdateCluster(c *gin.Context) {
 
 	log.Info("Check equality")
 	if err := commonCluster.CheckEqualityToUpdate(updateRequest); err != nil {
+		// only the autoscaler settings are changed, the node pools are left untouched
+		if isAutoscalerSettingsChanged(commonCluster, updateRequest) {
+			updateAutoscalerSettings(c, commonCluster, updateRequest.Autoscaler)
+			return
+		}
+
 		log.Errorf("Check changes failed: %s", err.Error())
 		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
 			Code:    http.StatusBadRequest,
@@ -511,6 +538,15 @@ func UpdateCluster(c *gin.Context) {
 		return
 	}
 
+	if updateRequest.Autoscaler != nil {
+		commonCluster.GetModel().AutoscalerSettings = updateRequest.Autoscaler
+	}
+
+	// node pool labels and taints are applied by Pipeline after the update
+	if settings := updateRequest.GetNodePoolSettings(); settings != nil {
+		commonCluster.GetModel().NodePoolSettings = settings
+	}
+
 	// save the updated cluster to database
 	if err := commonCluster.Persist(pkgCluster.Updating, pkgCluster.UpdatingMessage); err != nil {
 		log.Errorf("Error during cluster save %s", err.Error())
@@ -542,6 +578,8 @@ func postUpdateCluster(commonCluster cluster.CommonCluster, updateRequest *pkgCl
 		return err
 	}
 
+	cluster.PublishClusterWebhookEvent(commonCluster, pkgWebhook.EventClusterUpdated, userId)
+
 	log.Info("deploy autoscaler")
 	if err := cluster.DeployClusterAutoscaler(commonCluster); err != nil {
 		log.Errorf("Error during update cluster status: %s", err.Error())
@@ -567,7 +605,7 @@ func DeleteCluster(c *gin.Context) {
 		force = false
 	}
 
-	go postDeleteCluster(commonCluster, force)
+	go postDeleteCluster(commonCluster, force, auth.GetCurrentUser(c.Request).ID)
 
 	deleteName := commonCluster.GetName()
 	deleteId := commonCluster.GetID()
@@ -580,7 +618,7 @@ func DeleteCluster(c *gin.Context) {
 }
 
 // postDeleteCluster deletes a cluster (ASYNC)
-func postDeleteCluster(commonCluster cluster.CommonCluster, force bool) error {
+func postDeleteCluster(commonCluster cluster.CommonCluster, force bool, userID uint) error {
 
 	err := commonCluster.UpdateStatus(pkgCluster.Deleting, pkgCluster.DeletingMessage)
 	if err != nil {
@@ -607,12 +645,17 @@ func postDeleteCluster(commonCluster cluster.CommonCluster, force bool) error {
 	if err != nil && !force {
 		log.Errorf(errors.Wrap(err, "Error during delete cluster").Error())
 		commonCluster.UpdateStatus(pkgCluster.Error, err.Error())
+		cluster.PublishClusterEvent(commonCluster, pkgNotify.EventClusterFailed, "Cluster deletion failed: "+err.Error())
 		return err
 	}
 
 	// delete from proxy from kubeProxyCache if any
 	kubeProxyCache.Delete(GetGlobalClusterID(commonCluster))
 
+	if err := cluster.RevokeAlertReceiverToken(commonCluster); err != nil {
+		log.Errorf("Error revoking alert receiver token: %s", err.Error())
+	}
+
 	// delete cluster from database
 	deleteName := commonCluster.GetName()
 	err = commonCluster.DeleteFromDatabase()
@@ -622,6 +665,9 @@ func postDeleteCluster(commonCluster cluster.CommonCluster, force bool) error {
 		return err
 	}
 
+	cluster.PublishClusterEvent(commonCluster, pkgNotify.EventClusterDeleted, "Cluster deleted")
+	cluster.PublishClusterWebhookEvent(commonCluster, pkgWebhook.EventClusterDeleted, userID)
+
 	// Asyncron update prometheus
 	go cluster.UpdatePrometheus()
 
@@ -1386,7 +1432,61 @@ func ProxyToCluster(c *gin.Context) {
 		kubeProxy, _ = kubeProxyCache.LoadOrStore(clusterKey, kubeProxy)
 	}
 
+	if !setProxyImpersonation(c, commonCluster.GetOrganizationId()) {
+		return
+	}
+
 	kubeProxyHandler := kubeProxy.(gin.HandlerFunc)
 
 	kubeProxyHandler(c)
 }
+
+// setProxyImpersonation sets the impersonation headers based on the current user and their organization role,
+// so the cluster RBAC rules apply to the requests instead of the admin credentials of the proxy.
+// Without impersonation the proxy uses the admin credentials, so only organization admins are allowed to use it.
+func setProxyImpersonation(c *gin.Context, organizationID uint) bool {
+	user := auth.GetCurrentUser(c.Request)
+	role, err := auth.GetUserOrganizationRole(user, organizationID)
+	if err != nil {
+		log.Errorf("Error getting organization role of user %s: %s", user.Login, err.Error())
+		c.AbortWithStatusJSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "Error getting organization role of user",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	// virtual users and restricted tokens must not get the admin groups, which usually include system:masters
+	if role == auth.RoleAdmin && (user.Virtual || user.HasRestrictedToken()) {
+		role = auth.RoleMember
+	}
+
+	if !viper.GetBool(config.ProxyImpersonation) {
+		if role != auth.RoleAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+				Code:    http.StatusForbidden,
+				Message: "Only organization admins can use the cluster proxy without impersonation",
+				Error:   "forbidden",
+			})
+			return false
+		}
+		cluster.RemoveImpersonationHeaders(c.Request.Header)
+		return true
+	}
+
+	groups, err := cluster.GetKubernetesGroups(organizationID, role)
+	if err != nil {
+		log.Errorf("Error getting kubernetes groups of role %s: %s", role, err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error getting kubernetes groups",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	cluster.SetImpersonationHeaders(c.Request.Header, cluster.ImpersonatedUserPrefix+user.Login, groups)
+
+	return true
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/model"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+)
+
+// GetKubernetesGroupMappings lists the Kubernetes groups by organization role used by the cluster API proxy
+func GetKubernetesGroupMappings(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	mappings, err := model.GetKubernetesGroupMappings(organization.ID)
+	if err != nil {
+		log.Errorf("Error listing kubernetes group mappings: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error listing kubernetes group mappings",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, mappings)
+}
+
+// SetKubernetesGroupMappings replaces the Kubernetes groups by organization role used by the cluster API proxy.
+// Only organization admins are allowed to change the mappings.
+func SetKubernetesGroupMappings(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	role, err := auth.GetUserOrganizationRole(auth.GetCurrentUser(c.Request), organization.ID)
+	if err != nil || role != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "Only organization admins can change kubernetes group mappings",
+			Error:   "forbidden",
+		})
+		return
+	}
+
+	var mappings map[string][]string
+	if err := c.BindJSON(&mappings); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := model.SetKubernetesGroupMappings(organization.ID, mappings); err != nil {
+		log.Errorf("Error saving kubernetes group mappings: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error saving kubernetes group mappings",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, mappings)
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+)
+
+// GetClusterHealth returns the health report of the cluster with an overall green/yellow/red verdict
+func GetClusterHealth(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	health, err := cluster.GetClusterHealth(commonCluster)
+	if err != nil {
+		log.Errorf("Error during checking cluster health: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error during checking cluster health",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, health)
+}
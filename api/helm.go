@@ -1,15 +1,20 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"encoding/base64"
 	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/helm"
 	pkgCommmon "github.com/banzaicloud/pipeline/pkg/common"
 	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+	pkgNotify "github.com/banzaicloud/pipeline/pkg/notify"
+	pkgWebhook "github.com/banzaicloud/pipeline/pkg/webhook"
 	"github.com/banzaicloud/pipeline/utils"
+	"github.com/banzaicloud/pipeline/webhook"
 	"github.com/ghodss/yaml"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
@@ -66,6 +71,8 @@ func CreateDeployment(c *gin.Context) {
 	if err != nil {
 		//TODO distinguish error codes
 		log.Errorf("Error during create deployment. %s", err.Error())
+		cluster.PublishClusterEvent(parsedRequest.commonCluster, pkgNotify.EventDeploymentFailed,
+			fmt.Sprintf("Creating deployment %s failed: %s", parsedRequest.deploymentName, err.Error()))
 		c.JSON(http.StatusBadRequest, pkgCommmon.ErrorResponse{
 			Code:    http.StatusBadRequest,
 			Message: "Error creating deployment",
@@ -76,6 +83,12 @@ func CreateDeployment(c *gin.Context) {
 	log.Info("Create deployment succeeded")
 
 	releaseName := release.GetRelease().GetName()
+	publishDeploymentWebhookEvent(c, parsedRequest.commonCluster, pkgWebhook.EventDeploymentCreated, pkgWebhook.DeploymentPayload{
+		ReleaseName: releaseName,
+		Chart:       parsedRequest.deploymentName,
+		Version:     parsedRequest.deploymentVersion,
+		Namespace:   release.GetRelease().GetNamespace(),
+	})
 	releaseNotes := base64.StdEncoding.EncodeToString([]byte(release.GetRelease().GetInfo().GetStatus().GetNotes()))
 
 	log.Debug("Release name: ", releaseName)
@@ -317,6 +330,8 @@ func UpgradeDeployment(c *gin.Context) {
 		parsedRequest.reuseValues, parsedRequest.kubeConfig, helm.GenerateHelmRepoEnv(parsedRequest.organizationName))
 	if err != nil {
 		log.Errorf("Error during upgrading deployment. %s", err.Error())
+		cluster.PublishClusterEvent(parsedRequest.commonCluster, pkgNotify.EventDeploymentFailed,
+			fmt.Sprintf("Upgrading deployment %s failed: %s", name, err.Error()))
 		c.JSON(http.StatusInternalServerError, pkgCommmon.ErrorResponse{
 			Code:    http.StatusInternalServerError,
 			Message: "Error upgrading deployment",
@@ -325,6 +340,12 @@ func UpgradeDeployment(c *gin.Context) {
 		return
 	}
 	log.Info("Upgrade deployment succeeded")
+	publishDeploymentWebhookEvent(c, parsedRequest.commonCluster, pkgWebhook.EventDeploymentUpdated, pkgWebhook.DeploymentPayload{
+		ReleaseName: name,
+		Chart:       parsedRequest.deploymentName,
+		Version:     parsedRequest.deploymentVersion,
+		Namespace:   release.GetRelease().GetNamespace(),
+	})
 
 	releaseNotes := base64.StdEncoding.EncodeToString([]byte(release.GetRelease().GetInfo().GetStatus().GetNotes()))
 
@@ -341,11 +362,21 @@ func UpgradeDeployment(c *gin.Context) {
 func DeleteDeployment(c *gin.Context) {
 	name := c.Param("name")
 	log.Infof("Delete deployment: %s", name)
-	kubeConfig, ok := GetK8sConfig(c)
+	commonCluster, ok := GetCommonClusterFromRequest(c)
 	if ok != true {
 		return
 	}
-	err := helm.DeleteDeployment(name, kubeConfig)
+	kubeConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		log.Errorf("Error getting config: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommmon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error getting kubeconfig",
+			Error:   err.Error(),
+		})
+		return
+	}
+	err = helm.DeleteDeployment(name, kubeConfig)
 	if err != nil {
 		// error during delete deployment
 		log.Errorf("Error deleting deployment: %s", err.Error())
@@ -356,6 +387,9 @@ func DeleteDeployment(c *gin.Context) {
 		})
 		return
 	}
+	publishDeploymentWebhookEvent(c, commonCluster, pkgWebhook.EventDeploymentDeleted, pkgWebhook.DeploymentPayload{
+		ReleaseName: name,
+	})
 	c.JSON(http.StatusOK, pkgHelm.DeleteResponse{
 		Status:  http.StatusOK,
 		Message: "Deployment deleted!",
@@ -363,6 +397,23 @@ func DeleteDeployment(c *gin.Context) {
 	})
 }
 
+// publishDeploymentWebhookEvent sends a lifecycle event of the deployment to the subscribed webhooks of the organization
+func publishDeploymentWebhookEvent(c *gin.Context, commonCluster cluster.CommonCluster, eventType string, payload pkgWebhook.DeploymentPayload) {
+	payload.ClusterID = commonCluster.GetID()
+	payload.ClusterName = commonCluster.GetName()
+	webhook.Publish(webhook.Event{
+		Type:           eventType,
+		OrganizationID: commonCluster.GetOrganizationId(),
+		Resource: pkgWebhook.Resource{
+			Type: pkgWebhook.ResourceDeployment,
+			ID:   fmt.Sprintf("%d/%s", payload.ClusterID, payload.ReleaseName),
+			Name: payload.ReleaseName,
+		},
+		Actor:   webhook.ActorFromUser(auth.GetCurrentUser(c.Request)),
+		Payload: payload,
+	})
+}
+
 type parsedDeploymentRequest struct {
 	deploymentName        string
 	deploymentVersion     string
@@ -372,6 +423,7 @@ type parsedDeploymentRequest struct {
 	values                []byte
 	kubeConfig            []byte
 	organizationName      string
+	commonCluster         cluster.CommonCluster
 }
 
 func parseCreateUpdateDeploymentRequest(c *gin.Context) (*parsedDeploymentRequest, error) {
@@ -388,6 +440,7 @@ func parseCreateUpdateDeploymentRequest(c *gin.Context) (*parsedDeploymentReques
 	}
 
 	pdr.organizationName = organization.Name
+	pdr.commonCluster = commonCluster
 
 	var deployment *pkgHelm.CreateUpdateDeploymentRequest
 	err = c.BindJSON(&deployment)
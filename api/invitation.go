@@ -0,0 +1,156 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListInvitations lists the pending invitations of the organization
+func ListInvitations(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	invitations, err := auth.ListInvitations(organization.ID)
+	if err != nil {
+		message := "failed to list invitations: " + err.Error()
+		log.Info(message)
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: message,
+			Error:   message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// CreateInvitation invites a user by login or email to the organization with a role, member is the default role.
+// The invited user joins the organization on the next login with the provider of the invitation, matching the login
+// or the email address verified by the provider, or by accepting the returned token, which is shown only once.
+func CreateInvitation(c *gin.Context) {
+
+	log.Info("Inviting user to organization")
+
+	request := struct {
+		Login    string `json:"login"`
+		Email    string `json:"email"`
+		Provider string `json:"provider"`
+		Role     string `json:"role"`
+	}{}
+
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		message := fmt.Sprintf("error parsing request: %s", err)
+		log.Info(message)
+		c.JSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: message,
+			Error:   message,
+		})
+		return
+	}
+	if request.Role == "" {
+		request.Role = auth.RoleMember
+	}
+
+	organization := auth.GetCurrentOrganization(c.Request)
+	currentUser := auth.GetCurrentUser(c.Request)
+
+	invitation, err := auth.CreateInvitation(organization.ID, currentUser.ID, request.Login, request.Email, request.Provider, request.Role)
+	if err != nil {
+		message := "failed to create invitation: " + err.Error()
+		log.Info(message)
+		c.JSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: message,
+			Error:   message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// RevokeInvitation revokes a pending invitation of the organization
+func RevokeInvitation(c *gin.Context) {
+
+	log.Info("Revoking invitation")
+
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		message := fmt.Sprintf("error parsing invitation id: %s", err)
+		log.Info(message)
+		c.JSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: message,
+			Error:   message,
+		})
+		return
+	}
+
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	err = auth.RevokeInvitation(organization.ID, uint(id))
+	if err != nil {
+		message := "failed to revoke invitation: " + err.Error()
+		log.Info(message)
+		statusCode := auth.GormErrorToStatusCode(err)
+		c.JSON(statusCode, common.ErrorResponse{
+			Code:    statusCode,
+			Message: message,
+			Error:   message,
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AcceptInvitation adds the current user to the organization of the invitation with the token
+func AcceptInvitation(c *gin.Context) {
+
+	log.Info("Accepting invitation")
+
+	request := struct {
+		Token string `json:"token" binding:"required"`
+	}{}
+
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		message := fmt.Sprintf("error parsing request: %s", err)
+		log.Info(message)
+		c.JSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: message,
+			Error:   message,
+		})
+		return
+	}
+
+	invitation, err := auth.AcceptInvitationToken(auth.GetCurrentUser(c.Request), request.Token)
+	if err == auth.ErrInvitationNotFound {
+		c.JSON(http.StatusNotFound, common.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+			Error:   err.Error(),
+		})
+		return
+	} else if err != nil {
+		message := "failed to accept invitation: " + err.Error()
+		log.Info(message)
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: message,
+			Error:   message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizationId": invitation.OrganizationID, "role": invitation.Role})
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/kubernetes"
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// GetOrganizationKubeConfig returns a single kubeconfig with one context per selected cluster of the organization.
+// Clusters can be selected by the `labels` query param (label selector syntax), the contexts are named as
+// `<organization>/<cluster>` and their default namespace can be set by the `namespace` query param.
+// Organization admins get the admin credentials of the clusters, the other users get short-lived credentials of their own.
+func GetOrganizationKubeConfig(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+	user := auth.GetCurrentUser(c.Request)
+
+	selector, err := labels.Parse(c.Query("labels"))
+	if err != nil {
+		log.Errorf("Error parsing label selector: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing label selector",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// only the clusters of the current organization are listed, access to the organization is checked by the authorizer
+	clusters, err := model.QueryCluster(map[string]interface{}{"organization_id": organization.ID})
+	if err != nil {
+		log.Errorf("Error listing clusters: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error listing clusters",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	kubeConfigs := make(map[string][]byte)
+	for i := range clusters {
+		if !selector.Matches(labels.Set(clusters[i].Labels)) {
+			continue
+		}
+
+		commonCluster, err := cluster.GetCommonClusterFromModel(&clusters[i])
+		if err != nil {
+			log.Errorf("convert ClusterModel to CommonCluster failed: %s ", err.Error())
+			continue
+		}
+
+		if clusters[i].Status != pkgCluster.Running {
+			log.Infof("Skipping cluster %s with status %s", commonCluster.GetName(), clusters[i].Status)
+			continue
+		}
+
+		config, err := getUserKubeConfig(commonCluster, user)
+		if err == cluster.ErrClusterRoleNotAllowed {
+			c.JSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+				Code:    http.StatusForbidden,
+				Message: "Error during getting config",
+				Error:   err.Error(),
+			})
+			return
+		} else if err != nil {
+			log.Errorf("Error during getting config of cluster %s: %s", commonCluster.GetName(), err.Error())
+			continue
+		}
+
+		kubeConfigs[fmt.Sprintf("%s/%s", organization.Name, commonCluster.GetName())] = config
+	}
+
+	if len(kubeConfigs) == 0 {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "No running cluster found",
+			Error:   "no running cluster matches the selector",
+		})
+		return
+	}
+
+	config, err := kubernetes.MergeKubeConfigs(kubeConfigs, c.Query("namespace"))
+	if err != nil {
+		log.Errorf("Error during merging configs: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error during merging configs",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	contentType := c.NegotiateFormat(gin.MIMEPlain, gin.MIMEJSON)
+	switch contentType {
+	case gin.MIMEJSON:
+		c.JSON(http.StatusOK, pkgCluster.GetClusterConfigResponse{
+			Status: http.StatusOK,
+			Data:   string(config),
+		})
+	default:
+		c.String(http.StatusOK, string(config))
+	}
+}
+
+// CreateUserClusterConfig issues a short-lived credential to the current user on the cluster and returns
+// a kubeconfig using it instead of the shared admin credentials
+func CreateUserClusterConfig(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	var request pkgCluster.CreateUserConfigRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&request); err != nil {
+			log.Errorf("Error parsing request: %s", err.Error())
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Error parsing request",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	config, credential, err := cluster.CreateUserCredential(commonCluster, auth.GetCurrentUser(c.Request), &request)
+	if err == cluster.ErrClusterRoleNotAllowed {
+		c.JSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "Error during creating user credential",
+			Error:   err.Error(),
+		})
+		return
+	} else if err != nil {
+		log.Errorf("Error during creating user credential: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error during creating user credential",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	contentType := c.NegotiateFormat(gin.MIMEPlain, gin.MIMEJSON)
+	switch contentType {
+	case gin.MIMEJSON:
+		c.JSON(http.StatusCreated, pkgCluster.CreateUserConfigResponse{
+			Status:    http.StatusCreated,
+			Data:      string(config),
+			ExpiresAt: credential.ExpiresAt,
+		})
+	default:
+		c.String(http.StatusCreated, string(config))
+	}
+}
+
+// getUserKubeConfig returns the admin config of the cluster to organization admins and a kubeconfig with
+// a short-lived credential of their own to the other users, the admin credentials are never given to them
+func getUserKubeConfig(commonCluster cluster.CommonCluster, user *auth.User) ([]byte, error) {
+	role, err := auth.GetUserOrganizationRole(user, commonCluster.GetOrganizationId())
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting organization role of the user")
+	}
+
+	if role == auth.RoleAdmin {
+		return commonCluster.GetK8sConfig()
+	}
+
+	config, _, err := cluster.CreateUserCredential(commonCluster, user, &pkgCluster.CreateUserConfigRequest{})
+	return config, err
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// GetPodLogs returns the logs of a pod's container. In follow mode the new lines are streamed
+// over a chunked response until the client disconnects or the container terminates.
+func GetPodLogs(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	options, err := parsePodLogOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	stream, err := cluster.GetPodLogs(commonCluster, c.Param("namespace"), c.Param("pod"), options)
+	if err != nil {
+		log.Errorf("Error getting pod logs: %s", err.Error())
+		code := http.StatusBadRequest
+		if k8sErrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		c.JSON(code, pkgCommon.ErrorResponse{
+			Code:    code,
+			Message: "Error getting pod logs",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer stream.Close()
+
+	writePodLogs(c, stream, options.Follow)
+}
+
+// writePodLogs copies the log stream into the response, in follow mode line by line until the stream ends
+func writePodLogs(c *gin.Context, stream io.ReadCloser, follow bool) {
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	if !follow {
+		c.Status(http.StatusOK)
+		if _, err := io.Copy(c.Writer, stream); err != nil {
+			log.Errorf("Error writing pod logs: %s", err.Error())
+		}
+		return
+	}
+
+	// reading the stream blocks until a new line is written, so it's closed when the client disconnects
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-c.Request.Context().Done():
+			stream.Close()
+		case <-done:
+		}
+	}()
+
+	reader := bufio.NewReader(stream)
+	c.Status(http.StatusOK)
+	c.Stream(func(w io.Writer) bool {
+		line, err := reader.ReadBytes('\n')
+		if len(line) != 0 {
+			if _, err := w.Write(line); err != nil {
+				return false
+			}
+		}
+		return err == nil
+	})
+}
+
+// parsePodLogOptions parses the container, previous, tailLines, sinceSeconds, timestamps and follow query parameters
+func parsePodLogOptions(c *gin.Context) (*v1.PodLogOptions, error) {
+	options := &v1.PodLogOptions{
+		Container: c.Query("container"),
+	}
+
+	if options.Container != "" {
+		if errs := validation.IsDNS1123Label(options.Container); len(errs) != 0 {
+			return nil, errors.Errorf("invalid container: %s", strings.Join(errs, ", "))
+		}
+	}
+
+	var err error
+	if options.Previous, err = parseBoolQuery(c, "previous"); err != nil {
+		return nil, err
+	}
+	if options.Follow, err = parseBoolQuery(c, "follow"); err != nil {
+		return nil, err
+	}
+	if options.Timestamps, err = parseBoolQuery(c, "timestamps"); err != nil {
+		return nil, err
+	}
+	if options.Previous && options.Follow {
+		return nil, errors.New("logs of the previous container can't be followed")
+	}
+
+	if value := c.Query("tailLines"); value != "" {
+		tailLines, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || tailLines < 0 {
+			return nil, errors.Errorf("invalid tailLines: %s", value)
+		}
+		options.TailLines = &tailLines
+	}
+
+	if value := c.Query("sinceSeconds"); value != "" {
+		sinceSeconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || sinceSeconds < 1 {
+			return nil, errors.Errorf("invalid sinceSeconds: %s", value)
+		}
+		options.SinceSeconds = &sinceSeconds
+	}
+
+	return options, nil
+}
+
+func parseBoolQuery(c *gin.Context, name string) (bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.Errorf("invalid %s: %s", name, value)
+	}
+	return b, nil
+}
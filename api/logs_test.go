@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// closeNotifyRecorder is a response recorder which can be used for streaming responses
+type closeNotifyRecorder struct {
+	*httptest.ResponseRecorder
+	closed chan bool
+}
+
+func (r *closeNotifyRecorder) CloseNotify() <-chan bool {
+	return r.closed
+}
+
+func newPodLogsContext(ctx context.Context, query string) (*gin.Context, *closeNotifyRecorder) {
+	recorder := &closeNotifyRecorder{ResponseRecorder: httptest.NewRecorder(), closed: make(chan bool, 1)}
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/logs?"+query, nil).WithContext(ctx)
+	return c, recorder
+}
+
+func TestParsePodLogOptions(t *testing.T) {
+	tests := []struct {
+		query string
+		valid bool
+	}{
+		{"", true},
+		{"container=app&tailLines=100&sinceSeconds=60&timestamps=true", true},
+		{"follow=true", true},
+		{"tailLines=0", true},
+		{"tailLines=-1", false},
+		{"tailLines=ten", false},
+		{"sinceSeconds=0", false},
+		{"sinceSeconds=1.5", false},
+		{"container=App_1", false},
+		{"container=app%2F..", false},
+		{"follow=yes", false},
+		{"previous=true&follow=true", false},
+	}
+
+	for _, test := range tests {
+		c, _ := newPodLogsContext(context.Background(), test.query)
+		_, err := parsePodLogOptions(c)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("query %q: expected valid=%t, got error %v", test.query, test.valid, err)
+		}
+	}
+
+	c, _ := newPodLogsContext(context.Background(), "container=app&tailLines=100&sinceSeconds=60")
+	options, err := parsePodLogOptions(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Container != "app" || *options.TailLines != 100 || *options.SinceSeconds != 60 || options.Follow {
+		t.Errorf("unexpected options: %+v", options)
+	}
+}
+
+func TestWritePodLogsFollow(t *testing.T) {
+	reader, writer := io.Pipe()
+	c, recorder := newPodLogsContext(context.Background(), "follow=true")
+
+	done := make(chan struct{})
+	go func() {
+		writePodLogs(c, reader, true)
+		close(done)
+	}()
+
+	io.WriteString(writer, "first line\nsecond ")
+	io.WriteString(writer, "line\n")
+	writer.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("streaming didn't finish at the end of the logs")
+	}
+
+	if body := recorder.Body.String(); body != "first line\nsecond line\n" {
+		t.Errorf("unexpected logs: %q", body)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("unexpected content type: %s", contentType)
+	}
+}
+
+func TestWritePodLogsClientDisconnect(t *testing.T) {
+	reader, writer := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	c, _ := newPodLogsContext(ctx, "follow=true")
+
+	done := make(chan struct{})
+	go func() {
+		writePodLogs(c, reader, true)
+		close(done)
+	}()
+
+	io.WriteString(writer, "line\n")
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("streaming didn't stop when the client disconnected")
+	}
+
+	// the log stream is closed, so the container's logs are not read anymore
+	if _, err := io.WriteString(writer, "line\n"); err != io.ErrClosedPipe {
+		t.Errorf("expected the log stream to be closed, got %v", err)
+	}
+}
+
+func TestWritePodLogs(t *testing.T) {
+	c, recorder := newPodLogsContext(context.Background(), "")
+
+	writePodLogs(c, ioutil.NopCloser(strings.NewReader("line 1\nline 2")), false)
+
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "line 1\nline 2" {
+		t.Errorf("unexpected response: %d %q", recorder.Code, recorder.Body.String())
+	}
+}
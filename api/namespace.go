@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/cluster"
+	pkgNamespace "github.com/banzaicloud/pipeline/pkg/cluster/namespace"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ListNamespaces lists the namespaces of the cluster with their resource quotas and limit ranges
+func ListNamespaces(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	namespaces, err := cluster.ListNamespaces(commonCluster)
+	if err != nil {
+		respondWithNamespaceError(c, err, "Error listing namespaces")
+		return
+	}
+
+	c.JSON(http.StatusOK, namespaces)
+}
+
+// GetNamespace returns a namespace of the cluster with its resource quota and limit range
+func GetNamespace(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	namespace, err := cluster.GetNamespace(commonCluster, c.Param("namespace"))
+	if err != nil {
+		respondWithNamespaceError(c, err, "Error getting namespace")
+		return
+	}
+
+	c.JSON(http.StatusOK, namespace)
+}
+
+// CreateNamespace creates a namespace in the cluster with resource quota, limit range and organization secrets
+func CreateNamespace(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	var request pkgNamespace.Request
+	if err := c.BindJSON(&request); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	namespace, err := cluster.CreateNamespace(commonCluster, &request)
+	if err != nil {
+		respondWithNamespaceError(c, err, "Error creating namespace")
+		return
+	}
+
+	c.JSON(http.StatusCreated, namespace)
+}
+
+// UpdateNamespace merges the labels and annotations, and replaces the resource quota and limit range of a namespace
+func UpdateNamespace(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	var request pkgNamespace.Request
+	if err := c.BindJSON(&request); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	namespace, err := cluster.UpdateNamespace(commonCluster, c.Param("namespace"), &request)
+	if err != nil {
+		respondWithNamespaceError(c, err, "Error updating namespace")
+		return
+	}
+
+	c.JSON(http.StatusOK, namespace)
+}
+
+// DeleteNamespace deletes a namespace of the cluster with all of its resources
+func DeleteNamespace(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	if err := cluster.DeleteNamespace(commonCluster, c.Param("namespace")); err != nil {
+		respondWithNamespaceError(c, err, "Error deleting namespace")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+func respondWithNamespaceError(c *gin.Context, err error, message string) {
+	log.Errorf("%s: %s", message, err.Error())
+
+	code := http.StatusBadRequest
+	switch {
+	case k8sErrors.IsNotFound(err):
+		code = http.StatusNotFound
+	case k8sErrors.IsAlreadyExists(err):
+		code = http.StatusConflict
+	case err == cluster.ErrProtectedNamespace:
+		code = http.StatusForbidden
+	}
+
+	c.JSON(code, pkgCommon.ErrorResponse{
+		Code:    code,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
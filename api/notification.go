@@ -0,0 +1,320 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/notify"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	pkgNotify "github.com/banzaicloud/pipeline/pkg/notify"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// ListNotificationChannels lists the notification channels of the organization
+func ListNotificationChannels(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	channels, err := notify.ListChannels(organization.ID)
+	if err != nil {
+		respondWithNotificationQueryError(c, err, "Notification channel not found")
+		return
+	}
+
+	response := make([]*pkgNotify.ChannelResponse, 0, len(channels))
+	for _, channel := range channels {
+		response = append(response, channel.ToResponse())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetNotificationChannel returns a notification channel of the organization
+func GetNotificationChannel(c *gin.Context) {
+	channel, ok := getNotificationChannelFromRequest(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, channel.ToResponse())
+}
+
+// CreateNotificationChannel creates a notification channel for the organization
+func CreateNotificationChannel(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	channel := &notify.ChannelModel{
+		OrganizationID: organization.ID,
+		CreatedBy:      auth.GetCurrentUser(c.Request).ID,
+	}
+	if !saveNotificationChannel(c, channel) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, channel.ToResponse())
+}
+
+// UpdateNotificationChannel updates a notification channel of the organization
+func UpdateNotificationChannel(c *gin.Context) {
+	channel, ok := getNotificationChannelFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if !saveNotificationChannel(c, channel) {
+		return
+	}
+
+	c.JSON(http.StatusOK, channel.ToResponse())
+}
+
+// DeleteNotificationChannel deletes a notification channel and its subscriptions
+func DeleteNotificationChannel(c *gin.Context) {
+	channel, ok := getNotificationChannelFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := channel.Delete(); err != nil {
+		log.Errorf("Error deleting notification channel: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error deleting notification channel",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TestNotificationChannel sends a test notification to the channel
+func TestNotificationChannel(c *gin.Context) {
+	channel, ok := getNotificationChannelFromRequest(c)
+	if !ok {
+		return
+	}
+
+	event := &notify.Event{
+		Type:           "test",
+		OrganizationID: channel.OrganizationID,
+		Message:        "Test notification of channel " + channel.Name,
+	}
+	if err := notify.Send(channel, event); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error sending test notification",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListNotificationSubscriptions lists the notification subscriptions of the organization
+func ListNotificationSubscriptions(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	subscriptions, err := notify.ListSubscriptions(organization.ID)
+	if err != nil {
+		respondWithNotificationQueryError(c, err, "Notification subscription not found")
+		return
+	}
+
+	response := make([]*pkgNotify.SubscriptionResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		response = append(response, subscription.ToResponse())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateNotificationSubscription subscribes a notification channel to event types
+func CreateNotificationSubscription(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	var request pkgNotify.SubscriptionRequest
+	if err := c.BindJSON(&request); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid subscription",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if _, err := notify.GetChannel(organization.ID, request.ChannelID); err != nil {
+		respondWithNotificationQueryError(c, err, "Notification channel not found")
+		return
+	}
+
+	subscription := &notify.SubscriptionModel{
+		OrganizationID: organization.ID,
+		ChannelID:      request.ChannelID,
+		Events:         strings.Join(request.Events, ","),
+		CreatedBy:      auth.GetCurrentUser(c.Request).ID,
+	}
+	if err := subscription.Save(); err != nil {
+		log.Errorf("Error saving notification subscription: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error saving notification subscription",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription.ToResponse())
+}
+
+// DeleteNotificationSubscription deletes a notification subscription of the organization
+func DeleteNotificationSubscription(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	subscriptionID, err := strconv.ParseUint(c.Param("subscriptionid"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid subscription id",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	subscription, err := notify.GetSubscription(organization.ID, uint(subscriptionID))
+	if err != nil {
+		respondWithNotificationQueryError(c, err, "Notification subscription not found")
+		return
+	}
+
+	if err := subscription.Delete(); err != nil {
+		log.Errorf("Error deleting notification subscription: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error deleting notification subscription",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// getNotificationChannelFromRequest looks up the channel identified by the channelid path parameter,
+// this handles error messages directly
+func getNotificationChannelFromRequest(c *gin.Context) (*notify.ChannelModel, bool) {
+	channelID, err := strconv.ParseUint(c.Param("channelid"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid channel id",
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+
+	channel, err := notify.GetChannel(auth.GetCurrentOrganization(c.Request).ID, uint(channelID))
+	if err != nil {
+		respondWithNotificationQueryError(c, err, "Notification channel not found")
+		return nil, false
+	}
+
+	return channel, true
+}
+
+// saveNotificationChannel binds and validates the channel request and saves it into the channel,
+// this handles error messages directly
+func saveNotificationChannel(c *gin.Context, channel *notify.ChannelModel) bool {
+	var request pkgNotify.ChannelRequest
+	if err := c.BindJSON(&request); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	err := request.Validate()
+	if err == nil {
+		var channelSecret *secret.SecretItemResponse
+		if channelSecret, err = secret.Store.Get(channel.OrganizationID, request.SecretID); err == nil {
+			err = notify.ValidateChannelSecret(request.Type, channelSecret)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid notification channel",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	channels, err := notify.ListChannels(channel.OrganizationID)
+	if err != nil {
+		respondWithNotificationQueryError(c, err, "Notification channel not found")
+		return false
+	}
+	for _, other := range channels {
+		if other.Name == request.Name && other.ID != channel.ID {
+			c.JSON(http.StatusConflict, pkgCommon.ErrorResponse{
+				Code:    http.StatusConflict,
+				Message: "Notification channel already exists",
+				Error:   "a channel with the same name already exists",
+			})
+			return false
+		}
+	}
+
+	channel.Name = request.Name
+	channel.Type = request.Type
+	channel.SecretID = request.SecretID
+	channel.SlackChannel = request.SlackChannel
+	channel.Recipients = strings.Join(request.Recipients, ",")
+
+	if err := channel.Save(); err != nil {
+		log.Errorf("Error saving notification channel: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error saving notification channel",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+func respondWithNotificationQueryError(c *gin.Context, err error, notFoundMessage string) {
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: notFoundMessage,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	log.Errorf("Error querying notifications: %s", err.Error())
+	c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "Error querying notifications",
+		Error:   err.Error(),
+	})
+}
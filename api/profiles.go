@@ -3,12 +3,14 @@ package api
 import (
 	"net/http"
 
+	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/database"
 	"github.com/banzaicloud/pipeline/model/defaults"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
 	pkgErrors "github.com/banzaicloud/pipeline/pkg/errors"
 	oracle "github.com/banzaicloud/pipeline/pkg/providers/oracle/model"
+	"github.com/banzaicloud/pipeline/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 )
@@ -23,9 +25,10 @@ const (
 func GetClusterProfiles(c *gin.Context) {
 
 	cloudType := c.Param(cloudTypeKey)
-	log.Infof("Start getting saved cluster profiles [%s]", cloudType)
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+	log.Infof("Start getting saved cluster profiles [%s] of organization [%d]", cloudType, organizationID)
 
-	resp, err := getProfiles(cloudType)
+	resp, err := getProfiles(organizationID, cloudType)
 	if err != nil {
 		log.Errorf("Error during getting defaults to %s: %s", cloudType, err.Error())
 		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
@@ -41,7 +44,8 @@ func GetClusterProfiles(c *gin.Context) {
 
 // AddClusterProfile handles /profiles/cluster/:type POST api endpoint.
 // Saves ClusterProfileRequest data into the database.
-// Saving failed if profile with the given name is already exists
+// Saving failed if profile with the given name is already exists in the organization.
+// The values which are not set in the request are inherited from the extended profile (if any) whenever the profile is read.
 func AddClusterProfile(c *gin.Context) {
 
 	log.Info("Start getting save cluster profile")
@@ -61,12 +65,17 @@ func AddClusterProfile(c *gin.Context) {
 	log.Info("Parsing request succeeded")
 	log.Infof("Convert ClusterProfileRequest into ClusterProfile model with name: %s", profileRequest.Name)
 
+	profileRequest.OrganizationId = auth.GetCurrentOrganization(c.Request).ID
+
 	// convert request into ClusterProfile model
 	if prof, err := convertRequestToProfile(&profileRequest); err != nil {
-		log.Error("Error during convert profile: &s", err.Error())
+		log.Errorf("Error during convert profile: %s", err.Error())
+		sendBackGetProfileErrorResponse(c, err)
+	} else if err := validateProfile(prof, profileRequest.OrganizationId, profileRequest.SecretId); err != nil {
+		log.Errorf("Error during validate profile: %s", err.Error())
 		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
 			Code:    http.StatusBadRequest,
-			Message: "Error during convert profile",
+			Message: "Error during validate profile",
 			Error:   err.Error(),
 		})
 	} else if !prof.IsDefinedBefore() {
@@ -98,11 +107,11 @@ func AddClusterProfile(c *gin.Context) {
 
 }
 
-// getProfiles loads cluster profiles from database by cloud type
-func getProfiles(cloudType string) ([]pkgCluster.ClusterProfileResponse, error) {
+// getProfiles loads cluster profiles of the organization from database by cloud type
+func getProfiles(organizationID uint, cloudType string) ([]pkgCluster.ClusterProfileResponse, error) {
 
 	var response []pkgCluster.ClusterProfileResponse
-	profiles, err := defaults.GetAllProfiles(cloudType)
+	profiles, err := defaults.GetAllProfiles(organizationID, cloudType)
 	if err != nil {
 		// error during getting profiles
 		return nil, err
@@ -115,40 +124,130 @@ func getProfiles(cloudType string) ([]pkgCluster.ClusterProfileResponse, error)
 
 }
 
-// convertRequestToProfile converts a ClusterProfileRequest into ClusterProfile
+// convertRequestToProfile converts a ClusterProfileRequest into ClusterProfile,
+// in case of an extended profile the request's values are applied on the extended profile's values and kept as its own values
 func convertRequestToProfile(request *pkgCluster.ClusterProfileRequest) (defaults.ClusterProfile, error) {
 
+	var extendedRequest *pkgCluster.ClusterProfileRequest
+	if len(request.Extends) != 0 {
+		log.Infof("Load extended profile: %s[%s]", request.Extends, request.Cloud)
+		extended, err := defaults.GetProfile(request.OrganizationId, request.Cloud, request.Extends)
+		if err != nil {
+			return nil, err
+		}
+		extendedRequest = extended.GetProfile().ClusterProfileRequest()
+	}
+
+	var profile defaults.ClusterProfile
 	switch request.Cloud {
 	case pkgCluster.Amazon:
-		if request.Properties.Amazon != nil {
-			var awsProfile defaults.AWSProfile
-			awsProfile.UpdateProfile(request, false)
-			return &awsProfile, nil
+		if request.Properties.Amazon != nil || (extendedRequest != nil && extendedRequest.Properties.Amazon != nil) {
+			profile = &defaults.AWSProfile{}
+		} else {
+			profile = &defaults.EKSProfile{}
 		}
-		var eksProfile defaults.EKSProfile
-		eksProfile.UpdateProfile(request, false)
-		return &eksProfile, nil
 	case pkgCluster.Azure:
-		var aksProfile defaults.AKSProfile
-		aksProfile.UpdateProfile(request, false)
-		return &aksProfile, nil
+		profile = &defaults.AKSProfile{}
 	case pkgCluster.Google:
-		var gkeProfile defaults.GKEProfile
-		gkeProfile.UpdateProfile(request, false)
-		return &gkeProfile, nil
+		profile = &defaults.GKEProfile{}
 	case pkgCluster.Oracle:
-		var okeProfile oracle.Profile
-		okeProfile.UpdateProfile(request, false)
-		return &okeProfile, nil
+		profile = &oracle.Profile{}
 	default:
 		return nil, pkgErrors.ErrorNotSupportedCloudType
 	}
 
+	if extendedRequest != nil {
+		profile.UpdateProfile(extendedRequest, false)
+		defaults.UpdateOverrides(profile, request)
+	}
+	profile.UpdateProfile(request, false)
+
+	return profile, nil
+}
+
+// validateProfile validates the profile's autoscaler settings, and its location and instance types against cloud info,
+// the cloud info validation is skipped in case no secret is given
+func validateProfile(profile defaults.ClusterProfile, organizationID uint, secretID string) error {
+
+	p := profile.GetProfile()
+
+	if err := p.Autoscaler.Validate(); err != nil {
+		return err
+	}
+
+	if len(secretID) == 0 {
+		log.Info("Secret id is empty, skip validating profile against cloud info")
+		return nil
+	}
+
+	// EKS node pools use the instance types and locations of Amazon
+	cloudType := p.Cloud
+	if cloudType == pkgCluster.AmazonEKS {
+		cloudType = pkgCluster.Amazon
+	}
+
+	log.Infof("Validate profile %s[%s] against cloud info", p.Name, p.Cloud)
+	info, err := processCloudInfo(cloudType, &pkgCluster.CloudInfoRequest{
+		OrganizationId: organizationID,
+		SecretId:       secretID,
+		Filter: &pkgCluster.CloudInfoFilter{
+			Fields: []string{pkgCluster.KeyWordLocation, pkgCluster.KeyWordInstanceType},
+			InstanceType: &pkgCluster.InstanceFilter{
+				Location: p.Location,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if !utils.Contains(info.Locations, p.Location) {
+		return pkgErrors.ErrorNotValidLocation
+	}
+
+	for _, instanceType := range getProfileInstanceTypes(p) {
+		if !utils.Contains(info.NodeInstanceType[p.Location], instanceType) {
+			return pkgErrors.ErrorNotValidNodeInstanceType
+		}
+	}
+
+	log.Info("Validate profile passed")
+
+	return nil
+}
+
+// getProfileInstanceTypes collects the instance types used by the profile's node pools
+func getProfileInstanceTypes(p *pkgCluster.ClusterProfileResponse) (instanceTypes []string) {
+
+	switch {
+	case p.Properties.Amazon != nil:
+		for _, np := range p.Properties.Amazon.NodePools {
+			instanceTypes = append(instanceTypes, np.InstanceType)
+		}
+	case p.Properties.Eks != nil:
+		for _, np := range p.Properties.Eks.NodePools {
+			instanceTypes = append(instanceTypes, np.InstanceType)
+		}
+	case p.Properties.Azure != nil:
+		for _, np := range p.Properties.Azure.NodePools {
+			instanceTypes = append(instanceTypes, np.NodeInstanceType)
+		}
+	case p.Properties.Google != nil:
+		for _, np := range p.Properties.Google.NodePools {
+			instanceTypes = append(instanceTypes, np.NodeInstanceType)
+		}
+	case p.Properties.Oracle != nil:
+		for _, np := range p.Properties.Oracle.NodePools {
+			instanceTypes = append(instanceTypes, np.Shape)
+		}
+	}
+
+	return
 }
 
 // UpdateClusterProfile handles /cluster/profiles/:type PUT api endpoint.
-// Updates existing cluster profiles.
-// Updating failed if the name is the default name.
+// Updates existing cluster profiles of the organization.
+// Updating failed if the profile is a global one.
 func UpdateClusterProfile(c *gin.Context) {
 
 	log.Debug("Bind json into ClusterProfileRequest struct")
@@ -165,25 +264,58 @@ func UpdateClusterProfile(c *gin.Context) {
 	}
 	log.Debug("Parsing request succeeded")
 
-	if defaults.GetDefaultProfileName() == profileRequest.Name {
-		// default profiles cannot updated
-		log.Error("The default profile cannot be updated") // todo move to constants
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	log.Infof("Load cluster from database: %s[%s]", profileRequest.Name, profileRequest.Cloud)
+
+	// load cluster profile from database, the extended profile is resolved after the update
+	profile, err := defaults.LoadProfile(organizationID, profileRequest.Cloud, profileRequest.Name)
+	if err != nil {
+		// load from db failed
+		log.Error(errors.Wrap(err, "Error during getting profile"))
+		sendBackGetProfileErrorResponse(c, err)
+		return
+	}
+
+	if profile.GetOrganizationID() != organizationID {
+		// global profiles cannot updated
+		log.Error("Global profiles cannot be updated")
 		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
 			Code:    http.StatusBadRequest,
-			Message: "The default profile cannot be updated",
-			Error:   "The default profile cannot be updated",
+			Message: "Global profiles cannot be updated",
+			Error:   "Global profiles cannot be updated",
 		})
 		return
 	}
 
-	log.Infof("Load cluster from database: %s[%s]", profileRequest.Name, profileRequest.Cloud)
+	// the extended profile can't be changed
+	profileRequest.OrganizationId = organizationID
+	profileRequest.Extends = profile.GetProfile().Extends
+	if profile.GetOverrides() != nil {
+		defaults.UpdateOverrides(profile, &profileRequest)
+	}
 
-	// load cluster profile from database
-	if profile, err := defaults.GetProfile(profileRequest.Cloud, profileRequest.Name); err != nil {
-		// load from db failed
-		log.Error(errors.Wrap(err, "Error during getting profile"))
-		sendBackGetProfileErrorResponse(c, err)
-	} else if err := profile.UpdateProfile(&profileRequest, true); err != nil {
+	if err := profile.UpdateProfile(&profileRequest, false); err != nil {
+		log.Error(errors.Wrap(err, "Error during update profile"))
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error during update profile",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// the saved values of an extending profile are its resolved values
+	defaults.ResolveExtends(profile, profileRequest.Cloud)
+
+	if err := validateProfile(profile, organizationID, profileRequest.SecretId); err != nil {
+		log.Errorf("Error during validate profile: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error during validate profile",
+			Error:   err.Error(),
+		})
+	} else if err := profile.SaveInstance(); err != nil {
 		// updating failed
 		log.Error(errors.Wrap(err, "Error during update profile"))
 		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
@@ -200,32 +332,30 @@ func UpdateClusterProfile(c *gin.Context) {
 }
 
 // DeleteClusterProfile handles /cluster/profiles/:type/:name DELETE api endpoint.
-// Deletes saved cluster profile.
-// Deleting failed if the name is the default name.
+// Deletes saved cluster profile of the organization.
+// Deleting failed if the profile is a global one.
 func DeleteClusterProfile(c *gin.Context) {
 
 	cloudType := c.Param(cloudTypeKey)
 	name := c.Param(nameKey)
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
 	log.Infof("Start deleting cluster profile: %s[%s]", name, cloudType)
 
-	if defaults.GetDefaultProfileName() == name {
-		// default profile cannot deleted
-		log.Error("The default profile cannot be deleted")
-		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
-			Code:    http.StatusBadRequest,
-			Message: "The default profile cannot be deleted",
-			Error:   "The default profile cannot be deleted",
-		})
-		return
-	}
-
 	log.Infof("Load cluster profile from database: %s[%s]", name, cloudType)
 
 	// load cluster profile from database
-	if profile, err := defaults.GetProfile(cloudType, name); err != nil {
+	if profile, err := defaults.LoadProfile(organizationID, cloudType, name); err != nil {
 		// load from database failed
 		log.Error(errors.Wrap(err, "Error during getting profile"))
 		sendBackGetProfileErrorResponse(c, err)
+	} else if profile.GetOrganizationID() != organizationID {
+		// global profile cannot deleted
+		log.Error("Global profiles cannot be deleted")
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Global profiles cannot be deleted",
+			Error:   "Global profiles cannot be deleted",
+		})
 	} else {
 		log.Info("Getting profile succeeded")
 		log.Info("Delete from database")
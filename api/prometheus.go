@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/config"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// GetPrometheusTargets returns the clusters of the organization in Prometheus' HTTP service discovery format,
+// clusters can be selected by the `labels` query param (label selector syntax)
+func GetPrometheusTargets(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	selector, err := labels.Parse(c.Query("labels"))
+	if err != nil {
+		log.Errorf("Error parsing label selector: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing label selector",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	address := viper.GetString(config.MonitorSDAddress)
+	if len(address) == 0 {
+		address = c.Request.Host
+	}
+
+	scheme := viper.GetString(config.MonitorSDScheme)
+	if len(scheme) == 0 {
+		scheme = "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		} else if proto := c.GetHeader("X-Forwarded-Proto"); len(proto) != 0 {
+			scheme = proto
+		}
+	}
+
+	targets, err := cluster.GetPrometheusTargets(organization.ID, organization.Name, selector, address, scheme)
+	if err != nil {
+		log.Errorf("Error listing Prometheus targets: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error listing Prometheus targets",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, targets)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/config"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// GetClusterRecommendations recommends instance types and node counts for the node pools of the cluster
+// based on the requests of the running and unschedulable pods
+func GetClusterRecommendations(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	headroom := viper.GetFloat64(config.RecommendationHeadroom)
+	if value := c.Query("headroom"); len(value) != 0 {
+		var err error
+		if headroom, err = strconv.ParseFloat(value, 64); err != nil || headroom < 0 {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Error parsing request",
+				Error:   "headroom must be a non-negative number",
+			})
+			return
+		}
+	}
+	withUpdateRequest, _ := strconv.ParseBool(c.Query("updateRequest"))
+
+	candidates := getRecommendationCandidates(commonCluster)
+
+	recommendations, err := cluster.GetNodePoolRecommendations(commonCluster, candidates, headroom, withUpdateRequest)
+	if err != nil {
+		log.Errorf("Error during recommending node pools: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error during recommending node pools",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, recommendations)
+}
+
+// getRecommendationCandidates returns the machine types available in the location of the cluster,
+// the recommendations are limited to the current instance types when they can't be listed
+func getRecommendationCandidates(commonCluster cluster.CommonCluster) []string {
+	location := commonCluster.GetModel().Location
+	cloudInfo, err := processCloudInfo(commonCluster.GetType(), &pkgCluster.CloudInfoRequest{
+		OrganizationId: commonCluster.GetOrganizationId(),
+		SecretId:       commonCluster.GetSecretId(),
+		Filter: &pkgCluster.CloudInfoFilter{
+			Fields:       []string{pkgCluster.KeyWordInstanceType},
+			InstanceType: &pkgCluster.InstanceFilter{Location: location},
+		},
+	})
+	if err != nil {
+		log.Warnf("Error listing machine types: %s", err.Error())
+		return nil
+	}
+
+	return cloudInfo.NodeInstanceType[location]
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/auth"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListRoles lists the built-in and custom roles of the organization, the role of the calling user is marked as current
+func ListRoles(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	currentRole, err := auth.GetUserOrganizationRole(auth.GetCurrentUser(c.Request), organization.ID)
+	if err != nil {
+		log.Debugf("Error getting role of the user: %s", err.Error())
+	}
+
+	roles := auth.GetOrgRoles(organization.ID)
+	for i := range roles {
+		roles[i].Current = roles[i].Name == currentRole
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// SetRole creates or replaces a custom role of the organization.
+// Only organization admins are allowed to change roles.
+func SetRole(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	role, err := auth.GetUserOrganizationRole(auth.GetCurrentUser(c.Request), organization.ID)
+	if err != nil || role != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "Only organization admins can change roles",
+			Error:   "forbidden",
+		})
+		return
+	}
+
+	var request struct {
+		Rules []auth.RoleRule `json:"rules" binding:"required,dive"`
+	}
+	if err := c.BindJSON(&request); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	customRole := auth.Role{Name: c.Param("role"), Rules: request.Rules}
+	if err := auth.SetOrgRole(organization.ID, customRole); err != nil {
+		log.Infof("Error saving role: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error saving role",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, customRole)
+}
+
+// DeleteRole deletes a custom role of the organization, roles assigned to users can't be deleted.
+// Only organization admins are allowed to delete roles.
+func DeleteRole(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	role, err := auth.GetUserOrganizationRole(auth.GetCurrentUser(c.Request), organization.ID)
+	if err != nil || role != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "Only organization admins can delete roles",
+			Error:   "forbidden",
+		})
+		return
+	}
+
+	name := c.Param("role")
+	if auth.GetOrgRole(organization.ID, name) == nil {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Role not found",
+			Error:   "role not found",
+		})
+		return
+	}
+
+	if err := auth.DeleteOrgRole(organization.ID, name); err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err {
+		case auth.ErrBuiltInRole:
+			statusCode = http.StatusBadRequest
+		case auth.ErrRoleInUse:
+			statusCode = http.StatusConflict
+		}
+		log.Infof("Error deleting role: %s", err.Error())
+		c.JSON(statusCode, pkgCommon.ErrorResponse{
+			Code:    statusCode,
+			Message: "Error deleting role",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -5,14 +5,17 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/banzaicloud/pipeline/audit"
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/model"
 	"github.com/banzaicloud/pipeline/pkg/common"
 	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	pkgWebhook "github.com/banzaicloud/pipeline/pkg/webhook"
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/banzaicloud/pipeline/secret/verify"
 	"github.com/banzaicloud/pipeline/utils"
+	"github.com/banzaicloud/pipeline/webhook"
 	"github.com/gin-gonic/gin"
 	"github.com/go-errors/errors"
 )
@@ -140,6 +143,7 @@ func AddSecrets(c *gin.Context) {
 	}
 
 	log.Infof("Secret stored at: %d/%s", organizationID, secretID)
+	audit.SetResource(c, audit.ResourceSecret, secretID)
 
 	var errorMsg string
 	if validationError != nil {
@@ -157,6 +161,8 @@ func AddSecrets(c *gin.Context) {
 		return
 	}
 
+	publishSecretWebhookEvent(c, organizationID, pkgWebhook.EventSecretCreated, s)
+
 	c.JSON(http.StatusCreated, secret.CreateSecretResponse{
 		Name:      s.Name,
 		Type:      s.Type,
@@ -263,6 +269,8 @@ func UpdateSecrets(c *gin.Context) {
 		errorMsg = validationError.Error()
 	}
 
+	publishSecretWebhookEvent(c, organizationID, pkgWebhook.EventSecretUpdated, s)
+
 	c.JSON(http.StatusOK, secret.CreateSecretResponse{
 		Name:      s.Name,
 		Type:      s.Type,
@@ -343,6 +351,9 @@ func DeleteSecrets(c *gin.Context) {
 
 	secretID := c.Param("id")
 
+	// the secret is looked up before deletion to send its details with the event
+	deletedSecret, _ := secret.Store.Get(organizationID, secretID)
+
 	log.Infof("Check clusters before delete secret[%s]", secretID)
 	if err := checkClustersBeforeDelete(organizationID, secretID); err != nil {
 		log.Errorf("Cluster found with this secret[%s]: %s", secretID, err.Error())
@@ -369,10 +380,34 @@ func DeleteSecrets(c *gin.Context) {
 		c.AbortWithStatusJSON(code, resp)
 	} else {
 		log.Info("Delete secrets succeeded")
+		if deletedSecret != nil {
+			publishSecretWebhookEvent(c, organizationID, pkgWebhook.EventSecretDeleted, deletedSecret)
+		}
 		c.Status(http.StatusNoContent)
 	}
 }
 
+// publishSecretWebhookEvent sends a lifecycle event of the secret to the subscribed webhooks of the organization,
+// secret values are never sent
+func publishSecretWebhookEvent(c *gin.Context, organizationID uint, eventType string, s *secret.SecretItemResponse) {
+	webhook.Publish(webhook.Event{
+		Type:           eventType,
+		OrganizationID: organizationID,
+		Resource: pkgWebhook.Resource{
+			Type: pkgWebhook.ResourceSecret,
+			ID:   s.ID,
+			Name: s.Name,
+		},
+		Actor: webhook.ActorFromUser(auth.GetCurrentUser(c.Request)),
+		Payload: pkgWebhook.SecretPayload{
+			ID:   s.ID,
+			Name: s.Name,
+			Type: s.Type,
+			Tags: s.Tags,
+		},
+	})
+}
+
 // ListAllowedSecretTypes returns the allowed secret types and the required keys
 func ListAllowedSecretTypes(c *gin.Context) {
 
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/auth"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+)
+
+// GetTokenPolicy returns the policy limiting the lifetime of the API tokens having access to the organization
+func GetTokenPolicy(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	policy, err := auth.GetTokenPolicy(organization.ID)
+	if err != nil {
+		log.Errorf("Error getting token policy: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error getting token policy",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetTokenPolicy replaces the token policy of the organization, it applies to the tokens created afterwards.
+// Only organization admins are allowed to change the policy.
+func SetTokenPolicy(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	role, err := auth.GetUserOrganizationRole(auth.GetCurrentUser(c.Request), organization.ID)
+	if err != nil || role != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "Only organization admins can change the token policy",
+			Error:   "forbidden",
+		})
+		return
+	}
+
+	var policy auth.TokenPolicy
+	if err := c.BindJSON(&policy); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if policy.MaxLifetimeDays < 0 {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Maximum token lifetime can't be negative",
+			Error:   "invalid maxLifetimeDays",
+		})
+		return
+	}
+	policy.OrganizationID = organization.ID
+
+	if err := auth.SetTokenPolicy(&policy); err != nil {
+		log.Errorf("Error saving token policy: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error saving token policy",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/banzaicloud/pipeline/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// defaultUsageRange is the length of the returned usage time series when the request doesn't specify from
+const defaultUsageRange = 24 * time.Hour
+
+// GetClusterUsage returns the sampled resource summary of the cluster and its node pools as time series
+func GetClusterUsage(c *gin.Context) {
+	commonCluster, ok := GetCommonClusterFromRequest(c)
+	if ok != true {
+		return
+	}
+
+	from, to, step, err := parseUsageRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	usage, err := cluster.GetClusterUsage(commonCluster.GetID(), from, to, step)
+	if err != nil {
+		log.Errorf("Error getting cluster usage: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error getting cluster usage",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// parseUsageRange parses the RFC3339 from and to and the step duration query parameters
+func parseUsageRange(c *gin.Context) (from, to time.Time, step time.Duration, err error) {
+	to = time.Now().UTC()
+	if value := c.Query("to"); len(value) != 0 {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			err = errors.Wrap(err, "invalid to")
+			return
+		}
+	}
+
+	from = to.Add(-defaultUsageRange)
+	if value := c.Query("from"); len(value) != 0 {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			err = errors.Wrap(err, "invalid from")
+			return
+		}
+	}
+
+	if !from.Before(to) {
+		err = errors.New("from must be earlier than to")
+		return
+	}
+
+	step = cluster.DefaultUsageStep(from, to)
+	if value := c.Query("step"); len(value) != 0 {
+		if step, err = time.ParseDuration(value); err != nil {
+			err = errors.Wrap(err, "invalid step")
+			return
+		}
+		if step < time.Minute {
+			err = errors.New("step must be at least 1m")
+			return
+		}
+		if to.Sub(from)/step > cluster.MaxUsagePoints {
+			err = errors.Errorf("step is too short, the range can contain at most %d steps", cluster.MaxUsagePoints)
+			return
+		}
+	}
+
+	return
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/database"
 	"github.com/banzaicloud/pipeline/pkg/common"
 	"github.com/gin-gonic/gin"
@@ -65,7 +66,8 @@ func GetUsers(c *gin.Context) {
 	}
 }
 
-// AddUser adds a user to an organization, role=admin|member has to be in the body, otherwise member is the default role.
+// AddUser adds a user to an organization or changes the user's role, the role can be one of the
+// built-in (admin, member, viewer) or custom roles of the organization, member is the default role.
 func AddUser(c *gin.Context) {
 
 	log.Info("Adding user to organization")
@@ -84,8 +86,8 @@ func AddUser(c *gin.Context) {
 	}
 
 	role := struct {
-		Role string `json:"role" binding:"required,eq=member|eq=admin"`
-	}{Role: "member"}
+		Role string `json:"role" binding:"required"`
+	}{Role: auth.RoleMember}
 
 	if c.Request.ContentLength != 0 {
 		err = c.ShouldBindJSON(&role)
@@ -104,6 +106,23 @@ func AddUser(c *gin.Context) {
 	organization := auth.GetCurrentOrganization(c.Request)
 	user := &auth.User{ID: uint(id)}
 
+	if auth.GetOrgRole(organization.ID, role.Role) == nil {
+		message := fmt.Sprintf("role not found: %q", role.Role)
+		log.Info(message)
+		c.JSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: message,
+			Error:   message,
+		})
+		return
+	}
+
+	if role.Role != auth.RoleAdmin && !checkLastOrgAdmin(c, organization.ID, user.ID) {
+		return
+	}
+
+	previousRole, _ := auth.GetUserOrganizationRole(user, organization.ID)
+
 	err = addUserToOrgInDb(organization, user, role.Role)
 
 	if err != nil {
@@ -120,6 +139,11 @@ func AddUser(c *gin.Context) {
 
 	auth.AddOrgRoleForUser(user.ID, organization.ID)
 
+	// the cluster roles the credentials are bound to depend on the organization role
+	if previousRole != "" && previousRole != role.Role {
+		go cluster.DeleteUserCredentials(organization.ID, user.ID)
+	}
+
 	c.Status(http.StatusNoContent)
 }
 
@@ -144,6 +168,29 @@ func addUserToOrgInDb(organization *auth.Organization, user *auth.User, role str
 	return tx.Commit().Error
 }
 
+// checkLastOrgAdmin responds with 409 Conflict if the user is the last admin of the organization
+func checkLastOrgAdmin(c *gin.Context, orgID, userID uint) bool {
+	err := auth.CheckLastOrgAdmin(orgID, userID)
+	if err == auth.ErrLastAdmin {
+		c.JSON(http.StatusConflict, common.ErrorResponse{
+			Code:    http.StatusConflict,
+			Message: "The last admin of the organization can't be demoted or removed",
+			Error:   err.Error(),
+		})
+		return false
+	} else if err != nil {
+		message := "failed to check the admins of the organization: " + err.Error()
+		log.Info(message)
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: message,
+			Error:   message,
+		})
+		return false
+	}
+	return true
+}
+
 // RemoveUser removes a user from an organization
 func RemoveUser(c *gin.Context) {
 
@@ -164,6 +211,10 @@ func RemoveUser(c *gin.Context) {
 		return
 	}
 
+	if !checkLastOrgAdmin(c, organization.ID, uint(id)) {
+		return
+	}
+
 	db := database.GetDB()
 	err = db.Model(organization).Association("Users").Delete(auth.User{ID: uint(id)}).Error
 	if err != nil {
@@ -178,20 +229,10 @@ func RemoveUser(c *gin.Context) {
 		return
 	}
 
-	user, err := auth.GetCurrentUserFromDB(c.Request)
-	if err != nil {
-		message := "failed to query user for deletion: " + err.Error()
-		log.Info(message)
-		statusCode := auth.GormErrorToStatusCode(err)
-		c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
-			Code:    statusCode,
-			Message: message,
-			Error:   message,
-		})
-		return
-	}
+	auth.DeleteOrgRoleForUser(uint(id), organization.ID)
 
-	auth.DeleteOrgRoleForUser(user.ID, organization.ID)
+	// revoke the cluster credentials issued to the removed user
+	go cluster.DeleteUserCredentials(organization.ID, uint(id))
 
 	c.Status(http.StatusNoContent)
 }
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/config"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	pkgWebhook "github.com/banzaicloud/pipeline/pkg/webhook"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/banzaicloud/pipeline/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+)
+
+// signingSecretLength is the length of the generated webhook signing secrets
+const signingSecretLength = 32
+
+// defaultWebhookDeliveryLimit is the number of deliveries listed by default
+const defaultWebhookDeliveryLimit = 50
+
+// ListWebhooks lists the webhooks of the organization
+func ListWebhooks(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	webhooks, err := webhook.ListWebhooks(organization.ID)
+	if err != nil {
+		respondWithWebhookQueryError(c, err, "Webhook not found")
+		return
+	}
+
+	response := make([]*pkgWebhook.WebhookResponse, 0, len(webhooks))
+	for _, hook := range webhooks {
+		response = append(response, hook.ToResponse())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetWebhook returns a webhook of the organization
+func GetWebhook(c *gin.Context) {
+	hook, ok := getWebhookFromRequest(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, hook.ToResponse())
+}
+
+// CreateWebhook creates a webhook for the organization, the signing secret is returned only in this response
+func CreateWebhook(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	hook := &webhook.WebhookModel{
+		OrganizationID: organization.ID,
+		Active:         true,
+		CreatedBy:      auth.GetCurrentUser(c.Request).ID,
+	}
+	signingSecret, ok := saveWebhook(c, hook)
+	if !ok {
+		return
+	}
+
+	response := hook.ToResponse()
+	response.Secret = signingSecret
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdateWebhook updates a webhook of the organization, the signing secret is replaced only when it's given
+func UpdateWebhook(c *gin.Context) {
+	hook, ok := getWebhookFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := saveWebhook(c, hook); !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, hook.ToResponse())
+}
+
+// DeleteWebhook deletes a webhook of the organization with its delivery log and signing secret
+func DeleteWebhook(c *gin.Context) {
+	hook, ok := getWebhookFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := hook.Delete(); err != nil {
+		log.Errorf("Error deleting webhook: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error deleting webhook",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := secret.Store.Delete(hook.OrganizationID, hook.SecretID); err != nil {
+		log.Warnf("Error deleting signing secret of webhook %d: %s", hook.ID, err.Error())
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PingWebhook sends a ping event to the webhook and returns the delivery
+func PingWebhook(c *gin.Context) {
+	hook, ok := getWebhookFromRequest(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := webhook.Ping(hook, webhook.ActorFromUser(auth.GetCurrentUser(c.Request)))
+	if err != nil {
+		log.Errorf("Error sending ping event: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error sending ping event",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery.ToResponse(true))
+}
+
+// ListWebhookDeliveries lists the latest deliveries of the webhook
+func ListWebhookDeliveries(c *gin.Context) {
+	hook, ok := getWebhookFromRequest(c)
+	if !ok {
+		return
+	}
+
+	limit := defaultWebhookDeliveryLimit
+	if value := c.Query("limit"); len(value) != 0 {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid limit",
+				Error:   "limit must be a positive integer",
+			})
+			return
+		}
+	}
+
+	deliveries, err := webhook.ListDeliveries(hook.ID, limit)
+	if err != nil {
+		respondWithWebhookQueryError(c, err, "Webhook delivery not found")
+		return
+	}
+
+	response := make([]*pkgWebhook.DeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		response = append(response, delivery.ToResponse(false))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetWebhookDelivery returns a delivery of the webhook with the request and the response body
+func GetWebhookDelivery(c *gin.Context) {
+	_, delivery, ok := getWebhookDeliveryFromRequest(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery.ToResponse(true))
+}
+
+// RedeliverWebhookDelivery sends the event of a delivery again and returns the new delivery
+func RedeliverWebhookDelivery(c *gin.Context) {
+	hook, delivery, ok := getWebhookDeliveryFromRequest(c)
+	if !ok {
+		return
+	}
+
+	redelivery, err := webhook.Redeliver(hook, delivery)
+	if err != nil {
+		log.Errorf("Error redelivering webhook delivery: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error redelivering webhook delivery",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, redelivery.ToResponse(true))
+}
+
+// getWebhookFromRequest looks up the webhook identified by the webhookid path parameter,
+// this handles error messages directly
+func getWebhookFromRequest(c *gin.Context) (*webhook.WebhookModel, bool) {
+	webhookID, err := strconv.ParseUint(c.Param("webhookid"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid webhook id",
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+
+	hook, err := webhook.GetWebhook(auth.GetCurrentOrganization(c.Request).ID, uint(webhookID))
+	if err != nil {
+		respondWithWebhookQueryError(c, err, "Webhook not found")
+		return nil, false
+	}
+
+	return hook, true
+}
+
+// getWebhookDeliveryFromRequest looks up the webhook and its delivery identified by the webhookid and deliveryid
+// path parameters, this handles error messages directly
+func getWebhookDeliveryFromRequest(c *gin.Context) (*webhook.WebhookModel, *webhook.DeliveryModel, bool) {
+	hook, ok := getWebhookFromRequest(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	deliveryID, err := strconv.ParseUint(c.Param("deliveryid"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid delivery id",
+			Error:   err.Error(),
+		})
+		return nil, nil, false
+	}
+
+	delivery, err := webhook.GetDelivery(hook.ID, uint(deliveryID))
+	if err != nil {
+		respondWithWebhookQueryError(c, err, "Webhook delivery not found")
+		return nil, nil, false
+	}
+
+	return hook, delivery, true
+}
+
+// saveWebhook binds and validates the webhook request and saves it into the webhook, a signing secret
+// is generated for new webhooks without one. It returns the new signing secret, if any,
+// this handles error messages directly
+func saveWebhook(c *gin.Context, hook *webhook.WebhookModel) (string, bool) {
+	var request pkgWebhook.WebhookRequest
+	if err := c.BindJSON(&request); err != nil {
+		log.Errorf("Error parsing request: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return "", false
+	}
+
+	if err := request.Validate(viper.GetBool(config.WebhookAllowPrivateNetworks)); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid webhook",
+			Error:   err.Error(),
+		})
+		return "", false
+	}
+
+	webhooks, err := webhook.ListWebhooks(hook.OrganizationID)
+	if err != nil {
+		respondWithWebhookQueryError(c, err, "Webhook not found")
+		return "", false
+	}
+	for _, other := range webhooks {
+		if other.Name == request.Name && other.ID != hook.ID {
+			c.JSON(http.StatusConflict, pkgCommon.ErrorResponse{
+				Code:    http.StatusConflict,
+				Message: "Webhook already exists",
+				Error:   "a webhook with the same name already exists",
+			})
+			return "", false
+		}
+	}
+
+	signingSecret := request.Secret
+	if len(signingSecret) == 0 && len(hook.SecretID) == 0 {
+		if signingSecret, err = secret.RandomString("randAlphaNum", signingSecretLength); err != nil {
+			log.Errorf("Error generating signing secret: %s", err.Error())
+			c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Error generating signing secret",
+				Error:   err.Error(),
+			})
+			return "", false
+		}
+	}
+
+	previousSecretID := ""
+	if len(signingSecret) != 0 {
+		secretID, err := webhook.StoreSigningSecret(hook.OrganizationID, signingSecret)
+		if err != nil {
+			log.Errorf("Error storing signing secret: %s", err.Error())
+			c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Error storing signing secret",
+				Error:   err.Error(),
+			})
+			return "", false
+		}
+		previousSecretID, hook.SecretID = hook.SecretID, secretID
+	}
+
+	hook.Name = request.Name
+	hook.URL = request.URL
+	hook.Events = strings.Join(request.Events, ",")
+	if request.Active != nil {
+		hook.Active = *request.Active
+	}
+
+	if err := hook.Save(); err != nil {
+		log.Errorf("Error saving webhook: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error saving webhook",
+			Error:   err.Error(),
+		})
+		return "", false
+	}
+
+	if len(previousSecretID) != 0 {
+		if err := secret.Store.Delete(hook.OrganizationID, previousSecretID); err != nil {
+			log.Warnf("Error deleting previous signing secret of webhook %d: %s", hook.ID, err.Error())
+		}
+	}
+
+	return signingSecret, true
+}
+
+func respondWithWebhookQueryError(c *gin.Context, err error, notFoundMessage string) {
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: notFoundMessage,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	log.Errorf("Error querying webhooks: %s", err.Error())
+	c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "Error querying webhooks",
+		Error:   err.Error(),
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/textproto"
+	"net/url"
 	"strings"
 	"time"
 
@@ -16,7 +17,6 @@ import (
 
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/config"
-	"github.com/banzaicloud/pipeline/database"
 	"github.com/gin-gonic/gin"
 )
 
@@ -30,30 +30,103 @@ func (*closeableBuffer) Close() error {
 	return nil
 }
 
+// Resource types resolved from the request path
+const (
+	ResourceCluster = "cluster"
+	ResourceSecret  = "secret"
+)
+
+// resourceKey is the key of the resource set by the handlers in the Gin context
+const resourceKey = "auditResource"
+
 // AuditEvent holds all information related to a user interaction
 type AuditEvent struct {
-	ID         uint      `gorm:"primary_key"`
-	Time       time.Time `gorm:"index"`
-	ClientIP   string    `gorm:"size:45"`
-	UserAgent  string
-	Path       string `gorm:"size:8000"`
-	Method     string `gorm:"size:7"`
-	UserID     uint
-	StatusCode int
-	Body       *string `gorm:"type:json"`
-	Headers    string  `gorm:"type:json"`
+	ID             uint      `gorm:"primary_key"`
+	Time           time.Time `gorm:"index"`
+	ClientIP       string    `gorm:"size:45"`
+	UserAgent      string
+	Path           string `gorm:"size:8000"`
+	Method         string `gorm:"size:7"`
+	UserID         uint
+	OrganizationID uint `gorm:"index"`
+	ResourceType   string
+	ResourceID     string
+	StatusCode     int
+	Latency        time.Duration
+	Body           *string `gorm:"type:json"`
+	Headers        string  `gorm:"type:json"`
+	PrevHash       string  `gorm:"size:64"`
+	Hash           string  `gorm:"size:64"`
+}
+
+type resource struct {
+	resourceType string
+	id           string
 }
 
-// LogWriter instance is a Gin Middleware which logs all request data into MySQL audit_events table.
-func LogWriter(notloggedPaths []string, whitelistedHeaders []string) gin.HandlerFunc {
+// SetResource records the resource the request has been resolved to, e.g. the ID of a cluster referenced by name
+// or of a newly created secret, it overrides the resource resolved from the request path
+func SetResource(c *gin.Context, resourceType, id string) {
+	c.Set(resourceKey, resource{resourceType: resourceType, id: id})
+}
+
+// resolveResource returns the resource set by the handlers or else the one identified by the request path
+func resolveResource(c *gin.Context) (string, string) {
+	if value, ok := c.Get(resourceKey); ok {
+		r := value.(resource)
+		return r.resourceType, r.id
+	}
+
+	id := c.Param("id")
+	if len(id) == 0 {
+		return "", ""
+	}
+
+	segments := strings.Split(c.Request.URL.Path, "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i] != id {
+			continue
+		}
+		switch segments[i-1] {
+		case "clusters":
+			return ResourceCluster, id
+		case "secrets":
+			return ResourceSecret, id
+		}
+	}
+
+	return "", ""
+}
+
+// jsonBody returns the request body as it's stored in the json column of the audit events,
+// bodies which are not valid JSON are stored as JSON strings
+func jsonBody(rawBody []byte) *string {
+	if json.Valid(rawBody) {
+		body := string(rawBody)
+		return &body
+	}
+
+	encoded, _ := json.Marshal(string(rawBody))
+	body := string(encoded)
+	return &body
+}
+
+// ldapLoginBody returns the login of the LDAP login form without the password
+func ldapLoginBody(rawBody []byte) *string {
+	form, _ := url.ParseQuery(string(rawBody))
+	encoded, _ := json.Marshal(map[string]string{"login": form.Get("login")})
+	body := string(encoded)
+	return &body
+}
+
+// LogWriter instance is a Gin Middleware which sends all request data to the audit sinks of the dispatcher.
+func LogWriter(notloggedPaths []string, whitelistedHeaders []string, dispatcher *Dispatcher) gin.HandlerFunc {
 	skip := map[string]struct{}{}
 
 	for _, path := range notloggedPaths {
 		skip[path] = struct{}{}
 	}
 
-	db := database.GetDB()
-
 	return func(c *gin.Context) {
 		// Start timer
 		start := time.Now()
@@ -96,6 +169,9 @@ func LogWriter(notloggedPaths []string, whitelistedHeaders []string) gin.Handler
 				for k := range values {
 					values[k] = ""
 				}
+				if len(values) != 0 {
+					data["values"] = values
+				}
 				newBody, err := json.Marshal(data)
 				if err != nil {
 					c.AbortWithError(http.StatusInternalServerError, err)
@@ -104,15 +180,25 @@ func LogWriter(notloggedPaths []string, whitelistedHeaders []string) gin.Handler
 				}
 				newBodyString := string(newBody)
 				body = &newBodyString
-			} else if len(rawBody) > 0 {
-				newBodyString := string(rawBody)
+			} else if strings.HasSuffix(path, "/auth/ldap/login") && len(rawBody) > 0 {
+				// only the login of the LDAP login form is logged, the password is not
+				body = ldapLoginBody(rawBody)
+			} else if strings.HasSuffix(path, "/invitations/accept") && len(rawBody) > 0 {
+				// invitation tokens grant membership in the organization, so they are not logged
+				newBodyString := `{"token":""}`
 				body = &newBodyString
+			} else if len(rawBody) > 0 {
+				body = jsonBody(rawBody)
 			}
 
+			// Process request, the status code, the user and the organization are known only afterwards
+			c.Next()
+
 			clientIP := c.ClientIP()
 			method := c.Request.Method
 			userAgent := c.Request.UserAgent()
 			statusCode := c.Writer.Status()
+			latency := time.Since(start)
 
 			if raw != "" {
 				path = path + "?" + raw
@@ -124,6 +210,13 @@ func LogWriter(notloggedPaths []string, whitelistedHeaders []string) gin.Handler
 				userID = user.ID
 			}
 
+			var organizationID uint
+			if organization := auth.GetCurrentOrganization(c.Request); organization != nil {
+				organizationID = organization.ID
+			}
+
+			resourceType, resourceID := resolveResource(c)
+
 			filteredHeaders := http.Header{}
 			for _, header := range whitelistedHeaders {
 				if values := c.Request.Header[textproto.CanonicalMIMEHeaderKey(header)]; len(values) != 0 {
@@ -132,29 +225,27 @@ func LogWriter(notloggedPaths []string, whitelistedHeaders []string) gin.Handler
 			}
 			headers, err := json.Marshal(filteredHeaders)
 			if err != nil {
-				c.AbortWithError(http.StatusInternalServerError, err)
 				log.Errorln(err)
 				return
 			}
 
 			event := AuditEvent{
-				Time:       start,
-				ClientIP:   clientIP,
-				UserAgent:  userAgent,
-				UserID:     userID,
-				StatusCode: statusCode,
-				Method:     method,
-				Path:       path,
-				Body:       body,
-				Headers:    string(headers),
+				Time:           start,
+				ClientIP:       clientIP,
+				UserAgent:      userAgent,
+				UserID:         userID,
+				OrganizationID: organizationID,
+				ResourceType:   resourceType,
+				ResourceID:     resourceID,
+				StatusCode:     statusCode,
+				Latency:        latency,
+				Method:         method,
+				Path:           path,
+				Body:           body,
+				Headers:        string(headers),
 			}
 
-			err = db.Save(&event).Error
-			if err != nil {
-				c.AbortWithError(http.StatusInternalServerError, err)
-				log.Errorln(err)
-				return
-			}
+			dispatcher.Dispatch(&event)
 		}
 	}
 }
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResolveResource(t *testing.T) {
+	tests := []struct {
+		path         string
+		params       gin.Params
+		resourceType string
+		resourceID   string
+	}{
+		{"/api/v1/orgs/1/clusters/12/deployments", gin.Params{{Key: "orgid", Value: "1"}, {Key: "id", Value: "12"}}, ResourceCluster, "12"},
+		{"/api/v1/orgs/1/secrets/abc", gin.Params{{Key: "orgid", Value: "1"}, {Key: "id", Value: "abc"}}, ResourceSecret, "abc"},
+		{"/api/v1/orgs/1/users/3", gin.Params{{Key: "orgid", Value: "1"}, {Key: "id", Value: "3"}}, "", ""},
+		{"/api/v1/orgs/1/clusters", gin.Params{{Key: "orgid", Value: "1"}}, "", ""},
+	}
+
+	for _, test := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, test.path, nil)
+		c.Params = test.params
+
+		if resourceType, resourceID := resolveResource(c); resourceType != test.resourceType || resourceID != test.resourceID {
+			t.Errorf("%s: expected %s %q, got %s %q", test.path, test.resourceType, test.resourceID, resourceType, resourceID)
+		}
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/orgs/1/clusters/mycluster", nil)
+	c.Params = gin.Params{{Key: "orgid", Value: "1"}, {Key: "id", Value: "mycluster"}}
+	SetResource(c, ResourceCluster, "12")
+
+	if resourceType, resourceID := resolveResource(c); resourceType != ResourceCluster || resourceID != "12" {
+		t.Errorf("expected the resource set by the handler, got %s %q", resourceType, resourceID)
+	}
+}
+
+func TestParseStatusFilter(t *testing.T) {
+	tests := []struct {
+		status   string
+		from, to int
+		valid    bool
+	}{
+		{"404", 404, 404, true},
+		{"4xx", 400, 499, true},
+		{"5XX", 500, 599, true},
+		{"9xx", 0, 0, false},
+		{"abc", 0, 0, false},
+		{"42", 0, 0, false},
+	}
+
+	for _, test := range tests {
+		from, to, err := ParseStatusFilter(test.status)
+		if (err == nil) != test.valid || from != test.from || to != test.to {
+			t.Errorf("%s: unexpected result %d-%d, error: %v", test.status, from, to, err)
+		}
+	}
+}
+
+func TestJSONBody(t *testing.T) {
+	for raw, expected := range map[string]string{
+		`{"name":"cluster"}`: `{"name":"cluster"}`,
+		"name: cluster":      `"name: cluster"`,
+		`not "json"`:         `"not \"json\""`,
+	} {
+		if actual := *jsonBody([]byte(raw)); actual != expected {
+			t.Errorf("%s: expected %s, got %s", raw, expected, actual)
+		}
+	}
+}
+
+func TestLDAPLoginBody(t *testing.T) {
+	body := *ldapLoginBody([]byte("login=jdoe&password=secret"))
+	if body != `{"login":"jdoe"}` {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
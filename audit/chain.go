@@ -0,0 +1,475 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/database"
+	pkgAudit "github.com/banzaicloud/pipeline/pkg/audit"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// ErrNoCheckpointKey is returned when the checkpoint signing key is not configured
+var ErrNoCheckpointKey = errors.New("audit checkpoint key is not configured")
+
+// ChainHead holds the last event of the audit event chain of an organization, it's locked while events are appended
+type ChainHead struct {
+	OrganizationID uint `gorm:"primary_key;auto_increment:false"`
+	LastEventID    uint
+	LastHash       string `gorm:"size:64"`
+	UpdatedAt      time.Time
+}
+
+// TableName sets ChainHead's table name
+func (ChainHead) TableName() string {
+	return "audit_chain_heads"
+}
+
+// Checkpoint is a signed record of the audit event chain of an organization at an event. The checkpoints of an
+// organization are numbered and chained by the signature of the previous one, so deleted checkpoints are detected.
+type Checkpoint struct {
+	ID             uint `gorm:"primary_key"`
+	CreatedAt      time.Time
+	OrganizationID uint   `gorm:"unique_index:idx_org_sequence"`
+	Sequence       uint   `gorm:"unique_index:idx_org_sequence"`
+	EventID        uint   `gorm:"index"`
+	Hash           string `gorm:"size:64"`
+	PrevSignature  string `gorm:"size:64"`
+	Signature      string `gorm:"size:64"`
+	ExportedAt     *time.Time
+}
+
+// TableName sets Checkpoint's table name
+func (Checkpoint) TableName() string {
+	return "audit_checkpoints"
+}
+
+// canonicalJSON returns the JSON document re-encoded with sorted keys, canonical numbers and without whitespace,
+// as MySQL normalizes the stored JSON columns. Invalid documents are returned as is.
+func canonicalJSON(document string) string {
+	decoder := json.NewDecoder(strings.NewReader(document))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return document
+	}
+
+	canonical, err := json.Marshal(canonicalNumbers(value))
+	if err != nil {
+		return document
+	}
+	return string(canonical)
+}
+
+// canonicalNumbers replaces the numbers of the decoded JSON value with their canonical form, MySQL stores integers
+// as is and the other numbers as doubles, so 1.50 is read back as 1.5 and 1e2 as 100
+func canonicalNumbers(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = canonicalNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = canonicalNumbers(item)
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(value.String(), 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(i, 10))
+		}
+		f, err := strconv.ParseFloat(value.String(), 64)
+		if err != nil {
+			return value
+		}
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return json.Number(strconv.FormatInt(int64(f), 10))
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return value
+}
+
+// ComputeHash returns the hex encoded SHA-256 hash of the event chained to the hash of the previous event,
+// the time is hashed with the second precision it's stored with
+func (e *AuditEvent) ComputeHash() string {
+	var body string
+	if e.Body != nil {
+		body = canonicalJSON(*e.Body)
+	}
+
+	fields, _ := json.Marshal([]interface{}{
+		e.PrevHash,
+		e.OrganizationID,
+		e.UserID,
+		e.Time.UTC().Truncate(time.Second).Unix(),
+		e.ClientIP,
+		e.UserAgent,
+		e.Method,
+		e.Path,
+		e.StatusCode,
+		int64(e.Latency),
+		e.ResourceType,
+		e.ResourceID,
+		body,
+		canonicalJSON(e.Headers),
+	})
+
+	hash := sha256.Sum256(fields)
+	return hex.EncodeToString(hash[:])
+}
+
+// appendToChains stores the events chained to the last events of their organizations in a single transaction,
+// the chain heads are locked in organization order to avoid deadlocks between Pipeline instances
+func appendToChains(db *gorm.DB, events []*AuditEvent) error {
+	organizationIDs := make([]uint, 0)
+	heads := make(map[uint]*ChainHead)
+	for _, event := range events {
+		if _, ok := heads[event.OrganizationID]; !ok {
+			heads[event.OrganizationID] = nil
+			organizationIDs = append(organizationIDs, event.OrganizationID)
+		}
+	}
+	sort.Slice(organizationIDs, func(i, j int) bool { return organizationIDs[i] < organizationIDs[j] })
+
+	tx := db.Begin()
+	for _, organizationID := range organizationIDs {
+		head := &ChainHead{}
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Where("organization_id = ?", organizationID).First(head).Error
+		if err == gorm.ErrRecordNotFound {
+			head = &ChainHead{OrganizationID: organizationID}
+			err = tx.Create(head).Error
+		}
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "error locking audit chain")
+		}
+		heads[organizationID] = head
+	}
+
+	for _, e := range events {
+		// the events are shared with the other sinks, so the chained copies are stored
+		event := *e
+		head := heads[event.OrganizationID]
+		event.Time = event.Time.UTC().Truncate(time.Second)
+		event.PrevHash = head.LastHash
+		event.Hash = event.ComputeHash()
+		if err := tx.Create(&event).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		head.LastEventID, head.LastHash = event.ID, event.Hash
+	}
+
+	for _, organizationID := range organizationIDs {
+		if err := tx.Save(heads[organizationID]).Error; err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "error saving audit chain")
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// getCheckpointKey returns the configured checkpoint signing key, it's kept out of the database and the secret store
+// of the organizations, so the checkpoints can't be forged by their users
+func getCheckpointKey() ([]byte, error) {
+	key := viper.GetString(config.AuditCheckpointKey)
+	if len(key) == 0 {
+		return nil, ErrNoCheckpointKey
+	}
+	return []byte(key), nil
+}
+
+func signCheckpoint(key []byte, checkpoint *Checkpoint) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d:%d:%d:%s:%s",
+		checkpoint.OrganizationID, checkpoint.Sequence, checkpoint.EventID, checkpoint.Hash, checkpoint.PrevSignature)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ToResponse converts the checkpoint to API response
+func (c *Checkpoint) ToResponse() *pkgAudit.CheckpointResponse {
+	return &pkgAudit.CheckpointResponse{
+		OrganizationID: c.OrganizationID,
+		Sequence:       c.Sequence,
+		EventID:        c.EventID,
+		Hash:           c.Hash,
+		PrevSignature:  c.PrevSignature,
+		Signature:      c.Signature,
+		CreatedAt:      c.CreatedAt,
+	}
+}
+
+// CreateCheckpoints stores a signed checkpoint of every audit event chain that has grown since its last checkpoint,
+// chained to the last checkpoint of the organization. The sequence numbers are unique per organization, so if
+// multiple Pipeline instances create the same checkpoint, only one of them is stored.
+func CreateCheckpoints() error {
+	key, err := getCheckpointKey()
+	if err != nil {
+		return err
+	}
+
+	db := database.GetDB()
+
+	var heads []*ChainHead
+	if err := db.Find(&heads).Error; err != nil {
+		return err
+	}
+
+	for _, head := range heads {
+		if head.LastEventID == 0 {
+			continue
+		}
+		if err := createCheckpoint(db, key, head.OrganizationID, head.LastEventID, head.LastHash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createCheckpoint stores a signed checkpoint of the event chained to the last checkpoint of the organization,
+// unless the organization has a checkpoint of the event or a later one
+func createCheckpoint(db *gorm.DB, key []byte, organizationID, eventID uint, hash string) error {
+	var last []Checkpoint
+	if err := db.Where("organization_id = ?", organizationID).Order("sequence DESC").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+	if len(last) != 0 && last[0].EventID >= eventID {
+		return nil
+	}
+
+	checkpoint := &Checkpoint{OrganizationID: organizationID, Sequence: 1, EventID: eventID, Hash: hash}
+	if len(last) != 0 {
+		checkpoint.Sequence = last[0].Sequence + 1
+		checkpoint.PrevSignature = last[0].Signature
+	}
+	checkpoint.Signature = signCheckpoint(key, checkpoint)
+	if err := db.Create(checkpoint).Error; err != nil {
+		var count int
+		if db.Model(&Checkpoint{}).Where("organization_id = ? AND sequence = ?", checkpoint.OrganizationID, checkpoint.Sequence).Count(&count).Error == nil && count != 0 {
+			// stored by another instance in the meantime
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ExportCheckpoints posts the checkpoints which have not been exported yet as JSON lines to the configured collector,
+// the checkpoints stored outside of the database reveal if the latest checkpoints have been deleted.
+// Checkpoints may be exported more than once by multiple Pipeline instances, they are identified by
+// the organization and the sequence number.
+func ExportCheckpoints(url, authorization string, timeout time.Duration) error {
+	db := database.GetDB()
+
+	var checkpoints []*Checkpoint
+	if err := db.Where("exported_at IS NULL").Order("id").Limit(1000).Find(&checkpoints).Error; err != nil {
+		return err
+	}
+	if len(checkpoints) == 0 {
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	ids := make([]uint, 0, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		if err := encoder.Encode(checkpoint.ToResponse()); err != nil {
+			return err
+		}
+		ids = append(ids, checkpoint.ID)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, url, &buffer)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-ndjson")
+	if len(authorization) != 0 {
+		request.Header.Set("Authorization", authorization)
+	}
+
+	response, err := (&http.Client{Timeout: timeout}).Do(request)
+	if err != nil {
+		return errors.Wrap(err, "error posting audit checkpoints")
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with %s", response.Status)
+	}
+
+	return db.Model(&Checkpoint{}).Where("id IN (?)", ids).Update("exported_at", time.Now()).Error
+}
+
+// StartCheckpointer periodically stores signed checkpoints of the audit event chains and exports them
+func StartCheckpointer(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			if err := CreateCheckpoints(); err != nil {
+				log.Errorf("Error creating audit checkpoints: %s", err.Error())
+				continue
+			}
+
+			if url := viper.GetString(config.AuditCheckpointExportURL); len(url) != 0 {
+				err := ExportCheckpoints(url, viper.GetString(config.AuditCheckpointExportAuthorization), viper.GetDuration(config.AuditHTTPTimeout))
+				if err != nil {
+					log.Errorf("Error exporting audit checkpoints: %s", err.Error())
+				}
+			}
+		}
+	}()
+
+	return ticker
+}
+
+// VerifyChain walks the audit event chain of the organization up to its current head and reports the first broken link.
+// Events recorded before the chain was introduced and the events pruned by the retention policy are not verified.
+func VerifyChain(organizationID uint) (*pkgAudit.ChainVerification, error) {
+	db := database.GetDB()
+	result := &pkgAudit.ChainVerification{OrganizationID: organizationID, Verified: true}
+
+	var head ChainHead
+	if err := db.Where("organization_id = ?", organizationID).First(&head).Error; err == gorm.ErrRecordNotFound {
+		return result, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []*Checkpoint
+	if err := db.Where("organization_id = ?", organizationID).Order("sequence").Find(&checkpoints).Error; err != nil {
+		return nil, err
+	}
+	checkpointsByEvent := make(map[uint]*Checkpoint, len(checkpoints))
+	if len(checkpoints) != 0 {
+		key, err := getCheckpointKey()
+		if err != nil {
+			return nil, errors.Wrap(err, "error getting checkpoint key")
+		}
+		if link := verifyCheckpoints(key, checkpoints, head.LastEventID); link != nil {
+			result.Break(link.EventID, link.Reason, link.Expected, link.Actual)
+			return result, nil
+		}
+		for _, checkpoint := range checkpoints {
+			checkpointsByEvent[checkpoint.EventID] = checkpoint
+		}
+	}
+
+	rows, err := db.Model(&AuditEvent{}).Where("organization_id = ? AND id <= ?", organizationID, head.LastEventID).Order("id").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prevHash string
+	for rows.Next() {
+		var event AuditEvent
+		if err := db.ScanRows(rows, &event); err != nil {
+			return nil, err
+		}
+
+		if len(event.Hash) == 0 {
+			if result.FirstEventID != 0 {
+				result.Break(event.ID, "event has no hash", "", "")
+				return result, nil
+			}
+			continue
+		}
+
+		if result.FirstEventID == 0 {
+			result.FirstEventID = event.ID
+		} else if event.PrevHash != prevHash {
+			result.Break(event.ID, "previous event was modified or deleted", prevHash, event.PrevHash)
+			return result, nil
+		}
+
+		if hash := event.ComputeHash(); hash != event.Hash {
+			result.Break(event.ID, "event was modified", event.Hash, hash)
+			return result, nil
+		}
+
+		if checkpoint, ok := checkpointsByEvent[event.ID]; ok {
+			if checkpoint.Hash != event.Hash {
+				result.Break(event.ID, "event does not match its checkpoint", checkpoint.Hash, event.Hash)
+				return result, nil
+			}
+			delete(checkpointsByEvent, event.ID)
+			result.CheckpointsChecked++
+		}
+
+		prevHash = event.Hash
+		result.LastEventID = event.ID
+		result.EventsChecked++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// checkpoints of pruned events are ignored, the others must have been found
+	for _, checkpoint := range checkpoints {
+		if _, missing := checkpointsByEvent[checkpoint.EventID]; missing && checkpoint.EventID >= result.FirstEventID {
+			result.Break(checkpoint.EventID, "checkpointed event was deleted", checkpoint.Hash, "")
+			return result, nil
+		}
+	}
+
+	if prevHash != head.LastHash {
+		result.Break(head.LastEventID, "latest events were deleted", head.LastHash, prevHash)
+	}
+
+	return result, nil
+}
+
+// verifyCheckpoints checks the signatures of the checkpoints ordered by their sequence numbers, and that they form
+// an unbroken chain of increasing events up to the head of the event chain, it returns the first invalid checkpoint
+func verifyCheckpoints(key []byte, checkpoints []*Checkpoint, lastEventID uint) *pkgAudit.BrokenLink {
+	var prev *Checkpoint
+	for _, checkpoint := range checkpoints {
+		expectedSequence, expectedPrevSignature := uint(1), ""
+		if prev != nil {
+			expectedSequence, expectedPrevSignature = prev.Sequence+1, prev.Signature
+		}
+
+		switch {
+		case checkpoint.Sequence != expectedSequence:
+			return &pkgAudit.BrokenLink{
+				EventID:  checkpoint.EventID,
+				Reason:   "checkpoints are missing",
+				Expected: fmt.Sprintf("sequence %d", expectedSequence),
+				Actual:   fmt.Sprintf("sequence %d", checkpoint.Sequence),
+			}
+		case !hmac.Equal([]byte(signCheckpoint(key, checkpoint)), []byte(checkpoint.Signature)):
+			return &pkgAudit.BrokenLink{EventID: checkpoint.EventID, Reason: "checkpoint signature is invalid"}
+		case checkpoint.PrevSignature != expectedPrevSignature:
+			return &pkgAudit.BrokenLink{
+				EventID:  checkpoint.EventID,
+				Reason:   "checkpoint is not chained to the previous one",
+				Expected: expectedPrevSignature,
+				Actual:   checkpoint.PrevSignature,
+			}
+		case prev != nil && checkpoint.EventID <= prev.EventID, checkpoint.EventID > lastEventID:
+			return &pkgAudit.BrokenLink{EventID: checkpoint.EventID, Reason: "checkpoint is out of order"}
+		}
+
+		prev = checkpoint
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeHash(t *testing.T) {
+	body := `{"name": "cluster", "nodes": {"b": 2, "a": 1}}`
+	event := &AuditEvent{
+		Time:           time.Date(2018, 7, 1, 12, 0, 0, 500000000, time.UTC),
+		OrganizationID: 1,
+		UserID:         2,
+		Method:         "POST",
+		Path:           "/api/v1/orgs/1/clusters",
+		StatusCode:     201,
+		Body:           &body,
+		Headers:        `{"User-Agent": ["curl"]}`,
+	}
+	hash := event.ComputeHash()
+
+	// MySQL stores the time with second precision and normalizes the JSON documents
+	normalizedBody := `{"name":"cluster","nodes":{"a":1,"b":2}}`
+	stored := *event
+	stored.Time = event.Time.Truncate(time.Second)
+	stored.Body = &normalizedBody
+	stored.Headers = `{"User-Agent":["curl"]}`
+	if storedHash := stored.ComputeHash(); storedHash != hash {
+		t.Errorf("expected hash of the stored event %s to match %s", storedHash, hash)
+	}
+
+	modified := stored
+	modified.StatusCode = 200
+	if modified.ComputeHash() == hash {
+		t.Error("expected the hash to change when the event is modified")
+	}
+
+	next := stored
+	next.PrevHash = hash
+	if next.ComputeHash() == hash {
+		t.Error("expected the hash to depend on the previous hash")
+	}
+}
+
+func TestComputeHashNormalizedNumbers(t *testing.T) {
+	body := `{"price": 1.50, "count": 1e2, "ids": [1, 2.0]}`
+	event := &AuditEvent{OrganizationID: 1, Method: "POST", Body: &body, Headers: `{}`}
+	hash := event.ComputeHash()
+
+	// MySQL stores the numbers which are not integers as doubles
+	normalizedBody := `{"ids": [1, 2.0], "count": 100.0, "price": 1.5}`
+	stored := *event
+	stored.Body = &normalizedBody
+	if storedHash := stored.ComputeHash(); storedHash != hash {
+		t.Errorf("expected hash of the stored event %s to match %s", storedHash, hash)
+	}
+
+	modifiedBody := `{"ids": [1, 2.0], "count": 100.0, "price": 1.6}`
+	stored.Body = &modifiedBody
+	if stored.ComputeHash() == hash {
+		t.Error("expected the hash to change when a number is modified")
+	}
+}
+
+func TestSignCheckpoint(t *testing.T) {
+	checkpoint := &Checkpoint{OrganizationID: 1, Sequence: 1, EventID: 42, Hash: "abc"}
+	signature := signCheckpoint([]byte("key"), checkpoint)
+
+	if signCheckpoint([]byte("other"), checkpoint) == signature {
+		t.Error("expected the signature to depend on the key")
+	}
+
+	checkpoint.EventID = 43
+	if signCheckpoint([]byte("key"), checkpoint) == signature {
+		t.Error("expected the signature to depend on the checkpointed event")
+	}
+
+	checkpoint.EventID = 42
+	checkpoint.PrevSignature = "def"
+	if signCheckpoint([]byte("key"), checkpoint) == signature {
+		t.Error("expected the signature to depend on the previous checkpoint")
+	}
+}
+
+func TestVerifyCheckpoints(t *testing.T) {
+	key := []byte("key")
+	chain := func(eventIDs ...uint) []*Checkpoint {
+		var checkpoints []*Checkpoint
+		prevSignature := ""
+		for i, eventID := range eventIDs {
+			checkpoint := &Checkpoint{OrganizationID: 1, Sequence: uint(i + 1), EventID: eventID, Hash: "hash", PrevSignature: prevSignature}
+			checkpoint.Signature = signCheckpoint(key, checkpoint)
+			prevSignature = checkpoint.Signature
+			checkpoints = append(checkpoints, checkpoint)
+		}
+		return checkpoints
+	}
+
+	if link := verifyCheckpoints(key, chain(10, 20, 30), 30); link != nil {
+		t.Errorf("unexpected broken link: %+v", link)
+	}
+
+	gap := chain(10, 20, 30)
+	if link := verifyCheckpoints(key, append(gap[:1], gap[2:]...), 30); link == nil || link.EventID != 30 || link.Reason != "checkpoints are missing" {
+		t.Errorf("expected missing checkpoint to be reported, got %+v", link)
+	}
+
+	if link := verifyCheckpoints(key, chain(10, 20, 30)[1:], 30); link == nil || link.EventID != 20 {
+		t.Errorf("expected missing first checkpoint to be reported, got %+v", link)
+	}
+
+	forged := chain(10, 20, 30)
+	forged[1].Hash = "forged"
+	if link := verifyCheckpoints(key, forged, 30); link == nil || link.Reason != "checkpoint signature is invalid" {
+		t.Errorf("expected forged checkpoint to be reported, got %+v", link)
+	}
+
+	if link := verifyCheckpoints([]byte("other"), chain(10), 10); link == nil {
+		t.Error("expected checkpoint signed with another key to be reported")
+	}
+}
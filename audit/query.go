@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/database"
+	pkgAudit "github.com/banzaicloud/pipeline/pkg/audit"
+	"github.com/jinzhu/gorm"
+)
+
+// EventFilter describes the filters of the audit event queries of an organization
+type EventFilter struct {
+	OrganizationID uint
+	UserID         *uint
+	Method         string
+	PathPrefix     string
+	StatusFrom     int
+	StatusTo       int
+	From           *time.Time
+	To             *time.Time
+}
+
+// ParseStatusFilter parses an exact status code like 404 or a status class like 4xx into a status code range
+func ParseStatusFilter(status string) (int, int, error) {
+	if len(status) == 3 && strings.HasSuffix(strings.ToLower(status), "xx") {
+		class, err := strconv.Atoi(status[:1])
+		if err != nil || class < 1 || class > 5 {
+			return 0, 0, fmt.Errorf("invalid status class %q", status)
+		}
+		return class * 100, class*100 + 99, nil
+	}
+
+	code, err := strconv.Atoi(status)
+	if err != nil || code < 100 || code > 599 {
+		return 0, 0, fmt.Errorf("invalid status code %q", status)
+	}
+	return code, code, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func (f *EventFilter) query() *gorm.DB {
+	query := database.GetDB().Model(&AuditEvent{}).Where("organization_id = ?", f.OrganizationID)
+
+	if f.UserID != nil {
+		query = query.Where("user_id = ?", *f.UserID)
+	}
+	if len(f.Method) != 0 {
+		query = query.Where("method = ?", strings.ToUpper(f.Method))
+	}
+	if len(f.PathPrefix) != 0 {
+		query = query.Where("path LIKE ?", escapeLike(f.PathPrefix)+"%")
+	}
+	if f.StatusFrom != 0 {
+		query = query.Where("status_code BETWEEN ? AND ?", f.StatusFrom, f.StatusTo)
+	}
+	if f.From != nil {
+		query = query.Where("time >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("time < ?", *f.To)
+	}
+
+	return query
+}
+
+// QueryEvents returns a page of the audit events matching the filter, newest first
+func QueryEvents(filter *EventFilter, limit, offset int) ([]*AuditEvent, error) {
+	var events []*AuditEvent
+	err := filter.query().Order("id DESC").Limit(limit).Offset(offset).Find(&events).Error
+	return events, err
+}
+
+// CountEvents returns the number of audit events matching the filter
+func CountEvents(filter *EventFilter) (int, error) {
+	var count int
+	err := filter.query().Count(&count).Error
+	return count, err
+}
+
+// EachEvent calls the function with every audit event matching the filter, newest first,
+// without loading all of them into memory
+func EachEvent(filter *EventFilter, fn func(*AuditEvent) error) error {
+	db := filter.query().Order("id DESC")
+	rows, err := db.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event AuditEvent
+		if err := db.ScanRows(rows, &event); err != nil {
+			return err
+		}
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ToResponse converts the audit event to API response
+func (e *AuditEvent) ToResponse() *pkgAudit.EventResponse {
+	response := &pkgAudit.EventResponse{
+		ID:             e.ID,
+		Time:           e.Time,
+		UserID:         e.UserID,
+		OrganizationID: e.OrganizationID,
+		Method:         e.Method,
+		Path:           e.Path,
+		StatusCode:     e.StatusCode,
+		LatencyMs:      int64(e.Latency / time.Millisecond),
+		ResourceType:   e.ResourceType,
+		ResourceID:     e.ResourceID,
+		ClientIP:       e.ClientIP,
+		UserAgent:      e.UserAgent,
+		Body:           e.Body,
+		Hash:           e.Hash,
+	}
+	if len(e.Headers) != 0 {
+		response.Headers = []byte(e.Headers)
+	}
+	return response
+}
+
+// CSVRecord returns the audit event as a record of the CSV export
+func (e *AuditEvent) CSVRecord() []string {
+	return []string{
+		fmt.Sprint(e.ID),
+		e.Time.UTC().Format(time.RFC3339),
+		fmt.Sprint(e.UserID),
+		fmt.Sprint(e.OrganizationID),
+		e.Method,
+		e.Path,
+		fmt.Sprint(e.StatusCode),
+		fmt.Sprint(int64(e.Latency / time.Millisecond)),
+		e.ResourceType,
+		e.ResourceID,
+		e.ClientIP,
+		e.UserAgent,
+	}
+}
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/database"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// PruneEvents deletes the audit events older than maxAge and the oldest ones above maxRows,
+// zero values disable the limits. The events are chained in ID order, so they are deleted below an ID boundary.
+func PruneEvents(maxAge time.Duration, maxRows int) (int64, error) {
+	db := database.GetDB()
+	var deleted int64
+
+	if maxAge > 0 {
+		// the newest event older than maxAge and the events before it are deleted
+		var boundary sql.NullInt64
+		if err := db.Model(&AuditEvent{}).Where("time < ?", time.Now().Add(-maxAge)).Select("MAX(id)").Row().Scan(&boundary); err != nil {
+			return deleted, err
+		}
+		if boundary.Valid {
+			count, err := pruneEventsUpTo(db, uint(boundary.Int64))
+			deleted += count
+			if err != nil {
+				return deleted, err
+			}
+		}
+	}
+
+	if maxRows > 0 {
+		// the newest event above the limit and the older ones are deleted
+		var boundary []AuditEvent
+		if err := db.Select("id").Order("id DESC").Offset(maxRows).Limit(1).Find(&boundary).Error; err != nil {
+			return deleted, err
+		}
+		if len(boundary) != 0 {
+			count, err := pruneEventsUpTo(db, boundary[0].ID)
+			deleted += count
+			if err != nil {
+				return deleted, err
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+// pruneEventsUpTo deletes the audit events up to the boundary event, the last pruned event of every chain is
+// checkpointed first if a checkpoint key is configured, so the link of the first kept event remains signed
+func pruneEventsUpTo(db *gorm.DB, boundaryID uint) (int64, error) {
+	key, err := getCheckpointKey()
+	if err != nil && err != ErrNoCheckpointKey {
+		return 0, err
+	}
+
+	if key != nil {
+		rows, err := db.Model(&AuditEvent{}).Select("organization_id, MAX(id)").
+			Where("id <= ? AND hash <> ''", boundaryID).Group("organization_id").Rows()
+		if err != nil {
+			return 0, err
+		}
+		var eventIDs []uint
+		for rows.Next() {
+			var organizationID, eventID uint
+			if err := rows.Scan(&organizationID, &eventID); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			eventIDs = append(eventIDs, eventID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+
+		if len(eventIDs) != 0 {
+			var events []AuditEvent
+			if err := db.Select("id, organization_id, hash").Where("id IN (?)", eventIDs).Find(&events).Error; err != nil {
+				return 0, err
+			}
+			for _, event := range events {
+				if err := createCheckpoint(db, key, event.OrganizationID, event.ID, event.Hash); err != nil {
+					return 0, errors.Wrap(err, "error checkpointing pruned audit events")
+				}
+			}
+		}
+	}
+
+	result := db.Where("id <= ?", boundaryID).Delete(AuditEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// StartRetentionPruner periodically deletes the audit events above the configured retention limits
+func StartRetentionPruner(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			deleted, err := PruneEvents(viper.GetDuration(config.AuditRetentionMaxAge), viper.GetInt(config.AuditRetentionMaxRows))
+			if err != nil {
+				log.Errorf("Error pruning audit events: %s", err.Error())
+				continue
+			}
+			if deleted > 0 {
+				log.Infof("%d audit events pruned", deleted)
+			}
+		}
+	}()
+
+	return ticker
+}
@@ -0,0 +1,260 @@
+package audit
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+)
+
+// Sink types
+const (
+	SinkDB     = "db"
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+	SinkHTTP   = "http"
+)
+
+// Overflow policies of the sink queues
+const (
+	OverflowBlock = "block"
+	OverflowDrop  = "drop"
+)
+
+// maxWriteAttempts is the number of attempts to write a batch before its events are written one by one
+const maxWriteAttempts = 5
+
+// writeRetryDelay is the delay before the first retry of a failed batch, it's doubled after every attempt
+var writeRetryDelay = time.Second
+
+// Reasons of dropping audit events
+const (
+	dropReasonOverflow    = "overflow"
+	dropReasonWriteFailed = "write_failed"
+)
+
+// droppedEvents counts the audit events dropped by the sinks
+var droppedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "pipeline_audit_events_dropped_total",
+	Help: "Number of audit events dropped by the sinks, because their queue was full or they couldn't be written",
+}, []string{"sink", "reason"})
+
+func init() {
+	prometheus.MustRegister(droppedEvents)
+}
+
+// Sink stores or forwards audit events, batches are written from a single goroutine
+type Sink interface {
+	Name() string
+	Write(events []*AuditEvent) error
+	Close() error
+}
+
+// partialWriteError is returned by the sinks which sent the first events of a failed batch,
+// only the rest of the batch is retried
+type partialWriteError struct {
+	written int
+	err     error
+}
+
+func (e *partialWriteError) Error() string {
+	return e.err.Error()
+}
+
+// DispatcherOptions describes the batching of the audit events
+type DispatcherOptions struct {
+	BufferSize     int
+	BatchSize      int
+	FlushInterval  time.Duration
+	DropOnOverflow bool
+}
+
+// Dispatcher queues the audit events for each sink and writes them in batches in the background
+type Dispatcher struct {
+	queues []*sinkQueue
+}
+
+type sinkQueue struct {
+	sink    Sink
+	options DispatcherOptions
+	events  chan *AuditEvent
+	done    chan struct{}
+	dropped uint64
+}
+
+// NewDispatcher starts writing the dispatched audit events to the sinks
+func NewDispatcher(sinks []Sink, options DispatcherOptions) *Dispatcher {
+	dispatcher := &Dispatcher{}
+	for _, sink := range sinks {
+		queue := &sinkQueue{
+			sink:    sink,
+			options: options,
+			events:  make(chan *AuditEvent, options.BufferSize),
+			done:    make(chan struct{}),
+		}
+		go queue.run()
+		dispatcher.queues = append(dispatcher.queues, queue)
+	}
+	return dispatcher
+}
+
+// NewDispatcherFromConfig creates the configured sinks and starts writing the dispatched audit events to them
+func NewDispatcherFromConfig() (*Dispatcher, error) {
+	var sinks []Sink
+	for _, name := range viper.GetStringSlice(config.AuditSinks) {
+		sink, err := newSink(name)
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return nil, fmt.Errorf("error creating %s audit sink: %s", name, err.Error())
+		}
+		sinks = append(sinks, sink)
+	}
+
+	overflow := viper.GetString(config.AuditSinkOverflow)
+	if overflow != OverflowBlock && overflow != OverflowDrop {
+		return nil, fmt.Errorf("unsupported audit sink overflow %q", overflow)
+	}
+
+	return NewDispatcher(sinks, DispatcherOptions{
+		BufferSize:     viper.GetInt(config.AuditSinkBufferSize),
+		BatchSize:      viper.GetInt(config.AuditSinkBatchSize),
+		FlushInterval:  time.Duration(viper.GetInt(config.AuditSinkFlushIntervalSecond)) * time.Second,
+		DropOnOverflow: overflow == OverflowDrop,
+	}), nil
+}
+
+func newSink(name string) (Sink, error) {
+	switch name {
+	case SinkDB:
+		return &dbSink{db: database.GetDB()}, nil
+	case SinkFile:
+		return newFileSink(
+			viper.GetString(config.AuditFilePath),
+			viper.GetInt64(config.AuditFileMaxSizeMB)*1024*1024,
+			viper.GetInt(config.AuditFileMaxBackups),
+		)
+	case SinkSyslog:
+		return newSyslogSink(
+			viper.GetString(config.AuditSyslogNetwork),
+			viper.GetString(config.AuditSyslogAddress),
+			viper.GetInt(config.AuditSyslogFacility),
+			viper.GetString(config.AuditSyslogAppName),
+		)
+	case SinkHTTP:
+		return newHTTPSink(
+			viper.GetString(config.AuditHTTPURL),
+			viper.GetString(config.AuditHTTPAuthorization),
+			viper.GetDuration(config.AuditHTTPTimeout),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported sink type")
+	}
+}
+
+// Dispatch queues the audit event for every sink, it blocks while a queue is full unless events are dropped on overflow
+func (d *Dispatcher) Dispatch(event *AuditEvent) {
+	for _, queue := range d.queues {
+		queue.enqueue(event)
+	}
+}
+
+// Close writes the queued audit events and closes the sinks
+func (d *Dispatcher) Close() {
+	for _, queue := range d.queues {
+		close(queue.events)
+	}
+	for _, queue := range d.queues {
+		<-queue.done
+	}
+}
+
+func (q *sinkQueue) enqueue(event *AuditEvent) {
+	if !q.options.DropOnOverflow {
+		q.events <- event
+		return
+	}
+
+	select {
+	case q.events <- event:
+	default:
+		droppedEvents.WithLabelValues(q.sink.Name(), dropReasonOverflow).Inc()
+		if dropped := atomic.AddUint64(&q.dropped, 1); dropped == 1 || dropped%1000 == 0 {
+			log.Warnf("%s audit sink queue is full, %d events dropped so far", q.sink.Name(), dropped)
+		}
+	}
+}
+
+func (q *sinkQueue) run() {
+	ticker := time.NewTicker(q.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*AuditEvent, 0, q.options.BatchSize)
+	for {
+		select {
+		case event, ok := <-q.events:
+			if !ok {
+				q.write(batch)
+				if err := q.sink.Close(); err != nil {
+					log.Errorf("Error closing %s audit sink: %s", q.sink.Name(), err.Error())
+				}
+				close(q.done)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= q.options.BatchSize {
+				q.write(batch)
+				batch = make([]*AuditEvent, 0, q.options.BatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) != 0 {
+				q.write(batch)
+				batch = make([]*AuditEvent, 0, q.options.BatchSize)
+			}
+		}
+	}
+}
+
+// write retries failed batches with exponential backoff, meanwhile the queue fills up. If a batch still can't
+// be written, its events are written one by one, so a single invalid event doesn't drop the whole batch.
+func (q *sinkQueue) write(batch []*AuditEvent) {
+	if len(batch) == 0 {
+		return
+	}
+
+	delay := writeRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := q.sink.Write(batch)
+		if err == nil {
+			return
+		}
+		if partialErr, ok := err.(*partialWriteError); ok {
+			batch = batch[partialErr.written:]
+			err = partialErr.err
+		}
+		if attempt == maxWriteAttempts {
+			log.Errorf("Error writing %d audit events to the %s sink after %d attempts, writing them one by one: %s",
+				len(batch), q.sink.Name(), attempt, err.Error())
+			q.writeEach(batch)
+			return
+		}
+		log.Warnf("Error writing audit events to the %s sink: %s", q.sink.Name(), err.Error())
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// writeEach writes the events of a failed batch one by one, and drops the ones that still can't be written
+func (q *sinkQueue) writeEach(batch []*AuditEvent) {
+	for _, event := range batch {
+		if err := q.sink.Write([]*AuditEvent{event}); err != nil {
+			droppedEvents.WithLabelValues(q.sink.Name(), dropReasonWriteFailed).Inc()
+			log.Errorf("Dropping audit event of %s %s: %s", event.Method, event.Path, err.Error())
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pkgAudit "github.com/banzaicloud/pipeline/pkg/audit"
+)
+
+type memorySink struct {
+	sync.Mutex
+	batches [][]*AuditEvent
+	closed  bool
+}
+
+func (s *memorySink) Name() string { return "memory" }
+
+func (s *memorySink) Write(events []*AuditEvent) error {
+	s.Lock()
+	defer s.Unlock()
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestDispatcher(t *testing.T) {
+	sink := &memorySink{}
+	dispatcher := NewDispatcher([]Sink{sink}, DispatcherOptions{BufferSize: 10, BatchSize: 2, FlushInterval: time.Hour})
+
+	for i := 1; i <= 5; i++ {
+		dispatcher.Dispatch(&AuditEvent{ID: uint(i)})
+	}
+	dispatcher.Close()
+
+	if len(sink.batches) != 3 || len(sink.batches[0]) != 2 || len(sink.batches[2]) != 1 || !sink.closed {
+		t.Errorf("expected 2 full batches and the rest flushed on close, got %d batches", len(sink.batches))
+	}
+	if sink.batches[2][0].ID != 5 {
+		t.Errorf("unexpected event order")
+	}
+}
+
+// rejectingSink fails to write the batches containing the rejected event
+type rejectingSink struct {
+	memorySink
+	rejected uint
+}
+
+func (s *rejectingSink) Write(events []*AuditEvent) error {
+	for _, event := range events {
+		if event.ID == s.rejected {
+			return errors.New("invalid event")
+		}
+	}
+	return s.memorySink.Write(events)
+}
+
+func TestDispatcherWritesFailedBatchOneByOne(t *testing.T) {
+	delay := writeRetryDelay
+	writeRetryDelay = time.Millisecond
+	defer func() { writeRetryDelay = delay }()
+
+	sink := &rejectingSink{rejected: 2}
+	dispatcher := NewDispatcher([]Sink{sink}, DispatcherOptions{BufferSize: 10, BatchSize: 3, FlushInterval: time.Hour})
+	for i := 1; i <= 3; i++ {
+		dispatcher.Dispatch(&AuditEvent{ID: uint(i)})
+	}
+	dispatcher.Close()
+
+	if len(sink.batches) != 2 || sink.batches[0][0].ID != 1 || sink.batches[1][0].ID != 3 {
+		t.Errorf("expected the valid events to be written one by one, got %d batches", len(sink.batches))
+	}
+}
+
+// partialSink sends the first event of the first batch before it fails
+type partialSink struct {
+	memorySink
+	failed bool
+}
+
+func (s *partialSink) Write(events []*AuditEvent) error {
+	if !s.failed {
+		s.failed = true
+		s.memorySink.Write(events[:1])
+		return &partialWriteError{written: 1, err: errors.New("connection reset")}
+	}
+	return s.memorySink.Write(events)
+}
+
+func TestDispatcherRetriesUnsentEvents(t *testing.T) {
+	delay := writeRetryDelay
+	writeRetryDelay = time.Millisecond
+	defer func() { writeRetryDelay = delay }()
+
+	sink := &partialSink{}
+	dispatcher := NewDispatcher([]Sink{sink}, DispatcherOptions{BufferSize: 10, BatchSize: 3, FlushInterval: time.Hour})
+	for i := 1; i <= 3; i++ {
+		dispatcher.Dispatch(&AuditEvent{ID: uint(i)})
+	}
+	dispatcher.Close()
+
+	if len(sink.batches) != 2 || len(sink.batches[1]) != 2 || sink.batches[1][0].ID != 2 {
+		t.Errorf("expected only the unsent events to be retried, got %d batches", len(sink.batches))
+	}
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.jsonl")
+	sink, err := newFileSink(path, 300, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 6; i++ {
+		if err := sink.Write([]*AuditEvent{{ID: uint(i), Method: "GET", Path: "/api/v1/orgs/1/clusters"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.Close()
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 300 {
+			t.Errorf("%s is larger than the maximum size: %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("expected no more backups than the maximum")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan()
+	var event pkgAudit.EventResponse
+	if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.ID < 5 {
+		t.Errorf("expected the latest events in the current file, got %+v, error: %v", event, err)
+	}
+}
+
+func TestFormatSyslogMessage(t *testing.T) {
+	event := &AuditEvent{
+		ID:         1,
+		Time:       time.Date(2018, 7, 1, 12, 0, 0, 0, time.UTC),
+		Method:     "DELETE",
+		Path:       "/api/v1/orgs/1/clusters/2",
+		StatusCode: 404,
+	}
+
+	message, err := formatSyslogMessage(event, 16, "pipeline host", "pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := "<132>1 2018-07-01T12:00:00.000000Z pipelinehost pipeline "
+	if !strings.HasPrefix(string(message), prefix) {
+		t.Errorf("unexpected syslog header: %q", message)
+	}
+	if !strings.Contains(string(message), ` audit - {"id":1,`) {
+		t.Errorf("expected JSON event as message: %q", message)
+	}
+}
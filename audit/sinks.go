@@ -0,0 +1,282 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// dbSink stores the audit events in the audit_events table chained per organization
+type dbSink struct {
+	db *gorm.DB
+}
+
+func (s *dbSink) Name() string {
+	return SinkDB
+}
+
+func (s *dbSink) Write(events []*AuditEvent) error {
+	return appendToChains(s.db, events)
+}
+
+func (s *dbSink) Close() error {
+	return nil
+}
+
+// encodeJSONLines encodes the audit events as JSON lines
+func encodeJSONLines(events []*AuditEvent) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, event := range events {
+		if err := encoder.Encode(event.ToResponse()); err != nil {
+			return nil, err
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+// fileSink appends the audit events as JSON lines to a file, which is rotated when it reaches its maximum size.
+// Rotated files are renamed to path.1, path.2 and so on, the oldest above maxBackups are removed.
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newFileSink(path string, maxSize int64, maxBackups int) (*fileSink, error) {
+	sink := &fileSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file, s.size = file, info.Size()
+	return nil
+}
+
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxBackups))
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+	}
+	if s.maxBackups > 0 {
+		if err := os.Rename(s.path, s.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(s.path); err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+func (s *fileSink) Name() string {
+	return SinkFile
+}
+
+func (s *fileSink) Write(events []*AuditEvent) error {
+	data, err := encodeJSONLines(events)
+	if err != nil {
+		return err
+	}
+
+	if s.size > 0 && s.maxSize > 0 && s.size+int64(len(data)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return errors.Wrap(err, "error rotating audit file")
+		}
+	}
+
+	n, err := s.file.Write(data)
+	s.size += int64(n)
+	return err
+}
+
+func (s *fileSink) Close() error {
+	return s.file.Close()
+}
+
+// Syslog severities of the audit events by status code
+const (
+	syslogSeverityError   = 3
+	syslogSeverityWarning = 4
+	syslogSeverityInfo    = 6
+)
+
+// syslogSink sends the audit events as RFC 5424 messages to a syslog server, messages sent over TCP
+// are framed with octet counting (RFC 6587)
+type syslogSink struct {
+	network  string
+	address  string
+	facility int
+	appName  string
+	hostname string
+	conn     net.Conn
+}
+
+func newSyslogSink(network, address string, facility int, appName string) (*syslogSink, error) {
+	if facility < 0 || facility > 23 {
+		return nil, fmt.Errorf("invalid syslog facility %d", facility)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+
+	return &syslogSink{network: network, address: address, facility: facility, appName: appName, hostname: hostname}, nil
+}
+
+// formatSyslogMessage formats the audit event as an RFC 5424 message with the JSON event as MSG
+func formatSyslogMessage(event *AuditEvent, facility int, hostname, appName string) ([]byte, error) {
+	severity := syslogSeverityInfo
+	if event.StatusCode >= 500 {
+		severity = syslogSeverityError
+	} else if event.StatusCode >= 400 {
+		severity = syslogSeverityWarning
+	}
+
+	msg, err := json.Marshal(event.ToResponse())
+	if err != nil {
+		return nil, err
+	}
+
+	header := fmt.Sprintf("<%d>1 %s %s %s %d audit - ",
+		facility*8+severity,
+		event.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderValue(hostname, 255),
+		syslogHeaderValue(appName, 48),
+		os.Getpid(),
+	)
+
+	return append([]byte(header), msg...), nil
+}
+
+// syslogHeaderValue returns the value as a printable US-ASCII header field of the maximum length
+func syslogHeaderValue(value string, maxLength int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+	if len(value) == 0 {
+		return "-"
+	}
+	if len(value) > maxLength {
+		return value[:maxLength]
+	}
+	return value
+}
+
+func (s *syslogSink) Name() string {
+	return SinkSyslog
+}
+
+func (s *syslogSink) Write(events []*AuditEvent) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, 10*time.Second)
+		if err != nil {
+			return errors.Wrap(err, "error connecting to syslog server")
+		}
+		s.conn = conn
+	}
+
+	for i, event := range events {
+		message, err := formatSyslogMessage(event, s.facility, s.hostname, s.appName)
+		if err != nil {
+			return &partialWriteError{written: i, err: err}
+		}
+		if s.network == "tcp" || s.network == "tcp4" || s.network == "tcp6" {
+			message = append([]byte(fmt.Sprintf("%d ", len(message))), message...)
+		}
+		if _, err := s.conn.Write(message); err != nil {
+			// reconnect on the next attempt
+			s.conn.Close()
+			s.conn = nil
+			return &partialWriteError{written: i, err: errors.Wrap(err, "error sending syslog message")}
+		}
+	}
+
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// httpSink posts the batches of audit events as JSON lines to a collector endpoint
+type httpSink struct {
+	url           string
+	authorization string
+	client        *http.Client
+}
+
+func newHTTPSink(url, authorization string, timeout time.Duration) (*httpSink, error) {
+	if len(url) == 0 {
+		return nil, fmt.Errorf("collector url is required")
+	}
+	return &httpSink{url: url, authorization: authorization, client: &http.Client{Timeout: timeout}}, nil
+}
+
+func (s *httpSink) Name() string {
+	return SinkHTTP
+}
+
+func (s *httpSink) Write(events []*AuditEvent) error {
+	data, err := encodeJSONLines(events)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-ndjson")
+	if len(s.authorization) != 0 {
+		request.Header.Set("Authorization", s.authorization)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "error posting audit events")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with %s", response.Status)
+	}
+	return nil
+}
+
+func (s *httpSink) Close() error {
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	bauth "github.com/banzaicloud/bank-vaults/auth"
 	"github.com/banzaicloud/pipeline/config"
@@ -144,29 +145,87 @@ func Init() {
 		DeregisterHandler: BanzaiDeregisterHandler,
 	})
 
-	githubProvider := github.New(&github.Config{
-		// ClientID and ClientSecret is validated inside github.New()
-		ClientID:     viper.GetString("auth.clientid"),
-		ClientSecret: viper.GetString("auth.clientsecret"),
+	oidcEnabled := viper.GetBool(config.OIDCEnabled)
+	ldapEnabled := viper.GetBool(config.LDAPEnabled)
+	gitlabEnabled := viper.GetBool(config.GitlabEnabled)
+
+	// GitHub login is optional only when GitLab, OpenID Connect or LDAP login is enabled
+	if !(gitlabEnabled || oidcEnabled || ldapEnabled) || viper.GetString("auth.clientid") != "" {
+		githubProvider := github.New(&github.Config{
+			// ClientID and ClientSecret is validated inside github.New()
+			ClientID:     viper.GetString("auth.clientid"),
+			ClientSecret: viper.GetString("auth.clientsecret"),
+
+			// The same as Drone's scopes
+			Scopes: []string{
+				"repo",
+				"user:email",
+				"read:org",
+			},
+		})
+		githubProvider.AuthorizeHandler = NewGithubAuthorizeHandler(githubProvider)
+		Auth.RegisterProvider(githubProvider)
+	}
 
-		// The same as Drone's scopes
-		Scopes: []string{
-			"repo",
-			"user:email",
-			"read:org",
-		},
-	})
-	githubProvider.AuthorizeHandler = NewGithubAuthorizeHandler(githubProvider)
-	Auth.RegisterProvider(githubProvider)
+	if gitlabEnabled {
+		Auth.RegisterProvider(NewGitlabProvider(&GitlabConfig{
+			// ClientID and ClientSecret is validated inside NewGitlabProvider()
+			BaseURL:      viper.GetString(config.GitlabBaseURL),
+			ClientID:     viper.GetString(config.GitlabClientID),
+			ClientSecret: viper.GetString(config.GitlabClientSecret),
+			Scopes:       viper.GetStringSlice(config.GitlabScopes),
+		}))
+	}
+
+	if oidcEnabled {
+		Auth.RegisterProvider(NewOIDCProvider(&OIDCConfig{
+			// IssuerURL, ClientID and ClientSecret is validated inside NewOIDCProvider()
+			IssuerURL:        viper.GetString(config.OIDCIssuerURL),
+			ClientID:         viper.GetString(config.OIDCClientID),
+			ClientSecret:     viper.GetString(config.OIDCClientSecret),
+			Scopes:           viper.GetStringSlice(config.OIDCScopes),
+			LoginClaim:       viper.GetString(config.OIDCLoginClaim),
+			EmailClaim:       viper.GetString(config.OIDCEmailClaim),
+			NameClaim:        viper.GetString(config.OIDCNameClaim),
+			GroupsClaim:      viper.GetString(config.OIDCGroupsClaim),
+			GroupPrefix:      viper.GetString(config.OIDCGroupPrefix),
+			AdminGroupSuffix: viper.GetString(config.OIDCAdminGroupSuffix),
+		}))
+	}
+
+	if ldapEnabled {
+		Auth.RegisterProvider(NewLDAPProvider(&LDAPConfig{
+			// URL and UserBaseDN is validated inside NewLDAPProvider()
+			URL:                viper.GetString(config.LDAPURL),
+			StartTLS:           viper.GetBool(config.LDAPStartTLS),
+			InsecureSkipVerify: viper.GetBool(config.LDAPInsecureSkipVerify),
+			CAFile:             viper.GetString(config.LDAPCAFile),
+			Timeout:            viper.GetDuration(config.LDAPTimeout),
+			BindDN:             viper.GetString(config.LDAPBindDN),
+			BindPassword:       viper.GetString(config.LDAPBindPassword),
+			UserBaseDN:         viper.GetString(config.LDAPUserBaseDN),
+			UserFilter:         viper.GetString(config.LDAPUserFilter),
+			LoginAttribute:     viper.GetString(config.LDAPLoginAttribute),
+			EmailAttribute:     viper.GetString(config.LDAPEmailAttribute),
+			NameAttribute:      viper.GetString(config.LDAPNameAttribute),
+			GroupBaseDN:        viper.GetString(config.LDAPGroupBaseDN),
+			GroupFilter:        viper.GetString(config.LDAPGroupFilter),
+			GroupNameAttribute: viper.GetString(config.LDAPGroupNameAttribute),
+			GroupPrefix:        viper.GetString(config.LDAPGroupPrefix),
+			AdminGroupSuffix:   viper.GetString(config.LDAPAdminGroupSuffix),
+		}))
+	}
 
 	tokenStore = bauth.NewVaultTokenStore("pipeline")
 
 	jwtAuth := bauth.JWTAuth(tokenStore, signingKey, func(claims *bauth.ScopedClaims) interface{} {
 		userID, _ := strconv.ParseUint(claims.Subject, 10, 32)
 		return &User{
-			ID:      uint(userID),
-			Login:   claims.Text, // This is needed for Drone virtual user tokens
-			Virtual: claims.Type == DroneHookTokenType,
+			ID:          uint(userID),
+			Login:       claims.Text, // This is needed for Drone virtual user tokens
+			Virtual:     claims.Type == DroneHookTokenType,
+			TokenID:     claims.Id,
+			TokenScopes: strings.Fields(claims.Scope),
 		}
 	})
 
@@ -176,6 +235,9 @@ func Init() {
 			return
 		}
 		jwtAuth(c)
+		if currentUser := GetCurrentUser(c.Request); !c.IsAborted() && currentUser != nil && currentUser.TokenID != "" {
+			recordTokenUse(currentUser.TokenID)
+		}
 	}
 }
 
@@ -197,6 +259,15 @@ func Install(engine *gin.Engine) {
 		authGroup.GET("/github/logout", authHandler)
 		authGroup.GET("/github/register", authHandler)
 		authGroup.GET("/github/callback", authHandler)
+		authGroup.GET("/gitlab/login", authHandler)
+		authGroup.GET("/gitlab/logout", authHandler)
+		authGroup.GET("/gitlab/register", authHandler)
+		authGroup.GET("/gitlab/callback", authHandler)
+		authGroup.GET("/oidc/login", authHandler)
+		authGroup.GET("/oidc/logout", authHandler)
+		authGroup.GET("/oidc/register", authHandler)
+		authGroup.GET("/oidc/callback", authHandler)
+		authGroup.POST("/ldap/login", authHandler)
 		authGroup.POST("/tokens", GenerateToken)
 		authGroup.GET("/tokens", GetTokens)
 		authGroup.GET("/tokens/:id", GetTokens)
@@ -239,10 +310,16 @@ func GenerateToken(c *gin.Context) {
 		}
 	}
 
-	tokenRequest := struct {
-		Name        string `json:"name,omitempty"`
-		VirtualUser string `json:"virtualUser,omitempty"`
-	}{Name: "generated"}
+	if !tokenHasFullAccess(currentUser.TokenScopes) {
+		c.AbortWithStatusJSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "Tokens with restricted scopes can't create tokens",
+			Error:   "forbidden",
+		})
+		return
+	}
+
+	tokenRequest := TokenRequest{Name: "generated"}
 
 	if c.Request.Method == http.MethodPost && c.Request.ContentLength > 0 {
 		if err := c.ShouldBindJSON(&tokenRequest); err != nil {
@@ -252,6 +329,16 @@ func GenerateToken(c *gin.Context) {
 		}
 	}
 
+	scope, err := validateTokenScopes(tokenRequest.Scopes, tokenRequest.OrganizationID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Error:   err.Error(),
+		})
+		return
+	}
+
 	isForVirtualUser := tokenRequest.VirtualUser != ""
 
 	userID := currentUser.IDString()
@@ -263,51 +350,102 @@ func GenerateToken(c *gin.Context) {
 		tokenType = DroneHookTokenType
 	}
 
-	tokenID, signedToken, err := createAndStoreAPIToken(userID, userLogin, tokenType, tokenRequest.Name)
+	// The token policies of the organizations the token has access to limit its lifetime
+	db := Auth.GetDB(c.Request)
+	organizations := []Organization{}
+	if tokenRequest.OrganizationID != 0 || isForVirtualUser {
+		for _, organization := range []Organization{{ID: tokenRequest.OrganizationID}, {Name: GetOrgNameFromVirtualUser(tokenRequest.VirtualUser)}} {
+			if organization.ID == 0 && organization.Name == "" {
+				continue
+			}
+			err = db.Model(currentUser).Where(&organization).Related(&organization, "Organizations").Error
+			if err != nil {
+				statusCode := GormErrorToStatusCode(err)
+				err = c.AbortWithError(statusCode, err)
+				log.Info(c.ClientIP(), " ", err.Error())
+				return
+			}
+			organizations = append(organizations, organization)
+		}
+	} else if err = db.Model(currentUser).Related(&organizations, "Organizations").Error; err != nil {
+		err = c.AbortWithError(http.StatusInternalServerError, err)
+		log.Info(c.ClientIP(), " ", err.Error())
+		return
+	}
 
+	orgIDs := []uint{}
+	for _, organization := range organizations {
+		orgIDs = append(orgIDs, organization.ID)
+	}
+	maxLifetime, err := tokenMaxLifetime(db, orgIDs)
 	if err != nil {
-		err = c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("%s", err))
+		err = c.AbortWithError(http.StatusInternalServerError, err)
 		log.Info(c.ClientIP(), " ", err.Error())
 		return
 	}
 
-	if isForVirtualUser {
-		orgName := GetOrgNameFromVirtualUser(tokenRequest.VirtualUser)
-		organization := Organization{Name: orgName}
-		err = Auth.GetDB(c.Request).
-			Model(currentUser).
-			Where(&organization).
-			Related(&organization, "Organizations").Error
-		if err != nil {
-			statusCode := GormErrorToStatusCode(err)
-			err = c.AbortWithError(statusCode, err)
-			log.Info(c.ClientIP(), " ", err.Error())
+	now := time.Now()
+	expiresAt := tokenRequest.ExpiresAt
+	if expiresAt != nil && !expiresAt.After(now) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Token expiry must be in the future",
+			Error:   "invalid expiry",
+		})
+		return
+	}
+	if maxLifetime > 0 {
+		maxExpiresAt := now.Add(maxLifetime)
+		if expiresAt == nil {
+			expiresAt = &maxExpiresAt
+		} else if expiresAt.After(maxExpiresAt) {
+			message := fmt.Sprintf("Token lifetime exceeds the maximum of %d days", int(maxLifetime.Hours()/24))
+			c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: message,
+				Error:   message,
+			})
 			return
 		}
+	}
+
+	tokenID, signedToken, err := createAndStoreAPIToken(userID, userLogin, tokenType, tokenRequest.Name, scope, expiresAt)
+
+	if err != nil {
+		err = c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("%s", err))
+		log.Info(c.ClientIP(), " ", err.Error())
+		return
+	}
 
+	if isForVirtualUser {
 		AddDefaultRoleForVirtualUser(userID)
-		AddOrgRoleForUser(userID, organization.ID)
+		AddOrgRoleForUser(userID, organizations[len(organizations)-1].ID)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"id": tokenID, "token": signedToken})
+	c.JSON(http.StatusOK, gin.H{"id": tokenID, "token": signedToken, "expiresAt": expiresAt})
 }
 
-func createAPIToken(userID string, userLogin string, tokenType bauth.TokenType) (string, string, error) {
+func createAPIToken(userID string, userLogin string, tokenType bauth.TokenType, scope string, expiresAt *time.Time) (string, string, error) {
 	tokenID := uuid.NewV4().String()
 
+	var expiresAtUnix int64
+	if expiresAt != nil {
+		expiresAtUnix = expiresAt.Unix()
+	}
+
 	// Create the Claims
 	claims := &bauth.ScopedClaims{
 		StandardClaims: jwt.StandardClaims{
 			Issuer:    JwtIssuer,
 			Audience:  JwtAudience,
 			IssuedAt:  jwt.TimeFunc().Unix(),
-			ExpiresAt: 0,
+			ExpiresAt: expiresAtUnix,
 			Subject:   userID,
 			Id:        tokenID,
 		},
-		Scope: "api:invoke", // "scope" for Pipeline
-		Type:  tokenType,    // "type" for Drone
-		Text:  userLogin,    // "text" for Drone
+		Scope: scope,     // "scope" for Pipeline
+		Type:  tokenType, // "type" for Drone
+		Text:  userLogin, // "text" for Drone
 	}
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -319,8 +457,8 @@ func createAPIToken(userID string, userLogin string, tokenType bauth.TokenType)
 	return tokenID, signedToken, nil
 }
 
-func createAndStoreAPIToken(userID string, userLogin string, tokenType bauth.TokenType, tokenName string) (string, string, error) {
-	tokenID, signedToken, err := createAPIToken(userID, userLogin, tokenType)
+func createAndStoreAPIToken(userID string, userLogin string, tokenType bauth.TokenType, tokenName string, scope string, expiresAt *time.Time) (string, string, error) {
+	tokenID, signedToken, err := createAPIToken(userID, userLogin, tokenType, scope, expiresAt)
 	if err != nil {
 		return "", "", err
 	}
@@ -331,6 +469,11 @@ func createAndStoreAPIToken(userID string, userLogin string, tokenType bauth.Tok
 		return "", "", errors.Wrap(err, "Failed to store user token")
 	}
 
+	err = storeTokenMetadata(userID, tokenID, scope, expiresAt)
+	if err != nil {
+		return "", "", errors.Wrap(err, "Failed to store user token metadata")
+	}
+
 	return tokenID, signedToken, nil
 }
 
@@ -346,17 +489,26 @@ func GetTokens(c *gin.Context) {
 
 	if tokenID == "" {
 		tokens, err := tokenStore.List(currentUser.IDString())
+		if err == nil {
+			var responses []*TokenResponse
+			responses, err = newTokenResponses(currentUser.IDString(), tokens...)
+			if err == nil {
+				c.JSON(http.StatusOK, responses)
+			}
+		}
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, err)
-		} else {
-			c.JSON(http.StatusOK, tokens)
 		}
 	} else {
 		token, err := tokenStore.Lookup(currentUser.IDString(), tokenID)
+		var responses []*TokenResponse
+		if err == nil && token != nil {
+			responses, err = newTokenResponses(currentUser.IDString(), token)
+		}
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, err)
 		} else if token != nil {
-			c.JSON(http.StatusOK, token)
+			c.JSON(http.StatusOK, responses[0])
 		} else {
 			c.AbortWithStatusJSON(http.StatusNotFound, pkgCommon.ErrorResponse{
 				Code:    http.StatusNotFound,
@@ -381,6 +533,9 @@ func DeleteToken(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusBadRequest, fmt.Errorf("Missing token id"))
 	} else {
 		err := tokenStore.Revoke(currentUser.IDString(), tokenID)
+		if err == nil {
+			err = deleteTokenMetadata(currentUser.IDString(), tokenID)
+		}
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, err)
 		} else {
@@ -411,7 +566,7 @@ func (sessionStorer *BanzaiSessionStorer) Update(w http.ResponseWriter, req *htt
 
 	// Drone tokens have to stored in Vault, because they act as Pipeline API tokens as well
 	// TODO We need GC them somehow
-	_, droneToken, err := createAndStoreAPIToken(claims.UserID, currentUser.Login, DroneUserTokenType, "Drone session token")
+	_, droneToken, err := createAndStoreAPIToken(claims.UserID, currentUser.Login, DroneUserTokenType, "Drone session token", TokenScopeFull, nil)
 	if err != nil {
 		log.Info(req.RemoteAddr, err.Error())
 		return err
@@ -503,6 +658,9 @@ func BanzaiDeregisterHandler(context *auth.Context) {
 
 	for _, token := range tokens {
 		err = tokenStore.Revoke(user.IDString(), token.ID)
+		if err == nil {
+			err = deleteTokenMetadata(user.IDString(), token.ID)
+		}
 		if err != nil {
 			log.Errorln("Failed remove user's tokens during user deletetion:", err)
 			http.Error(context.Writer, err.Error(), http.StatusInternalServerError)
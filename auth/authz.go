@@ -3,6 +3,8 @@ package auth
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/banzaicloud/pipeline/database"
@@ -26,7 +28,7 @@ g = _, _
 e = some(where (p.eft == allow))
 
 [matchers]
-m = g(r.sub, p.sub) && keyMatch(r.obj, p.obj) && (r.act == p.act || p.act == "*")
+m = g(r.sub, p.sub) && (keyMatch(r.obj, p.obj) || resourceMatch(r.obj, p.obj)) && (r.act == p.act || p.act == "*")
 `
 
 const logging = false
@@ -39,8 +41,11 @@ func NewAuthorizer() gin.HandlerFunc {
 	adapter := gormadapter.NewAdapter("mysql", database.GetDataSource(dbName), true)
 	model := casbin.NewModel(modelDefinition)
 	enforcer = casbin.NewSyncedEnforcer(model, adapter, logging)
+	enforcer.AddFunction("resourceMatch", resourceMatchFunc)
 	enforcer.StartAutoLoadPolicy(10 * time.Second)
 	addDefaultPolicies()
+	migrateOrgRoles()
+	updateBuiltInRoles()
 	return newAuthorizer(enforcer)
 }
 
@@ -76,6 +81,9 @@ func (a *BearerAuthorizer) CheckPermission(r *http.Request) bool {
 	userID := a.GetUserID(r)
 	method := r.Method
 	path := r.URL.Path
+	if !tokenAllows(GetCurrentUser(r).TokenScopes, method, strings.TrimPrefix(path, viper.GetString("pipeline.basepath")+"/api/v1")) {
+		return false
+	}
 	return a.enforcer.Enforce(userID, path, method)
 }
 
@@ -91,6 +99,7 @@ func addDefaultPolicies() {
 	enforcer.AddPolicy("default", basePath+"/api/v1/orgs", "*")
 	enforcer.AddPolicy("default", basePath+"/api/v1/token", "*")
 	enforcer.AddPolicy("default", basePath+"/api/v1/tokens", "*")
+	enforcer.AddPolicy("default", basePath+"/api/v1/invitations/accept", "POST")
 	enforcer.AddPolicy("defaultVirtual", basePath+"/api/v1/orgs", "GET")
 }
 
@@ -104,25 +113,39 @@ func AddDefaultRoleForVirtualUser(userID interface{}) {
 	enforcer.AddRoleForUser(fmt.Sprint(userID), "defaultVirtual")
 }
 
-// AddOrgRoles creates an organization role, by adding the default (*) org policies for the given organization.
+// AddOrgRoles creates the built-in roles (admin, member, viewer) of the given organizations.
 func AddOrgRoles(orgids ...uint) {
-	basePath := viper.GetString("pipeline.basepath")
 	for _, orgid := range orgids {
-		enforcer.AddPolicy(orgRoleName(orgid), fmt.Sprintf("%s/api/v1/orgs/%d", basePath, orgid), "*")
-		enforcer.AddPolicy(orgRoleName(orgid), fmt.Sprintf("%s/api/v1/orgs/%d/*", basePath, orgid), "*")
+		for _, role := range builtInRoles() {
+			addRolePolicies(orgid, role)
+		}
 	}
 }
 
-// AddOrgRoleForUser adds a user to an organization by adding the associated organization role.
+// AddOrgRoleForUser adds a user to organizations by adding the role the user has in them.
+// Users who aren't members of the organization (e.g. Drone virtual users) get the member role.
 func AddOrgRoleForUser(userID interface{}, orgids ...uint) {
 	for _, orgid := range orgids {
-		enforcer.AddRoleForUser(fmt.Sprint(userID), orgRoleName(orgid))
+		role := RoleMember
+		if id, err := strconv.ParseUint(fmt.Sprint(userID), 10, 32); err == nil {
+			membership := UserOrganization{}
+			err := database.GetDB().Where(&UserOrganization{UserID: uint(id), OrganizationID: orgid}).First(&membership).Error
+			if err == nil && membership.Role != "" {
+				role = membership.Role
+			}
+		}
+		setOrgRoleForUser(fmt.Sprint(userID), orgid, role)
 	}
 }
 
-// DeleteOrgRoleForUser removes a user from an organization by removing the associated organization role.
+// DeleteOrgRoleForUser removes a user from an organization by removing the user's organization role.
 func DeleteOrgRoleForUser(userID uint, orgid uint) {
-	enforcer.DeleteRoleForUser(fmt.Sprint(userID), orgRoleName(orgid))
+	user := fmt.Sprint(userID)
+	for _, role := range enforcer.GetRolesForUser(user) {
+		if role == orgRoleName(orgid) || strings.HasPrefix(role, orgRoleName(orgid)+"-") {
+			enforcer.DeleteRoleForUser(user, role)
+		}
+	}
 }
 
 // DeleteRolesForUser removes all roles for a given user.
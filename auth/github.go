@@ -65,10 +65,13 @@ func NewGithubAuthorizeHandler(provider *githubauth.GithubProvider) func(context
 			return nil, err
 		}
 
+		verifiedEmail := getGithubVerifiedEmail(client)
+
 		authInfo.Provider = provider.GetName()
 		authInfo.UID = fmt.Sprint(*user.ID)
 
 		if !tx.Model(authIdentity).Where(authInfo).Scan(&authInfo).RecordNotFound() {
+			acceptLoginInvitations(authInfo.UserID, provider.GetName(), verifiedEmail)
 			return authInfo.ToClaims(), nil
 		}
 
@@ -89,6 +92,7 @@ func NewGithubAuthorizeHandler(provider *githubauth.GithubProvider) func(context
 		}
 
 		if err = tx.Where(authInfo).FirstOrCreate(authIdentity).Error; err == nil {
+			acceptLoginInvitations(authInfo.UserID, provider.GetName(), verifiedEmail)
 			return authInfo.ToClaims(), nil
 		}
 
@@ -97,6 +101,21 @@ func NewGithubAuthorizeHandler(provider *githubauth.GithubProvider) func(context
 	}
 }
 
+// getGithubVerifiedEmail returns the primary email address of the GitHub user if it's verified
+func getGithubVerifiedEmail(client *github.Client) string {
+	emails, _, err := client.Users.ListEmails(oauth2.NoContext, nil)
+	if err != nil {
+		log.Warnf("Error listing GitHub emails: %s", err.Error())
+		return ""
+	}
+	for _, email := range emails {
+		if email.GetPrimary() && email.GetVerified() {
+			return email.GetEmail()
+		}
+	}
+	return ""
+}
+
 // GetGithubUser returns github user by token
 func GetGithubUser(accessToken string) (*github.User, error) {
 	client := github.NewClient(oauth2.NewClient(oauth2.NoContext, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken})))
@@ -0,0 +1,362 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qor/auth"
+	"github.com/qor/auth/auth_identity"
+	"github.com/qor/auth/claims"
+	"github.com/qor/qor/utils"
+	"golang.org/x/oauth2"
+)
+
+// GitlabProviderName is the name of the GitLab login provider, it's used in the /auth/gitlab/... paths
+const GitlabProviderName = "gitlab"
+
+// gitlabHTTPTimeout is the timeout of the requests to the GitLab API
+const gitlabHTTPTimeout = 10 * time.Second
+
+// Access levels of the GitLab group members: owners are imported as organization admins, developers and
+// maintainers as members, guests and reporters as viewers
+const (
+	gitlabGuestAccessLevel     = 10
+	gitlabDeveloperAccessLevel = 30
+	gitlabOwnerAccessLevel     = 50
+)
+
+// gitlabHTTPClient is the client of the requests to the GitLab API
+var gitlabHTTPClient = &http.Client{Timeout: gitlabHTTPTimeout}
+
+// GitlabExtraInfo struct for GitLab credentials
+type GitlabExtraInfo struct {
+	Login   string
+	Token   string
+	BaseURL string
+}
+
+// GitlabConfig describes a GitLab instance and the OAuth application registered in it
+type GitlabConfig struct {
+	// BaseURL of the GitLab instance, e.g. https://gitlab.com or a self-hosted instance
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// GitlabProvider provides login with GitLab
+type GitlabProvider struct {
+	*GitlabConfig
+}
+
+// gitlabUser is the used fields of a GitLab user
+type gitlabUser struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+	// ConfirmedAt is set when the user has confirmed its primary email address
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+}
+
+// verifiedEmail returns the primary email address of the user if it's confirmed
+func (user *gitlabUser) verifiedEmail() string {
+	if user.ConfirmedAt == nil {
+		return ""
+	}
+	return user.Email
+}
+
+// gitlabGroup is the used fields of a GitLab group
+type gitlabGroup struct {
+	ID       int64  `json:"id"`
+	FullPath string `json:"full_path"`
+}
+
+// NewGitlabProvider returns a GitLab login provider
+func NewGitlabProvider(config *GitlabConfig) *GitlabProvider {
+	if config.ClientID == "" {
+		panic(errors.New("GitLab's ClientID can't be blank"))
+	}
+	if config.ClientSecret == "" {
+		panic(errors.New("GitLab's ClientSecret can't be blank"))
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://gitlab.com"
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+
+	return &GitlabProvider{GitlabConfig: config}
+}
+
+// GetName return provider name
+func (*GitlabProvider) GetName() string {
+	return GitlabProviderName
+}
+
+// ConfigAuth config auth
+func (*GitlabProvider) ConfigAuth(*auth.Auth) {
+}
+
+// OAuthConfig return oauth config based on configuration
+func (provider *GitlabProvider) OAuthConfig(context *auth.Context) *oauth2.Config {
+	scheme := "http://"
+	if IsHttps(context.Request) {
+		scheme = "https://"
+	}
+
+	return &oauth2.Config{
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  provider.BaseURL + "/oauth/authorize",
+			TokenURL: provider.BaseURL + "/oauth/token",
+		},
+		RedirectURL: scheme + context.Request.Host + context.Auth.AuthURL(GitlabProviderName+"/callback"),
+		Scopes:      provider.Scopes,
+	}
+}
+
+// Login implemented login with GitLab provider
+func (provider *GitlabProvider) Login(context *auth.Context) {
+	claims := claims.Claims{}
+	claims.Subject = "state"
+	signedToken := context.Auth.SessionStorer.SignedToken(&claims)
+
+	url := provider.OAuthConfig(context).AuthCodeURL(signedToken)
+	http.Redirect(context.Writer, context.Request, url, http.StatusFound)
+}
+
+// Logout implemented logout with GitLab provider
+func (*GitlabProvider) Logout(context *auth.Context) {
+}
+
+// Register implemented register with GitLab provider
+func (provider *GitlabProvider) Register(context *auth.Context) {
+	provider.Login(context)
+}
+
+// Deregister implemented deregister with GitLab provider
+func (*GitlabProvider) Deregister(context *auth.Context) {
+	context.Writer.WriteHeader(http.StatusNotImplemented)
+}
+
+// Callback implement Callback with GitLab provider
+func (provider *GitlabProvider) Callback(context *auth.Context) {
+	context.Auth.LoginHandler(context, provider.authorize)
+}
+
+// ServeHTTP implement ServeHTTP with GitLab provider
+func (*GitlabProvider) ServeHTTP(*auth.Context) {
+}
+
+// authorize exchanges the authorization code for a token and logs in the GitLab user, new users are registered
+func (provider *GitlabProvider) authorize(context *auth.Context) (*claims.Claims, error) {
+	var (
+		schema       auth.Schema
+		authInfo     auth_identity.Basic
+		authIdentity = reflect.New(utils.ModelType(context.Auth.Config.AuthIdentityModel)).Interface()
+		req          = context.Request
+		tx           = context.Auth.GetDB(req)
+		oauthCfg     = provider.OAuthConfig(context)
+	)
+
+	state := req.URL.Query().Get("state")
+	stateClaims, err := context.Auth.SessionStorer.ValidateClaims(state)
+	if err != nil {
+		log.Info(context.Request.RemoteAddr, err.Error())
+		return nil, err
+	}
+	if stateClaims.Valid() != nil || stateClaims.Subject != "state" {
+		log.Info(context.Request.RemoteAddr, auth.ErrUnauthorized.Error())
+		return nil, auth.ErrUnauthorized
+	}
+
+	token, err := exchangeGitlabCode(oauthCfg, req.URL.Query().Get("code"))
+	if err != nil {
+		log.Info(context.Request.RemoteAddr, err.Error())
+		return nil, err
+	}
+
+	user := &gitlabUser{}
+	if err := getGitlab(provider.BaseURL, token.AccessToken, "/user", user); err != nil {
+		log.Info(context.Request.RemoteAddr, err.Error())
+		return nil, err
+	}
+
+	authInfo.Provider = provider.GetName()
+	authInfo.UID = fmt.Sprint(user.ID)
+
+	if !tx.Model(authIdentity).Where(authInfo).Scan(&authInfo).RecordNotFound() {
+		acceptLoginInvitations(authInfo.UserID, provider.GetName(), user.verifiedEmail())
+		return authInfo.ToClaims(), nil
+	}
+
+	{
+		schema.Provider = provider.GetName()
+		schema.UID = fmt.Sprint(user.ID)
+		schema.Name = user.Name
+		schema.Email = user.Email
+		schema.Image = user.AvatarURL
+		schema.RawInfo = &GitlabExtraInfo{Login: user.Username, Token: token.AccessToken, BaseURL: provider.BaseURL}
+	}
+	if _, userID, err := context.Auth.UserStorer.Save(&schema, context); err == nil {
+		if userID != "" {
+			authInfo.UserID = userID
+		}
+	} else {
+		return nil, err
+	}
+
+	if err = tx.Where(authInfo).FirstOrCreate(authIdentity).Error; err == nil {
+		acceptLoginInvitations(authInfo.UserID, provider.GetName(), user.verifiedEmail())
+		return authInfo.ToClaims(), nil
+	}
+
+	log.Info(context.Request.RemoteAddr, err.Error())
+	return nil, err
+}
+
+// exchangeGitlabCode exchanges the authorization code for a token with the timeout of the GitLab API requests
+func exchangeGitlabCode(oauthCfg *oauth2.Config, code string) (*oauth2.Token, error) {
+	return oauthCfg.Exchange(context.WithValue(context.Background(), oauth2.HTTPClient, gitlabHTTPClient), code)
+}
+
+// getGitlab gets the resource of the GitLab API v4 with the token
+func getGitlab(baseURL, token, path string, v interface{}) error {
+	request, err := http.NewRequest(http.MethodGet, baseURL+"/api/v4"+path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	response, err := gitlabHTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitLab API %s responded with %s", path, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(v)
+}
+
+// listGitlabGroups lists the groups the user has at least the access level in, following the pages of the result
+func listGitlabGroups(baseURL, token string, minAccessLevel int) ([]*gitlabGroup, error) {
+	groups := []*gitlabGroup{}
+	for page := 1; ; page++ {
+		var pageGroups []*gitlabGroup
+		path := "/groups?min_access_level=" + strconv.Itoa(minAccessLevel) + "&per_page=100&page=" + strconv.Itoa(page)
+		if err := getGitlab(baseURL, token, path, &pageGroups); err != nil {
+			return nil, err
+		}
+		groups = append(groups, pageGroups...)
+		if len(pageGroups) < 100 {
+			return groups, nil
+		}
+	}
+}
+
+// gitlabRole returns the role of the GitLab group members with the access level
+func gitlabRole(accessLevel int) string {
+	switch {
+	case accessLevel >= gitlabOwnerAccessLevel:
+		return RoleAdmin
+	case accessLevel >= gitlabDeveloperAccessLevel:
+		return RoleMember
+	default:
+		return RoleViewer
+	}
+}
+
+func getGitlabOrganizations(baseURL, token string) ([]*Organization, error) {
+	groups, err := listGitlabGroups(baseURL, token, gitlabGuestAccessLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := map[int64]string{}
+	for _, level := range []int{gitlabDeveloperAccessLevel, gitlabOwnerAccessLevel} {
+		levelGroups, err := listGitlabGroups(baseURL, token, level)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range levelGroups {
+			roles[group.ID] = gitlabRole(level)
+		}
+	}
+
+	orgs := []*Organization{}
+	for _, group := range groups {
+		role, ok := roles[group.ID]
+		if !ok {
+			role = gitlabRole(gitlabGuestAccessLevel)
+		}
+		gitlabID := group.ID
+		// subgroups are imported as separate organizations named by their full path
+		name := strings.Replace(group.FullPath, "/", "-", -1)
+		org := Organization{Name: name, GitlabID: &gitlabID, Role: role}
+		orgs = append(orgs, &org)
+	}
+	return orgs, nil
+}
+
+func importGitlabOrganizations(currentUser *User, context *auth.Context, baseURL, gitlabToken string) ([]uint, error) {
+
+	gitlabOrgs, err := getGitlabOrganizations(baseURL, gitlabToken)
+	if err != nil {
+		log.Info("Failed to list organizations", err)
+		gitlabOrgs = []*Organization{}
+	}
+
+	orgids := []uint{}
+
+	tx := context.Auth.GetDB(context.Request).Begin()
+	{
+		for _, gitlabOrg := range gitlabOrgs {
+			// groups are matched by ID, so a group never grants access to an organization created otherwise
+			role := gitlabOrg.Role
+			err = tx.Where(Organization{GitlabID: gitlabOrg.GitlabID}).First(gitlabOrg).Error
+			if err != nil && tx.Where("name = ?", gitlabOrg.Name).First(&Organization{}).RecordNotFound() {
+				err = tx.Create(gitlabOrg).Error
+			} else if err != nil {
+				log.Warnf("Organization %s exists already, skipping the import of its GitLab group", gitlabOrg.Name)
+				continue
+			}
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			previousRole := membershipRole(tx, currentUser.ID, gitlabOrg.ID)
+			err = tx.Model(currentUser).Association("Organizations").Append(gitlabOrg).Error
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			userRoleInOrg := UserOrganization{UserID: currentUser.ID, OrganizationID: gitlabOrg.ID}
+			err = tx.Model(&UserOrganization{}).Where(userRoleInOrg).Update("role", role).Error
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			revokeChangedRoleCredentials(currentUser.ID, gitlabOrg.ID, previousRole, role)
+			orgids = append(orgids, gitlabOrg.ID)
+		}
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return orgids, nil
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestGetGitlabOrganizations(t *testing.T) {
+	groups := []gitlabGroup{{ID: 1, FullPath: "dev"}, {ID: 2, FullPath: "team/ops"}}
+	for id := int64(3); id < 105; id++ {
+		groups = append(groups, gitlabGroup{ID: id, FullPath: "group" + strconv.FormatInt(id, 10)})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/groups" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Query().Get("min_access_level") {
+		case "50":
+			json.NewEncoder(w).Encode(groups[1:2])
+			return
+		case "30":
+			json.NewEncoder(w).Encode(groups[:3])
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start, end := (page-1)*100, page*100
+		if end > len(groups) {
+			end = len(groups)
+		}
+		json.NewEncoder(w).Encode(groups[start:end])
+	}))
+	defer server.Close()
+
+	orgs, err := getGitlabOrganizations(server.URL, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orgs) != len(groups) {
+		t.Fatalf("expected %d organizations, got %d", len(groups), len(orgs))
+	}
+	if orgs[0].Name != "dev" || *orgs[0].GitlabID != 1 || orgs[0].Role != RoleMember {
+		t.Errorf("unexpected organization: %+v", orgs[0])
+	}
+	if orgs[1].Name != "team-ops" || *orgs[1].GitlabID != 2 || orgs[1].Role != RoleAdmin {
+		t.Errorf("unexpected organization: %+v", orgs[1])
+	}
+	if orgs[3].Name != "group4" || orgs[3].Role != RoleViewer {
+		t.Errorf("expected guests to be imported as viewers, got %+v", orgs[3])
+	}
+
+	if _, err := getGitlabOrganizations(server.URL, "other"); err == nil {
+		t.Error("expected error for unauthorized token")
+	}
+}
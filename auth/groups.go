@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Columns of the organizations holding the group an organization was imported from
+const (
+	oidcGroupColumn = "oidc_group"
+	ldapGroupColumn = "ldap_group"
+)
+
+// groupRoles maps the groups with the prefix to organization names and the roles of the user in them.
+// Group paths (e.g. Keycloak's /team/dev) are joined with dashes, members of the admin
+// groups are admins of their organization, the others are members.
+func groupRoles(groups []string, prefix, adminSuffix string) map[string]string {
+	roles := map[string]string{}
+	for _, group := range groups {
+		name := strings.TrimPrefix(group, "/")
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		name = strings.TrimPrefix(name, prefix)
+
+		role := RoleMember
+		if adminSuffix != "" && strings.HasSuffix(name, adminSuffix) {
+			name = strings.TrimSuffix(name, adminSuffix)
+			role = RoleAdmin
+		}
+
+		name = strings.Trim(strings.Replace(name, "/", "-", -1), "-")
+		if name == "" {
+			continue
+		}
+		if roles[name] != RoleAdmin {
+			roles[name] = role
+		}
+	}
+	return roles
+}
+
+func newGroupOrganization(groupColumn, name string) *Organization {
+	organization := &Organization{Name: name}
+	switch groupColumn {
+	case oidcGroupColumn:
+		organization.OIDCGroup = &name
+	case ldapGroupColumn:
+		organization.LDAPGroup = &name
+	}
+	return organization
+}
+
+// importGroupOrganizations adds the user to the organizations of its groups with the given roles, creating the missing ones.
+// Organizations are matched by their group, so a group never grants access to an organization created otherwise.
+func importGroupOrganizations(db *gorm.DB, currentUser *User, roles map[string]string, groupColumn string) ([]uint, error) {
+	names := make([]string, 0, len(roles))
+	for name := range roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	orgids := []uint{}
+
+	tx := db.Begin()
+	{
+		for _, name := range names {
+			organization := &Organization{}
+			err := tx.Where(groupColumn+" = ?", name).First(organization).Error
+			if err == gorm.ErrRecordNotFound {
+				if !tx.Where("name = ?", name).First(&Organization{}).RecordNotFound() {
+					log.Warnf("Organization %s exists already, skipping the import of its group", name)
+					continue
+				}
+				organization = newGroupOrganization(groupColumn, name)
+				err = tx.Create(organization).Error
+			}
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			previousRole := membershipRole(tx, currentUser.ID, organization.ID)
+			err = tx.Model(currentUser).Association("Organizations").Append(organization).Error
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			userRoleInOrg := UserOrganization{UserID: currentUser.ID, OrganizationID: organization.ID}
+			err = tx.Model(&UserOrganization{}).Where(userRoleInOrg).Update("role", roles[name]).Error
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			revokeChangedRoleCredentials(currentUser.ID, organization.ID, previousRole, roles[name])
+			orgids = append(orgids, organization.ID)
+		}
+	}
+
+	err := tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return orgids, nil
+}
+
+// syncGroupOrganizations imports the organizations of the user's groups and grants the roles the groups map to.
+// With revoke the user is removed from the imported organizations of the groups it's no longer a member of.
+func syncGroupOrganizations(db *gorm.DB, currentUser *User, roles map[string]string, groupColumn string, revoke bool) error {
+	orgids, err := importGroupOrganizations(db, currentUser, roles, groupColumn)
+	if err != nil {
+		return err
+	}
+
+	AddOrgRoles(orgids...)
+	AddOrgRoleForUser(currentUser.ID, orgids...)
+
+	if !revoke {
+		return nil
+	}
+
+	var memberships []UserOrganization
+	err = db.Joins("JOIN organizations ON organizations.id = user_organizations.organization_id").
+		Where("user_organizations.user_id = ? AND organizations."+groupColumn+" IS NOT NULL", currentUser.ID).
+		Find(&memberships).Error
+	if err != nil {
+		return err
+	}
+
+	for _, orgid := range revokedOrganizations(memberships, orgids) {
+		err := db.Where(UserOrganization{UserID: currentUser.ID, OrganizationID: orgid}).
+			Delete(&UserOrganization{}).Error
+		if err != nil {
+			return err
+		}
+		DeleteOrgRoleForUser(currentUser.ID, orgid)
+		go RevokeUserCredentials(orgid, currentUser.ID)
+		log.Infof("Removed user %s from organization %d", currentUser.Login, orgid)
+	}
+
+	return nil
+}
+
+// revokedOrganizations returns the organizations of the memberships which are not among the current group organizations
+func revokedOrganizations(memberships []UserOrganization, orgids []uint) []uint {
+	current := map[uint]bool{}
+	for _, orgid := range orgids {
+		current[orgid] = true
+	}
+
+	revoked := []uint{}
+	for _, membership := range memberships {
+		if !current[membership.OrganizationID] {
+			revoked = append(revoked, membership.OrganizationID)
+		}
+	}
+	return revoked
+}
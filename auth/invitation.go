@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/database"
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+)
+
+// ErrInvitationNotFound is returned when no pending invitation matches the token
+var ErrInvitationNotFound = errors.New("invitation not found or expired")
+
+// Invitation invites a user by login or email to an organization with a role.
+// Invitations to a login provider are accepted when the user logs in with the provider,
+// other invitations are accepted only with their token.
+type Invitation struct {
+	ID             uint       `gorm:"primary_key" json:"id"`
+	OrganizationID uint       `gorm:"index;not null" json:"organizationId"`
+	Login          string     `gorm:"index" json:"login,omitempty"`
+	Email          string     `gorm:"index" json:"email,omitempty"`
+	Provider       string     `json:"provider,omitempty"`
+	Role           string     `gorm:"not null" json:"role"`
+	TokenHash      string     `gorm:"unique;size:64;not null" json:"-"`
+	InvitedBy      uint       `json:"invitedBy"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	RevokedAt      *time.Time `json:"-"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// InvitationResponse is a created invitation with its token, the token is not stored
+type InvitationResponse struct {
+	*Invitation
+	Token string `json:"token"`
+}
+
+// pending tells whether the invitation can still be accepted
+func (invitation *Invitation) pending(now time.Time) bool {
+	return invitation.RevokedAt == nil && now.Before(invitation.ExpiresAt)
+}
+
+// acceptableOnLogin tells whether the pending invitation is accepted when the user logs in with the provider.
+// Only the login and the email address verified by the provider the invitation was sent to are trusted.
+func (invitation *Invitation) acceptableOnLogin(user *User, provider, verifiedEmail string, now time.Time) bool {
+	if !invitation.pending(now) || invitation.Provider == "" || invitation.Provider != provider {
+		return false
+	}
+	if invitation.Login != "" && invitation.Login == user.Login {
+		return true
+	}
+	return invitation.Email != "" && invitation.Email == strings.ToLower(verifiedEmail)
+}
+
+func hashInvitationToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func newInvitationToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// CreateInvitation invites a user by login or email to the organization with the role,
+// pending invitations of the same user to the organization are replaced.
+// The invitation is accepted when the user logs in with the provider, without a provider only its token accepts it.
+func CreateInvitation(orgID, invitedBy uint, login, email, provider, role string) (*InvitationResponse, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	login = strings.TrimSpace(login)
+	if login == "" && email == "" {
+		return nil, errors.New("login or email is required")
+	}
+	if provider != "" && Auth.GetProvider(provider) == nil {
+		return nil, fmt.Errorf("login provider not found: %q", provider)
+	}
+	if GetOrgRole(orgID, role) == nil {
+		return nil, fmt.Errorf("role not found: %q", role)
+	}
+
+	token, err := newInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &Invitation{
+		OrganizationID: orgID,
+		Login:          login,
+		Email:          email,
+		Provider:       provider,
+		Role:           role,
+		TokenHash:      hashInvitationToken(token),
+		InvitedBy:      invitedBy,
+		ExpiresAt:      time.Now().Add(viper.GetDuration(config.InvitationExpiry)),
+	}
+
+	tx := database.GetDB().Begin()
+	{
+		err = tx.Where(&Invitation{OrganizationID: orgID, Login: login, Email: email}).Delete(&Invitation{}).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		err = tx.Create(invitation).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &InvitationResponse{Invitation: invitation, Token: token}, nil
+}
+
+// ListInvitations lists the pending invitations of the organization, its expired and revoked invitations are deleted
+func ListInvitations(orgID uint) ([]*Invitation, error) {
+	db := database.GetDB()
+	now := time.Now()
+
+	err := db.Where("organization_id = ? AND (expires_at <= ? OR revoked_at IS NOT NULL)", orgID, now).
+		Delete(&Invitation{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []*Invitation{}
+	err = db.Where("organization_id = ? AND expires_at > ? AND revoked_at IS NULL", orgID, now).
+		Order("id").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+// RevokeInvitation revokes a pending invitation of the organization, its token can't be accepted afterwards
+func RevokeInvitation(orgID, invitationID uint) error {
+	db := database.GetDB().Model(&Invitation{}).
+		Where("id = ? AND organization_id = ? AND revoked_at IS NULL", invitationID, orgID).
+		Update("revoked_at", time.Now())
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected != 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AcceptInvitations adds the user logging in with the provider to the organizations of the pending invitations
+// sent to the provider, matching its login or the email address verified by the provider.
+// The invitations of new users are accepted on their first login this way.
+func AcceptInvitations(user *User, provider, verifiedEmail string) ([]uint, error) {
+	db := database.GetDB()
+	now := time.Now()
+
+	query := db.Where("provider = ? AND expires_at > ? AND revoked_at IS NULL", provider, now)
+	if email := strings.ToLower(verifiedEmail); email != "" {
+		query = query.Where("login = ? OR email = ?", user.Login, email)
+	} else {
+		query = query.Where("login = ?", user.Login)
+	}
+
+	var invitations []*Invitation
+	if err := query.Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+
+	orgids := []uint{}
+	for _, invitation := range invitations {
+		if !invitation.acceptableOnLogin(user, provider, verifiedEmail, now) {
+			continue
+		}
+		err := acceptInvitation(db, user, invitation)
+		if err == ErrInvitationNotFound {
+			continue
+		} else if err != nil {
+			return orgids, err
+		}
+		orgids = append(orgids, invitation.OrganizationID)
+	}
+	return orgids, nil
+}
+
+// acceptLoginInvitations accepts the invitations of the user logging in with the provider,
+// verifiedEmail is the email address of the user verified by the provider, if any
+func acceptLoginInvitations(userID, provider, verifiedEmail string) {
+	user := &User{}
+	if err := database.GetDB().Where("id = ?", userID).First(user).Error; err != nil {
+		log.Warnf("Error getting user %s to accept its invitations: %s", userID, err.Error())
+		return
+	}
+	if _, err := AcceptInvitations(user, provider, verifiedEmail); err != nil {
+		log.Warnf("Error accepting invitations of user %s: %s", user.Login, err.Error())
+	}
+}
+
+// AcceptInvitationToken adds the user to the organization of the pending invitation with the token,
+// regardless of the login or email the invitation was sent to
+func AcceptInvitationToken(user *User, token string) (*Invitation, error) {
+	db := database.GetDB()
+
+	invitation := &Invitation{}
+	err := db.Where("token_hash = ?", hashInvitationToken(token)).First(invitation).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrInvitationNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if !invitation.pending(time.Now()) {
+		return nil, ErrInvitationNotFound
+	}
+
+	return invitation, acceptInvitation(db, user, invitation)
+}
+
+// acceptInvitation deletes the invitation and creates the membership with the invited role,
+// users who are already members of the organization keep their role.
+// Invitations revoked or accepted in the meantime aren't accepted.
+func acceptInvitation(db *gorm.DB, user *User, invitation *Invitation) error {
+	organization := &Organization{ID: invitation.OrganizationID}
+
+	tx := db.Begin()
+	{
+		deleted := tx.Where("revoked_at IS NULL").Delete(invitation)
+		if deleted.Error != nil {
+			tx.Rollback()
+			return deleted.Error
+		}
+		if deleted.RowsAffected != 1 {
+			tx.Rollback()
+			return ErrInvitationNotFound
+		}
+
+		membership := UserOrganization{UserID: user.ID, OrganizationID: organization.ID}
+		if tx.Where(membership).First(&UserOrganization{}).RecordNotFound() {
+			err := tx.Model(user).Association("Organizations").Append(organization).Error
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			err = tx.Model(&UserOrganization{}).Where(membership).Update("role", invitation.Role).Error
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	err := tx.Commit().Error
+	if err != nil {
+		return err
+	}
+
+	AddOrgRoleForUser(user.ID, organization.ID)
+	log.Infof("User %s accepted the invitation to organization %d", user.Login, organization.ID)
+
+	return nil
+}
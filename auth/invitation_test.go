@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInvitationPending(t *testing.T) {
+	now := time.Now()
+	revokedAt := now.Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		invitation Invitation
+		pending    bool
+	}{
+		{"pending", Invitation{ExpiresAt: now.Add(time.Hour)}, true},
+		{"expired", Invitation{ExpiresAt: now.Add(-time.Hour)}, false},
+		{"expires now", Invitation{ExpiresAt: now}, false},
+		{"revoked", Invitation{ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt}, false},
+	}
+	for _, test := range tests {
+		if pending := test.invitation.pending(now); pending != test.pending {
+			t.Errorf("%s: expected pending %v, got %v", test.name, test.pending, pending)
+		}
+	}
+}
+
+func TestInvitationAcceptableOnLogin(t *testing.T) {
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+	revokedAt := now.Add(-time.Minute)
+	user := &User{Login: "jdoe", Email: "jdoe@example.com"}
+
+	tests := []struct {
+		name          string
+		invitation    Invitation
+		provider      string
+		verifiedEmail string
+		acceptable    bool
+	}{
+		{"login", Invitation{Login: "jdoe", Provider: "github", ExpiresAt: expiresAt}, "github", "", true},
+		{"login of other provider", Invitation{Login: "jdoe", Provider: "github", ExpiresAt: expiresAt}, "gitlab", "", false},
+		{"other login", Invitation{Login: "jane", Provider: "github", ExpiresAt: expiresAt}, "github", "", false},
+		{"verified email", Invitation{Email: "jdoe@example.com", Provider: "oidc", ExpiresAt: expiresAt}, "oidc", "JDoe@example.com", true},
+		{"unverified email", Invitation{Email: "jdoe@example.com", Provider: "oidc", ExpiresAt: expiresAt}, "oidc", "", false},
+		{"verified email of other provider", Invitation{Email: "jdoe@example.com", Provider: "oidc", ExpiresAt: expiresAt}, "gitlab", "jdoe@example.com", false},
+		{"no provider", Invitation{Login: "jdoe", Email: "jdoe@example.com", ExpiresAt: expiresAt}, "github", "jdoe@example.com", false},
+		{"expired", Invitation{Login: "jdoe", Provider: "github", ExpiresAt: now.Add(-time.Hour)}, "github", "", false},
+		{"revoked", Invitation{Login: "jdoe", Provider: "github", ExpiresAt: expiresAt, RevokedAt: &revokedAt}, "github", "", false},
+	}
+	for _, test := range tests {
+		if acceptable := test.invitation.acceptableOnLogin(user, test.provider, test.verifiedEmail, now); acceptable != test.acceptable {
+			t.Errorf("%s: expected acceptable %v, got %v", test.name, test.acceptable, acceptable)
+		}
+	}
+}
+
+func TestInvitationToken(t *testing.T) {
+	token, err := newInvitationToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newInvitationToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(token) != 64 || token == other {
+		t.Errorf("unexpected tokens %q and %q", token, other)
+	}
+	if hash := hashInvitationToken(token); len(hash) != 64 || hash == token || hash != hashInvitationToken(token) {
+		t.Errorf("unexpected token hash %q", hash)
+	}
+}
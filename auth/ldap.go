@@ -0,0 +1,418 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/banzaicloud/pipeline/database"
+	"github.com/qor/auth"
+	"github.com/qor/auth/auth_identity"
+	"github.com/qor/auth/claims"
+	"github.com/qor/qor/utils"
+	"gopkg.in/ldap.v2"
+)
+
+// LDAPProviderName is the name of the LDAP login provider, it's used in the /auth/ldap/... paths
+const LDAPProviderName = "ldap"
+
+// errLDAPUserNotFound is returned when no user matches the user search
+var errLDAPUserNotFound = errors.New("LDAP user not found")
+
+// LDAPExtraInfo struct for LDAP users
+type LDAPExtraInfo struct {
+	Login string
+	// Roles maps the names of the organizations of the user's groups to the user's roles
+	Roles map[string]string
+}
+
+// LDAPConfig describes an LDAP server, the searches of the users and their groups and the mapping of the groups to organizations
+type LDAPConfig struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	// CAFile is the PEM file of the CA certificates the server certificate is verified with, empty uses the system roots
+	CAFile  string
+	Timeout time.Duration
+
+	BindDN       string
+	BindPassword string
+
+	UserBaseDN     string
+	UserFilter     string
+	LoginAttribute string
+	EmailAttribute string
+	NameAttribute  string
+
+	GroupBaseDN        string
+	GroupFilter        string
+	GroupNameAttribute string
+
+	// GroupPrefix selects the groups imported as organizations, it's removed from the organization names
+	GroupPrefix string
+	// AdminGroupSuffix marks the groups granting the admin role, it's removed from the organization names
+	AdminGroupSuffix string
+}
+
+// LDAPProvider provides username and password login with an LDAP server, e.g. OpenLDAP or Active Directory
+type LDAPProvider struct {
+	*LDAPConfig
+	tlsConfig *tls.Config
+}
+
+// ldapUser is a user found in the LDAP directory
+type ldapUser struct {
+	DN     string
+	Login  string
+	Email  string
+	Name   string
+	Groups []string
+}
+
+// NewLDAPProvider returns an LDAP login provider
+func NewLDAPProvider(config *LDAPConfig) *LDAPProvider {
+	if config.URL == "" {
+		panic(errors.New("LDAP URL can't be blank"))
+	}
+	if config.UserBaseDN == "" {
+		panic(errors.New("LDAP user base DN can't be blank"))
+	}
+
+	tlsConfig, err := newLDAPTLSConfig(config)
+	if err != nil {
+		panic(err)
+	}
+
+	return &LDAPProvider{LDAPConfig: config, tlsConfig: tlsConfig}
+}
+
+// newLDAPTLSConfig returns the TLS config of the connections to the LDAP server,
+// the server certificate is verified with the CA certificates of the CA file when it's given
+func newLDAPTLSConfig(config *LDAPConfig) (*tls.Config, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL: %s", err.Error())
+	}
+
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: config.InsecureSkipVerify}
+	if config.CAFile != "" {
+		pem, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading LDAP CA file: %s", err.Error())
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in LDAP CA file %s", config.CAFile)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// GetName return provider name
+func (*LDAPProvider) GetName() string {
+	return LDAPProviderName
+}
+
+// ConfigAuth config auth
+func (*LDAPProvider) ConfigAuth(*auth.Auth) {
+}
+
+// Login logs in the user with the login and password form values
+func (provider *LDAPProvider) Login(context *auth.Context) {
+	if context.Request.Method != http.MethodPost {
+		context.Writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	context.Auth.LoginHandler(context, provider.authorize)
+}
+
+// Logout implemented logout with LDAP provider
+func (*LDAPProvider) Logout(context *auth.Context) {
+}
+
+// Register implemented register with LDAP provider, users are registered on their first login
+func (provider *LDAPProvider) Register(context *auth.Context) {
+	provider.Login(context)
+}
+
+// Deregister implemented deregister with LDAP provider
+func (*LDAPProvider) Deregister(context *auth.Context) {
+	context.Writer.WriteHeader(http.StatusNotImplemented)
+}
+
+// Callback implement Callback with LDAP provider
+func (*LDAPProvider) Callback(context *auth.Context) {
+}
+
+// ServeHTTP implement ServeHTTP with LDAP provider
+func (*LDAPProvider) ServeHTTP(*auth.Context) {
+}
+
+// authorize authenticates the user with the LDAP server and logs it in.
+// New users are registered, the organizations of existing users are synchronized with their groups.
+func (provider *LDAPProvider) authorize(context *auth.Context) (*claims.Claims, error) {
+	var (
+		schema       auth.Schema
+		authInfo     auth_identity.Basic
+		authIdentity = reflect.New(utils.ModelType(context.Auth.Config.AuthIdentityModel)).Interface()
+		req          = context.Request
+		tx           = context.Auth.GetDB(req)
+	)
+
+	user, err := provider.authenticate(req.PostFormValue("login"), req.PostFormValue("password"))
+	if err != nil {
+		log.Info(req.RemoteAddr, " ", err.Error())
+		if err == errLDAPUserNotFound || ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, auth.ErrInvalidPassword
+		}
+		return nil, err
+	}
+	roles := groupRoles(user.Groups, provider.GroupPrefix, provider.AdminGroupSuffix)
+
+	authInfo.Provider = provider.GetName()
+	authInfo.UID = user.Login
+
+	if !tx.Model(authIdentity).Where(authInfo).Scan(&authInfo).RecordNotFound() {
+		currentUser := &User{}
+		if err := tx.Where("id = ?", authInfo.UserID).First(currentUser).Error; err != nil {
+			return nil, err
+		}
+		if err := syncGroupOrganizations(tx, currentUser, roles, ldapGroupColumn, true); err != nil {
+			log.Errorf("Error synchronizing organizations of user %s: %s", currentUser.Login, err.Error())
+		}
+		// the email addresses of the directory are managed by its administrators
+		acceptLoginInvitations(authInfo.UserID, provider.GetName(), user.Email)
+		return authInfo.ToClaims(), nil
+	}
+
+	{
+		schema.Provider = provider.GetName()
+		schema.UID = user.Login
+		schema.Name = user.Name
+		schema.Email = user.Email
+		schema.RawInfo = &LDAPExtraInfo{Login: user.Login, Roles: roles}
+	}
+	if _, userID, err := context.Auth.UserStorer.Save(&schema, context); err == nil {
+		if userID != "" {
+			authInfo.UserID = userID
+		}
+	} else {
+		return nil, err
+	}
+
+	if err = tx.Where(authInfo).FirstOrCreate(authIdentity).Error; err == nil {
+		acceptLoginInvitations(authInfo.UserID, provider.GetName(), user.Email)
+		return authInfo.ToClaims(), nil
+	}
+
+	log.Info(req.RemoteAddr, err.Error())
+	return nil, err
+}
+
+// dial opens a connection to the LDAP server, ldaps:// URLs are connected with TLS,
+// ldap:// connections are upgraded with StartTLS when it's enabled
+func (provider *LDAPProvider) dial() (*ldap.Conn, error) {
+	u, err := url.Parse(provider.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: provider.Timeout}
+	var netConn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+		netConn, err = dialer.Dial("tcp", host)
+	case "ldaps":
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+		netConn, err = tls.DialWithDialer(dialer, "tcp", host, provider.tlsConfig)
+	default:
+		return nil, fmt.Errorf("unsupported LDAP URL scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn := ldap.NewConn(netConn, u.Scheme == "ldaps")
+	conn.Start()
+	conn.SetTimeout(provider.Timeout)
+
+	if provider.StartTLS && u.Scheme == "ldap" {
+		if err := conn.StartTLS(provider.tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// connect opens a connection to the LDAP server bound as the service account
+func (provider *LDAPProvider) connect() (*ldap.Conn, error) {
+	conn, err := provider.dial()
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to LDAP server: %s", err.Error())
+	}
+
+	if provider.BindDN != "" {
+		if err := conn.Bind(provider.BindDN, provider.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error binding LDAP service account: %s", err.Error())
+		}
+	}
+
+	return conn, nil
+}
+
+// authenticate looks up the user by its login and checks its password by binding as the user
+func (provider *LDAPProvider) authenticate(login, password string) (*ldapUser, error) {
+	if login == "" || password == "" {
+		return nil, auth.ErrInvalidPassword
+	}
+
+	conn, err := provider.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	user, err := provider.findUser(conn, login)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(user.DN, password); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// findUser searches the user with the login and its groups
+func (provider *LDAPProvider) findUser(conn *ldap.Conn, login string) (*ldapUser, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		provider.UserBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(provider.UserFilter, ldap.EscapeFilter(login)),
+		[]string{provider.LoginAttribute, provider.EmailAttribute, provider.NameAttribute},
+		nil,
+	))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("multiple LDAP users match login %s", login)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error searching LDAP user: %s", err.Error())
+	}
+	if len(result.Entries) == 0 {
+		return nil, errLDAPUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("multiple LDAP users match login %s", login)
+	}
+
+	entry := result.Entries[0]
+	user := &ldapUser{
+		DN:    entry.DN,
+		Login: entry.GetAttributeValue(provider.LoginAttribute),
+		Email: entry.GetAttributeValue(provider.EmailAttribute),
+		Name:  entry.GetAttributeValue(provider.NameAttribute),
+	}
+	if user.Login == "" {
+		return nil, fmt.Errorf("LDAP user %s has no %s attribute", entry.DN, provider.LoginAttribute)
+	}
+
+	if provider.GroupBaseDN != "" {
+		groups, err := conn.Search(ldap.NewSearchRequest(
+			provider.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			fmt.Sprintf(provider.GroupFilter, ldap.EscapeFilter(entry.DN)),
+			[]string{provider.GroupNameAttribute},
+			nil,
+		))
+		if err != nil {
+			return nil, fmt.Errorf("error searching LDAP groups: %s", err.Error())
+		}
+		for _, group := range groups.Entries {
+			if name := group.GetAttributeValue(provider.GroupNameAttribute); name != "" {
+				user.Groups = append(user.Groups, name)
+			}
+		}
+	}
+
+	return user, nil
+}
+
+// SyncGroups synchronizes the organizations of the LDAP users with their groups, users who are
+// removed from a group or from the directory are removed from the organization of the group
+func (provider *LDAPProvider) SyncGroups() error {
+	db := database.GetDB()
+
+	var identities []AuthIdentity
+	if err := db.Where("provider = ?", provider.GetName()).Find(&identities).Error; err != nil {
+		return err
+	}
+	if len(identities) == 0 {
+		return nil
+	}
+
+	conn, err := provider.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, identity := range identities {
+		currentUser := &User{}
+		if err := db.Where("id = ?", identity.UserID).First(currentUser).Error; err != nil {
+			log.Warnf("Error getting user of LDAP identity %s: %s", identity.UID, err.Error())
+			continue
+		}
+
+		var roles map[string]string
+		user, err := provider.findUser(conn, identity.UID)
+		if err == errLDAPUserNotFound {
+			roles = map[string]string{}
+		} else if err != nil {
+			return err
+		} else {
+			roles = groupRoles(user.Groups, provider.GroupPrefix, provider.AdminGroupSuffix)
+		}
+
+		if err := syncGroupOrganizations(db, currentUser, roles, ldapGroupColumn, true); err != nil {
+			log.Errorf("Error synchronizing organizations of user %s: %s", currentUser.Login, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// StartLDAPGroupSync periodically synchronizes the organizations of the LDAP users with their groups
+func StartLDAPGroupSync(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			provider, ok := Auth.GetProvider(LDAPProviderName).(*LDAPProvider)
+			if !ok {
+				continue
+			}
+			if err := provider.SyncGroups(); err != nil {
+				log.Errorf("Error synchronizing LDAP groups: %s", err.Error())
+			}
+		}
+	}()
+
+	return ticker
+}
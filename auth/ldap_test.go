@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewLDAPTLSConfig(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Example CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caFile, err := ioutil.TempFile("", "ldap-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(caFile.Name())
+	pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	caFile.Close()
+
+	tlsConfig, err := newLDAPTLSConfig(&LDAPConfig{URL: "ldaps://ldap.example.com:636", CAFile: caFile.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig.ServerName != "ldap.example.com" || tlsConfig.RootCAs == nil || tlsConfig.InsecureSkipVerify {
+		t.Errorf("unexpected TLS config: %+v", tlsConfig)
+	}
+
+	if _, err := newLDAPTLSConfig(&LDAPConfig{URL: "ldaps://ldap.example.com", CAFile: os.DevNull}); err == nil {
+		t.Error("expected an error for a CA file without certificates")
+	}
+
+	tlsConfig, err = newLDAPTLSConfig(&LDAPConfig{URL: "ldap://ldap.example.com"})
+	if err != nil || tlsConfig.RootCAs != nil {
+		t.Errorf("expected the system roots without CA file, got %v", err)
+	}
+}
@@ -0,0 +1,432 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qor/auth"
+	"github.com/qor/auth/auth_identity"
+	"github.com/qor/auth/claims"
+	"github.com/qor/qor/utils"
+	"golang.org/x/oauth2"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// OIDCProviderName is the name of the OpenID Connect login provider, it's used in the /auth/oidc/... paths
+const OIDCProviderName = "oidc"
+
+// oidcHTTPTimeout is the timeout of the requests to the OpenID Connect provider
+const oidcHTTPTimeout = 10 * time.Second
+
+// OIDCExtraInfo struct for OpenID Connect users
+type OIDCExtraInfo struct {
+	Login string
+	// Roles maps the names of the organizations of the user's groups to the user's roles
+	Roles map[string]string
+}
+
+// OIDCConfig describes an OpenID Connect provider and the mapping of its claims to Pipeline users and organizations
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	LoginClaim  string
+	EmailClaim  string
+	NameClaim   string
+	GroupsClaim string
+
+	// GroupPrefix selects the groups imported as organizations, it's removed from the organization names
+	GroupPrefix string
+	// AdminGroupSuffix marks the groups granting the admin role, it's removed from the organization names
+	AdminGroupSuffix string
+}
+
+// OIDCProvider provides login with a generic OpenID Connect provider, e.g. Keycloak or Dex
+type OIDCProvider struct {
+	*OIDCConfig
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      *jose.JSONWebKeySet
+}
+
+// oidcDiscovery holds the used fields of the OpenID Connect discovery document
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcUser is a user described by the claims of the OpenID Connect provider
+type oidcUser struct {
+	Subject string
+	Login   string
+	Email   string
+	Name    string
+	Groups  []string
+	// EmailVerified tells whether the provider has verified the email address of the user
+	EmailVerified bool
+}
+
+// verifiedEmail returns the email address of the user if it's verified by the provider
+func (user *oidcUser) verifiedEmail() string {
+	if !user.EmailVerified {
+		return ""
+	}
+	return user.Email
+}
+
+// NewOIDCProvider returns an OpenID Connect login provider, the endpoints of the provider are discovered on first use
+func NewOIDCProvider(config *OIDCConfig) *OIDCProvider {
+	if config.IssuerURL == "" {
+		panic(errors.New("OpenID Connect issuer URL can't be blank"))
+	}
+	if config.ClientID == "" {
+		panic(errors.New("OpenID Connect ClientID can't be blank"))
+	}
+	if config.ClientSecret == "" {
+		panic(errors.New("OpenID Connect ClientSecret can't be blank"))
+	}
+
+	return &OIDCProvider{OIDCConfig: config}
+}
+
+// GetName return provider name
+func (*OIDCProvider) GetName() string {
+	return OIDCProviderName
+}
+
+// ConfigAuth config auth
+func (*OIDCProvider) ConfigAuth(*auth.Auth) {
+}
+
+// Login redirects to the authorization endpoint of the OpenID Connect provider
+func (provider *OIDCProvider) Login(context *auth.Context) {
+	discovery, err := provider.discover()
+	if err != nil {
+		log.Errorf("Error discovering OpenID Connect provider: %s", err.Error())
+		http.Error(context.Writer, "OpenID Connect provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	claims := claims.Claims{}
+	claims.Subject = "state"
+	signedToken := context.Auth.SessionStorer.SignedToken(&claims)
+
+	url := provider.OAuthConfig(context, discovery).AuthCodeURL(signedToken)
+	http.Redirect(context.Writer, context.Request, url, http.StatusFound)
+}
+
+// Logout implemented logout with OpenID Connect provider
+func (*OIDCProvider) Logout(context *auth.Context) {
+}
+
+// Register implemented register with OpenID Connect provider
+func (provider *OIDCProvider) Register(context *auth.Context) {
+	provider.Login(context)
+}
+
+// Deregister implemented deregister with OpenID Connect provider
+func (*OIDCProvider) Deregister(context *auth.Context) {
+	context.Writer.WriteHeader(http.StatusNotImplemented)
+}
+
+// Callback implement Callback with OpenID Connect provider
+func (provider *OIDCProvider) Callback(context *auth.Context) {
+	context.Auth.LoginHandler(context, provider.authorize)
+}
+
+// ServeHTTP implement ServeHTTP with OpenID Connect provider
+func (*OIDCProvider) ServeHTTP(*auth.Context) {
+}
+
+// OAuthConfig return oauth config based on configuration
+func (provider *OIDCProvider) OAuthConfig(context *auth.Context, discovery *oidcDiscovery) *oauth2.Config {
+	scheme := "http://"
+	if IsHttps(context.Request) {
+		scheme = "https://"
+	}
+
+	return &oauth2.Config{
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+		RedirectURL: scheme + context.Request.Host + context.Auth.AuthURL(OIDCProviderName+"/callback"),
+		Scopes:      provider.Scopes,
+	}
+}
+
+// authorize exchanges the authorization code for an ID token and logs in the user it identifies.
+// New users are registered, the organizations of existing users are synchronized with their groups.
+func (provider *OIDCProvider) authorize(context *auth.Context) (*claims.Claims, error) {
+	var (
+		schema       auth.Schema
+		authInfo     auth_identity.Basic
+		authIdentity = reflect.New(utils.ModelType(context.Auth.Config.AuthIdentityModel)).Interface()
+		req          = context.Request
+		tx           = context.Auth.GetDB(req)
+	)
+
+	state := req.URL.Query().Get("state")
+	stateClaims, err := context.Auth.SessionStorer.ValidateClaims(state)
+	if err != nil {
+		log.Info(req.RemoteAddr, err.Error())
+		return nil, err
+	}
+	if stateClaims.Valid() != nil || stateClaims.Subject != "state" {
+		log.Info(req.RemoteAddr, auth.ErrUnauthorized.Error())
+		return nil, auth.ErrUnauthorized
+	}
+
+	discovery, err := provider.discover()
+	if err != nil {
+		log.Errorf("Error discovering OpenID Connect provider: %s", err.Error())
+		return nil, err
+	}
+
+	token, err := provider.OAuthConfig(context, discovery).Exchange(oauth2.NoContext, req.URL.Query().Get("code"))
+	if err != nil {
+		log.Info(req.RemoteAddr, err.Error())
+		return nil, err
+	}
+
+	user, err := provider.getUser(discovery, token)
+	if err != nil {
+		log.Info(req.RemoteAddr, err.Error())
+		return nil, err
+	}
+	roles := groupRoles(user.Groups, provider.GroupPrefix, provider.AdminGroupSuffix)
+
+	authInfo.Provider = provider.GetName()
+	authInfo.UID = user.Subject
+
+	if !tx.Model(authIdentity).Where(authInfo).Scan(&authInfo).RecordNotFound() {
+		currentUser := &User{}
+		if err := tx.Where("id = ?", authInfo.UserID).First(currentUser).Error; err != nil {
+			return nil, err
+		}
+		if err := syncGroupOrganizations(tx, currentUser, roles, oidcGroupColumn, true); err != nil {
+			log.Errorf("Error synchronizing organizations of user %s: %s", currentUser.Login, err.Error())
+		}
+		acceptLoginInvitations(authInfo.UserID, provider.GetName(), user.verifiedEmail())
+		return authInfo.ToClaims(), nil
+	}
+
+	{
+		schema.Provider = provider.GetName()
+		schema.UID = user.Subject
+		schema.Name = user.Name
+		schema.Email = user.Email
+		schema.RawInfo = &OIDCExtraInfo{Login: user.Login, Roles: roles}
+	}
+	if _, userID, err := context.Auth.UserStorer.Save(&schema, context); err == nil {
+		if userID != "" {
+			authInfo.UserID = userID
+		}
+	} else {
+		return nil, err
+	}
+
+	if err = tx.Where(authInfo).FirstOrCreate(authIdentity).Error; err == nil {
+		acceptLoginInvitations(authInfo.UserID, provider.GetName(), user.verifiedEmail())
+		return authInfo.ToClaims(), nil
+	}
+
+	log.Info(req.RemoteAddr, err.Error())
+	return nil, err
+}
+
+// getUser verifies the ID token of the token response and returns the user it identifies,
+// claims missing from the ID token are looked up at the userinfo endpoint
+func (provider *OIDCProvider) getUser(discovery *oidcDiscovery, token *oauth2.Token) (*oidcUser, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response contains no ID token")
+	}
+
+	idClaims, err := provider.verifyIDToken(discovery, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %s", err.Error())
+	}
+
+	if discovery.UserinfoEndpoint != "" {
+		userinfo := map[string]interface{}{}
+		client := &http.Client{
+			Timeout:   oidcHTTPTimeout,
+			Transport: &oauth2.Transport{Source: oauth2.StaticTokenSource(token)},
+		}
+		if err := getJSON(client, discovery.UserinfoEndpoint, &userinfo); err != nil {
+			log.Warnf("Error getting OpenID Connect userinfo: %s", err.Error())
+		} else if userinfo["sub"] == idClaims["sub"] {
+			for name, value := range userinfo {
+				if _, ok := idClaims[name]; !ok {
+					idClaims[name] = value
+				}
+			}
+		}
+	}
+
+	return provider.userFromClaims(idClaims)
+}
+
+// verifyIDToken checks the signature, issuer, audience and expiry of the ID token and returns its claims
+func (provider *OIDCProvider) verifyIDToken(discovery *oidcDiscovery, rawIDToken string) (map[string]interface{}, error) {
+	token, err := jwt.ParseSigned(rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if len(token.Headers) == 0 {
+		return nil, errors.New("token has no header")
+	}
+
+	keys, err := provider.verificationKeys(discovery, token.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var standardClaims jwt.Claims
+	var allClaims map[string]interface{}
+	err = errors.New("no matching verification key")
+	for _, key := range keys {
+		if err = token.Claims(key, &standardClaims, &allClaims); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if standardClaims.Expiry == 0 {
+		return nil, errors.New("token has no expiry")
+	}
+	err = standardClaims.Validate(jwt.Expected{
+		Issuer:   discovery.Issuer,
+		Audience: jwt.Audience{provider.ClientID},
+		Time:     time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return allClaims, nil
+}
+
+// userFromClaims maps the claims to a user, the subject and the login are required
+func (provider *OIDCProvider) userFromClaims(claims map[string]interface{}) (*oidcUser, error) {
+	user := &oidcUser{
+		Subject: stringClaim(claims, "sub"),
+		Login:   stringClaim(claims, provider.LoginClaim),
+		Email:   stringClaim(claims, provider.EmailClaim),
+		Name:    stringClaim(claims, provider.NameClaim),
+	}
+	// email_verified is the standard claim about the email claim, it's also sent as a string by some providers
+	if provider.EmailClaim == "email" {
+		verified := claims["email_verified"]
+		user.EmailVerified = verified == true || verified == "true"
+	}
+	if user.Subject == "" {
+		return nil, errors.New("sub claim is missing")
+	}
+	if user.Login == "" {
+		return nil, fmt.Errorf("%s claim is missing", provider.LoginClaim)
+	}
+
+	if provider.GroupsClaim != "" {
+		switch groups := claims[provider.GroupsClaim].(type) {
+		case string:
+			user.Groups = []string{groups}
+		case []interface{}:
+			for _, group := range groups {
+				if name, ok := group.(string); ok {
+					user.Groups = append(user.Groups, name)
+				}
+			}
+		}
+	}
+
+	return user, nil
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// discover returns the discovery document of the provider, it's fetched on first use
+func (provider *OIDCProvider) discover() (*oidcDiscovery, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.discovery != nil {
+		return provider.discovery, nil
+	}
+
+	discovery := &oidcDiscovery{}
+	url := strings.TrimSuffix(provider.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(&http.Client{Timeout: oidcHTTPTimeout}, url, discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != strings.TrimSuffix(provider.IssuerURL, "/") && discovery.Issuer != provider.IssuerURL {
+		return nil, fmt.Errorf("issuer %q of the discovery document doesn't match %q", discovery.Issuer, provider.IssuerURL)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document misses required endpoints")
+	}
+
+	provider.discovery = discovery
+	return discovery, nil
+}
+
+// verificationKeys returns the signing keys of the provider with the key ID,
+// the key set is fetched again when the key is unknown, as the provider may have rotated its keys
+func (provider *OIDCProvider) verificationKeys(discovery *oidcDiscovery, keyID string) ([]jose.JSONWebKey, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	for refreshed := false; ; refreshed = true {
+		if provider.keys == nil || refreshed {
+			keys := &jose.JSONWebKeySet{}
+			if err := getJSON(&http.Client{Timeout: oidcHTTPTimeout}, discovery.JWKSURI, keys); err != nil {
+				return nil, fmt.Errorf("error getting signing keys: %s", err.Error())
+			}
+			provider.keys = keys
+		}
+
+		var keys []jose.JSONWebKey
+		if keyID == "" {
+			keys = provider.keys.Keys
+		} else {
+			keys = provider.keys.Key(keyID)
+		}
+		if len(keys) != 0 || refreshed {
+			return keys, nil
+		}
+	}
+}
+
+func getJSON(client *http.Client, url string, v interface{}) error {
+	response, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with %s", url, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(v)
+}
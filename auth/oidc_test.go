@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestGroupRoles(t *testing.T) {
+	roles := groupRoles([]string{
+		"/pipeline-dev",
+		"pipeline-ops:admin",
+		"pipeline-ops",
+		"pipeline-team/qa",
+		"other",
+		"pipeline-",
+	}, "pipeline-", ":admin")
+
+	expected := map[string]string{"dev": RoleMember, "ops": RoleAdmin, "team-qa": RoleMember}
+	if !reflect.DeepEqual(roles, expected) {
+		t.Errorf("expected roles %v, got %v", expected, roles)
+	}
+}
+
+func TestRevokedOrganizations(t *testing.T) {
+	memberships := []UserOrganization{
+		{UserID: 1, OrganizationID: 10, Role: RoleMember},
+		{UserID: 1, OrganizationID: 11, Role: RoleAdmin},
+		{UserID: 1, OrganizationID: 12, Role: RoleMember},
+	}
+
+	// the user was removed from the group of organization 11
+	revoked := revokedOrganizations(memberships, []uint{10, 12})
+	if !reflect.DeepEqual(revoked, []uint{11}) {
+		t.Errorf("expected organization 11 to be revoked, got %v", revoked)
+	}
+
+	if revoked := revokedOrganizations(memberships, []uint{10, 11, 12}); len(revoked) != 0 {
+		t.Errorf("expected no revoked organizations, got %v", revoked)
+	}
+}
+
+func TestOIDCVerifiedEmail(t *testing.T) {
+	provider := &OIDCProvider{OIDCConfig: &OIDCConfig{LoginClaim: "preferred_username", EmailClaim: "email"}}
+
+	tests := []struct {
+		verified      interface{}
+		verifiedEmail string
+	}{
+		{true, "jdoe@example.com"},
+		{"true", "jdoe@example.com"},
+		{false, ""},
+		{nil, ""},
+	}
+	for _, test := range tests {
+		claims := map[string]interface{}{"sub": "1234", "preferred_username": "jdoe", "email": "jdoe@example.com"}
+		if test.verified != nil {
+			claims["email_verified"] = test.verified
+		}
+		user, err := provider.userFromClaims(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if email := user.verifiedEmail(); email != test.verifiedEmail {
+			t.Errorf("email_verified %v: expected verified email %q, got %q", test.verified, test.verifiedEmail, email)
+		}
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "key", Algorithm: string(jose.RS256), Use: "sig"},
+		}})
+	}))
+	defer server.Close()
+
+	discovery := &oidcDiscovery{Issuer: "https://issuer.example.com", JWKSURI: server.URL}
+	provider := &OIDCProvider{OIDCConfig: &OIDCConfig{ClientID: "pipeline", LoginClaim: "preferred_username", GroupsClaim: "groups"}}
+
+	sign := func(signingKey *rsa.PrivateKey, claims jwt.Claims) string {
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.RS256, Key: signingKey},
+			(&jose.SignerOptions{}).WithHeader("kid", "key"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := jwt.Signed(signer).Claims(claims).Claims(map[string]interface{}{
+			"preferred_username": "jdoe",
+			"groups":             []string{"dev"},
+		}).CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	valid := jwt.Claims{
+		Issuer:   discovery.Issuer,
+		Subject:  "1234",
+		Audience: jwt.Audience{"pipeline"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+	claims, err := provider.verifyIDToken(discovery, sign(key, valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := provider.userFromClaims(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Subject != "1234" || user.Login != "jdoe" || !reflect.DeepEqual(user.Groups, []string{"dev"}) {
+		t.Errorf("unexpected user: %+v", user)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.verifyIDToken(discovery, sign(otherKey, valid)); err == nil {
+		t.Error("expected error for token signed by unknown key")
+	}
+
+	otherAudience := valid
+	otherAudience.Audience = jwt.Audience{"other"}
+	if _, err := provider.verifyIDToken(discovery, sign(key, otherAudience)); err == nil {
+		t.Error("expected error for token of other audience")
+	}
+
+	expired := valid
+	expired.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	if _, err := provider.verifyIDToken(discovery, sign(key, expired)); err == nil {
+		t.Error("expected error for expired token")
+	}
+}
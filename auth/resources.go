@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"strings"
+)
+
+// apiResources are the resources token scopes and organization roles grant access to, named by their path under /api/v1/orgs/:orgid
+var apiResources = map[string]bool{
+	"orgs":               true,
+	"tokens":             true,
+	"applications":       true,
+	"catalogs":           true,
+	"clusters":           true,
+	"clusteralerts":      true,
+	"clusterproxy":       true,
+	"kubeconfig":         true,
+	"clustercredentials": true,
+	"deployments":        true,
+	"secrets":            true,
+	"alertrules":         true,
+	"backups":            true,
+	"notifications":      true,
+	"webhooks":           true,
+	"profiles":           true,
+	"kubernetes":         true,
+	"audit":              true,
+	"users":              true,
+	"roles":              true,
+	"tokenpolicy":        true,
+	"buckets":            true,
+	"cloudinfo":          true,
+}
+
+// clusterSubresources maps the paths under /clusters/:id to the resources they belong to, the others belong to clusters
+var clusterSubresources = map[string]string{
+	"config":          "kubeconfig",
+	"userconfig":      "clustercredentials",
+	"deployments":     "deployments",
+	"helminit":        "deployments",
+	"secrets":         "secrets",
+	"alertrules":      "alertrules",
+	"alerts":          "clusteralerts",
+	"proxy":           "clusterproxy",
+	"backups":         "backups",
+	"backupschedules": "backups",
+	"restores":        "backups",
+}
+
+// apiResource returns the resource and the organization of an API path relative to /api/v1
+func apiResource(path string) (resource string, orgID string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch segments[0] {
+	case "orgs":
+		if len(segments) > 1 {
+			orgID = segments[1]
+		}
+		if len(segments) < 3 {
+			return "orgs", orgID
+		}
+		resource = segments[2]
+		switch resource {
+		case "clusters":
+			if len(segments) > 4 && clusterSubresources[segments[4]] != "" {
+				resource = clusterSubresources[segments[4]]
+			}
+		case "prometheus":
+			resource = "clusters"
+		case "helm":
+			resource = "deployments"
+		case "invitations":
+			resource = "users"
+		}
+		return resource, orgID
+	case "token", "tokens":
+		return "tokens", ""
+	case "allowed":
+		return "secrets", ""
+	}
+
+	return segments[0], ""
+}
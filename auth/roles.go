@@ -0,0 +1,347 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/database"
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+)
+
+// resourceObjectPrefix prefixes the objects of the policies granting access to the resources of an organization
+const resourceObjectPrefix = "resource:"
+
+var (
+	// ErrBuiltInRole is returned when a built-in role would be changed or deleted
+	ErrBuiltInRole = errors.New("built-in roles can't be changed")
+	// ErrLastAdmin is returned when the last admin of an organization would be demoted or removed
+	ErrLastAdmin = errors.New("the organization must have an admin")
+	// ErrRoleInUse is returned when a role to be deleted is assigned to users
+	ErrRoleInUse = errors.New("role is assigned to users")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,31}$`)
+
+var roleMethods = map[string]bool{
+	"*":                true,
+	http.MethodGet:     true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+}
+
+// RoleRule grants access to a resource of the organization with an HTTP method, * stands for all resources or methods
+type RoleRule struct {
+	Resource string `json:"resource" binding:"required"`
+	Method   string `json:"method" binding:"required"`
+}
+
+// Role is a named set of rules within an organization
+type Role struct {
+	Name    string     `json:"name"`
+	BuiltIn bool       `json:"builtIn"`
+	Rules   []RoleRule `json:"rules"`
+	// Current tells whether the calling user has the role
+	Current bool `json:"current,omitempty"`
+}
+
+// memberResources are the resources members can change, they can read all resources except adminResources.
+// Members can use the cluster API proxy, which impersonates them with the Kubernetes groups of their role.
+var memberResources = []string{
+	"applications", "catalogs", "clusters", "clusteralerts", "clusterproxy", "kubeconfig", "deployments", "secrets",
+	"alertrules", "backups", "notifications", "webhooks", "profiles", "buckets", "cloudinfo",
+}
+
+// adminResources are accessible only by admins and the custom roles granting them explicitly,
+// e.g. the audit log and the cluster credentials issued to users
+var adminResources = map[string]bool{
+	"audit":              true,
+	"clustercredentials": true,
+}
+
+// viewerResources are the resources viewers can read, they can't read secrets and cluster credentials
+// and can't use the cluster API proxy
+var viewerResources = []string{
+	"orgs", "applications", "catalogs", "clusters", "deployments", "alertrules", "backups",
+	"notifications", "webhooks", "profiles", "buckets", "cloudinfo", "users", "roles",
+}
+
+// builtInRoles returns the roles every organization has
+func builtInRoles() []Role {
+	member := Role{Name: RoleMember, BuiltIn: true}
+	resources := make([]string, 0, len(apiResources))
+	for resource := range apiResources {
+		if !adminResources[resource] {
+			resources = append(resources, resource)
+		}
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		member.Rules = append(member.Rules, RoleRule{Resource: resource, Method: http.MethodGet})
+	}
+	for _, resource := range memberResources {
+		member.Rules = append(member.Rules, RoleRule{Resource: resource, Method: "*"})
+	}
+
+	viewer := Role{Name: RoleViewer, BuiltIn: true}
+	for _, resource := range viewerResources {
+		viewer.Rules = append(viewer.Rules, RoleRule{Resource: resource, Method: http.MethodGet})
+	}
+
+	return []Role{
+		{Name: RoleAdmin, BuiltIn: true, Rules: []RoleRule{{Resource: "*", Method: "*"}}},
+		member,
+		viewer,
+	}
+}
+
+func isBuiltInRole(name string) bool {
+	return name == RoleAdmin || name == RoleMember || name == RoleViewer
+}
+
+func orgRoleSubject(orgid uint, role string) string {
+	return fmt.Sprintf("%s-%s", orgRoleName(orgid), role)
+}
+
+func resourceObject(orgid uint, resource string) string {
+	return fmt.Sprintf("%s%d:%s", resourceObjectPrefix, orgid, resource)
+}
+
+// resourceMatch tells whether the request path belongs to the resource object of a policy
+func resourceMatch(path, object string) bool {
+	if !strings.HasPrefix(object, resourceObjectPrefix) {
+		return false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(object, resourceObjectPrefix), ":", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	apiPath := viper.GetString("pipeline.basepath") + "/api/v1/"
+	if !strings.HasPrefix(path, apiPath) {
+		return false
+	}
+	resource, orgID := apiResource(strings.TrimPrefix(path, apiPath))
+
+	return orgID != "" && orgID == parts[0] && (parts[1] == "*" || parts[1] == resource)
+}
+
+// resourceMatchFunc is the casbin function of resourceMatch
+func resourceMatchFunc(args ...interface{}) (interface{}, error) {
+	return resourceMatch(args[0].(string), args[1].(string)), nil
+}
+
+func addRolePolicies(orgid uint, role Role) {
+	for _, rule := range role.Rules {
+		enforcer.AddPolicy(orgRoleSubject(orgid, role.Name), resourceObject(orgid, rule.Resource), rule.Method)
+	}
+}
+
+// setOrgRoleForUser replaces the role of the user in the organization
+func setOrgRoleForUser(user string, orgid uint, role string) {
+	subject := orgRoleSubject(orgid, role)
+	for _, userRole := range enforcer.GetRolesForUser(user) {
+		if userRole != subject && (userRole == orgRoleName(orgid) || strings.HasPrefix(userRole, orgRoleName(orgid)+"-")) {
+			enforcer.DeleteRoleForUser(user, userRole)
+		}
+	}
+	enforcer.AddRoleForUser(user, subject)
+}
+
+// GetOrgRoles returns the built-in and the custom roles of the organization
+func GetOrgRoles(orgid uint) []Role {
+	roles := builtInRoles()
+
+	custom := map[string]*Role{}
+	prefix := orgRoleName(orgid) + "-"
+	for _, policy := range enforcer.GetPolicy() {
+		if len(policy) < 3 || !strings.HasPrefix(policy[0], prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(policy[0], prefix)
+		if isBuiltInRole(name) {
+			continue
+		}
+		if custom[name] == nil {
+			custom[name] = &Role{Name: name}
+		}
+		resource := strings.TrimPrefix(policy[1], resourceObject(orgid, ""))
+		custom[name].Rules = append(custom[name].Rules, RoleRule{Resource: resource, Method: policy[2]})
+	}
+
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		roles = append(roles, *custom[name])
+	}
+
+	return roles
+}
+
+// GetOrgRole returns the role of the organization, nil if it doesn't exist
+func GetOrgRole(orgid uint, name string) *Role {
+	for _, role := range GetOrgRoles(orgid) {
+		if role.Name == name {
+			return &role
+		}
+	}
+	return nil
+}
+
+// SetOrgRole creates or replaces a custom role of the organization, users having the role get the new rules
+func SetOrgRole(orgid uint, role Role) error {
+	if isBuiltInRole(role.Name) {
+		return ErrBuiltInRole
+	}
+	if !roleNamePattern.MatchString(role.Name) {
+		return fmt.Errorf("invalid role name: %q", role.Name)
+	}
+	if len(role.Rules) == 0 {
+		return errors.New("role must have rules")
+	}
+	for _, rule := range role.Rules {
+		if rule.Resource != "*" && !apiResources[rule.Resource] {
+			return fmt.Errorf("unknown resource: %q", rule.Resource)
+		}
+		if !roleMethods[rule.Method] {
+			return fmt.Errorf("unknown method: %q", rule.Method)
+		}
+	}
+
+	enforcer.RemoveFilteredPolicy(0, orgRoleSubject(orgid, role.Name))
+	addRolePolicies(orgid, role)
+	return nil
+}
+
+// DeleteOrgRole deletes a custom role of the organization, roles assigned to users can't be deleted
+func DeleteOrgRole(orgid uint, name string) error {
+	if isBuiltInRole(name) {
+		return ErrBuiltInRole
+	}
+
+	var count int
+	err := database.GetDB().Model(&UserOrganization{}).Where(&UserOrganization{OrganizationID: orgid, Role: name}).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrRoleInUse
+	}
+
+	enforcer.RemoveFilteredPolicy(0, orgRoleSubject(orgid, name))
+	return nil
+}
+
+// migrateOrgRoles replaces the org-<id> roles granting all permissions in the organizations,
+// which preceded the organization roles, with the roles of the users
+func migrateOrgRoles() {
+	orgRole := regexp.MustCompile(`^org-(\d+)$`)
+	migrated := map[uint]bool{}
+
+	for _, grouping := range enforcer.GetGroupingPolicy() {
+		matches := orgRole.FindStringSubmatch(grouping[1])
+		if matches == nil {
+			continue
+		}
+		orgid, err := strconv.ParseUint(matches[1], 10, 32)
+		if err != nil {
+			continue
+		}
+
+		if !migrated[uint(orgid)] {
+			AddOrgRoles(uint(orgid))
+			enforcer.RemoveFilteredPolicy(0, grouping[1])
+			migrated[uint(orgid)] = true
+		}
+		AddOrgRoleForUser(grouping[0], uint(orgid))
+	}
+
+	if len(migrated) > 0 {
+		log.Infof("Migrated the roles of %d organizations", len(migrated))
+	}
+}
+
+// updateBuiltInRoles replaces the policies of the built-in roles of the organizations
+// which differ from the current rules of the built-in roles
+func updateBuiltInRoles() {
+	builtIn := map[string]Role{}
+	for _, role := range builtInRoles() {
+		builtIn[role.Name] = role
+	}
+
+	policies := map[string]map[string]bool{}
+	orgRole := regexp.MustCompile(`^org-(\d+)-(admin|member|viewer)$`)
+	for _, policy := range enforcer.GetPolicy() {
+		if len(policy) < 3 || !orgRole.MatchString(policy[0]) {
+			continue
+		}
+		if policies[policy[0]] == nil {
+			policies[policy[0]] = map[string]bool{}
+		}
+		policies[policy[0]][policy[1]+" "+policy[2]] = true
+	}
+
+	updated := 0
+	for subject, rules := range policies {
+		matches := orgRole.FindStringSubmatch(subject)
+		orgid, err := strconv.ParseUint(matches[1], 10, 32)
+		if err != nil {
+			continue
+		}
+		role := builtIn[matches[2]]
+
+		current := len(rules) == len(role.Rules)
+		for _, rule := range role.Rules {
+			current = current && rules[resourceObject(uint(orgid), rule.Resource)+" "+rule.Method]
+		}
+		if current {
+			continue
+		}
+
+		enforcer.RemoveFilteredPolicy(0, subject)
+		addRolePolicies(uint(orgid), role)
+		updated++
+	}
+
+	if updated > 0 {
+		log.Infof("Updated %d built-in organization roles", updated)
+	}
+}
+
+// CheckLastOrgAdmin returns ErrLastAdmin if the user is the only admin of the organization,
+// so the user can't be demoted or removed
+func CheckLastOrgAdmin(orgid, userID uint) error {
+	db := database.GetDB()
+
+	var membership UserOrganization
+	err := db.Where(&UserOrganization{UserID: userID, OrganizationID: orgid}).First(&membership).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if membership.Role != RoleAdmin {
+		return nil
+	}
+
+	var admins int
+	if err := db.Model(&UserOrganization{}).Where(&UserOrganization{OrganizationID: orgid, Role: RoleAdmin}).Count(&admins).Error; err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/casbin/casbin"
+)
+
+func TestOrgRoles(t *testing.T) {
+	enforcer = casbin.NewSyncedEnforcer(casbin.NewModel(modelDefinition), false)
+	enforcer.AddFunction("resourceMatch", resourceMatchFunc)
+	enforcer.BuildRoleLinks()
+	defer func() { enforcer = nil }()
+
+	AddOrgRoles(1)
+	setOrgRoleForUser("1", 1, RoleAdmin)
+	setOrgRoleForUser("2", 1, RoleMember)
+	setOrgRoleForUser("3", 1, RoleAdmin)
+	setOrgRoleForUser("3", 1, RoleViewer)
+
+	err := SetOrgRole(1, Role{Name: "deployer", Rules: []RoleRule{
+		{Resource: "clusters", Method: http.MethodGet},
+		{Resource: "deployments", Method: "*"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setOrgRoleForUser("4", 1, "deployer")
+
+	tests := []struct {
+		user    string
+		method  string
+		path    string
+		allowed bool
+	}{
+		{"1", http.MethodDelete, "/api/v1/orgs/1", true},
+		{"1", http.MethodPost, "/api/v1/orgs/1/users/2", true},
+		{"1", http.MethodGet, "/api/v1/orgs/2/clusters", false},
+		{"2", http.MethodDelete, "/api/v1/orgs/1/clusters/3", true},
+		{"2", http.MethodGet, "/api/v1/orgs/1/secrets/abc", true},
+		{"2", http.MethodGet, "/api/v1/orgs/1/clusters/3/config", true},
+		{"2", http.MethodPost, "/api/v1/orgs/1/clusters/3/userconfig", false},
+		{"1", http.MethodPost, "/api/v1/orgs/1/clusters/3/userconfig", true},
+		{"2", http.MethodGet, "/api/v1/orgs/1/audit", false},
+		{"1", http.MethodGet, "/api/v1/orgs/1/audit/verify", true},
+		{"2", http.MethodPost, "/api/v1/orgs/1/users/3", false},
+		{"1", http.MethodPost, "/api/v1/orgs/1/invitations", true},
+		{"2", http.MethodPost, "/api/v1/orgs/1/invitations", false},
+		{"3", http.MethodGet, "/api/v1/orgs/1/invitations", true},
+		{"3", http.MethodDelete, "/api/v1/orgs/1/invitations/5", false},
+		{"2", http.MethodDelete, "/api/v1/orgs/1", false},
+		{"3", http.MethodGet, "/api/v1/orgs/1/clusters/3/details", true},
+		{"3", http.MethodDelete, "/api/v1/orgs/1/clusters/3", false},
+		{"3", http.MethodGet, "/api/v1/orgs/1/secrets", false},
+		{"3", http.MethodGet, "/api/v1/orgs/1/clusters/3/config", false},
+		{"3", http.MethodGet, "/api/v1/orgs/1/clusters/3/proxy/api/v1/pods", false},
+		{"2", http.MethodPost, "/api/v1/orgs/1/clusters/3/proxy/api/v1/namespaces/default/pods", true},
+		{"3", http.MethodPost, "/api/v1/orgs/1/users/2", false},
+		{"4", http.MethodPost, "/api/v1/orgs/1/clusters/3/deployments", true},
+		{"4", http.MethodGet, "/api/v1/orgs/1/clusters", true},
+		{"4", http.MethodDelete, "/api/v1/orgs/1/clusters/3", false},
+	}
+	for _, test := range tests {
+		if allowed := enforcer.Enforce(test.user, test.path, test.method); allowed != test.allowed {
+			t.Errorf("user %s %s %s: expected allowed=%t", test.user, test.method, test.path, test.allowed)
+		}
+	}
+
+	roles := GetOrgRoles(1)
+	if len(roles) != 4 || roles[3].Name != "deployer" || len(roles[3].Rules) != 2 || roles[3].BuiltIn {
+		t.Errorf("unexpected roles: %+v", roles)
+	}
+
+	if err := SetOrgRole(1, Role{Name: RoleMember, Rules: []RoleRule{{Resource: "*", Method: "*"}}}); err != ErrBuiltInRole {
+		t.Errorf("expected built-in role error, got %v", err)
+	}
+	if err := SetOrgRole(1, Role{Name: "other", Rules: []RoleRule{{Resource: "unknown", Method: "*"}}}); err == nil {
+		t.Error("expected error for unknown resource")
+	}
+
+	DeleteOrgRoleForUser(3, 1)
+	if enforcer.Enforce("3", "/api/v1/orgs/1/clusters", http.MethodGet) {
+		t.Error("expected removed user to be denied")
+	}
+}
+
+func TestUpdateBuiltInRoles(t *testing.T) {
+	enforcer = casbin.NewSyncedEnforcer(casbin.NewModel(modelDefinition), false)
+	enforcer.AddFunction("resourceMatch", resourceMatchFunc)
+	enforcer.BuildRoleLinks()
+	defer func() { enforcer = nil }()
+
+	// the member role used to read all resources
+	AddOrgRoles(1)
+	enforcer.RemoveFilteredPolicy(0, orgRoleSubject(1, RoleMember))
+	enforcer.AddPolicy(orgRoleSubject(1, RoleMember), resourceObject(1, "*"), http.MethodGet)
+	setOrgRoleForUser("2", 1, RoleMember)
+	if !enforcer.Enforce("2", "/api/v1/orgs/1/audit", http.MethodGet) {
+		t.Fatal("expected the old member role to read the audit log")
+	}
+
+	updateBuiltInRoles()
+
+	if enforcer.Enforce("2", "/api/v1/orgs/1/audit", http.MethodGet) {
+		t.Error("expected the updated member role not to read the audit log")
+	}
+	if !enforcer.Enforce("2", "/api/v1/orgs/1/clusters", http.MethodGet) || !enforcer.Enforce("2", "/api/v1/orgs/1/clusters/3", http.MethodDelete) {
+		t.Error("expected the updated member role to manage clusters")
+	}
+}
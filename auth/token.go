@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	bauth "github.com/banzaicloud/bank-vaults/auth"
+	"github.com/banzaicloud/pipeline/database"
+	"github.com/jinzhu/gorm"
+)
+
+// API token scopes, besides them "<resource>:read" and "<resource>:write" scopes grant access to a single resource
+const (
+	// TokenScopeFull grants all permissions of the user, it's the scope of the tokens created without scopes
+	TokenScopeFull = "api:invoke"
+	// TokenScopeReadOnly grants read access to all resources except the credentials in readOnlyExcludedResources
+	TokenScopeReadOnly = "read-only"
+
+	// tokenScopeOrgPrefix prefixes the organization the token is restricted to in the scope claim
+	tokenScopeOrgPrefix = "org:"
+)
+
+// readOnlyExcludedResources hold credentials or grant their access, e.g. the proxy to the cluster API servers,
+// they are readable only with their explicit "<resource>:read" scope
+var readOnlyExcludedResources = map[string]bool{
+	"kubeconfig":   true,
+	"secrets":      true,
+	"clusterproxy": true,
+}
+
+// tokenUseRecordInterval is the precision of the last-used time of the tokens
+const tokenUseRecordInterval = time.Minute
+
+// tokenUses holds the times the use of the tokens was last recorded by this instance
+var tokenUses = struct {
+	sync.Mutex
+	recorded map[string]time.Time
+}{recorded: map[string]time.Time{}}
+
+// APIToken stores the properties of an API token which are not stored in Vault
+type APIToken struct {
+	ID             string     `gorm:"primary_key;size:36" json:"id"`
+	UserID         string     `gorm:"index" json:"-"`
+	OrganizationID *uint      `json:"organizationId,omitempty"`
+	Scope          string     `json:"-"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt     *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt      time.Time  `json:"-"`
+}
+
+// TokenPolicy limits the lifetime of the API tokens having access to the organization
+type TokenPolicy struct {
+	OrganizationID uint `gorm:"primary_key;auto_increment:false" json:"-"`
+	// MaxLifetimeDays is the maximum lifetime of the tokens in days, 0 means unlimited
+	MaxLifetimeDays int       `json:"maxLifetimeDays"`
+	UpdatedAt       time.Time `json:"-"`
+}
+
+// TokenRequest describes the API token to create
+type TokenRequest struct {
+	Name           string     `json:"name,omitempty"`
+	VirtualUser    string     `json:"virtualUser,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	Scopes         []string   `json:"scopes,omitempty"`
+	OrganizationID uint       `json:"organizationId,omitempty"`
+}
+
+// TokenResponse describes an API token of the user
+type TokenResponse struct {
+	*bauth.Token
+	Scopes         []string   `json:"scopes"`
+	OrganizationID *uint      `json:"organizationId,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt     *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// validateTokenScopes checks that the scopes are known and returns the scope claim of the token
+func validateTokenScopes(scopes []string, orgID uint) (string, error) {
+	if len(scopes) == 0 {
+		scopes = []string{TokenScopeFull}
+	}
+
+	for _, scope := range scopes {
+		if scope == TokenScopeFull || scope == TokenScopeReadOnly {
+			continue
+		}
+		parts := strings.Split(scope, ":")
+		if len(parts) != 2 || !apiResources[parts[0]] || (parts[1] != "read" && parts[1] != "write") {
+			return "", fmt.Errorf("unknown token scope: %q", scope)
+		}
+	}
+
+	if orgID != 0 {
+		scopes = append(scopes, fmt.Sprint(tokenScopeOrgPrefix, orgID))
+	}
+	return strings.Join(scopes, " "), nil
+}
+
+// tokenHasFullAccess tells whether the scopes grant all permissions of the user. Scopes of
+// sessions and tokens created before scopes were introduced are empty or api:invoke.
+func tokenHasFullAccess(scopes []string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	full := false
+	for _, scope := range scopes {
+		if strings.HasPrefix(scope, tokenScopeOrgPrefix) {
+			return false
+		}
+		if scope == TokenScopeFull {
+			full = true
+		}
+	}
+	return full
+}
+
+// tokenAllows tells whether the scopes of the token allow the request, methods other
+// than GET, HEAD and OPTIONS need write access
+func tokenAllows(scopes []string, method, path string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+
+	write := method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions
+	resource, orgID := apiResource(path)
+
+	for _, scope := range scopes {
+		if strings.HasPrefix(scope, tokenScopeOrgPrefix) {
+			restrictedOrgID := strings.TrimPrefix(scope, tokenScopeOrgPrefix)
+			// tokens restricted to an organization can't change anything outside of it
+			if orgID != restrictedOrgID && (orgID != "" || write) {
+				return false
+			}
+		}
+	}
+
+	for _, scope := range scopes {
+		switch scope {
+		case TokenScopeFull, resource + ":write":
+			return true
+		case TokenScopeReadOnly:
+			if !write && !readOnlyExcludedResources[resource] {
+				return true
+			}
+		case resource + ":read":
+			if !write {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tokenMaxLifetime returns the strictest maximum token lifetime of the organizations, 0 means unlimited
+func tokenMaxLifetime(db *gorm.DB, orgIDs []uint) (time.Duration, error) {
+	if len(orgIDs) == 0 {
+		return 0, nil
+	}
+
+	var policies []TokenPolicy
+	err := db.Where("organization_id IN (?) AND max_lifetime_days > 0", orgIDs).Order("max_lifetime_days").Limit(1).Find(&policies).Error
+	if err != nil || len(policies) == 0 {
+		return 0, err
+	}
+	return time.Duration(policies[0].MaxLifetimeDays) * 24 * time.Hour, nil
+}
+
+// GetTokenPolicy returns the token policy of the organization
+func GetTokenPolicy(orgID uint) (*TokenPolicy, error) {
+	policy := &TokenPolicy{OrganizationID: orgID}
+	err := database.GetDB().Where(policy).First(policy).Error
+	if err == gorm.ErrRecordNotFound {
+		return policy, nil
+	}
+	return policy, err
+}
+
+// SetTokenPolicy saves the token policy of the organization, it applies to the tokens created afterwards
+func SetTokenPolicy(policy *TokenPolicy) error {
+	return database.GetDB().Save(policy).Error
+}
+
+// storeTokenMetadata saves the properties of the token not stored in Vault
+func storeTokenMetadata(userID, tokenID, scope string, expiresAt *time.Time) error {
+	token := &APIToken{ID: tokenID, UserID: userID, Scope: scope, ExpiresAt: expiresAt}
+	for _, s := range strings.Fields(scope) {
+		var orgID uint
+		if _, err := fmt.Sscanf(s, tokenScopeOrgPrefix+"%d", &orgID); err == nil {
+			token.OrganizationID = &orgID
+		}
+	}
+	return database.GetDB().Create(token).Error
+}
+
+// recordTokenUse updates the last-used time of the token in the background, at most once a minute per instance,
+// so the requests are not delayed by the update
+func recordTokenUse(tokenID string) {
+	now := time.Now()
+	if !shouldRecordTokenUse(tokenID, now) {
+		return
+	}
+
+	go func() {
+		err := database.GetDB().Model(&APIToken{}).
+			Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", tokenID, now.Add(-tokenUseRecordInterval)).
+			Update("last_used_at", now).Error
+		if err != nil {
+			log.Warnf("Error recording use of token %s: %s", tokenID, err.Error())
+		}
+	}()
+}
+
+// shouldRecordTokenUse tells whether the use of the token hasn't been recorded within the record interval,
+// and marks it recorded at the given time
+func shouldRecordTokenUse(tokenID string, now time.Time) bool {
+	tokenUses.Lock()
+	defer tokenUses.Unlock()
+
+	if last, ok := tokenUses.recorded[tokenID]; ok && now.Sub(last) < tokenUseRecordInterval {
+		return false
+	}
+
+	if len(tokenUses.recorded) >= 10000 {
+		for id, last := range tokenUses.recorded {
+			if now.Sub(last) >= tokenUseRecordInterval {
+				delete(tokenUses.recorded, id)
+			}
+		}
+	}
+	tokenUses.recorded[tokenID] = now
+	return true
+}
+
+// deleteTokenMetadata deletes the properties of a revoked token
+func deleteTokenMetadata(userID, tokenID string) error {
+	return database.GetDB().Where(&APIToken{ID: tokenID, UserID: userID}).Delete(&APIToken{}).Error
+}
+
+// newTokenResponses adds the stored properties to the tokens
+func newTokenResponses(userID string, tokens ...*bauth.Token) ([]*TokenResponse, error) {
+	ids := []string{}
+	for _, token := range tokens {
+		if token != nil {
+			ids = append(ids, token.ID)
+		}
+	}
+
+	var metadata []APIToken
+	if len(ids) > 0 {
+		err := database.GetDB().Where("user_id = ? AND id IN (?)", userID, ids).Find(&metadata).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	byID := map[string]APIToken{}
+	for _, m := range metadata {
+		byID[m.ID] = m
+	}
+
+	responses := []*TokenResponse{}
+	for _, token := range tokens {
+		if token == nil {
+			continue
+		}
+		response := &TokenResponse{Token: token, Scopes: []string{TokenScopeFull}}
+		if m, ok := byID[token.ID]; ok {
+			response.Scopes = []string{}
+			for _, scope := range strings.Fields(m.Scope) {
+				if !strings.HasPrefix(scope, tokenScopeOrgPrefix) {
+					response.Scopes = append(response.Scopes, scope)
+				}
+			}
+			response.OrganizationID = m.OrganizationID
+			response.ExpiresAt = m.ExpiresAt
+			response.LastUsedAt = m.LastUsedAt
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// CreateVirtualUserToken creates a non-expiring token of a virtual user of the organization restricted to the scopes
+// and the organization, the earlier tokens of the virtual user are revoked. It's used for the credentials Pipeline
+// provisions into the clusters and revokes with them, so the token policy of the organization doesn't apply.
+func CreateVirtualUserToken(organization *Organization, name string, scopes []string) (string, error) {
+	scope, err := validateTokenScopes(scopes, organization.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := RevokeVirtualUserTokens(organization, name); err != nil {
+		return "", err
+	}
+
+	virtualUser := fmt.Sprintf("%s/%s", organization.Name, name)
+	_, signedToken, err := createAndStoreAPIToken(virtualUser, virtualUser, DroneHookTokenType, name, scope, nil)
+	if err != nil {
+		return "", err
+	}
+
+	AddDefaultRoleForVirtualUser(virtualUser)
+	AddOrgRoleForUser(virtualUser, organization.ID)
+
+	return signedToken, nil
+}
+
+// RevokeVirtualUserTokens revokes the tokens of a virtual user of the organization
+func RevokeVirtualUserTokens(organization *Organization, name string) error {
+	virtualUser := fmt.Sprintf("%s/%s", organization.Name, name)
+
+	tokens, err := tokenStore.List(virtualUser)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if err := tokenStore.Revoke(virtualUser, token.ID); err != nil {
+			return err
+		}
+		if err := deleteTokenMetadata(virtualUser, token.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTokenScopes(t *testing.T) {
+	scope, err := validateTokenScopes(nil, 0)
+	if err != nil || scope != TokenScopeFull {
+		t.Errorf("unexpected default scope %q: %v", scope, err)
+	}
+
+	scope, err = validateTokenScopes([]string{"clusters:read", "deployments:write"}, 12)
+	if err != nil || scope != "clusters:read deployments:write org:12" {
+		t.Errorf("unexpected scope %q: %v", scope, err)
+	}
+
+	for _, scopes := range [][]string{{"clusters"}, {"clusters:delete"}, {"unknown:read"}, {"org:1"}} {
+		if _, err := validateTokenScopes(scopes, 0); err == nil {
+			t.Errorf("expected error for scopes %v", scopes)
+		}
+	}
+}
+
+func TestTokenAllows(t *testing.T) {
+	tests := []struct {
+		scope   string
+		method  string
+		path    string
+		allowed bool
+	}{
+		{"", http.MethodDelete, "/orgs/1/clusters/2", true},
+		{"api:invoke", http.MethodDelete, "/orgs/1/clusters/2", true},
+		{"read-only", http.MethodGet, "/orgs/1/clusters/2", true},
+		{"read-only", http.MethodPost, "/orgs/1/clusters", false},
+		{"read-only", http.MethodGet, "/orgs/1/secrets", false},
+		{"read-only", http.MethodGet, "/orgs/1/clusters/2/config", false},
+		{"read-only", http.MethodGet, "/orgs/1/kubeconfig", false},
+		{"read-only", http.MethodGet, "/orgs/1/clusters/2/proxy/api/v1/namespaces/kube-system/secrets", false},
+		{"clusters:read", http.MethodGet, "/orgs/1/clusters/2/proxy/api/v1/pods", false},
+		{"clusterproxy:read", http.MethodGet, "/orgs/1/clusters/2/proxy/api/v1/pods", true},
+		{"secrets:read", http.MethodGet, "/orgs/1/secrets", true},
+		{"kubeconfig:read", http.MethodGet, "/orgs/1/clusters/2/config", true},
+		{"clusters:read", http.MethodGet, "/orgs/1/clusters/2/details", true},
+		{"clusters:read", http.MethodGet, "/orgs/1/clusters/2/secrets", false},
+		{"clusters:read", http.MethodPut, "/orgs/1/clusters/2", false},
+		{"clusters:write", http.MethodPut, "/orgs/1/clusters/2", true},
+		{"deployments:write", http.MethodPost, "/orgs/1/clusters/2/deployments", true},
+		{"clusteralerts:write org:1", http.MethodPost, "/orgs/1/clusters/2/alerts", true},
+		{"clusteralerts:write org:1", http.MethodPut, "/orgs/1/clusters/2/alertrules/group", false},
+		{"deployments:write", http.MethodGet, "/orgs/1/helm/repos", true},
+		{"secrets:read", http.MethodGet, "/allowed/secrets/aws", true},
+		{"api:invoke org:1", http.MethodDelete, "/orgs/1/clusters/2", true},
+		{"api:invoke org:1", http.MethodGet, "/orgs/2/clusters", false},
+		{"api:invoke org:1", http.MethodGet, "/orgs", true},
+		{"api:invoke org:1", http.MethodPost, "/orgs", false},
+	}
+
+	for _, test := range tests {
+		if allowed := tokenAllows(strings.Fields(test.scope), test.method, test.path); allowed != test.allowed {
+			t.Errorf("%q %s %s: expected allowed=%t", test.scope, test.method, test.path, test.allowed)
+		}
+	}
+
+	if !tokenHasFullAccess(nil) || !tokenHasFullAccess([]string{TokenScopeFull}) ||
+		tokenHasFullAccess([]string{TokenScopeFull, "org:1"}) || tokenHasFullAccess([]string{TokenScopeReadOnly}) {
+		t.Error("unexpected full access of scopes")
+	}
+}
+
+func TestShouldRecordTokenUse(t *testing.T) {
+	now := time.Now()
+	if !shouldRecordTokenUse("token", now) {
+		t.Error("expected the first use to be recorded")
+	}
+	if shouldRecordTokenUse("token", now.Add(30*time.Second)) {
+		t.Error("expected the use within a minute not to be recorded")
+	}
+	if !shouldRecordTokenUse("other", now.Add(30*time.Second)) {
+		t.Error("expected the use of another token to be recorded")
+	}
+	if !shouldRecordTokenUse("token", now.Add(time.Minute)) {
+		t.Error("expected the use after a minute to be recorded")
+	}
+}
@@ -48,6 +48,8 @@ type User struct {
 	Image         string         `form:"image" json:"image,omitempty"`
 	Organizations []Organization `gorm:"many2many:user_organizations" json:"organizations,omitempty"`
 	Virtual       bool           `json:"-" gorm:"-"` // Used only internally
+	TokenID       string         `json:"-" gorm:"-"` // ID of the API token used in the request
+	TokenScopes   []string       `json:"-" gorm:"-"` // Scopes of the API token used in the request
 }
 
 //DroneUser struct
@@ -65,6 +67,13 @@ type DroneUser struct {
 	Synced int64  `gorm:"column:user_synced"`
 }
 
+// Built-in organization roles, organizations can define custom roles as well
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
 // UserOrganization describes the user organization
 type UserOrganization struct {
 	UserID         uint
@@ -76,6 +85,9 @@ type UserOrganization struct {
 type Organization struct {
 	ID        uint                 `gorm:"primary_key" json:"id"`
 	GithubID  *int64               `gorm:"unique" json:"githubId,omitempty"`
+	GitlabID  *int64               `gorm:"unique" json:"gitlabId,omitempty"`
+	OIDCGroup *string              `gorm:"column:oidc_group;unique" json:"oidcGroup,omitempty"`
+	LDAPGroup *string              `gorm:"column:ldap_group;unique" json:"ldapGroup,omitempty"`
 	CreatedAt time.Time            `json:"createdAt"`
 	UpdatedAt time.Time            `json:"updatedAt"`
 	Name      string               `gorm:"unique;not null" json:"name"`
@@ -89,6 +101,11 @@ func (user *User) IDString() string {
 	return fmt.Sprint(user.ID)
 }
 
+// HasRestrictedToken tells whether the user is authenticated by an API token restricted by scopes or to an organization
+func (user *User) HasRestrictedToken() bool {
+	return !tokenHasFullAccess(user.TokenScopes)
+}
+
 //IDString returns the ID as string
 func (org *Organization) IDString() string {
 	return fmt.Sprint(org.ID)
@@ -146,15 +163,25 @@ func (bus BanzaiUserStorer) Save(schema *auth.Schema, context *auth.Context) (us
 		return nil, "", err
 	}
 
-	// This assumes GitHub auth only right now
-	githubExtraInfo := schema.RawInfo.(*GithubExtraInfo)
-	currentUser.Login = githubExtraInfo.Login
-	err = bus.createUserInDroneDB(currentUser, githubExtraInfo.Token)
-	if err != nil {
-		log.Info(context.Request.RemoteAddr, err.Error())
-		return nil, "", err
+	switch extraInfo := schema.RawInfo.(type) {
+	case *GithubExtraInfo:
+		currentUser.Login = extraInfo.Login
+		err = bus.createUserInDroneDB(currentUser, extraInfo.Token)
+		if err != nil {
+			log.Info(context.Request.RemoteAddr, err.Error())
+			return nil, "", err
+		}
+		bus.synchronizeDroneRepos(currentUser.Login)
+	case *GitlabExtraInfo:
+		// Drone needs a GitHub token, so GitLab, OpenID Connect and LDAP users are not registered in Drone
+		currentUser.Login = extraInfo.Login
+	case *OIDCExtraInfo:
+		currentUser.Login = extraInfo.Login
+	case *LDAPExtraInfo:
+		currentUser.Login = extraInfo.Login
+	default:
+		return nil, "", fmt.Errorf("unsupported auth provider: %s", schema.Provider)
 	}
-	bus.synchronizeDroneRepos(currentUser.Login)
 
 	// When a user registers a default organization is created in which he/she is admin
 	userOrg := Organization{
@@ -175,11 +202,21 @@ func (bus BanzaiUserStorer) Save(schema *auth.Schema, context *auth.Context) (us
 
 	AddDefaultRoleForUser(currentUser.ID)
 
-	githubOrgIDs, err := importGithubOrganizations(currentUser, context, githubExtraInfo.Token)
+	var importedOrgIDs []uint
+	switch extraInfo := schema.RawInfo.(type) {
+	case *GithubExtraInfo:
+		importedOrgIDs, err = importGithubOrganizations(currentUser, context, extraInfo.Token)
+	case *GitlabExtraInfo:
+		importedOrgIDs, err = importGitlabOrganizations(currentUser, context, extraInfo.BaseURL, extraInfo.Token)
+	case *OIDCExtraInfo:
+		importedOrgIDs, err = importGroupOrganizations(db, currentUser, extraInfo.Roles, oidcGroupColumn)
+	case *LDAPExtraInfo:
+		importedOrgIDs, err = importGroupOrganizations(db, currentUser, extraInfo.Roles, ldapGroupColumn)
+	}
 
 	if err == nil {
 		orgids := []uint{currentUser.Organizations[0].ID}
-		orgids = append(orgids, githubOrgIDs...)
+		orgids = append(orgids, importedOrgIDs...)
 		AddOrgRoles(orgids...)
 		AddOrgRoleForUser(currentUser.ID, orgids...)
 	}
@@ -272,6 +309,7 @@ func importGithubOrganizations(currentUser *User, context *auth.Context, githubT
 				tx.Rollback()
 				return nil, err
 			}
+			previousRole := membershipRole(tx, currentUser.ID, githubOrg.ID)
 			err = tx.Model(currentUser).Association("Organizations").Append(githubOrg).Error
 			if err != nil {
 				tx.Rollback()
@@ -283,6 +321,7 @@ func importGithubOrganizations(currentUser *User, context *auth.Context, githubT
 				tx.Rollback()
 				return nil, err
 			}
+			revokeChangedRoleCredentials(currentUser.ID, githubOrg.ID, previousRole, githubOrg.Role)
 			orgids = append(orgids, githubOrg.ID)
 		}
 	}
@@ -303,6 +342,38 @@ func GetOrganizationById(orgID uint) (*Organization, error) {
 	return &org, err
 }
 
+// RevokeUserCredentials revokes the cluster credentials issued to the user in the organization.
+// It's called when a login or group sync removes the user from an organization or changes its role there,
+// the credentials are revoked by the cluster package, so main sets it.
+var RevokeUserCredentials = func(organizationID, userID uint) {}
+
+// membershipRole returns the current role of the user in the organization, or an empty string if it's not a member
+func membershipRole(db *gorm.DB, userID, orgID uint) string {
+	var membership UserOrganization
+	db.Where(UserOrganization{UserID: userID, OrganizationID: orgID}).First(&membership)
+	return membership.Role
+}
+
+// revokeChangedRoleCredentials revokes the cluster credentials of the user in the organization if its role has been changed
+func revokeChangedRoleCredentials(userID, orgID uint, previousRole, role string) {
+	if previousRole != "" && previousRole != role {
+		go RevokeUserCredentials(orgID, userID)
+	}
+}
+
+// GetUserOrganizationRole returns the role of the user in the organization.
+// Virtual users are created by the organization's CI tokens, so they act as admins.
+func GetUserOrganizationRole(user *User, orgID uint) (string, error) {
+	if user.Virtual {
+		return RoleAdmin, nil
+	}
+
+	db := database.GetDB()
+	var userOrganization UserOrganization
+	err := db.Where(UserOrganization{UserID: user.ID, OrganizationID: orgID}).First(&userOrganization).Error
+	return userOrganization.Role, err
+}
+
 // GetUserById returns user
 func GetUserById(userId uint) (*User, error) {
 	db := database.GetDB()
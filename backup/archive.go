@@ -0,0 +1,277 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	archiveMetadataFile  = "metadata.json"
+	archiveResourcesDir  = "resources"
+	archiveCoreGroupName = "core"
+)
+
+// skippedResources are not backed up because they are recreated by the cluster itself
+var skippedResources = map[string]bool{
+	"events":              true,
+	"endpoints":           true,
+	"controllerrevisions": true,
+}
+
+// restorePriorities defines the order of resources during restore, the rest of them are restored afterwards
+var restorePriorities = []string{
+	"serviceaccounts",
+	"secrets",
+	"configmaps",
+	"limitranges",
+	"resourcequotas",
+	"persistentvolumeclaims",
+	"roles",
+	"rolebindings",
+	"services",
+}
+
+// archiveMetadata describes the content of a backup archive
+type archiveMetadata struct {
+	ClusterName   string    `json:"clusterName"`
+	Namespaces    []string  `json:"namespaces"`
+	LabelSelector string    `json:"labelSelector,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// resourceItem is a backed up Kubernetes resource
+type resourceItem struct {
+	Group     string
+	Version   string
+	Resource  string
+	Namespace string
+	Name      string
+	Object    map[string]interface{}
+}
+
+// archivePath returns the path of the resource in the archive:
+// resources/<namespace>/<group>/<version>/<resource>/<name>.json
+func (i *resourceItem) archivePath() string {
+	group := i.Group
+	if group == "" {
+		group = archiveCoreGroupName
+	}
+	return path.Join(archiveResourcesDir, i.Namespace, group, i.Version, i.Resource, i.Name+".json")
+}
+
+// String returns a short human readable identifier of the resource
+func (i *resourceItem) String() string {
+	return fmt.Sprintf("%s/%s/%s", i.Namespace, i.Resource, i.Name)
+}
+
+// shouldBackup decides whether the resource has to be backed up. Resources owned by other
+// resources are left out as they are recreated by their owners (e.g. pods of a deployment).
+func shouldBackup(resource string, obj map[string]interface{}) bool {
+	if skippedResources[resource] {
+		return false
+	}
+
+	if owners, found, _ := unstructured.NestedSlice(obj, "metadata", "ownerReferences"); found && len(owners) > 0 {
+		return false
+	}
+
+	switch resource {
+	case "secrets":
+		secretType, _, _ := unstructured.NestedString(obj, "type")
+		return secretType != "kubernetes.io/service-account-token"
+	case "serviceaccounts":
+		name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+		return name != "default"
+	}
+
+	return true
+}
+
+// cleanObject removes the fields which are specific to the cluster the resource was taken from
+func cleanObject(resource string, obj map[string]interface{}) {
+	for _, field := range []string{
+		"uid",
+		"resourceVersion",
+		"selfLink",
+		"creationTimestamp",
+		"deletionTimestamp",
+		"deletionGracePeriodSeconds",
+		"generation",
+		"ownerReferences",
+		"initializers",
+		"finalizers",
+	} {
+		unstructured.RemoveNestedField(obj, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj, "status")
+
+	if annotations, found, _ := unstructured.NestedStringMap(obj, "metadata", "annotations"); found {
+		for key := range annotations {
+			if strings.HasPrefix(key, "pv.kubernetes.io/") || strings.HasPrefix(key, "volume.beta.kubernetes.io/storage-provisioner") ||
+				key == "deployment.kubernetes.io/revision" {
+				delete(annotations, key)
+			}
+		}
+		if len(annotations) == 0 {
+			unstructured.RemoveNestedField(obj, "metadata", "annotations")
+		} else {
+			unstructured.SetNestedStringMap(obj, annotations, "metadata", "annotations")
+		}
+	}
+
+	switch resource {
+	case "services":
+		if clusterIP, _, _ := unstructured.NestedString(obj, "spec", "clusterIP"); clusterIP != "None" {
+			unstructured.RemoveNestedField(obj, "spec", "clusterIP")
+		}
+		if ports, found, _ := unstructured.NestedSlice(obj, "spec", "ports"); found {
+			for _, port := range ports {
+				if p, ok := port.(map[string]interface{}); ok {
+					delete(p, "nodePort")
+				}
+			}
+			unstructured.SetNestedSlice(obj, ports, "spec", "ports")
+		}
+	case "persistentvolumeclaims":
+		unstructured.RemoveNestedField(obj, "spec", "volumeName")
+	case "serviceaccounts":
+		unstructured.RemoveNestedField(obj, "secrets")
+	case "pods":
+		unstructured.RemoveNestedField(obj, "spec", "nodeName")
+	}
+}
+
+// writeArchive writes the backed up resources into a gzipped tarball
+func writeArchive(metadata *archiveMetadata, items []resourceItem) ([]byte, error) {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	writeFile := func(name string, content []byte) error {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: metadata.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(content)
+		return err
+	}
+
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling backup metadata")
+	}
+	if err := writeFile(archiveMetadataFile, content); err != nil {
+		return nil, errors.Wrap(err, "error writing backup metadata")
+	}
+
+	for _, item := range items {
+		content, err := json.Marshal(item.Object)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error marshalling %s", item.String())
+		}
+		if err := writeFile(item.archivePath(), content); err != nil {
+			return nil, errors.Wrapf(err, "error writing %s", item.String())
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// readArchive reads the backed up resources from a gzipped tarball in restore order
+func readArchive(archive []byte) (*archiveMetadata, []resourceItem, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error opening backup archive")
+	}
+	defer gzipReader.Close()
+
+	var metadata *archiveMetadata
+	var items []resourceItem
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error reading backup archive")
+		}
+
+		content, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "error reading %s", header.Name)
+		}
+
+		if header.Name == archiveMetadataFile {
+			metadata = &archiveMetadata{}
+			if err := json.Unmarshal(content, metadata); err != nil {
+				return nil, nil, errors.Wrap(err, "error parsing backup metadata")
+			}
+			continue
+		}
+
+		parts := strings.Split(header.Name, "/")
+		if len(parts) != 6 || parts[0] != archiveResourcesDir || !strings.HasSuffix(parts[5], ".json") {
+			return nil, nil, errors.Errorf("unexpected file in backup archive: %s", header.Name)
+		}
+
+		item := resourceItem{
+			Group:     parts[2],
+			Version:   parts[3],
+			Resource:  parts[4],
+			Namespace: parts[1],
+			Name:      strings.TrimSuffix(parts[5], ".json"),
+		}
+		if item.Group == archiveCoreGroupName {
+			item.Group = ""
+		}
+		if err := json.Unmarshal(content, &item.Object); err != nil {
+			return nil, nil, errors.Wrapf(err, "error parsing %s", header.Name)
+		}
+		items = append(items, item)
+	}
+
+	if metadata == nil {
+		return nil, nil, errors.New("backup metadata is missing from the archive")
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return restorePriority(items[i].Resource) < restorePriority(items[j].Resource)
+	})
+
+	return metadata, items, nil
+}
+
+func restorePriority(resource string) int {
+	for i, r := range restorePriorities {
+		if r == resource {
+			return i
+		}
+	}
+	return len(restorePriorities)
+}
@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCleanObject(t *testing.T) {
+	service := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":              "web",
+			"namespace":         "default",
+			"uid":               "b6c4a0d1",
+			"resourceVersion":   "1234",
+			"selfLink":          "/api/v1/namespaces/default/services/web",
+			"creationTimestamp": "2018-07-01T10:00:00Z",
+			"labels":            map[string]interface{}{"app": "web"},
+		},
+		"spec": map[string]interface{}{
+			"clusterIP": "10.0.0.12",
+			"ports": []interface{}{
+				map[string]interface{}{"port": int64(80), "nodePort": int64(30080)},
+			},
+		},
+		"status": map[string]interface{}{"loadBalancer": map[string]interface{}{}},
+	}
+
+	cleanObject("services", service)
+
+	expected := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":      "web",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"app": "web"},
+		},
+		"spec": map[string]interface{}{
+			"ports": []interface{}{
+				map[string]interface{}{"port": int64(80)},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, service) {
+		t.Errorf("unexpected cleaned object: %v", service)
+	}
+}
+
+func TestShouldBackup(t *testing.T) {
+	cases := []struct {
+		resource string
+		obj      map[string]interface{}
+		expected bool
+	}{
+		{"deployments", map[string]interface{}{"metadata": map[string]interface{}{"name": "web"}}, true},
+		{"events", map[string]interface{}{"metadata": map[string]interface{}{"name": "web.1"}}, false},
+		{"pods", map[string]interface{}{"metadata": map[string]interface{}{
+			"name":            "web-1",
+			"ownerReferences": []interface{}{map[string]interface{}{"kind": "ReplicaSet", "name": "web"}},
+		}}, false},
+		{"secrets", map[string]interface{}{"type": "kubernetes.io/service-account-token"}, false},
+		{"secrets", map[string]interface{}{"type": "Opaque"}, true},
+		{"serviceaccounts", map[string]interface{}{"metadata": map[string]interface{}{"name": "default"}}, false},
+	}
+
+	for _, c := range cases {
+		if actual := shouldBackup(c.resource, c.obj); actual != c.expected {
+			t.Errorf("shouldBackup(%s, %v) = %t, expected %t", c.resource, c.obj, actual, c.expected)
+		}
+	}
+}
+
+func TestArchive(t *testing.T) {
+	metadata := &archiveMetadata{
+		ClusterName: "test",
+		Namespaces:  []string{"default"},
+		CreatedAt:   time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC),
+	}
+	items := []resourceItem{
+		{
+			Group:     "apps",
+			Version:   "v1",
+			Resource:  "deployments",
+			Namespace: "default",
+			Name:      "web",
+			Object:    map[string]interface{}{"kind": "Deployment"},
+		},
+		{
+			Version:   "v1",
+			Resource:  "configmaps",
+			Namespace: "default",
+			Name:      "web",
+			Object:    map[string]interface{}{"kind": "ConfigMap"},
+		},
+	}
+
+	archive, err := writeArchive(metadata, items)
+	if err != nil {
+		t.Fatalf("error writing archive: %s", err.Error())
+	}
+
+	readMetadata, readItems, err := readArchive(archive)
+	if err != nil {
+		t.Fatalf("error reading archive: %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(metadata, readMetadata) {
+		t.Errorf("unexpected metadata: %v", readMetadata)
+	}
+
+	// config maps have to be restored before deployments
+	expected := []resourceItem{items[1], items[0]}
+	if !reflect.DeepEqual(expected, readItems) {
+		t.Errorf("unexpected items: %v", readItems)
+	}
+}
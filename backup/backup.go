@@ -0,0 +1,267 @@
+package backup
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/helm"
+	"github.com/banzaicloud/pipeline/objectstore"
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+var log *logrus.Logger
+
+// Simple init for logging
+func init() {
+	log = config.Logger()
+}
+
+// CreateBackup validates the request and starts backing up the namespaces of the cluster in the background
+func CreateBackup(commonCluster cluster.CommonCluster, request *pkgBackup.CreateBackupRequest, userID, scheduleID uint) (*ClusterBackupModel, error) {
+	if len(request.Namespaces) == 0 {
+		return nil, errors.New("at least one namespace must be specified")
+	}
+
+	if _, err := labels.Parse(request.LabelSelector); err != nil {
+		return nil, errors.Wrap(err, "invalid label selector")
+	}
+
+	bucket := newBucketModel(request.Bucket)
+	objectStore, err := newObjectStore(commonCluster.GetOrganizationId(), bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	name := request.Name
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", commonCluster.GetName(), time.Now().UTC().Format("20060102150405"))
+	}
+
+	backup := &ClusterBackupModel{
+		OrganizationID: commonCluster.GetOrganizationId(),
+		ClusterID:      commonCluster.GetID(),
+		ScheduleID:     scheduleID,
+		Name:           name,
+		Namespaces:     strings.Join(request.Namespaces, ","),
+		LabelSelector:  request.LabelSelector,
+		BucketModel:    bucket,
+		// backup names aren't unique, so the key is made unique for a backup not to overwrite or delete the archive of another
+		ObjectKey: fmt.Sprintf("%s/%d/%s/%s-%s.tar.gz",
+			viper.GetString(config.BackupObjectKeyPrefix), commonCluster.GetOrganizationId(), commonCluster.GetName(), name, uuid.NewV4().String()),
+		Status:    pkgBackup.StatusCreating,
+		CreatedBy: userID,
+	}
+	if err := backup.Save(); err != nil {
+		return nil, errors.Wrap(err, "error saving backup")
+	}
+
+	go runBackup(commonCluster, objectStore, backup)
+
+	return backup, nil
+}
+
+func runBackup(commonCluster cluster.CommonCluster, objectStore objectstore.ObjectStore, backup *ClusterBackupModel) {
+	log := log.WithFields(logrus.Fields{"cluster": commonCluster.GetName(), "backup": backup.Name})
+	log.Info("Creating backup")
+
+	err := func() error {
+		items, err := collectResources(commonCluster, backup.GetNamespaces(), backup.LabelSelector)
+		if err != nil {
+			return err
+		}
+
+		archive, err := writeArchive(&archiveMetadata{
+			ClusterName:   commonCluster.GetName(),
+			Namespaces:    backup.GetNamespaces(),
+			LabelSelector: backup.LabelSelector,
+			CreatedAt:     time.Now().UTC(),
+		}, items)
+		if err != nil {
+			return err
+		}
+
+		if err := objectStore.PutObject(backup.BucketName, backup.ObjectKey, archive); err != nil {
+			return errors.Wrap(err, "error uploading backup archive")
+		}
+
+		backup.ResourceCount = len(items)
+		backup.Size = int64(len(archive))
+		return nil
+	}()
+
+	if err != nil {
+		log.Errorf("Error creating backup: %s", err.Error())
+		backup.Status = pkgBackup.StatusFailed
+		backup.StatusMessage = err.Error()
+	} else {
+		log.Infof("Backup of %d resources created", backup.ResourceCount)
+		backup.Status = pkgBackup.StatusCompleted
+	}
+
+	if err := backup.Save(); err != nil {
+		log.Errorf("Error saving backup: %s", err.Error())
+	}
+}
+
+// collectResources lists the resources of the namespaces which have to be backed up
+func collectResources(commonCluster cluster.CommonCluster, namespaces []string, labelSelector string) ([]resourceItem, error) {
+	kubeConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting cluster config")
+	}
+
+	client, err := helm.GetK8sConnection(kubeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	restConfig, err := helm.GetK8sClientConfig(kubeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes client config")
+	}
+	clientPool := dynamic.NewDynamicClientPool(restConfig)
+
+	// resources of the groups which failed discovery (e.g. unavailable aggregated APIs) are skipped
+	resourceLists, err := client.Discovery().ServerPreferredNamespacedResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, errors.Wrap(err, "error discovering cluster resources")
+	}
+
+	var items []resourceItem
+	for _, resourceList := range resourceLists {
+		groupVersion, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid group version %s", resourceList.GroupVersion)
+		}
+
+		for _, apiResource := range resourceList.APIResources {
+			apiResource := apiResource
+			if skippedResources[apiResource.Name] || strings.Contains(apiResource.Name, "/") ||
+				!hasVerbs(apiResource.Verbs, "list", "create") {
+				continue
+			}
+
+			resourceClient, err := clientPool.ClientForGroupVersionResource(groupVersion.WithResource(apiResource.Name))
+			if err != nil {
+				return nil, errors.Wrapf(err, "error creating client for %s", apiResource.Name)
+			}
+
+			for _, namespace := range namespaces {
+				list, err := resourceClient.Resource(&apiResource, namespace).List(metav1.ListOptions{LabelSelector: labelSelector})
+				if err != nil {
+					return nil, errors.Wrapf(err, "error listing %s in namespace %s", apiResource.Name, namespace)
+				}
+
+				unstructuredList, ok := list.(*unstructured.UnstructuredList)
+				if !ok {
+					return nil, errors.Errorf("unexpected list type of %s", apiResource.Name)
+				}
+
+				for _, obj := range unstructuredList.Items {
+					if !shouldBackup(apiResource.Name, obj.Object) {
+						continue
+					}
+					cleanObject(apiResource.Name, obj.Object)
+					items = append(items, resourceItem{
+						Group:     groupVersion.Group,
+						Version:   groupVersion.Version,
+						Resource:  apiResource.Name,
+						Namespace: namespace,
+						Name:      obj.GetName(),
+						Object:    obj.Object,
+					})
+				}
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// DeleteBackup deletes the backup archive from the bucket and the backup from DB
+func DeleteBackup(backup *ClusterBackupModel) error {
+	objectStore, err := newObjectStore(backup.OrganizationID, backup.BucketModel)
+	if err != nil {
+		return err
+	}
+
+	if backup.Status == pkgBackup.StatusCompleted {
+		if err := objectStore.DeleteObject(backup.BucketName, backup.ObjectKey); err != nil {
+			return errors.Wrap(err, "error deleting backup archive")
+		}
+	}
+
+	return backup.Delete()
+}
+
+// newObjectStore creates an object store client for the bucket with the organization's secret
+func newObjectStore(organizationID uint, bucket BucketModel) (objectstore.ObjectStore, error) {
+	organization, err := auth.GetOrganizationById(organizationID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting organization")
+	}
+
+	bucketSecret, err := secret.Store.Get(organizationID, bucket.SecretID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting bucket secret")
+	}
+
+	if err := bucketSecret.ValidateSecretType(bucket.Cloud); err != nil {
+		return nil, err
+	}
+
+	objectStore, err := objectstore.NewObjectStore(bucket.Cloud, bucketSecret, organization)
+	if err != nil {
+		return nil, err
+	}
+
+	switch bucket.Cloud {
+	case pkgCluster.Azure:
+		if bucket.ResourceGroup == "" || bucket.StorageAccount == "" {
+			return nil, errors.New("resource group and storage account are required for Azure buckets")
+		}
+		objectStore.WithResourceGroup(bucket.ResourceGroup)
+		objectStore.WithStorageAccount(bucket.StorageAccount)
+	case pkgCluster.Oracle:
+		if bucket.Location == "" {
+			return nil, errors.New("location is required for Oracle buckets")
+		}
+		objectStore.WithRegion(bucket.Location)
+	default:
+		objectStore.WithRegion(bucket.Location)
+	}
+
+	return objectStore, nil
+}
+
+func hasVerbs(verbs metav1.Verbs, required ...string) bool {
+	for _, r := range required {
+		found := false
+		for _, verb := range verbs {
+			if verb == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
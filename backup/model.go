@@ -0,0 +1,292 @@
+package backup
+
+import (
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/database"
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+)
+
+// Table names of the backup models
+const (
+	TableNameClusterBackups         = "cluster_backups"
+	TableNameClusterBackupSchedules = "cluster_backup_schedules"
+	TableNameClusterBackupRestores  = "cluster_backup_restores"
+)
+
+// BucketModel is the embedded description of the bucket a backup is stored in
+type BucketModel struct {
+	Cloud          string
+	SecretID       string
+	BucketName     string
+	Location       string
+	ResourceGroup  string
+	StorageAccount string
+}
+
+// ClusterBackupModel describes a backup of a cluster's namespaces
+type ClusterBackupModel struct {
+	ID             uint `gorm:"primary_key"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	OrganizationID uint `gorm:"index:idx_org_cluster"`
+	ClusterID      uint `gorm:"index:idx_org_cluster"`
+	ScheduleID     uint `gorm:"index"`
+	Name           string
+	Namespaces     string
+	LabelSelector  string
+	BucketModel
+	ObjectKey     string
+	Status        string
+	StatusMessage string `sql:"type:text"`
+	ResourceCount int
+	Size          int64
+	CreatedBy     uint
+}
+
+// ClusterBackupScheduleModel describes a periodic backup of a cluster's namespaces
+type ClusterBackupScheduleModel struct {
+	ID             uint `gorm:"primary_key"`
+	CreatedAt      time.Time
+	OrganizationID uint `gorm:"index:idx_org_cluster"`
+	ClusterID      uint `gorm:"index:idx_org_cluster"`
+	Name           string
+	Interval       string
+	Retention      int
+	Namespaces     string
+	LabelSelector  string
+	BucketModel
+	LastRunAt *time.Time
+	NextRunAt time.Time `gorm:"index"`
+	CreatedBy uint
+}
+
+// ClusterBackupRestoreModel describes the restore of a backup into a cluster
+type ClusterBackupRestoreModel struct {
+	ID              uint `gorm:"primary_key"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	OrganizationID  uint `gorm:"index"`
+	BackupID        uint `gorm:"index"`
+	TargetClusterID uint `gorm:"index"`
+	Status          string
+	StatusMessage   string `sql:"type:text"`
+	Created         int
+	Skipped         int
+	Failed          string `sql:"type:text"`
+	CreatedBy       uint
+}
+
+// TableName sets ClusterBackupModel's table name
+func (ClusterBackupModel) TableName() string {
+	return TableNameClusterBackups
+}
+
+// TableName sets ClusterBackupScheduleModel's table name
+func (ClusterBackupScheduleModel) TableName() string {
+	return TableNameClusterBackupSchedules
+}
+
+// TableName sets ClusterBackupRestoreModel's table name
+func (ClusterBackupRestoreModel) TableName() string {
+	return TableNameClusterBackupRestores
+}
+
+// Save saves the backup to DB
+func (b *ClusterBackupModel) Save() error {
+	return database.GetDB().Save(b).Error
+}
+
+// Delete deletes the backup from DB
+func (b *ClusterBackupModel) Delete() error {
+	return database.GetDB().Delete(b).Error
+}
+
+// GetNamespaces returns the backed up namespaces
+func (b *ClusterBackupModel) GetNamespaces() []string {
+	return splitNamespaces(b.Namespaces)
+}
+
+// ToResponse converts the backup to its API representation
+func (b *ClusterBackupModel) ToResponse() *pkgBackup.BackupResponse {
+	return &pkgBackup.BackupResponse{
+		ID:            b.ID,
+		Name:          b.Name,
+		ClusterID:     b.ClusterID,
+		ScheduleID:    b.ScheduleID,
+		Namespaces:    b.GetNamespaces(),
+		LabelSelector: b.LabelSelector,
+		Bucket:        b.BucketModel.toBucket(),
+		ObjectKey:     b.ObjectKey,
+		Status:        b.Status,
+		StatusMessage: b.StatusMessage,
+		ResourceCount: b.ResourceCount,
+		Size:          b.Size,
+		CreatedBy:     b.CreatedBy,
+		CreatedAt:     b.CreatedAt,
+	}
+}
+
+// Save saves the schedule to DB
+func (s *ClusterBackupScheduleModel) Save() error {
+	return database.GetDB().Save(s).Error
+}
+
+// Delete deletes the schedule from DB
+func (s *ClusterBackupScheduleModel) Delete() error {
+	return database.GetDB().Delete(s).Error
+}
+
+// ToResponse converts the schedule to its API representation
+func (s *ClusterBackupScheduleModel) ToResponse() *pkgBackup.ScheduleResponse {
+	return &pkgBackup.ScheduleResponse{
+		ID:            s.ID,
+		Name:          s.Name,
+		ClusterID:     s.ClusterID,
+		Interval:      s.Interval,
+		Retention:     s.Retention,
+		Namespaces:    splitNamespaces(s.Namespaces),
+		LabelSelector: s.LabelSelector,
+		Bucket:        s.BucketModel.toBucket(),
+		LastRunAt:     s.LastRunAt,
+		NextRunAt:     s.NextRunAt,
+	}
+}
+
+// Save saves the restore to DB
+func (r *ClusterBackupRestoreModel) Save() error {
+	return database.GetDB().Save(r).Error
+}
+
+// ToResponse converts the restore to its API representation
+func (r *ClusterBackupRestoreModel) ToResponse() *pkgBackup.RestoreResponse {
+	var failed []string
+	if r.Failed != "" {
+		failed = strings.Split(r.Failed, "\n")
+	}
+
+	return &pkgBackup.RestoreResponse{
+		ID:              r.ID,
+		BackupID:        r.BackupID,
+		TargetClusterID: r.TargetClusterID,
+		Status:          r.Status,
+		StatusMessage:   r.StatusMessage,
+		Created:         r.Created,
+		Skipped:         r.Skipped,
+		Failed:          failed,
+		CreatedBy:       r.CreatedBy,
+		CreatedAt:       r.CreatedAt,
+	}
+}
+
+func newBucketModel(bucket pkgBackup.Bucket) BucketModel {
+	return BucketModel{
+		Cloud:          bucket.Cloud,
+		SecretID:       bucket.SecretID,
+		BucketName:     bucket.Name,
+		Location:       bucket.Location,
+		ResourceGroup:  bucket.ResourceGroup,
+		StorageAccount: bucket.StorageAccount,
+	}
+}
+
+func (b BucketModel) toBucket() pkgBackup.Bucket {
+	return pkgBackup.Bucket{
+		Cloud:          b.Cloud,
+		SecretID:       b.SecretID,
+		Name:           b.BucketName,
+		Location:       b.Location,
+		ResourceGroup:  b.ResourceGroup,
+		StorageAccount: b.StorageAccount,
+	}
+}
+
+func splitNamespaces(namespaces string) []string {
+	if namespaces == "" {
+		return []string{}
+	}
+	return strings.Split(namespaces, ",")
+}
+
+// QueryBackups returns the backups of the cluster, or of the whole organization if the cluster ID is 0, newest first
+func QueryBackups(organizationID, clusterID uint) ([]ClusterBackupModel, error) {
+	var backups []ClusterBackupModel
+	err := database.GetDB().
+		Where(&ClusterBackupModel{OrganizationID: organizationID, ClusterID: clusterID}).
+		Order("created_at desc").
+		Find(&backups).Error
+	return backups, err
+}
+
+// GetBackup returns a backup of the organization by its ID
+func GetBackup(organizationID, backupID uint) (*ClusterBackupModel, error) {
+	var backup ClusterBackupModel
+	err := database.GetDB().Where(&ClusterBackupModel{ID: backupID, OrganizationID: organizationID}).First(&backup).Error
+	if err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// QueryScheduledBackups returns the backups created by the schedule, newest first
+func QueryScheduledBackups(scheduleID uint) ([]ClusterBackupModel, error) {
+	var backups []ClusterBackupModel
+	err := database.GetDB().
+		Where(&ClusterBackupModel{ScheduleID: scheduleID}).
+		Order("created_at desc").
+		Find(&backups).Error
+	return backups, err
+}
+
+// QuerySchedules returns the backup schedules of the cluster
+func QuerySchedules(organizationID, clusterID uint) ([]ClusterBackupScheduleModel, error) {
+	var schedules []ClusterBackupScheduleModel
+	err := database.GetDB().
+		Where(&ClusterBackupScheduleModel{OrganizationID: organizationID, ClusterID: clusterID}).
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// GetSchedule returns a backup schedule of the cluster by its ID
+func GetSchedule(organizationID, clusterID, scheduleID uint) (*ClusterBackupScheduleModel, error) {
+	var schedule ClusterBackupScheduleModel
+	err := database.GetDB().
+		Where(&ClusterBackupScheduleModel{ID: scheduleID, OrganizationID: organizationID, ClusterID: clusterID}).
+		First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// QueryDueSchedules returns the backup schedules which should have run before the given time
+func QueryDueSchedules(before time.Time) ([]ClusterBackupScheduleModel, error) {
+	var schedules []ClusterBackupScheduleModel
+	err := database.GetDB().Where("next_run_at <= ?", before).Find(&schedules).Error
+	return schedules, err
+}
+
+// claimSchedule moves the next run of the due schedule forward, it returns false if the schedule has been
+// claimed by another Pipeline instance in the meantime
+func claimSchedule(schedule *ClusterBackupScheduleModel, runAt time.Time, nextRunAt time.Time) (bool, error) {
+	result := database.GetDB().Model(ClusterBackupScheduleModel{}).
+		Where("id = ? AND next_run_at = ?", schedule.ID, schedule.NextRunAt).
+		Updates(map[string]interface{}{"last_run_at": runAt, "next_run_at": nextRunAt})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	schedule.LastRunAt = &runAt
+	schedule.NextRunAt = nextRunAt
+	return result.RowsAffected == 1, nil
+}
+
+// QueryRestores returns the restores into the cluster, newest first
+func QueryRestores(organizationID, clusterID uint) ([]ClusterBackupRestoreModel, error) {
+	var restores []ClusterBackupRestoreModel
+	err := database.GetDB().
+		Where(&ClusterBackupRestoreModel{OrganizationID: organizationID, TargetClusterID: clusterID}).
+		Order("created_at desc").
+		Find(&restores).Error
+	return restores, err
+}
@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"strings"
+
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/helm"
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RestoreBackup starts re-applying the resources of the backup into the target cluster in the background.
+// Resources already present in the target cluster are left untouched.
+func RestoreBackup(backup *ClusterBackupModel, targetCluster cluster.CommonCluster, userID uint) (*ClusterBackupRestoreModel, error) {
+	if backup.Status != pkgBackup.StatusCompleted {
+		return nil, errors.Errorf("backup is not completed: %s", backup.Status)
+	}
+
+	objectStore, err := newObjectStore(backup.OrganizationID, backup.BucketModel)
+	if err != nil {
+		return nil, err
+	}
+
+	restore := &ClusterBackupRestoreModel{
+		OrganizationID:  backup.OrganizationID,
+		BackupID:        backup.ID,
+		TargetClusterID: targetCluster.GetID(),
+		Status:          pkgBackup.StatusRestoring,
+		CreatedBy:       userID,
+	}
+	if err := restore.Save(); err != nil {
+		return nil, errors.Wrap(err, "error saving restore")
+	}
+
+	go func() {
+		log := log.WithFields(logrus.Fields{"cluster": targetCluster.GetName(), "backup": backup.Name})
+		log.Info("Restoring backup")
+
+		err := func() error {
+			archive, err := objectStore.GetObject(backup.BucketName, backup.ObjectKey)
+			if err != nil {
+				return errors.Wrap(err, "error downloading backup archive")
+			}
+
+			metadata, items, err := readArchive(archive)
+			if err != nil {
+				return err
+			}
+
+			return restoreResources(targetCluster, metadata.Namespaces, items, restore)
+		}()
+
+		if err != nil {
+			log.Errorf("Error restoring backup: %s", err.Error())
+			restore.Status = pkgBackup.StatusFailed
+			restore.StatusMessage = err.Error()
+		} else {
+			log.Infof("Backup restored, created: %d, skipped: %d", restore.Created, restore.Skipped)
+			restore.Status = pkgBackup.StatusCompleted
+		}
+
+		if err := restore.Save(); err != nil {
+			log.Errorf("Error saving restore: %s", err.Error())
+		}
+	}()
+
+	return restore, nil
+}
+
+// restoreResources creates the namespaces and the resources in the target cluster and records the results
+func restoreResources(targetCluster cluster.CommonCluster, namespaces []string, items []resourceItem, restore *ClusterBackupRestoreModel) error {
+	kubeConfig, err := targetCluster.GetK8sConfig()
+	if err != nil {
+		return errors.Wrap(err, "error getting cluster config")
+	}
+
+	for _, namespace := range namespaces {
+		if err := helm.CreateNamespaceIfNotExist(kubeConfig, namespace); err != nil {
+			return errors.Wrapf(err, "error creating namespace %s", namespace)
+		}
+	}
+
+	client, err := helm.GetK8sConnection(kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	restConfig, err := helm.GetK8sClientConfig(kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating kubernetes client config")
+	}
+	clientPool := dynamic.NewDynamicClientPool(restConfig)
+
+	apiResources := map[string]map[string]metav1.APIResource{}
+	var failed []string
+
+	for _, item := range items {
+		groupVersion := schema.GroupVersion{Group: item.Group, Version: item.Version}
+
+		apiResource, err := findAPIResource(client, apiResources, groupVersion, item.Resource)
+		if err == nil {
+			var resourceClient dynamic.Interface
+			resourceClient, err = clientPool.ClientForGroupVersionResource(groupVersion.WithResource(item.Resource))
+			if err == nil {
+				_, err = resourceClient.Resource(apiResource, item.Namespace).Create(&unstructured.Unstructured{Object: item.Object})
+			}
+		}
+
+		switch {
+		case err == nil:
+			restore.Created++
+		case k8sErrors.IsAlreadyExists(err):
+			restore.Skipped++
+		default:
+			log.Warnf("Error restoring %s: %s", item.String(), err.Error())
+			failed = append(failed, item.String()+": "+err.Error())
+		}
+	}
+
+	restore.Failed = strings.Join(failed, "\n")
+
+	return nil
+}
+
+// findAPIResource looks up the resource in the target cluster, the discovered group versions are cached in apiResources
+func findAPIResource(client *kubernetes.Clientset, apiResources map[string]map[string]metav1.APIResource,
+	groupVersion schema.GroupVersion, resource string) (*metav1.APIResource, error) {
+
+	resources, ok := apiResources[groupVersion.String()]
+	if !ok {
+		resources = map[string]metav1.APIResource{}
+		resourceList, err := client.Discovery().ServerResourcesForGroupVersion(groupVersion.String())
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "error discovering %s resources", groupVersion.String())
+		}
+		if resourceList != nil {
+			for _, apiResource := range resourceList.APIResources {
+				resources[apiResource.Name] = apiResource
+			}
+		}
+		apiResources[groupVersion.String()] = resources
+	}
+
+	apiResource, ok := resources[resource]
+	if !ok {
+		return nil, errors.Errorf("%s is not served by the cluster in %s", resource, groupVersion.String())
+	}
+	return &apiResource, nil
+}
@@ -0,0 +1,171 @@
+package backup
+
+import (
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/model"
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// CreateSchedule validates the request and saves a periodic backup of the cluster. The first backup is taken
+// at the next run of the scheduler.
+func CreateSchedule(commonCluster cluster.CommonCluster, request *pkgBackup.CreateScheduleRequest, userID uint) (*ClusterBackupScheduleModel, error) {
+	interval, err := time.ParseDuration(request.Interval)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid interval")
+	}
+
+	minInterval, err := time.ParseDuration(viper.GetString(config.BackupMinScheduleInterval))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid minimum schedule interval configuration")
+	}
+	if interval < minInterval {
+		return nil, errors.Errorf("interval must be at least %s", minInterval)
+	}
+
+	if request.Retention < 0 {
+		return nil, errors.New("retention must not be negative")
+	}
+
+	if len(request.Namespaces) == 0 {
+		return nil, errors.New("at least one namespace must be specified")
+	}
+
+	if _, err := labels.Parse(request.LabelSelector); err != nil {
+		return nil, errors.Wrap(err, "invalid label selector")
+	}
+
+	bucket := newBucketModel(request.Bucket)
+	if _, err := newObjectStore(commonCluster.GetOrganizationId(), bucket); err != nil {
+		return nil, err
+	}
+
+	schedule := &ClusterBackupScheduleModel{
+		OrganizationID: commonCluster.GetOrganizationId(),
+		ClusterID:      commonCluster.GetID(),
+		Name:           request.Name,
+		Interval:       interval.String(),
+		Retention:      request.Retention,
+		Namespaces:     strings.Join(request.Namespaces, ","),
+		LabelSelector:  request.LabelSelector,
+		BucketModel:    bucket,
+		NextRunAt:      time.Now(),
+		CreatedBy:      userID,
+	}
+	if err := schedule.Save(); err != nil {
+		return nil, errors.Wrap(err, "error saving backup schedule")
+	}
+
+	return schedule, nil
+}
+
+// RunDueSchedules starts the backups of the due schedules and deletes the backups exceeding their retention.
+// Schedules of deleted clusters are removed. Each due schedule is claimed before its backup is started,
+// so only one of the Pipeline instances runs it.
+func RunDueSchedules() {
+	now := time.Now().Truncate(time.Second)
+
+	schedules, err := QueryDueSchedules(now)
+	if err != nil {
+		log.Errorf("Error listing due backup schedules: %s", err.Error())
+		return
+	}
+
+	for i := range schedules {
+		schedule := &schedules[i]
+
+		clusters, err := model.QueryCluster(map[string]interface{}{"id": schedule.ClusterID})
+		if err != nil {
+			log.Errorf("Error getting cluster %d of backup schedule %d: %s", schedule.ClusterID, schedule.ID, err.Error())
+			continue
+		}
+		if len(clusters) == 0 {
+			log.Infof("Deleting backup schedule %d of deleted cluster %d", schedule.ID, schedule.ClusterID)
+			if err := schedule.Delete(); err != nil {
+				log.Errorf("Error deleting backup schedule %d: %s", schedule.ID, err.Error())
+			}
+			continue
+		}
+
+		interval, err := time.ParseDuration(schedule.Interval)
+		if err != nil {
+			log.Errorf("Invalid interval of backup schedule %d: %s", schedule.ID, err.Error())
+			continue
+		}
+
+		claimed, err := claimSchedule(schedule, now, now.Add(interval))
+		if err != nil {
+			log.Errorf("Error claiming backup schedule %d: %s", schedule.ID, err.Error())
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		commonCluster, err := cluster.GetCommonClusterFromModel(&clusters[0])
+		if err != nil {
+			log.Errorf("Error getting cluster %d of backup schedule %d: %s", schedule.ClusterID, schedule.ID, err.Error())
+			continue
+		}
+
+		_, err = CreateBackup(commonCluster, &pkgBackup.CreateBackupRequest{
+			Namespaces:    splitNamespaces(schedule.Namespaces),
+			LabelSelector: schedule.LabelSelector,
+			Bucket:        schedule.BucketModel.toBucket(),
+		}, schedule.CreatedBy, schedule.ID)
+		if err != nil {
+			log.Errorf("Error creating backup of schedule %d: %s", schedule.ID, err.Error())
+		}
+
+		if schedule.Retention > 0 {
+			pruneScheduledBackups(schedule)
+		}
+	}
+}
+
+// pruneScheduledBackups deletes the oldest finished backups of the schedule exceeding its retention
+func pruneScheduledBackups(schedule *ClusterBackupScheduleModel) {
+	backups, err := QueryScheduledBackups(schedule.ID)
+	if err != nil {
+		log.Errorf("Error listing backups of schedule %d: %s", schedule.ID, err.Error())
+		return
+	}
+
+	kept := 0
+	for i := range backups {
+		backup := &backups[i]
+		if backup.Status == pkgBackup.StatusCreating {
+			continue
+		}
+
+		kept++
+		if kept <= schedule.Retention {
+			continue
+		}
+
+		log.Infof("Deleting backup %s of schedule %d", backup.Name, schedule.ID)
+		if err := DeleteBackup(backup); err != nil {
+			log.Errorf("Error deleting backup %s: %s", backup.Name, err.Error())
+		}
+	}
+}
+
+// StartScheduler periodically runs the due backup schedules
+func StartScheduler(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			log.Debug("Backup scheduler running")
+			RunDueSchedules()
+		}
+	}()
+
+	return ticker
+}
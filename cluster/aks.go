@@ -462,6 +462,8 @@ func (c *AKSCluster) CheckEqualityToUpdate(r *pkgCluster.UpdateClusterRequest) e
 				MinCount:    preP.NodeMinCount,
 				MaxCount:    preP.NodeMaxCount,
 				Count:       preP.Count,
+
+				NodePoolSettings: getNodePoolSettings(c.modelCluster.NodePoolSettings, preP.Name),
 			}
 		}
 	}
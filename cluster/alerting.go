@@ -0,0 +1,401 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/auth"
+	pipConfig "github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/helm"
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgNotify "github.com/banzaicloud/pipeline/pkg/notify"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+	"k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// alertRulesMountPath is the directory the alert rules ConfigMap is mounted to in the Prometheus server
+const alertRulesMountPath = "/etc/config/pipeline"
+
+// prometheusRuleFile describes the rule file format of Prometheus
+type prometheusRuleFile struct {
+	Groups []prometheusRuleGroup `yaml:"groups"`
+}
+
+type prometheusRuleGroup struct {
+	Name     string           `yaml:"name"`
+	Interval string           `yaml:"interval,omitempty"`
+	Rules    []prometheusRule `yaml:"rules"`
+}
+
+type prometheusRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// NewAlertRuleGroupResponse converts the stored alert rule group to its API representation
+func NewAlertRuleGroupResponse(group *model.AlertRuleGroupModel) (*pkgCluster.AlertRuleGroupResponse, error) {
+	response := &pkgCluster.AlertRuleGroupResponse{
+		AlertRuleGroup: pkgCluster.AlertRuleGroup{
+			Name:     group.Name,
+			Interval: group.Interval,
+		},
+		Scope:     pkgCluster.AlertRuleScopeOrganization,
+		ClusterID: group.ClusterID,
+	}
+	if group.ClusterID != 0 {
+		response.Scope = pkgCluster.AlertRuleScopeCluster
+	}
+
+	if err := json.Unmarshal([]byte(group.Rules), &response.Rules); err != nil {
+		return nil, errors.Wrapf(err, "error parsing rules of group %s", group.Name)
+	}
+
+	return response, nil
+}
+
+// SaveAlertRuleGroup stores the validated alert rule group, then syncs the rules into the affected clusters,
+// the clusters failing to sync are reconciled by the alert rule reconciler
+func SaveAlertRuleGroup(group *model.AlertRuleGroupModel, request *pkgCluster.AlertRuleGroup) error {
+	rules, err := json.Marshal(request.Rules)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling rules")
+	}
+
+	group.Name = request.Name
+	group.Interval = request.Interval
+	group.Rules = string(rules)
+
+	if err := group.Save(); err != nil {
+		return errors.Wrap(err, "error saving alert rule group")
+	}
+
+	go syncAlertRulesOfScope(group.OrganizationID, group.ClusterID)
+
+	return nil
+}
+
+// DeleteAlertRuleGroup deletes the alert rule group, then removes its rules from the affected clusters
+func DeleteAlertRuleGroup(group *model.AlertRuleGroupModel) error {
+	if err := group.Delete(); err != nil {
+		return errors.Wrap(err, "error deleting alert rule group")
+	}
+
+	go syncAlertRulesOfScope(group.OrganizationID, group.ClusterID)
+
+	return nil
+}
+
+// syncAlertRulesOfScope syncs the alert rules into the given cluster or
+// into every running cluster of the organization when the cluster id is zero
+func syncAlertRulesOfScope(organizationID, clusterID uint) {
+	filter := map[string]interface{}{"organization_id": organizationID, "status": pkgCluster.Running}
+	if clusterID != 0 {
+		filter["id"] = clusterID
+	}
+
+	syncAlertRulesOfClusters(filter)
+}
+
+// ReconcileAlertRules syncs the alert rules into all running clusters, so the clusters which were
+// unreachable when their rules changed, or whose rules were modified in the cluster, get the stored ones
+func ReconcileAlertRules() {
+	syncAlertRulesOfClusters(map[string]interface{}{"status": pkgCluster.Running})
+}
+
+// StartAlertRuleReconciler periodically syncs the alert rules into the running clusters
+func StartAlertRuleReconciler(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			log.Debug("Alert rule reconciler running")
+			ReconcileAlertRules()
+		}
+	}()
+
+	return ticker
+}
+
+func syncAlertRulesOfClusters(filter map[string]interface{}) {
+	clusters, err := model.QueryCluster(filter)
+	if err != nil {
+		log.Errorf("Error listing clusters to sync alert rules: %s", err.Error())
+		return
+	}
+
+	for i := range clusters {
+		commonCluster, err := GetCommonClusterFromModel(&clusters[i])
+		if err != nil {
+			log.Errorf("Error getting cluster %d: %s", clusters[i].ID, err.Error())
+			continue
+		}
+
+		if err := SyncAlertRules(commonCluster); err != nil {
+			log.Warnf("Error syncing alert rules of cluster %d: %s", clusters[i].ID, err.Error())
+		}
+	}
+}
+
+// SyncAlertRules writes the organization and cluster level alert rules into the ConfigMap managed by Pipeline,
+// which is mounted into the Prometheus server installed by the monitoring posthook and reloaded on change.
+// The ConfigMap is created if it doesn't exist, it has to exist before the Prometheus server mounting it is installed.
+func SyncAlertRules(commonCluster CommonCluster) error {
+	groups, err := model.QueryAlertRuleGroupsOfCluster(commonCluster.GetOrganizationId(), commonCluster.GetID())
+	if err != nil {
+		return errors.Wrap(err, "error listing alert rule groups")
+	}
+
+	rules, err := renderAlertRules(groups)
+	if err != nil {
+		return err
+	}
+
+	kubeConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		return errors.Wrap(err, "error getting cluster config")
+	}
+
+	client, err := helm.GetK8sConnection(kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	namespace := viper.GetString(pipConfig.PipelineMonitorNamespace)
+	configMapName := viper.GetString(pipConfig.MonitorAlertingConfigMap)
+	rulesKey := viper.GetString(pipConfig.MonitorAlertingRulesKey)
+	configMaps := client.CoreV1().ConfigMaps(namespace)
+
+	configMap, err := configMaps.Get(configMapName, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		if err := helm.CreateNamespaceIfNotExist(kubeConfig, namespace); err != nil {
+			return err
+		}
+
+		configMap = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: configMapName},
+			Data:       map[string]string{rulesKey: string(rules)},
+		}
+		if _, err := configMaps.Create(configMap); err != nil {
+			return errors.Wrap(err, "error creating alert rules configmap")
+		}
+
+		log.Infof("Alert rules ConfigMap of cluster %d created (%d groups)", commonCluster.GetID(), len(groups))
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "error getting alert rules configmap")
+	}
+
+	if configMap.Data[rulesKey] == string(rules) {
+		return nil
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[rulesKey] = string(rules)
+
+	if _, err := configMaps.Update(configMap); err != nil {
+		return errors.Wrap(err, "error updating alert rules configmap")
+	}
+
+	log.Infof("Alert rules of cluster %d synced (%d groups)", commonCluster.GetID(), len(groups))
+
+	return nil
+}
+
+// alertRulesHelmValues returns the values of the monitoring chart which mount the alert rules ConfigMap
+// into the Prometheus server, and add its rule file to the ones loaded and watched for changes
+func alertRulesHelmValues() map[string]interface{} {
+	mount := map[string]interface{}{
+		"name":      "pipeline-alert-rules",
+		"mountPath": alertRulesMountPath,
+		"configMap": viper.GetString(pipConfig.MonitorAlertingConfigMap),
+		"readOnly":  true,
+	}
+
+	return map[string]interface{}{
+		"server": map[string]interface{}{
+			"extraConfigmapMounts": []interface{}{mount},
+		},
+		"configmapReload": map[string]interface{}{
+			"extraConfigmapMounts": []interface{}{mount},
+		},
+		"serverFiles": map[string]interface{}{
+			"prometheus.yml": map[string]interface{}{
+				"rule_files": []string{
+					"/etc/config/rules",
+					"/etc/config/alerts",
+					alertRulesMountPath + "/" + viper.GetString(pipConfig.MonitorAlertingRulesKey),
+				},
+			},
+		},
+	}
+}
+
+// alertReceiverName returns the name of the virtual user the Alertmanager of the cluster sends the alerts as
+func alertReceiverName(commonCluster CommonCluster) string {
+	return fmt.Sprintf("cluster-%d-alertmanager", commonCluster.GetID())
+}
+
+// alertReceiverHelmValues returns the values of the monitoring chart which configure the Alertmanager to send
+// the alerts of the rules marked with notify to Pipeline, with a token allowed only to post the alerts.
+// It returns nil if the Pipeline URL the clusters can reach isn't configured.
+func alertReceiverHelmValues(commonCluster CommonCluster) (map[string]interface{}, error) {
+	pipelineURL := strings.TrimSuffix(viper.GetString(pipConfig.MonitorAlertingPipelineURL), "/")
+	if pipelineURL == "" {
+		log.Warnf("Pipeline URL for the alerts isn't configured, the alerts of cluster %d won't be received", commonCluster.GetID())
+		return nil, nil
+	}
+
+	organization, err := auth.GetOrganizationById(commonCluster.GetOrganizationId())
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting organization")
+	}
+
+	token, err := auth.CreateVirtualUserToken(organization, alertReceiverName(commonCluster), []string{"clusteralerts:write"})
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating alert receiver token")
+	}
+
+	url := fmt.Sprintf("%s%s/api/v1/orgs/%d/clusters/%d/alerts",
+		pipelineURL, viper.GetString("pipeline.basepath"), commonCluster.GetOrganizationId(), commonCluster.GetID())
+
+	return map[string]interface{}{
+		"alertmanagerFiles": map[string]interface{}{
+			"alertmanager.yml": map[string]interface{}{
+				"global": map[string]interface{}{},
+				"route": map[string]interface{}{
+					"receiver": "default-receiver",
+					"group_by": []string{"alertname"},
+					"routes": []interface{}{
+						map[string]interface{}{
+							"receiver": "pipeline",
+							"match":    map[string]string{pkgCluster.AlertNotifyLabel: "true"},
+						},
+					},
+				},
+				"receivers": []interface{}{
+					map[string]interface{}{"name": "default-receiver"},
+					map[string]interface{}{
+						"name": "pipeline",
+						"webhook_configs": []interface{}{
+							map[string]interface{}{
+								"url":           url,
+								"send_resolved": true,
+								"http_config":   map[string]interface{}{"bearer_token": token},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// RevokeAlertReceiverToken revokes the token of the cluster's Alertmanager, it's called when the cluster is deleted
+func RevokeAlertReceiverToken(commonCluster CommonCluster) error {
+	organization, err := auth.GetOrganizationById(commonCluster.GetOrganizationId())
+	if err != nil {
+		return errors.Wrap(err, "error getting organization")
+	}
+
+	return auth.RevokeVirtualUserTokens(organization, alertReceiverName(commonCluster))
+}
+
+// renderAlertRules converts the alert rule groups to a Prometheus rule file,
+// cluster level groups override the organization level groups with the same name
+func renderAlertRules(groups []*model.AlertRuleGroupModel) ([]byte, error) {
+	byName := make(map[string]*model.AlertRuleGroupModel, len(groups))
+	for _, group := range groups {
+		if current, ok := byName[group.Name]; ok && current.ClusterID != 0 {
+			continue
+		}
+		byName[group.Name] = group
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ruleFile := prometheusRuleFile{Groups: make([]prometheusRuleGroup, 0, len(names))}
+	for _, name := range names {
+		group := byName[name]
+
+		var rules []pkgCluster.AlertRule
+		if err := json.Unmarshal([]byte(group.Rules), &rules); err != nil {
+			return nil, errors.Wrapf(err, "error parsing rules of group %s", group.Name)
+		}
+
+		ruleGroup := prometheusRuleGroup{
+			Name:     group.Name,
+			Interval: group.Interval,
+			Rules:    make([]prometheusRule, 0, len(rules)),
+		}
+		for _, rule := range rules {
+			labels := rule.Labels
+			if rule.Notify {
+				labels = make(map[string]string, len(rule.Labels)+1)
+				for k, v := range rule.Labels {
+					labels[k] = v
+				}
+				labels[pkgCluster.AlertNotifyLabel] = "true"
+			}
+
+			ruleGroup.Rules = append(ruleGroup.Rules, prometheusRule{
+				Alert:       rule.Alert,
+				Expr:        rule.Expr,
+				For:         rule.For,
+				Labels:      labels,
+				Annotations: rule.Annotations,
+			})
+		}
+
+		ruleFile.Groups = append(ruleFile.Groups, ruleGroup)
+	}
+
+	return yaml.Marshal(ruleFile)
+}
+
+// NotifyAlerts publishes the alerts of the rules marked with notify to the notification channels
+// of the organization subscribed to cluster alerts
+func NotifyAlerts(commonCluster CommonCluster, message *pkgCluster.AlertmanagerMessage) *pkgCluster.AlertNotifyResponse {
+	response := &pkgCluster.AlertNotifyResponse{Received: len(message.Alerts)}
+
+	for _, alert := range message.Alerts {
+		if alert.Labels[pkgCluster.AlertNotifyLabel] != "true" {
+			continue
+		}
+
+		PublishClusterEvent(commonCluster, pkgNotify.EventClusterAlert, formatAlert(&alert))
+		response.Notified++
+	}
+
+	return response
+}
+
+// formatAlert returns the notification text of the alert
+func formatAlert(alert *pkgCluster.AlertmanagerAlert) string {
+	text := fmt.Sprintf("[%s] %s", strings.ToUpper(alert.Status), alert.Labels["alertname"])
+
+	if summary := alert.Annotations["summary"]; len(summary) != 0 {
+		text += ": " + summary
+	}
+	if description := alert.Annotations["description"]; len(description) != 0 {
+		text += "\n" + description
+	}
+
+	return text
+}
@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+func TestAlertRuleGroupValidate(t *testing.T) {
+	valid := pkgCluster.AlertRule{
+		Alert:       "HighErrorRate",
+		Expr:        `sum(rate(http_requests_total{code=~"5.."}[5m])) / sum(rate(http_requests_total[5m])) > 0.05`,
+		For:         "10m",
+		Labels:      map[string]string{"severity": "critical"},
+		Annotations: map[string]string{"summary": "Error rate is {{ $value }} on {{ $labels.instance }}"},
+	}
+
+	cases := map[string]func(rule *pkgCluster.AlertRule){
+		"":                    func(rule *pkgCluster.AlertRule) {},
+		"invalid alert name":  func(rule *pkgCluster.AlertRule) { rule.Alert = "high-error-rate" },
+		"empty expression":    func(rule *pkgCluster.AlertRule) { rule.Expr = " " },
+		"unclosed":            func(rule *pkgCluster.AlertRule) { rule.Expr = "sum(rate(x[5m])" },
+		"unterminated string": func(rule *pkgCluster.AlertRule) { rule.Expr = `up{job="api}` },
+		"invalid expression":  func(rule *pkgCluster.AlertRule) { rule.Expr = "rate(http_requests_total)" },
+		"invalid for":         func(rule *pkgCluster.AlertRule) { rule.For = "10 minutes" },
+		"invalid label name":  func(rule *pkgCluster.AlertRule) { rule.Labels = map[string]string{"team-name": "x"} },
+		"reserved label":      func(rule *pkgCluster.AlertRule) { rule.Labels = map[string]string{pkgCluster.AlertNotifyLabel: "true"} },
+		"invalid annotation":  func(rule *pkgCluster.AlertRule) { rule.Annotations = map[string]string{"summary": "{{ $value "} },
+	}
+
+	for name, modify := range cases {
+		rule := valid
+		modify(&rule)
+		group := pkgCluster.AlertRuleGroup{Name: "api", Rules: []pkgCluster.AlertRule{rule}}
+
+		err := group.Validate()
+		if name == "" && err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+		if name != "" && err == nil {
+			t.Errorf("expected error for %s", name)
+		}
+	}
+}
+
+func TestRenderAlertRules(t *testing.T) {
+	groups := []*model.AlertRuleGroupModel{
+		{OrganizationID: 1, Name: "nodes", Rules: `[{"alert":"NodeDown","expr":"up == 0","for":"5m","notify":true}]`},
+		{OrganizationID: 1, ClusterID: 2, Name: "nodes", Rules: `[{"alert":"NodeDown","expr":"up == 0","for":"1m"}]`},
+		{OrganizationID: 1, Name: "api", Interval: "30s", Rules: `[{"alert":"ApiDown","expr":"up{job=\"api\"} == 0","labels":{"severity":"critical"},"notify":true}]`},
+	}
+
+	rules, err := renderAlertRules(groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `groups:
+- name: api
+  interval: 30s
+  rules:
+  - alert: ApiDown
+    expr: up{job="api"} == 0
+    labels:
+      pipeline_notify: "true"
+      severity: critical
+- name: nodes
+  rules:
+  - alert: NodeDown
+    expr: up == 0
+    for: 1m
+`
+	if strings.TrimSpace(string(rules)) != strings.TrimSpace(expected) {
+		t.Errorf("unexpected rule file:\n%s", rules)
+	}
+}
+
+func TestRenderNoAlertRules(t *testing.T) {
+	rules, err := renderAlertRules(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the mounted rule file is kept loadable by Prometheus when the groups are deleted
+	if strings.TrimSpace(string(rules)) != "groups: []" {
+		t.Errorf("unexpected rule file:\n%s", rules)
+	}
+}
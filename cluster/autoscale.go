@@ -1,6 +1,8 @@
 package cluster
 
 import (
+	"errors"
+
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/helm"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
@@ -201,9 +203,9 @@ func DeployClusterAutoscaler(cluster CommonCluster) error {
 	}
 
 	if isAutoscalerDeployedAlready(releaseName, kubeConfig) {
-		// no need to upgrade in case of EKS since we're using nodepool autodiscovery
+		// node groups are auto discovered in case of EKS, only the autoscaler settings need to be upgraded
 		if _, isEks := cluster.(*EKSCluster); isEks {
-			return nil
+			return deployAutoscalerChart(cluster, nodeGroups, kubeConfig, upgrade)
 		}
 		if len(nodeGroups) == 0 {
 			// delete
@@ -258,6 +260,13 @@ func deployAutoscalerChart(cluster CommonCluster, nodeGroups []nodeGroup, kubeCo
 	default:
 		return nil
 	}
+	if values == nil {
+		return errors.New("unable to create autoscaler chart values")
+	}
+	// cluster level settings override the defaults
+	for name, value := range cluster.GetModel().AutoscalerSettings.ExtraArgs() {
+		values.ExtraArgs[name] = value
+	}
 	yamlValues, err := yaml.Marshal(*values)
 	if err != nil {
 		log.Errorf("Error during values marshal: %s", err.Error())
@@ -0,0 +1,156 @@
+package cluster
+
+import (
+	"bufio"
+	"sort"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/helm"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+const (
+	autoscalerStatusConfigMap = "cluster-autoscaler-status"
+	autoscalerEventSource     = "cluster-autoscaler"
+	autoscalerEventLimit      = 50
+)
+
+// GetAutoscalerStatus returns the settings and the status reported by the cluster-autoscaler
+// together with its recent events
+func GetAutoscalerStatus(commonCluster CommonCluster) (*pkgCluster.AutoscalerStatusResponse, error) {
+	kubeConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting cluster config")
+	}
+
+	client, err := helm.GetK8sConnection(kubeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	response := &pkgCluster.AutoscalerStatusResponse{
+		Deployed: isAutoscalerDeployedAlready(releaseName, kubeConfig),
+		Settings: commonCluster.GetModel().AutoscalerSettings,
+	}
+
+	configMap, err := client.CoreV1().ConfigMaps(helm.SystemNamespace).Get(autoscalerStatusConfigMap, metav1.GetOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "error getting autoscaler status")
+	} else if err == nil {
+		parseAutoscalerStatus(configMap.Data["status"], response)
+	}
+
+	events, err := client.CoreV1().Events(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("source", autoscalerEventSource).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing autoscaler events")
+	}
+
+	response.Events = convertAutoscalerEvents(events.Items, autoscalerEventLimit)
+
+	return response, nil
+}
+
+// convertAutoscalerEvents returns the most recent events first
+func convertAutoscalerEvents(events []v1.Event, limit int) []*pkgCluster.AutoscalerEvent {
+	sort.Slice(events, func(i, j int) bool {
+		return events[j].LastTimestamp.Before(&events[i].LastTimestamp)
+	})
+
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	result := make([]*pkgCluster.AutoscalerEvent, 0, len(events))
+	for _, e := range events {
+		object := strings.ToLower(e.InvolvedObject.Kind) + "/" + e.InvolvedObject.Name
+		if len(e.InvolvedObject.Namespace) != 0 {
+			object = e.InvolvedObject.Namespace + "/" + object
+		}
+
+		result = append(result, &pkgCluster.AutoscalerEvent{
+			Type:      e.Type,
+			Reason:    e.Reason,
+			Message:   e.Message,
+			Object:    object,
+			Count:     e.Count,
+			Timestamp: e.LastTimestamp.Time,
+		})
+	}
+
+	return result
+}
+
+// parseAutoscalerStatus parses the human readable status written by the cluster-autoscaler into its status ConfigMap
+func parseAutoscalerStatus(status string, response *pkgCluster.AutoscalerStatusResponse) {
+	var conditions *pkgCluster.AutoscalerConditions
+	var condition *pkgCluster.AutoscalerCondition
+
+	scanner := bufio.NewScanner(strings.NewReader(status))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "Cluster-autoscaler status at "):
+			response.LastUpdated = strings.TrimSuffix(strings.TrimPrefix(line, "Cluster-autoscaler status at "), ":")
+			continue
+		case line == "Cluster-wide:":
+			response.ClusterWide = &pkgCluster.AutoscalerConditions{}
+			conditions, condition = response.ClusterWide, nil
+			continue
+		case line == "NodeGroups:":
+			conditions, condition = nil, nil
+			continue
+		}
+
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		key, value := line[:i], strings.TrimSpace(line[i+1:])
+
+		switch key {
+		case "Name":
+			nodeGroup := &pkgCluster.AutoscalerNodeGroupStatus{Name: value}
+			response.NodeGroups = append(response.NodeGroups, nodeGroup)
+			conditions, condition = &nodeGroup.AutoscalerConditions, nil
+		case "Health", "ScaleUp", "ScaleDown":
+			if conditions == nil {
+				continue
+			}
+			condition = parseAutoscalerCondition(value)
+			switch key {
+			case "Health":
+				conditions.Health = condition
+			case "ScaleUp":
+				conditions.ScaleUp = condition
+			case "ScaleDown":
+				conditions.ScaleDown = condition
+			}
+		case "LastProbeTime":
+			if condition != nil {
+				condition.LastProbeTime = value
+			}
+		case "LastTransitionTime":
+			if condition != nil {
+				condition.LastTransitionTime = value
+			}
+		}
+	}
+}
+
+// parseAutoscalerCondition parses a condition like "Healthy (ready=3 unready=0)"
+func parseAutoscalerCondition(value string) *pkgCluster.AutoscalerCondition {
+	condition := &pkgCluster.AutoscalerCondition{Status: value}
+	if i := strings.Index(value, " ("); i >= 0 {
+		condition.Status = value[:i]
+		condition.Details = strings.TrimSuffix(value[i+2:], ")")
+	}
+	return condition
+}
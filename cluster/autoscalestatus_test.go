@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"testing"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+const testAutoscalerStatus = `Cluster-autoscaler status at 2018-07-10 12:19:46.373451454 +0000 UTC:
+Cluster-wide:
+  Health:      Healthy (ready=3 unready=0 notStarted=0 longNotStarted=0 registered=3 longUnregistered=0)
+               LastProbeTime:      2018-07-10 12:19:46.236574436 +0000 UTC
+               LastTransitionTime: 2018-07-10 10:09:23.711853393 +0000 UTC
+  ScaleUp:     NoActivity (ready=3 registered=3)
+               LastProbeTime:      2018-07-10 12:19:46.236574436 +0000 UTC
+               LastTransitionTime: 2018-07-10 10:09:23.711853393 +0000 UTC
+  ScaleDown:   NoCandidates (candidates=0)
+               LastProbeTime:      2018-07-10 12:19:46.236574436 +0000 UTC
+               LastTransitionTime: 2018-07-10 10:09:23.711853393 +0000 UTC
+
+NodeGroups:
+  Name:        mycluster.node.pool1
+  Health:      Healthy (ready=2 unready=0 notStarted=0 longNotStarted=0 registered=2 longUnregistered=0 cloudProviderTarget=2 (minSize=1, maxSize=4))
+               LastProbeTime:      2018-07-10 12:19:46.236574436 +0000 UTC
+               LastTransitionTime: 2018-07-10 10:09:23.711853393 +0000 UTC
+  ScaleUp:     InProgress (ready=2 cloudProviderTarget=3)
+               LastProbeTime:      2018-07-10 12:19:46.236574436 +0000 UTC
+               LastTransitionTime: 2018-07-10 12:18:40.105221455 +0000 UTC
+  ScaleDown:   NoCandidates (candidates=0)
+               LastProbeTime:      2018-07-10 12:19:46.236574436 +0000 UTC
+               LastTransitionTime: 2018-07-10 10:09:23.711853393 +0000 UTC
+`
+
+func TestParseAutoscalerStatus(t *testing.T) {
+	response := &pkgCluster.AutoscalerStatusResponse{}
+	parseAutoscalerStatus(testAutoscalerStatus, response)
+
+	if response.LastUpdated != "2018-07-10 12:19:46.373451454 +0000 UTC" {
+		t.Errorf("unexpected last updated: %q", response.LastUpdated)
+	}
+
+	if response.ClusterWide == nil || response.ClusterWide.Health == nil || response.ClusterWide.ScaleDown == nil {
+		t.Fatalf("missing cluster wide conditions: %+v", response.ClusterWide)
+	}
+	if h := response.ClusterWide.Health; h.Status != "Healthy" || h.LastTransitionTime != "2018-07-10 10:09:23.711853393 +0000 UTC" {
+		t.Errorf("unexpected cluster health: %+v", h)
+	}
+
+	if len(response.NodeGroups) != 1 {
+		t.Fatalf("expected 1 node group, got %d", len(response.NodeGroups))
+	}
+	nodeGroup := response.NodeGroups[0]
+	if nodeGroup.Name != "mycluster.node.pool1" {
+		t.Errorf("unexpected node group name: %q", nodeGroup.Name)
+	}
+	if nodeGroup.ScaleUp == nil || nodeGroup.ScaleUp.Status != "InProgress" || nodeGroup.ScaleUp.Details != "ready=2 cloudProviderTarget=3" {
+		t.Errorf("unexpected node group scale up: %+v", nodeGroup.ScaleUp)
+	}
+	if nodeGroup.Health.Details != "ready=2 unready=0 notStarted=0 longNotStarted=0 registered=2 longUnregistered=0 cloudProviderTarget=2 (minSize=1, maxSize=4)" {
+		t.Errorf("unexpected node group health details: %q", nodeGroup.Health.Details)
+	}
+}
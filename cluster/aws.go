@@ -954,11 +954,11 @@ func (c *AWSCluster) getImageFromNodePool(nodePoolName string) string {
 
 //CheckEqualityToUpdate validates the update request
 func (c *AWSCluster) CheckEqualityToUpdate(r *pkgCluster.UpdateClusterRequest) error {
-	return CheckEqualityToUpdate(r, c.modelCluster.Amazon.NodePools)
+	return CheckEqualityToUpdate(r, c.modelCluster.Amazon.NodePools, c.modelCluster.NodePoolSettings)
 }
 
 //CheckEqualityToUpdate validates the update request
-func CheckEqualityToUpdate(r *pkgCluster.UpdateClusterRequest, nodePools []*model.AmazonNodePoolsModel) error {
+func CheckEqualityToUpdate(r *pkgCluster.UpdateClusterRequest, nodePools []*model.AmazonNodePoolsModel, settings map[string]*pkgCommon.NodePoolSettings) error {
 	// create update request struct with the stored data to check equality
 	preNodePools := make(map[string]*amazon.NodePool)
 	for _, preNp := range nodePools {
@@ -970,6 +970,8 @@ func CheckEqualityToUpdate(r *pkgCluster.UpdateClusterRequest, nodePools []*mode
 			MaxCount:     preNp.NodeMaxCount,
 			Count:        preNp.Count,
 			Image:        preNp.NodeImage,
+
+			NodePoolSettings: getNodePoolSettings(settings, preNp.Name),
 		}
 	}
 
@@ -318,6 +318,19 @@ func CreateCommonClusterFromRequest(createClusterRequest *pkgCluster.CreateClust
 		return nil, err
 	}
 
+	commonCluster, err := createCommonClusterFromRequest(createClusterRequest, orgId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	commonCluster.GetModel().Labels = createClusterRequest.Labels
+	commonCluster.GetModel().NodePoolSettings = createClusterRequest.GetNodePoolSettings()
+	commonCluster.GetModel().AutoscalerSettings = createClusterRequest.Autoscaler
+
+	return commonCluster, nil
+}
+
+func createCommonClusterFromRequest(createClusterRequest *pkgCluster.CreateClusterRequest, orgId, userId uint) (CommonCluster, error) {
 	cloudType := createClusterRequest.Cloud
 	switch cloudType {
 	case pkgCluster.Amazon:
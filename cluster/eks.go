@@ -707,7 +707,7 @@ func (e *EKSCluster) GetModel() *model.ClusterModel {
 
 // CheckEqualityToUpdate validates the update request
 func (e *EKSCluster) CheckEqualityToUpdate(r *pkgCluster.UpdateClusterRequest) error {
-	return CheckEqualityToUpdate(r, e.modelCluster.Eks.NodePools)
+	return CheckEqualityToUpdate(r, e.modelCluster.Eks.NodePools, e.modelCluster.NodePoolSettings)
 }
 
 // AddDefaultsToUpdate adds defaults to update request
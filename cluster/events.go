@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/banzaicloud/pipeline/notify"
+	pkgWebhook "github.com/banzaicloud/pipeline/pkg/webhook"
+	"github.com/banzaicloud/pipeline/webhook"
+)
+
+// PublishClusterEvent sends an event of the cluster to the subscribed notification channels of its organization
+func PublishClusterEvent(commonCluster CommonCluster, eventType, message string) {
+	notify.Publish(notify.Event{
+		Type:           eventType,
+		OrganizationID: commonCluster.GetOrganizationId(),
+		ClusterID:      commonCluster.GetID(),
+		ClusterName:    commonCluster.GetName(),
+		Message:        message,
+	})
+}
+
+// PublishClusterWebhookEvent sends a lifecycle event of the cluster to the subscribed webhooks of its organization
+func PublishClusterWebhookEvent(commonCluster CommonCluster, eventType string, userID uint) {
+	clusterModel := commonCluster.GetModel()
+	webhook.Publish(webhook.Event{
+		Type:           eventType,
+		OrganizationID: commonCluster.GetOrganizationId(),
+		Resource: pkgWebhook.Resource{
+			Type: pkgWebhook.ResourceCluster,
+			ID:   fmt.Sprint(commonCluster.GetID()),
+			Name: commonCluster.GetName(),
+		},
+		Actor: webhook.ActorFromUserID(userID),
+		Payload: pkgWebhook.ClusterPayload{
+			ID:       commonCluster.GetID(),
+			Name:     commonCluster.GetName(),
+			Cloud:    clusterModel.Cloud,
+			Location: clusterModel.Location,
+			Status:   clusterModel.Status,
+		},
+	})
+}
@@ -1739,6 +1739,9 @@ func (g *GKECluster) CheckEqualityToUpdate(r *pkgCluster.UpdateClusterRequest) e
 
 	// create update request struct with the stored data to check equality
 	nodePools, _ := createNodePoolsRequestDataFromNodePoolModel(g.modelCluster.Google.NodePools)
+	for name, np := range nodePools {
+		np.NodePoolSettings = getNodePoolSettings(g.modelCluster.NodePoolSettings, name)
+	}
 	preCl := &pkgClusterGoogle.UpdateClusterGoogle{
 		Master: &pkgClusterGoogle.Master{
 			Version: g.modelCluster.Google.MasterVersion,
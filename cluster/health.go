@@ -0,0 +1,263 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/banzaicloud/pipeline/helm"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	rls "k8s.io/helm/pkg/proto/hapi/services"
+)
+
+// Cluster health check names
+const (
+	healthCheckAPIServer      = "apiserver"
+	healthCheckNodes          = "nodes"
+	healthCheckPods           = "pods"
+	healthCheckSystemPods     = "systemPods"
+	healthCheckCrashLoopPods  = "crashLoopPods"
+	healthCheckPendingPods    = "unschedulablePods"
+	healthCheckHelmReleases   = "helmReleases"
+	crashLoopBackOffReason    = "CrashLoopBackOff"
+	podUnschedulableCondition = "Unschedulable"
+)
+
+// Limits of the health check, so an unreachable or large cluster doesn't hold the request
+const (
+	healthCheckTimeout     = 10 * time.Second
+	healthCheckPodPageSize = 500
+	healthCheckMaxPods     = 5000
+)
+
+// nodePressureConditions are the node conditions which indicate a problem when they are true
+var nodePressureConditions = []v1.NodeConditionType{
+	v1.NodeMemoryPressure,
+	v1.NodeDiskPressure,
+	v1.NodePIDPressure,
+	v1.NodeNetworkUnavailable,
+}
+
+// GetClusterHealth checks the control plane, nodes, pods and Helm releases of the cluster
+// and aggregates the results into a green/yellow/red verdict
+func GetClusterHealth(commonCluster CommonCluster) (*pkgCluster.HealthResponse, error) {
+	kubeConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting cluster config")
+	}
+
+	restConfig, err := helm.GetK8sClientConfig(kubeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes client config")
+	}
+	restConfig.Timeout = healthCheckTimeout
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	report := &pkgCluster.HealthResponse{Status: pkgCluster.HealthGreen}
+
+	// the other checks can't be done without a reachable control plane
+	apiServerCheck := &pkgCluster.HealthCheck{Name: healthCheckAPIServer, Status: pkgCluster.HealthGreen}
+	version, err := client.Discovery().ServerVersion()
+	if err != nil {
+		apiServerCheck.Status = pkgCluster.HealthRed
+		apiServerCheck.Message = fmt.Sprintf("control plane endpoint is not reachable: %s", err.Error())
+		report.AddCheck(apiServerCheck)
+		return report, nil
+	}
+	apiServerCheck.Message = fmt.Sprintf("Kubernetes %s", version.GitVersion)
+	report.AddCheck(apiServerCheck)
+
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		report.AddCheck(failedHealthCheck(healthCheckNodes, err))
+	} else {
+		report.AddCheck(checkNodes(nodes.Items))
+	}
+
+	pods, complete, err := listHealthCheckPods(client)
+	if err != nil {
+		report.AddCheck(failedHealthCheck(healthCheckPods, err))
+	} else {
+		if !complete {
+			report.AddCheck(&pkgCluster.HealthCheck{
+				Name:    healthCheckPods,
+				Status:  pkgCluster.HealthYellow,
+				Message: fmt.Sprintf("only the first %d pods are checked", len(pods)),
+			})
+		}
+		report.AddCheck(checkSystemPods(pods))
+		report.AddCheck(checkCrashLoopPods(pods))
+		report.AddCheck(checkUnschedulablePods(pods))
+	}
+
+	releases, err := listFailedReleases(kubeConfig)
+	report.AddCheck(checkHelmReleases(releases, err))
+
+	return report, nil
+}
+
+// listHealthCheckPods lists the pods of the cluster page by page up to healthCheckMaxPods,
+// it tells whether all the pods have been listed
+func listHealthCheckPods(client *kubernetes.Clientset) ([]v1.Pod, bool, error) {
+	var pods []v1.Pod
+	options := metav1.ListOptions{Limit: healthCheckPodPageSize}
+
+	for len(pods) < healthCheckMaxPods {
+		page, err := client.CoreV1().Pods(metav1.NamespaceAll).List(options)
+		if err != nil {
+			return nil, false, err
+		}
+		pods = append(pods, page.Items...)
+
+		if page.Continue == "" {
+			return pods, true, nil
+		}
+		options.Continue = page.Continue
+	}
+
+	return pods, false, nil
+}
+
+// listFailedReleases lists the failed Helm releases, giving up after healthCheckTimeout,
+// since the connection to Tiller through the port forward has no timeout of its own
+func listFailedReleases(kubeConfig []byte) (*rls.ListReleasesResponse, error) {
+	type result struct {
+		releases *rls.ListReleasesResponse
+		err      error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		releases, err := helm.ListFailedDeployments(kubeConfig)
+		done <- result{releases: releases, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.releases, r.err
+	case <-time.After(healthCheckTimeout):
+		return nil, errors.Errorf("timeout listing helm releases after %s", healthCheckTimeout)
+	}
+}
+
+func failedHealthCheck(name string, err error) *pkgCluster.HealthCheck {
+	return &pkgCluster.HealthCheck{
+		Name:    name,
+		Status:  pkgCluster.HealthRed,
+		Message: err.Error(),
+	}
+}
+
+// newHealthCheck creates a check which is yellow if there are problematic items
+func newHealthCheck(name string, items []string, message string) *pkgCluster.HealthCheck {
+	check := &pkgCluster.HealthCheck{Name: name, Status: pkgCluster.HealthGreen, Items: items}
+	if len(items) != 0 {
+		check.Status = pkgCluster.HealthYellow
+		check.Message = fmt.Sprintf("%d %s", len(items), message)
+	}
+	return check
+}
+
+// checkNodes checks the Ready and pressure conditions of the nodes. It's red if none of the nodes are ready.
+func checkNodes(nodes []v1.Node) *pkgCluster.HealthCheck {
+	var items []string
+	readyNodes := 0
+
+	for _, node := range nodes {
+		ready := false
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == v1.NodeReady && condition.Status == v1.ConditionTrue {
+				ready = true
+			}
+			for _, pressure := range nodePressureConditions {
+				if condition.Type == pressure && condition.Status == v1.ConditionTrue {
+					items = append(items, fmt.Sprintf("%s: %s", node.Name, condition.Type))
+				}
+			}
+		}
+
+		if ready {
+			readyNodes++
+		} else {
+			items = append(items, fmt.Sprintf("%s: not ready", node.Name))
+		}
+	}
+
+	check := newHealthCheck(healthCheckNodes, items, "node problem(s) found")
+	if readyNodes == 0 {
+		check.Status = pkgCluster.HealthRed
+		check.Message = "there are no ready nodes"
+	}
+	return check
+}
+
+// checkSystemPods checks whether the kube-system pods are running
+func checkSystemPods(pods []v1.Pod) *pkgCluster.HealthCheck {
+	var items []string
+	for _, pod := range pods {
+		if pod.Namespace != metav1.NamespaceSystem {
+			continue
+		}
+		if pod.Status.Phase != v1.PodRunning && pod.Status.Phase != v1.PodSucceeded {
+			items = append(items, fmt.Sprintf("%s: %s", pod.Name, pod.Status.Phase))
+		}
+	}
+	return newHealthCheck(healthCheckSystemPods, items, "kube-system pod(s) not running")
+}
+
+// checkCrashLoopPods looks for containers in CrashLoopBackOff state in all namespaces
+func checkCrashLoopPods(pods []v1.Pod) *pkgCluster.HealthCheck {
+	var items []string
+	for _, pod := range pods {
+		for _, statuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, status := range statuses {
+				if status.State.Waiting != nil && status.State.Waiting.Reason == crashLoopBackOffReason {
+					items = append(items, fmt.Sprintf("%s/%s: container %s restarted %d times", pod.Namespace, pod.Name, status.Name, status.RestartCount))
+				}
+			}
+		}
+	}
+	return newHealthCheck(healthCheckCrashLoopPods, items, "container(s) in CrashLoopBackOff")
+}
+
+// checkUnschedulablePods looks for pending pods the scheduler can't place
+func checkUnschedulablePods(pods []v1.Pod) *pkgCluster.HealthCheck {
+	var items []string
+	for _, pod := range pods {
+		if pod.Status.Phase != v1.PodPending {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionFalse && condition.Reason == podUnschedulableCondition {
+				items = append(items, fmt.Sprintf("%s/%s: %s", pod.Namespace, pod.Name, condition.Message))
+			}
+		}
+	}
+	return newHealthCheck(healthCheckPendingPods, items, "pending pod(s) can't be scheduled")
+}
+
+// checkHelmReleases checks for failed Helm releases
+func checkHelmReleases(releases *rls.ListReleasesResponse, err error) *pkgCluster.HealthCheck {
+	if err != nil {
+		return &pkgCluster.HealthCheck{
+			Name:    healthCheckHelmReleases,
+			Status:  pkgCluster.HealthYellow,
+			Message: fmt.Sprintf("error listing releases: %s", err.Error()),
+		}
+	}
+
+	var items []string
+	if releases != nil {
+		for _, release := range releases.Releases {
+			items = append(items, fmt.Sprintf("%s (%s)", release.Name, release.Namespace))
+		}
+	}
+	return newHealthCheck(healthCheckHelmReleases, items, "failed Helm release(s)")
+}
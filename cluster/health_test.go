@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"testing"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestNode(name string, conditions ...v1.NodeCondition) v1.Node {
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     v1.NodeStatus{Conditions: conditions},
+	}
+}
+
+func TestCheckNodes(t *testing.T) {
+	ready := v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionTrue}
+	notReady := v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionFalse}
+	diskPressure := v1.NodeCondition{Type: v1.NodeDiskPressure, Status: v1.ConditionTrue}
+
+	cases := []struct {
+		name     string
+		nodes    []v1.Node
+		expected string
+		items    int
+	}{
+		{name: "all ready", nodes: []v1.Node{newTestNode("n1", ready), newTestNode("n2", ready)}, expected: pkgCluster.HealthGreen},
+		{name: "pressure", nodes: []v1.Node{newTestNode("n1", ready, diskPressure)}, expected: pkgCluster.HealthYellow, items: 1},
+		{name: "one not ready", nodes: []v1.Node{newTestNode("n1", ready), newTestNode("n2", notReady)}, expected: pkgCluster.HealthYellow, items: 1},
+		{name: "none ready", nodes: []v1.Node{newTestNode("n1", notReady)}, expected: pkgCluster.HealthRed, items: 1},
+		{name: "no nodes", nodes: nil, expected: pkgCluster.HealthRed},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checkNodes(tc.nodes)
+			if check.Status != tc.expected {
+				t.Errorf("expected status %s, got %s", tc.expected, check.Status)
+			}
+			if len(check.Items) != tc.items {
+				t.Errorf("expected %d items, got %v", tc.items, check.Items)
+			}
+		})
+	}
+}
+
+func TestCheckPods(t *testing.T) {
+	pods := []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-dns", Namespace: metav1.NamespaceSystem},
+			Status:     v1.PodStatus{Phase: v1.PodRunning},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: metav1.NamespaceSystem},
+			Status:     v1.PodStatus{Phase: v1.PodFailed},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{{
+					Name:         "app",
+					RestartCount: 5,
+					State:        v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: crashLoopBackOffReason}},
+				}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "big", Namespace: "default"},
+			Status: v1.PodStatus{
+				Phase: v1.PodPending,
+				Conditions: []v1.PodCondition{{
+					Type:    v1.PodScheduled,
+					Status:  v1.ConditionFalse,
+					Reason:  podUnschedulableCondition,
+					Message: "0/3 nodes are available: 3 Insufficient cpu.",
+				}},
+			},
+		},
+	}
+
+	report := &pkgCluster.HealthResponse{Status: pkgCluster.HealthGreen}
+	for _, check := range []*pkgCluster.HealthCheck{checkSystemPods(pods), checkCrashLoopPods(pods), checkUnschedulablePods(pods)} {
+		if check.Status != pkgCluster.HealthYellow || len(check.Items) != 1 {
+			t.Errorf("expected one problem in check %s, got %s: %v", check.Name, check.Status, check.Items)
+		}
+		report.AddCheck(check)
+	}
+
+	if report.Status != pkgCluster.HealthYellow {
+		t.Errorf("expected overall status yellow, got %s", report.Status)
+	}
+
+	report.AddCheck(&pkgCluster.HealthCheck{Name: healthCheckAPIServer, Status: pkgCluster.HealthRed})
+	report.AddCheck(&pkgCluster.HealthCheck{Name: healthCheckHelmReleases, Status: pkgCluster.HealthGreen})
+	if report.Status != pkgCluster.HealthRed {
+		t.Errorf("expected overall status red, got %s", report.Status)
+	}
+}
@@ -2,7 +2,6 @@ package cluster
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"encoding/json"
@@ -13,6 +12,7 @@ import (
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
 	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+	pkgNotify "github.com/banzaicloud/pipeline/pkg/notify"
 	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
 	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
 	"github.com/banzaicloud/pipeline/secret"
@@ -39,6 +39,7 @@ func RunPostHooks(postHooks []PostFunctioner, cluster CommonCluster) (err error)
 			if err != nil {
 				log.Errorf("Error during posthook function[%s]: %s", postHook, err.Error())
 				postHook.Error(cluster, err)
+				PublishClusterEvent(cluster, pkgNotify.EventPosthookFailed, fmt.Sprintf("Posthook %s failed: %s", postHook, err.Error()))
 				return
 			}
 
@@ -135,17 +136,32 @@ func InstallMonitoring(input interface{}) error {
 	}
 	log.Debugf("Grafana Secret Stored id: %s", secretID)
 
-	grafanaValues := map[string]map[string]string{
-		"grafana": {
+	prometheusValues := alertRulesHelmValues()
+	receiverValues, err := alertReceiverHelmValues(cluster)
+	if err != nil {
+		return errors.Errorf("Error creating alert receiver: %s", err.Error())
+	}
+	for key, value := range receiverValues {
+		prometheusValues[key] = value
+	}
+
+	grafanaValues := map[string]interface{}{
+		"grafana": map[string]string{
 			"adminUser":     grafanaAdminUsername,
 			"adminPassword": grafanaAdminPass,
 		},
+		"prometheus": prometheusValues,
 	}
 	grafanaValuesJson, err := json.Marshal(grafanaValues)
 	if err != nil {
 		return errors.Errorf("Json Convert Failed : %s", err.Error())
 	}
 
+	// the alert rules ConfigMap mounted into Prometheus has to exist before it's installed
+	if err := SyncAlertRules(cluster); err != nil {
+		return errors.Errorf("Error creating alert rules configmap: %s", err.Error())
+	}
+
 	return installDeployment(cluster, grafanaNamespace, pkgHelm.BanzaiRepository+"/pipeline-cluster-monitor", "pipeline-monitoring", grafanaValuesJson, "InstallMonitoring")
 }
 
@@ -407,8 +423,14 @@ func InstallHelmPostHook(input interface{}) error {
 	return nil
 }
 
-//UpdatePrometheus updates a configmap used by Prometheus
+//UpdatePrometheus updates a configmap used by Prometheus, unless the clusters are discovered by Prometheus
+//through the HTTP service discovery endpoint
 func UpdatePrometheus() {
+	if viper.GetBool(pipConfig.MonitorSDEnabled) {
+		log.Debug("Prometheus service discovery is enabled, skipping Prometheus configmap update")
+		return
+	}
+
 	err := UpdatePrometheusConfig()
 	if err != nil {
 		log.Warnf("Could not update prometheus configmap: %v", err)
@@ -515,7 +537,7 @@ func RegisterDomainPostHook(input interface{}) error {
 	return installDeployment(commonCluster, route53SecretNamespace, pkgHelm.StableRepository+"/external-dns", "pipeline-dns", externalDnsValuesJson, "InstallMonitoring")
 }
 
-// LabelNodes adds labels for all nodes
+// LabelNodes adds the node pool name label and the node pool labels and taints to all nodes
 func LabelNodes(input interface{}) error {
 
 	log.Info("start adding labels to nodes")
@@ -525,52 +547,29 @@ func LabelNodes(input interface{}) error {
 		return errors.Errorf("Wrong parameter type: %T", commonCluster)
 	}
 
-	log.Infof("get K8S config")
-	kubeConfig, err := commonCluster.GetK8sConfig()
-	if err != nil {
+	if err := applyNodePoolSettings(commonCluster); err != nil {
 		return err
 	}
 
-	log.Info("get K8S connection")
-	client, err := helm.GetK8sConnection(kubeConfig)
-	if err != nil {
-		return err
-	}
-
-	log.Info("list node names")
-	nodeNames, err := commonCluster.ListNodeNames()
-	if err != nil {
-		return err
-	}
-
-	log.Infof("node names: %v", nodeNames)
-
-	for name, nodes := range nodeNames {
-
-		log.Infof("nodepool: [%s]", name)
-		for _, nodeName := range nodes {
-			log.Infof("add label to node [%s]", nodeName)
-			if err := addLabelsToNode(client, nodeName, map[string]string{pkgCommon.LabelKey: name}); err != nil {
-				log.Warnf("error during adding label to node [%s]: %s", nodeName, err.Error())
-			}
-		}
-	}
-
 	log.Info("add labels finished")
 
 	return nil
 }
 
-// addLabelsToNode add label to the given node
-func addLabelsToNode(client *kubernetes.Clientset, nodeName string, labels map[string]string) (err error) {
+// addLabelsToNode applies the labels and taints to the given node and removes the ones previously applied by Pipeline
+// but no longer present in the node pool settings
+func addLabelsToNode(client *kubernetes.Clientset, nodeName string, settings pkgCommon.NodePoolSettings) (err error) {
 
-	tokens := make([]string, 0, len(labels))
-	for k, v := range labels {
-		tokens = append(tokens, "\""+k+"\":\""+v+"\"")
+	node, err := client.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return
+	}
+
+	patch, err := nodePoolSettingsPatch(node, settings)
+	if err != nil || patch == nil {
+		return
 	}
-	labelString := "{" + strings.Join(tokens, ",") + "}"
-	patch := fmt.Sprintf(`{"metadata":{"labels":%v}}`, labelString)
 
-	_, err = client.CoreV1().Nodes().Patch(nodeName, types.MergePatchType, []byte(patch))
+	_, err = client.CoreV1().Nodes().Patch(nodeName, types.MergePatchType, patch)
 	return
 }
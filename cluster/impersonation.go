@@ -0,0 +1,50 @@
+package cluster
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/model"
+	"github.com/spf13/viper"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+// ImpersonatedUserPrefix prefixes the Pipeline logins in the impersonated user names,
+// so a login can't match a Kubernetes user like system:admin
+const ImpersonatedUserPrefix = "pipeline:"
+
+// GetKubernetesGroups returns the Kubernetes groups organization members with the given role are impersonated with.
+// Mappings configured for the organization take precedence over the default ones.
+func GetKubernetesGroups(organizationID uint, role string) ([]string, error) {
+	mappings, err := model.GetKubernetesGroupMappings(organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if groups, ok := mappings[role]; ok {
+		return groups, nil
+	}
+
+	return viper.GetStringMapStringSlice(config.ProxyDefaultGroups)[role], nil
+}
+
+// SetImpersonationHeaders replaces the impersonation headers of a proxied request with the given identity
+func SetImpersonationHeaders(header http.Header, userName string, groups []string) {
+	RemoveImpersonationHeaders(header)
+
+	header.Set(authenticationv1.ImpersonateUserHeader, userName)
+	for _, group := range groups {
+		header.Add(authenticationv1.ImpersonateGroupHeader, group)
+	}
+}
+
+// RemoveImpersonationHeaders removes the client supplied impersonation headers of a proxied request
+func RemoveImpersonationHeaders(header http.Header) {
+	for key := range header {
+		if key == authenticationv1.ImpersonateUserHeader || key == authenticationv1.ImpersonateGroupHeader ||
+			strings.HasPrefix(key, authenticationv1.ImpersonateUserExtraHeaderPrefix) {
+			header.Del(key)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package cluster
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSetImpersonationHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer token")
+	header.Set("Impersonate-User", "system:admin")
+	header.Add("Impersonate-Group", "system:masters")
+	header.Set("Impersonate-Extra-Scopes", "all")
+
+	SetImpersonationHeaders(header, "johndoe", []string{"pipeline:member", "developers"})
+
+	if header.Get("Authorization") != "Bearer token" {
+		t.Error("unrelated headers should be kept")
+	}
+	if user := header.Get("Impersonate-User"); user != "johndoe" {
+		t.Errorf("expected impersonated user johndoe, got %q", user)
+	}
+	if groups := header["Impersonate-Group"]; !reflect.DeepEqual(groups, []string{"pipeline:member", "developers"}) {
+		t.Errorf("unexpected impersonated groups: %v", groups)
+	}
+	if _, ok := header["Impersonate-Extra-Scopes"]; ok {
+		t.Error("client supplied extra impersonation headers should be removed")
+	}
+}
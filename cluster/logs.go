@@ -0,0 +1,25 @@
+package cluster
+
+import (
+	"io"
+
+	"github.com/banzaicloud/pipeline/helm"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+)
+
+// GetPodLogs opens a stream of the logs of a pod's container. The stream is kept open while new lines are
+// written if the Follow option is set, so the caller must close it.
+func GetPodLogs(commonCluster CommonCluster, namespace, pod string, options *v1.PodLogOptions) (io.ReadCloser, error) {
+	kubeConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting cluster config")
+	}
+
+	client, err := helm.GetK8sConnection(kubeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	return client.CoreV1().Pods(namespace).GetLogs(pod, options).Stream()
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/spf13/viper"
+)
+
+const (
+	cloudInfoTimeout  = 10 * time.Second
+	machineTypesCache = time.Hour
+	gib               = 1 << 30
+)
+
+// cloudInfoProducts holds the used fields of the products listed by the cloud info service,
+// CPUs are measured in vCPUs, memory in GiB
+type cloudInfoProducts struct {
+	Products []struct {
+		Type      string  `json:"type"`
+		CpusPerVm float64 `json:"cpusPerVm"`
+		MemPerVm  float64 `json:"memPerVm"`
+	} `json:"products"`
+}
+
+type machineTypeSpecs struct {
+	specs     map[string]pkgCluster.MachineTypeSpec
+	fetchedAt time.Time
+}
+
+var (
+	machineTypesMu sync.Mutex
+	machineTypes   = map[string]*machineTypeSpecs{}
+)
+
+// getMachineTypeSpecs returns the capacity of the machine types available in the location of the cloud,
+// the machine types are listed by the cloud info service and cached for an hour
+func getMachineTypeSpecs(cloud, location string) (map[string]pkgCluster.MachineTypeSpec, error) {
+	var provider string
+	switch cloud {
+	case pkgCluster.Amazon, pkgCluster.AmazonEKS:
+		provider = "amazon"
+	case pkgCluster.Azure:
+		provider = "azure"
+	case pkgCluster.Google:
+		provider = "google"
+		// GKE clusters are located in zones, the machine types are listed by regions
+		if parts := strings.Split(location, "-"); len(parts) == 3 {
+			location = parts[0] + "-" + parts[1]
+		}
+	default:
+		return nil, fmt.Errorf("machine types of %s are not listed by the cloud info", cloud)
+	}
+
+	machineTypesMu.Lock()
+	defer machineTypesMu.Unlock()
+
+	key := provider + "/" + location
+	if cached, ok := machineTypes[key]; ok && time.Since(cached.fetchedAt) < machineTypesCache {
+		return cached.specs, nil
+	}
+
+	endpoint := fmt.Sprintf("%s/providers/%s/services/compute/regions/%s/products",
+		strings.TrimSuffix(viper.GetString(config.RecommendationCloudInfoURL), "/"), provider, url.PathEscape(location))
+	response, err := (&http.Client{Timeout: cloudInfoTimeout}).Get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with %s", endpoint, response.Status)
+	}
+
+	products := &cloudInfoProducts{}
+	if err := json.NewDecoder(response.Body).Decode(products); err != nil {
+		return nil, err
+	}
+
+	specs := make(map[string]pkgCluster.MachineTypeSpec, len(products.Products))
+	for _, product := range products.Products {
+		specs[product.Type] = pkgCluster.MachineTypeSpec{
+			Cpu:    int64(product.CpusPerVm * 1000),
+			Memory: int64(product.MemPerVm * gib),
+		}
+	}
+
+	machineTypes[key] = &machineTypeSpecs{specs: specs, fetchedAt: time.Now()}
+	return specs, nil
+}
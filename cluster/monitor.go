@@ -13,11 +13,6 @@ import (
 	"k8s.io/client-go/rest"
 )
 
-type prometheusTarget struct {
-	Targets []string          `json:"targets"`
-	Labels  map[string]string `json:"labels"`
-}
-
 //PrometheusCfg describes Prometheus config
 type PrometheusCfg struct {
 	Endpoint     string
@@ -28,6 +23,9 @@ type PrometheusCfg struct {
 }
 
 //UpdatePrometheusConfig updates the Prometheus configuration
+//
+// Deprecated: it overwrites the configuration of a single Prometheus with the clusters of all organizations,
+// use the HTTP service discovery endpoint served by Pipeline instead (see GetPrometheusTargets)
 func UpdatePrometheusConfig() error {
 	//TODO configsets
 	if !viper.GetBool("monitor.enabled") {
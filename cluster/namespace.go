@@ -0,0 +1,389 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/helm"
+	pkgNamespace "github.com/banzaicloud/pipeline/pkg/cluster/namespace"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Names of the objects managed in the namespaces
+const (
+	namespaceResourceQuotaName = "pipeline-quota"
+	namespaceLimitRangeName    = "pipeline-limits"
+)
+
+// ErrProtectedNamespace is returned when a system namespace is about to be updated or deleted
+var ErrProtectedNamespace = errors.New("namespace is protected")
+
+// ListNamespaces returns the namespaces of the cluster with their resource quotas and limit ranges
+func ListNamespaces(commonCluster CommonCluster) ([]*pkgNamespace.Response, error) {
+	client, _, err := getNamespaceClient(commonCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, err := client.CoreV1().Namespaces().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing namespaces")
+	}
+
+	response := make([]*pkgNamespace.Response, 0, len(namespaces.Items))
+	for i := range namespaces.Items {
+		namespace, err := describeNamespace(client, &namespaces.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, namespace)
+	}
+	return response, nil
+}
+
+// GetNamespace returns a namespace of the cluster with its resource quota and limit range
+func GetNamespace(commonCluster CommonCluster, name string) (*pkgNamespace.Response, error) {
+	client, _, err := getNamespaceClient(commonCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace, err := client.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return describeNamespace(client, namespace)
+}
+
+// CreateNamespace creates a namespace with the default and the requested labels and annotations, its resource quota
+// and limit range, and installs the selected secrets of the organization into it
+func CreateNamespace(commonCluster CommonCluster, request *pkgNamespace.Request) (*pkgNamespace.Response, error) {
+	if err := validateNamespaceRequest(request); err != nil {
+		return nil, err
+	}
+
+	client, kubeConfig, err := getNamespaceClient(commonCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace, err := client.CoreV1().Namespaces().Create(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        request.Name,
+			Labels:      mergeStringMaps(viper.GetStringMapString(config.NamespaceDefaultLabels), request.Labels),
+			Annotations: mergeStringMaps(viper.GetStringMapString(config.NamespaceDefaultAnnotations), request.Annotations),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applyNamespaceSettings(commonCluster, client, kubeConfig, namespace, request)
+}
+
+// UpdateNamespace merges the requested labels and annotations into the ones of a namespace, replaces its
+// resource quota and limit range if they are present in the request (an empty one deletes them),
+// and installs the selected secrets of the organization into it. System namespaces can't be updated.
+func UpdateNamespace(commonCluster CommonCluster, name string, request *pkgNamespace.Request) (*pkgNamespace.Response, error) {
+	if isProtectedNamespace(name) {
+		return nil, ErrProtectedNamespace
+	}
+
+	request.Name = name
+	if err := validateNamespaceRequest(request); err != nil {
+		return nil, err
+	}
+
+	client, kubeConfig, err := getNamespaceClient(commonCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace, err := client.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	mergeNamespaceMetadata(namespace, request)
+	namespace, err = client.CoreV1().Namespaces().Update(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return applyNamespaceSettings(commonCluster, client, kubeConfig, namespace, request)
+}
+
+// DeleteNamespace deletes a namespace of the cluster with all of its resources. System namespaces can't be deleted.
+func DeleteNamespace(commonCluster CommonCluster, name string) error {
+	if isProtectedNamespace(name) {
+		return ErrProtectedNamespace
+	}
+
+	client, _, err := getNamespaceClient(commonCluster)
+	if err != nil {
+		return err
+	}
+
+	return client.CoreV1().Namespaces().Delete(name, &metav1.DeleteOptions{})
+}
+
+func getNamespaceClient(commonCluster CommonCluster) (*kubernetes.Clientset, []byte, error) {
+	kubeConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error getting cluster config")
+	}
+
+	client, err := helm.GetK8sConnection(kubeConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	return client, kubeConfig, nil
+}
+
+// applyNamespaceSettings creates, updates or deletes the resource quota and limit range of the namespace
+// and installs the selected secrets. The resource quota and the limit range are left untouched if they are
+// missing from the request, and deleted if they are empty.
+func applyNamespaceSettings(commonCluster CommonCluster, client *kubernetes.Clientset, kubeConfig []byte,
+	namespace *v1.Namespace, request *pkgNamespace.Request) (*pkgNamespace.Response, error) {
+
+	if request.ResourceQuota != nil {
+		var quota *v1.ResourceQuota
+		if len(request.ResourceQuota) != 0 {
+			hard, err := parseResourceList(request.ResourceQuota)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid resource quota")
+			}
+			quota = &v1.ResourceQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: namespaceResourceQuotaName, Namespace: namespace.Name},
+				Spec:       v1.ResourceQuotaSpec{Hard: hard},
+			}
+		}
+		if err := applyResourceQuota(client, namespace.Name, quota); err != nil {
+			return nil, errors.Wrap(err, "error applying resource quota")
+		}
+	}
+
+	if request.LimitRange != nil {
+		limitRange, err := newLimitRange(namespace.Name, request.LimitRange)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyLimitRange(client, namespace.Name, limitRange); err != nil {
+			return nil, errors.Wrap(err, "error applying limit range")
+		}
+	}
+
+	var installedSecrets []secretTypes.K8SSourceMeta
+	if request.Secrets != nil {
+		var err error
+		installedSecrets, err = InstallSecretsByK8SConfig(kubeConfig, commonCluster.GetOrganizationId(), request.Secrets, namespace.Name)
+		if err != nil {
+			return nil, errors.Wrap(err, "error installing secrets")
+		}
+	}
+
+	response, err := describeNamespace(client, namespace)
+	if err != nil {
+		return nil, err
+	}
+	response.Secrets = installedSecrets
+	return response, nil
+}
+
+// newLimitRange creates the container limit range of the namespace, or returns nil if the requested one is empty
+func newLimitRange(namespace string, request *pkgNamespace.LimitRange) (*v1.LimitRange, error) {
+	var err error
+	item := v1.LimitRangeItem{Type: v1.LimitTypeContainer}
+
+	if item.Default, err = parseResourceList(request.Default); err != nil {
+		return nil, errors.Wrap(err, "invalid limit range default")
+	}
+	if item.DefaultRequest, err = parseResourceList(request.DefaultRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid limit range defaultRequest")
+	}
+	if item.Max, err = parseResourceList(request.Max); err != nil {
+		return nil, errors.Wrap(err, "invalid limit range max")
+	}
+	if item.Min, err = parseResourceList(request.Min); err != nil {
+		return nil, errors.Wrap(err, "invalid limit range min")
+	}
+
+	if len(item.Default) == 0 && len(item.DefaultRequest) == 0 && len(item.Max) == 0 && len(item.Min) == 0 {
+		return nil, nil
+	}
+
+	return &v1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: namespaceLimitRangeName, Namespace: namespace},
+		Spec:       v1.LimitRangeSpec{Limits: []v1.LimitRangeItem{item}},
+	}, nil
+}
+
+func applyResourceQuota(client *kubernetes.Clientset, namespace string, quota *v1.ResourceQuota) error {
+	quotas := client.CoreV1().ResourceQuotas(namespace)
+
+	current, err := quotas.Get(namespaceResourceQuotaName, metav1.GetOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	switch {
+	case quota == nil && exists:
+		return quotas.Delete(namespaceResourceQuotaName, &metav1.DeleteOptions{})
+	case quota != nil && exists:
+		current.Spec = quota.Spec
+		_, err = quotas.Update(current)
+		return err
+	case quota != nil:
+		_, err = quotas.Create(quota)
+		return err
+	}
+	return nil
+}
+
+func applyLimitRange(client *kubernetes.Clientset, namespace string, limitRange *v1.LimitRange) error {
+	limitRanges := client.CoreV1().LimitRanges(namespace)
+
+	current, err := limitRanges.Get(namespaceLimitRangeName, metav1.GetOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	switch {
+	case limitRange == nil && exists:
+		return limitRanges.Delete(namespaceLimitRangeName, &metav1.DeleteOptions{})
+	case limitRange != nil && exists:
+		current.Spec = limitRange.Spec
+		_, err = limitRanges.Update(current)
+		return err
+	case limitRange != nil:
+		_, err = limitRanges.Create(limitRange)
+		return err
+	}
+	return nil
+}
+
+// describeNamespace collects the managed resource quota and limit range of the namespace
+func describeNamespace(client *kubernetes.Clientset, namespace *v1.Namespace) (*pkgNamespace.Response, error) {
+	response := &pkgNamespace.Response{
+		Name:        namespace.Name,
+		Status:      string(namespace.Status.Phase),
+		Labels:      namespace.Labels,
+		Annotations: namespace.Annotations,
+		CreatedAt:   namespace.CreationTimestamp.Time,
+	}
+
+	quota, err := client.CoreV1().ResourceQuotas(namespace.Name).Get(namespaceResourceQuotaName, metav1.GetOptions{})
+	if err == nil {
+		response.ResourceQuota = formatResourceList(quota.Spec.Hard)
+		response.ResourceQuotaUsed = formatResourceList(quota.Status.Used)
+	} else if !k8sErrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "error getting resource quota of namespace %s", namespace.Name)
+	}
+
+	limitRange, err := client.CoreV1().LimitRanges(namespace.Name).Get(namespaceLimitRangeName, metav1.GetOptions{})
+	if err == nil {
+		for _, item := range limitRange.Spec.Limits {
+			if item.Type == v1.LimitTypeContainer {
+				response.LimitRange = &pkgNamespace.LimitRange{
+					Default:        formatResourceList(item.Default),
+					DefaultRequest: formatResourceList(item.DefaultRequest),
+					Max:            formatResourceList(item.Max),
+					Min:            formatResourceList(item.Min),
+				}
+			}
+		}
+	} else if !k8sErrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "error getting limit range of namespace %s", namespace.Name)
+	}
+
+	return response, nil
+}
+
+// validateNamespaceRequest checks the name, the resource quantities and the secret selection of the request
+func validateNamespaceRequest(request *pkgNamespace.Request) error {
+	if request.Name == "" {
+		return errors.New("namespace name is required")
+	}
+
+	if _, err := parseResourceList(request.ResourceQuota); err != nil {
+		return errors.Wrap(err, "invalid resource quota")
+	}
+
+	if request.LimitRange != nil {
+		if _, err := newLimitRange(request.Name, request.LimitRange); err != nil {
+			return err
+		}
+	}
+
+	// an empty query would select every secret of the organization
+	if request.Secrets != nil && request.Secrets.Type == "" && request.Secrets.Tag == "" {
+		return errors.New("secrets must be selected by type or tag")
+	}
+
+	return nil
+}
+
+func parseResourceList(resources map[string]string) (v1.ResourceList, error) {
+	if len(resources) == 0 {
+		return nil, nil
+	}
+
+	list := v1.ResourceList{}
+	for name, value := range resources {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err.Error())
+		}
+		list[v1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
+
+func formatResourceList(list v1.ResourceList) map[string]string {
+	if len(list) == 0 {
+		return nil
+	}
+
+	resources := make(map[string]string, len(list))
+	for name, quantity := range list {
+		resources[string(name)] = quantity.String()
+	}
+	return resources
+}
+
+// mergeNamespaceMetadata adds the default and the requested labels and annotations to the namespace,
+// the existing ones, e.g. the ones set by the system, are kept unless the request overrides them
+func mergeNamespaceMetadata(namespace *v1.Namespace, request *pkgNamespace.Request) {
+	namespace.Labels = mergeStringMaps(namespace.Labels, viper.GetStringMapString(config.NamespaceDefaultLabels), request.Labels)
+	namespace.Annotations = mergeStringMaps(namespace.Annotations, viper.GetStringMapString(config.NamespaceDefaultAnnotations), request.Annotations)
+}
+
+func isProtectedNamespace(name string) bool {
+	switch name {
+	case metav1.NamespaceDefault, metav1.NamespaceSystem, metav1.NamespacePublic, viper.GetString(config.PipelineMonitorNamespace):
+		return true
+	}
+	return false
+}
+
+// mergeStringMaps returns the union of the maps, the latter ones take precedence
+func mergeStringMaps(maps ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, m := range maps {
+		for key, value := range m {
+			merged[key] = value
+		}
+	}
+	return merged
+}
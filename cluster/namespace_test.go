@@ -0,0 +1,124 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/banzaicloud/pipeline/config"
+	pkgNamespace "github.com/banzaicloud/pipeline/pkg/cluster/namespace"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/spf13/viper"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMergeNamespaceMetadata(t *testing.T) {
+	defaultLabels := viper.GetStringMapString(config.NamespaceDefaultLabels)
+	viper.Set(config.NamespaceDefaultLabels, map[string]string{"owner": "pipeline"})
+	defer viper.Set(config.NamespaceDefaultLabels, defaultLabels)
+
+	namespace := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "team",
+			Labels:      map[string]string{"istio-injection": "enabled", "team": "a"},
+			Annotations: map[string]string{"scheduler.alpha.kubernetes.io/node-selector": "pool=a"},
+		},
+	}
+	request := &pkgNamespace.Request{
+		Labels:      map[string]string{"team": "b"},
+		Annotations: map[string]string{"description": "team b"},
+	}
+
+	mergeNamespaceMetadata(namespace, request)
+
+	expectedLabels := map[string]string{"istio-injection": "enabled", "team": "b", "owner": "pipeline"}
+	if !reflect.DeepEqual(namespace.Labels, expectedLabels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, namespace.Labels)
+	}
+	expectedAnnotations := map[string]string{"scheduler.alpha.kubernetes.io/node-selector": "pool=a", "description": "team b"}
+	if !reflect.DeepEqual(namespace.Annotations, expectedAnnotations) {
+		t.Errorf("expected annotations %v, got %v", expectedAnnotations, namespace.Annotations)
+	}
+}
+
+func TestIsProtectedNamespace(t *testing.T) {
+	monitorNamespace := viper.GetString(config.PipelineMonitorNamespace)
+	viper.Set(config.PipelineMonitorNamespace, "pipeline-system")
+	defer viper.Set(config.PipelineMonitorNamespace, monitorNamespace)
+
+	for name, expected := range map[string]bool{
+		"default":         true,
+		"kube-system":     true,
+		"kube-public":     true,
+		"pipeline-system": true,
+		"team":            false,
+	} {
+		if actual := isProtectedNamespace(name); actual != expected {
+			t.Errorf("%s: expected protected to be %t, got %t", name, expected, actual)
+		}
+	}
+}
+
+func TestUpdateProtectedNamespace(t *testing.T) {
+	_, err := UpdateNamespace(nil, "kube-system", &pkgNamespace.Request{})
+	if err != ErrProtectedNamespace {
+		t.Errorf("expected %v, got %v", ErrProtectedNamespace, err)
+	}
+}
+
+func TestValidateNamespaceRequest(t *testing.T) {
+	cases := []struct {
+		name    string
+		request pkgNamespace.Request
+		valid   bool
+	}{
+		{name: "valid", request: pkgNamespace.Request{Name: "team", ResourceQuota: map[string]string{"requests.cpu": "2", "limits.memory": "4Gi"}}, valid: true},
+		{name: "missing name", request: pkgNamespace.Request{}},
+		{name: "invalid quota", request: pkgNamespace.Request{Name: "team", ResourceQuota: map[string]string{"requests.cpu": "two"}}},
+		{name: "invalid limit", request: pkgNamespace.Request{Name: "team", LimitRange: &pkgNamespace.LimitRange{Max: map[string]string{"memory": "1XB"}}}},
+		{name: "secrets by tag", request: pkgNamespace.Request{Name: "team", Secrets: &secretTypes.ListSecretsQuery{Tag: "team"}}, valid: true},
+		{name: "all secrets", request: pkgNamespace.Request{Name: "team", Secrets: &secretTypes.ListSecretsQuery{}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateNamespaceRequest(&tc.request)
+			if tc.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if !tc.valid && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseResourceList(t *testing.T) {
+	list, err := parseResourceList(map[string]string{"requests.cpu": "500m", "limits.memory": "1Gi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{"requests.cpu": "500m", "limits.memory": "1Gi"}
+	if actual := formatResourceList(list); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestNewLimitRange(t *testing.T) {
+	limitRange, err := newLimitRange("team", &pkgNamespace.LimitRange{})
+	if err != nil || limitRange != nil {
+		t.Errorf("expected no limit range for an empty request, got %v, %v", limitRange, err)
+	}
+
+	limitRange, err = newLimitRange("team", &pkgNamespace.LimitRange{Max: map[string]string{"memory": "1Gi"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if max := formatResourceList(limitRange.Spec.Limits[0].Max); !reflect.DeepEqual(max, map[string]string{"memory": "1Gi"}) {
+		t.Errorf("unexpected max: %v", max)
+	}
+
+	if _, err := newLimitRange("team", &pkgNamespace.LimitRange{Min: map[string]string{"cpu": "one"}}); err == nil {
+		t.Error("expected an error")
+	}
+}
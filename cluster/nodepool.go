@@ -0,0 +1,235 @@
+package cluster
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/helm"
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"k8s.io/api/core/v1"
+)
+
+// Node annotations which record the labels and taints applied by Pipeline, so the ones removed
+// from the node pool can be removed from the node as well
+const (
+	nodeManagedLabelsAnnotation = "pipeline.banzaicloud.com/managed-labels"
+	nodeManagedTaintsAnnotation = "pipeline.banzaicloud.com/managed-taints"
+)
+
+// getNodePoolSettings returns the stored labels and taints of the given node pool
+func getNodePoolSettings(settings map[string]*pkgCommon.NodePoolSettings, nodePoolName string) pkgCommon.NodePoolSettings {
+	if s := settings[nodePoolName]; s != nil {
+		return *s
+	}
+	return pkgCommon.NodePoolSettings{}
+}
+
+// applyNodePoolSettings applies the node pool name label and the labels and taints of the node pools
+// to all nodes of the cluster
+func applyNodePoolSettings(commonCluster CommonCluster) error {
+	nodeNames, err := commonCluster.ListNodeNames()
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("node names: %v", nodeNames)
+
+	if len(nodeNames) == 0 {
+		return nil
+	}
+
+	kubeConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := helm.GetK8sConnection(kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	for name, nodes := range nodeNames {
+		settings := getNodePoolSettings(commonCluster.GetModel().NodePoolSettings, name)
+
+		labels := make(map[string]string, len(settings.Labels)+1)
+		for k, v := range settings.Labels {
+			labels[k] = v
+		}
+		labels[pkgCommon.LabelKey] = name
+		settings.Labels = labels
+
+		log.Debugf("nodepool: [%s]", name)
+		for _, nodeName := range nodes {
+			log.Debugf("apply labels and taints to node [%s]", nodeName)
+			if err := addLabelsToNode(client, nodeName, settings); err != nil {
+				log.Warnf("error during adding labels and taints to node [%s]: %s", nodeName, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// nodePoolSettingsPatch returns the merge patch which makes the node's labels and taints match the node pool settings,
+// nil is returned when the node is up to date
+func nodePoolSettingsPatch(node *v1.Node, settings pkgCommon.NodePoolSettings) ([]byte, error) {
+	metadata := map[string]interface{}{}
+	spec := map[string]interface{}{}
+
+	// labels
+	labels := map[string]interface{}{}
+	for k, v := range settings.Labels {
+		if current, ok := node.Labels[k]; !ok || current != v {
+			labels[k] = v
+		}
+	}
+	for _, k := range splitManagedKeys(node.Annotations[nodeManagedLabelsAnnotation]) {
+		if _, ok := settings.Labels[k]; !ok {
+			if _, ok := node.Labels[k]; ok {
+				labels[k] = nil
+			}
+		}
+	}
+	if len(labels) != 0 {
+		metadata["labels"] = labels
+	}
+
+	// taints
+	desiredTaints := make(map[string]bool, len(settings.Taints))
+	for _, t := range settings.Taints {
+		desiredTaints[taintID(t.Key, t.Effect)] = true
+	}
+	managedTaints := make(map[string]bool)
+	for _, id := range splitManagedKeys(node.Annotations[nodeManagedTaintsAnnotation]) {
+		managedTaints[id] = true
+	}
+
+	taints := make([]v1.Taint, 0, len(node.Spec.Taints)+len(settings.Taints))
+	for _, t := range node.Spec.Taints {
+		id := taintID(t.Key, string(t.Effect))
+		if !managedTaints[id] && !desiredTaints[id] {
+			taints = append(taints, t)
+		}
+	}
+	for _, t := range settings.Taints {
+		taints = append(taints, v1.Taint{
+			Key:    t.Key,
+			Value:  t.Value,
+			Effect: v1.TaintEffect(t.Effect),
+		})
+	}
+	if !equalTaints(node.Spec.Taints, taints) {
+		spec["taints"] = taints
+	}
+
+	// the applied keys are recorded in annotations
+	annotations := map[string]interface{}{}
+	labelKeys := make([]string, 0, len(settings.Labels))
+	for k := range settings.Labels {
+		labelKeys = append(labelKeys, k)
+	}
+	if value := joinManagedKeys(labelKeys); node.Annotations[nodeManagedLabelsAnnotation] != value {
+		annotations[nodeManagedLabelsAnnotation] = value
+	}
+	taintIDs := make([]string, 0, len(desiredTaints))
+	for id := range desiredTaints {
+		taintIDs = append(taintIDs, id)
+	}
+	if value := joinManagedKeys(taintIDs); node.Annotations[nodeManagedTaintsAnnotation] != value {
+		annotations[nodeManagedTaintsAnnotation] = value
+	}
+	if len(annotations) != 0 {
+		metadata["annotations"] = annotations
+	}
+
+	if len(metadata) == 0 && len(spec) == 0 {
+		return nil, nil
+	}
+
+	// fail on concurrent modification instead of overwriting taints added in the meantime
+	metadata["resourceVersion"] = node.ResourceVersion
+
+	patch := map[string]interface{}{"metadata": metadata}
+	if len(spec) != 0 {
+		patch["spec"] = spec
+	}
+
+	return json.Marshal(patch)
+}
+
+// taintID returns the identifier of a taint, a node can have only one taint with the same key and effect
+func taintID(key, effect string) string {
+	return key + ":" + effect
+}
+
+// equalTaints compares the key, value and effect of the taints regardless of their order
+func equalTaints(a, b []v1.Taint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	values := make(map[string]string, len(a))
+	for _, t := range a {
+		values[taintID(t.Key, string(t.Effect))] = t.Value
+	}
+	for _, t := range b {
+		value, ok := values[taintID(t.Key, string(t.Effect))]
+		if !ok || value != t.Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func splitManagedKeys(value string) []string {
+	if len(value) == 0 {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func joinManagedKeys(keys []string) string {
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// LabelNodePools reapplies the node pool labels and taints to the nodes of all running clusters,
+// so the nodes added by scaling up a node pool get them as well
+func LabelNodePools() {
+	clusters, err := model.QueryCluster(map[string]interface{}{"status": pkgCluster.Running})
+	if err != nil {
+		log.Errorf("Error listing running clusters: %s", err.Error())
+		return
+	}
+
+	for i := range clusters {
+		commonCluster, err := GetCommonClusterFromModel(&clusters[i])
+		if err != nil {
+			log.Errorf("Error getting cluster %d: %s", clusters[i].ID, err.Error())
+			continue
+		}
+
+		if err := applyNodePoolSettings(commonCluster); err != nil {
+			log.Warnf("Error applying node pool labels to cluster %d: %s", clusters[i].ID, err.Error())
+		}
+	}
+}
+
+// StartNodePoolLabeler periodically reapplies the node pool labels and taints to the nodes of running clusters
+func StartNodePoolLabeler(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			log.Debug("Node pool labeler running")
+			LabelNodePools()
+		}
+	}()
+
+	return ticker
+}
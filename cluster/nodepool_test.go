@@ -0,0 +1,120 @@
+package cluster
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodePoolSettingsPatch(t *testing.T) {
+	settings := pkgCommon.NodePoolSettings{
+		Labels: map[string]string{pkgCommon.LabelKey: "pool1", "workload": "gpu"},
+		Taints: []pkgCommon.NodeTaint{{Key: "nvidia.com/gpu", Value: "present", Effect: pkgCommon.TaintEffectNoSchedule}},
+	}
+
+	t.Run("new node", func(t *testing.T) {
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "n1", ResourceVersion: "10"},
+			Spec: v1.NodeSpec{Taints: []v1.Taint{
+				{Key: "node.kubernetes.io/not-ready", Effect: v1.TaintEffectNoExecute},
+			}},
+		}
+
+		patch := decodePatch(t, node, settings)
+
+		metadata := patch["metadata"].(map[string]interface{})
+		expectedLabels := map[string]interface{}{pkgCommon.LabelKey: "pool1", "workload": "gpu"}
+		if !reflect.DeepEqual(metadata["labels"], expectedLabels) {
+			t.Errorf("expected labels %v, got %v", expectedLabels, metadata["labels"])
+		}
+		if metadata["resourceVersion"] != "10" {
+			t.Errorf("expected resource version precondition, got %v", metadata["resourceVersion"])
+		}
+
+		annotations := metadata["annotations"].(map[string]interface{})
+		if annotations[nodeManagedLabelsAnnotation] != pkgCommon.LabelKey+",workload" {
+			t.Errorf("unexpected managed labels annotation: %v", annotations[nodeManagedLabelsAnnotation])
+		}
+		if annotations[nodeManagedTaintsAnnotation] != "nvidia.com/gpu:NoSchedule" {
+			t.Errorf("unexpected managed taints annotation: %v", annotations[nodeManagedTaintsAnnotation])
+		}
+
+		taints := patch["spec"].(map[string]interface{})["taints"].([]interface{})
+		if len(taints) != 2 {
+			t.Errorf("expected the existing and the node pool taint, got %v", taints)
+		}
+	})
+
+	t.Run("removed label and taint", func(t *testing.T) {
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "n1",
+				Labels: map[string]string{pkgCommon.LabelKey: "pool1", "workload": "gpu", "batch": "true"},
+				Annotations: map[string]string{
+					nodeManagedLabelsAnnotation: "batch," + pkgCommon.LabelKey + ",workload",
+					nodeManagedTaintsAnnotation: "batch:NoSchedule,nvidia.com/gpu:NoSchedule",
+				},
+			},
+			Spec: v1.NodeSpec{Taints: []v1.Taint{
+				{Key: "batch", Effect: v1.TaintEffectNoSchedule},
+				{Key: "nvidia.com/gpu", Value: "present", Effect: v1.TaintEffectNoSchedule},
+			}},
+		}
+
+		patch := decodePatch(t, node, settings)
+
+		labels := patch["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+		if v, ok := labels["batch"]; !ok || v != nil || len(labels) != 1 {
+			t.Errorf("expected only the removal of the batch label, got %v", labels)
+		}
+
+		taints := patch["spec"].(map[string]interface{})["taints"].([]interface{})
+		if len(taints) != 1 || taints[0].(map[string]interface{})["key"] != "nvidia.com/gpu" {
+			t.Errorf("expected only the node pool taint, got %v", taints)
+		}
+	})
+
+	t.Run("up to date node", func(t *testing.T) {
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "n1",
+				Labels: map[string]string{pkgCommon.LabelKey: "pool1", "workload": "gpu", "kubernetes.io/hostname": "n1"},
+				Annotations: map[string]string{
+					nodeManagedLabelsAnnotation: pkgCommon.LabelKey + ",workload",
+					nodeManagedTaintsAnnotation: "nvidia.com/gpu:NoSchedule",
+				},
+			},
+			Spec: v1.NodeSpec{Taints: []v1.Taint{
+				{Key: "nvidia.com/gpu", Value: "present", Effect: v1.TaintEffectNoSchedule},
+			}},
+		}
+
+		patch, err := nodePoolSettingsPatch(node, settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if patch != nil {
+			t.Errorf("expected no patch, got %s", patch)
+		}
+	})
+}
+
+func decodePatch(t *testing.T, node *v1.Node, settings pkgCommon.NodePoolSettings) map[string]interface{} {
+	raw, err := nodePoolSettingsPatch(node, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw == nil {
+		t.Fatal("expected a patch")
+	}
+
+	var patch map[string]interface{}
+	if err := json.Unmarshal(raw, &patch); err != nil {
+		t.Fatal(err)
+	}
+	return patch
+}
@@ -220,6 +220,9 @@ func (o *OKECluster) GetModel() *model.ClusterModel {
 func (o *OKECluster) CheckEqualityToUpdate(r *pkgCluster.UpdateClusterRequest) error {
 
 	cluster := o.modelCluster.Oracle.GetClusterRequestFromModel()
+	for name, np := range cluster.NodePools {
+		np.Taints = getNodePoolSettings(o.modelCluster.NodePoolSettings, name).Taints
+	}
 
 	log.Info("Check stored & updated cluster equals")
 
@@ -0,0 +1,87 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	pipConfig "github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Prometheus labels of the cluster target groups
+const (
+	prometheusLabelScheme      = "__scheme__"
+	prometheusLabelMetricsPath = "__metrics_path__"
+	prometheusLabelOrg         = "org"
+	prometheusLabelCluster     = "cluster"
+	prometheusLabelClusterID   = "cluster_id"
+	prometheusLabelCloud       = "cloud"
+	prometheusLabelPrefix      = "label_"
+)
+
+var invalidPrometheusLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// PrometheusTargetGroup describes a target group in Prometheus' HTTP service discovery format
+type PrometheusTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// GetPrometheusTargets returns the running clusters of the organization matching the selector as Prometheus target groups.
+// The clusters are scraped through Pipeline's cluster API proxy at the given address, so their TLS
+// credentials are read from the secret store by Pipeline instead of being mounted into Prometheus.
+func GetPrometheusTargets(organizationID uint, organizationName string, selector labels.Selector, address, scheme string) ([]*PrometheusTargetGroup, error) {
+	clusters, err := model.QueryCluster(map[string]interface{}{"organization_id": organizationID})
+	if err != nil {
+		return nil, err
+	}
+
+	return prometheusTargetGroups(clusters, organizationID, organizationName, selector, address, scheme), nil
+}
+
+// prometheusTargetGroups returns the running clusters matching the selector as target groups ordered by their IDs
+func prometheusTargetGroups(clusters []model.ClusterModel, organizationID uint, organizationName string, selector labels.Selector, address, scheme string) []*PrometheusTargetGroup {
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].ID < clusters[j].ID
+	})
+
+	basePath := viper.GetString("pipeline.basepath")
+	federatePath := viper.GetString(pipConfig.MonitorSDFederatePath)
+
+	targetGroups := make([]*PrometheusTargetGroup, 0, len(clusters))
+	for _, cluster := range clusters {
+		if cluster.Status != pkgCluster.Running && cluster.Status != pkgCluster.Updating {
+			continue
+		}
+		if !selector.Matches(labels.Set(cluster.Labels)) {
+			continue
+		}
+
+		targetLabels := make(map[string]string, len(cluster.Labels)+6)
+		for k, v := range cluster.Labels {
+			targetLabels[prometheusLabelName(k)] = v
+		}
+		targetLabels[prometheusLabelScheme] = scheme
+		targetLabels[prometheusLabelMetricsPath] = fmt.Sprintf("%s/api/v1/orgs/%d/clusters/%d/proxy%s", basePath, organizationID, cluster.ID, federatePath)
+		targetLabels[prometheusLabelOrg] = organizationName
+		targetLabels[prometheusLabelCluster] = cluster.Name
+		targetLabels[prometheusLabelClusterID] = fmt.Sprint(cluster.ID)
+		targetLabels[prometheusLabelCloud] = cluster.Cloud
+
+		targetGroups = append(targetGroups, &PrometheusTargetGroup{
+			Targets: []string{address},
+			Labels:  targetLabels,
+		})
+	}
+
+	return targetGroups
+}
+
+// prometheusLabelName converts a cluster label key to a valid Prometheus label name
+func prometheusLabelName(key string) string {
+	return prometheusLabelPrefix + invalidPrometheusLabelChars.ReplaceAllString(key, "_")
+}
@@ -0,0 +1,87 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	pipConfig "github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestPrometheusLabelName(t *testing.T) {
+	tests := map[string]string{
+		"env":                    "label_env",
+		"app.kubernetes.io/name": "label_app_kubernetes_io_name",
+		"team-name":              "label_team_name",
+		"1st":                    "label_1st",
+	}
+
+	for key, expected := range tests {
+		if name := prometheusLabelName(key); name != expected {
+			t.Errorf("expected %q for %q, got %q", expected, key, name)
+		}
+	}
+}
+
+func TestPrometheusTargetGroups(t *testing.T) {
+	basePath := viper.GetString("pipeline.basepath")
+	federatePath := viper.GetString(pipConfig.MonitorSDFederatePath)
+	viper.Set("pipeline.basepath", "/pipeline")
+	viper.Set(pipConfig.MonitorSDFederatePath, "/federate")
+	defer func() {
+		viper.Set("pipeline.basepath", basePath)
+		viper.Set(pipConfig.MonitorSDFederatePath, federatePath)
+	}()
+
+	clusters := []model.ClusterModel{
+		{ID: 3, Name: "staging", Cloud: pkgCluster.Google, Status: pkgCluster.Updating, Labels: map[string]string{"env": "staging"}},
+		{ID: 1, Name: "prod", Cloud: pkgCluster.Amazon, Status: pkgCluster.Running, Labels: map[string]string{"env": "prod", "team-name": "a"}},
+		{ID: 2, Name: "creating", Cloud: pkgCluster.Amazon, Status: pkgCluster.Creating, Labels: map[string]string{"env": "prod"}},
+		{ID: 4, Name: "dev", Cloud: pkgCluster.Azure, Status: pkgCluster.Running, Labels: map[string]string{"env": "dev"}},
+	}
+
+	selector, err := labels.Parse("env in (prod, staging)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targetGroups := prometheusTargetGroups(clusters, 5, "acme", selector, "pipeline.example.com:443", "https")
+
+	expected := []*PrometheusTargetGroup{
+		{
+			Targets: []string{"pipeline.example.com:443"},
+			Labels: map[string]string{
+				"__scheme__":       "https",
+				"__metrics_path__": "/pipeline/api/v1/orgs/5/clusters/1/proxy/federate",
+				"org":              "acme",
+				"cluster":          "prod",
+				"cluster_id":       "1",
+				"cloud":            pkgCluster.Amazon,
+				"label_env":        "prod",
+				"label_team_name":  "a",
+			},
+		},
+		{
+			Targets: []string{"pipeline.example.com:443"},
+			Labels: map[string]string{
+				"__scheme__":       "https",
+				"__metrics_path__": "/pipeline/api/v1/orgs/5/clusters/3/proxy/federate",
+				"org":              "acme",
+				"cluster":          "staging",
+				"cluster_id":       "3",
+				"cloud":            pkgCluster.Google,
+				"label_env":        "staging",
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(targetGroups, expected) {
+		t.Errorf("unexpected target groups:")
+		for _, targetGroup := range targetGroups {
+			t.Errorf("%+v", targetGroup)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/banzaicloud/pipeline/helm"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgAmazon "github.com/banzaicloud/pipeline/pkg/cluster/amazon"
+	pkgAzure "github.com/banzaicloud/pipeline/pkg/cluster/azure"
+	pkgEks "github.com/banzaicloud/pipeline/pkg/cluster/eks"
+	pkgGoogle "github.com/banzaicloud/pipeline/pkg/cluster/google"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	resourceHelper "k8s.io/kubernetes/pkg/api/v1/resource"
+)
+
+// defaultAllocatableRatio is the part of a node's capacity assumed to be allocatable when the
+// node pool has no nodes to measure it on
+const defaultAllocatableRatio = 0.9
+
+// nodePoolWorkload describes the nodes of a node pool and the resources requested by the pods running
+// on them or waiting to be scheduled to them. CPU is measured in millicores, memory in bytes.
+type nodePoolWorkload struct {
+	nodes             int
+	cpuCapacity       int64
+	cpuAllocatable    int64
+	memoryCapacity    int64
+	memoryAllocatable int64
+
+	// requests of the DaemonSet pods, these are needed on every node
+	cpuOverhead    int64
+	memoryOverhead int64
+
+	cpuRequest    int64
+	memoryRequest int64
+	maxPodCpu     int64
+	maxPodMemory  int64
+	pendingPods   int
+}
+
+// GetNodePoolRecommendations recommends instance types and node counts for the node pools of the cluster which fit
+// the requests of their running and unschedulable pods with the given headroom. The instance types are chosen from
+// the current and the candidate instance types, their capacities are listed by the cloud info service. The returned
+// UpdateClusterRequest resizes the node pools of Amazon, EKS, Azure and Google clusters, it keeps the current instance
+// types as those can't be changed in place, so the node pools recommended to change their instance type aren't resized.
+func GetNodePoolRecommendations(commonCluster CommonCluster, candidates []string, headroom float64, withUpdateRequest bool) (*pkgCluster.RecommendationResponse, error) {
+	status, err := commonCluster.GetStatus()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting cluster status")
+	}
+
+	kubeConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting cluster config")
+	}
+
+	client, err := helm.GetK8sConnection(kubeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing nodes")
+	}
+
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing pods")
+	}
+
+	nodePoolLabels := make(map[string]map[string]string, len(status.NodePools))
+	for name := range status.NodePools {
+		labels := map[string]string{pkgCommon.LabelKey: name}
+		for k, v := range getNodePoolSettings(commonCluster.GetModel().NodePoolSettings, name).Labels {
+			labels[k] = v
+		}
+		nodePoolLabels[name] = labels
+	}
+
+	workloads, unassigned := calculateNodePoolWorkloads(nodePoolLabels, nodes.Items, pods.Items)
+
+	response := &pkgCluster.RecommendationResponse{
+		Headroom:       headroom,
+		NodePools:      make(map[string]*pkgCluster.NodePoolRecommendation, len(status.NodePools)),
+		UnassignedPods: unassigned,
+	}
+
+	specs, err := getMachineTypeSpecs(commonCluster.GetType(), commonCluster.GetModel().Location)
+	if err != nil {
+		// the capacity of the current instance types is still measured on the nodes
+		log.Warnf("Error listing machine types: %s", err.Error())
+	}
+
+	for name, nodePool := range status.NodePools {
+		response.NodePools[name] = recommendNodePool(specs, nodePool, workloads[name], candidates, headroom)
+	}
+
+	if withUpdateRequest {
+		response.UpdateClusterRequest = recommendationUpdateRequest(commonCluster, status, response.NodePools)
+	}
+
+	return response, nil
+}
+
+// calculateNodePoolWorkloads assigns the nodes and pods to node pools, unschedulable pods are assigned
+// to the first node pool whose labels match their node selector. The unschedulable pods which can't be
+// assigned to any of the node pools are returned as well.
+func calculateNodePoolWorkloads(nodePoolLabels map[string]map[string]string, nodes []v1.Node, pods []v1.Pod) (map[string]*nodePoolWorkload, []string) {
+	workloads := make(map[string]*nodePoolWorkload, len(nodePoolLabels))
+	for name := range nodePoolLabels {
+		workloads[name] = &nodePoolWorkload{}
+	}
+
+	nodePoolOfNode := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		name := node.Labels[pkgCommon.LabelKey]
+		workload, ok := workloads[name]
+		if !ok {
+			continue
+		}
+		nodePoolOfNode[node.Name] = name
+
+		workload.nodes++
+		workload.cpuCapacity += node.Status.Capacity.Cpu().MilliValue()
+		workload.cpuAllocatable += node.Status.Allocatable.Cpu().MilliValue()
+		workload.memoryCapacity += node.Status.Capacity.Memory().Value()
+		workload.memoryAllocatable += node.Status.Allocatable.Memory().Value()
+	}
+
+	nodePoolNames := make([]string, 0, len(nodePoolLabels))
+	for name := range nodePoolLabels {
+		nodePoolNames = append(nodePoolNames, name)
+	}
+	sort.Strings(nodePoolNames)
+
+	var unassigned []string
+	overheads := make(map[string][2]int64)
+	for i := range pods {
+		pod := &pods[i]
+
+		var workload *nodePoolWorkload
+		switch {
+		case pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed:
+			continue
+		case len(pod.Spec.NodeName) == 0 && isDaemonSetPod(pod):
+			// DaemonSet pods are bound to their nodes, they can't be moved to another node pool
+			continue
+		case len(pod.Spec.NodeName) != 0:
+			workload = workloads[nodePoolOfNode[pod.Spec.NodeName]]
+		case isPodUnschedulable(pod):
+			for _, name := range nodePoolNames {
+				if matchesNodeSelector(pod.Spec.NodeSelector, nodePoolLabels[name]) {
+					workload = workloads[name]
+					workload.pendingPods++
+					break
+				}
+			}
+			if workload == nil {
+				unassigned = append(unassigned, pod.Namespace+"/"+pod.Name)
+			}
+		}
+		if workload == nil {
+			continue
+		}
+
+		reqs, _ := resourceHelper.PodRequestsAndLimits(pod)
+		requests := v1.ResourceList(reqs)
+		cpu, memory := requests.Cpu().MilliValue(), requests.Memory().Value()
+
+		if isDaemonSetPod(pod) {
+			overhead := overheads[pod.Spec.NodeName]
+			overheads[pod.Spec.NodeName] = [2]int64{overhead[0] + cpu, overhead[1] + memory}
+			continue
+		}
+
+		workload.cpuRequest += cpu
+		workload.memoryRequest += memory
+		if cpu > workload.maxPodCpu {
+			workload.maxPodCpu = cpu
+		}
+		if memory > workload.maxPodMemory {
+			workload.maxPodMemory = memory
+		}
+	}
+
+	// the highest DaemonSet overhead of the nodes is reserved on every node
+	for nodeName, overhead := range overheads {
+		workload := workloads[nodePoolOfNode[nodeName]]
+		if workload == nil {
+			continue
+		}
+		if overhead[0] > workload.cpuOverhead {
+			workload.cpuOverhead = overhead[0]
+		}
+		if overhead[1] > workload.memoryOverhead {
+			workload.memoryOverhead = overhead[1]
+		}
+	}
+
+	return workloads, unassigned
+}
+
+// recommendNodePool keeps the current instance type if it fits the largest pod, otherwise it recommends
+// the smallest candidate which does, then calculates the node count needed for the requests with headroom
+func recommendNodePool(specs map[string]pkgCluster.MachineTypeSpec, nodePool *pkgCluster.NodePoolStatus, workload *nodePoolWorkload, candidates []string, headroom float64) *pkgCluster.NodePoolRecommendation {
+	currentCount := nodePool.Count
+	if workload.nodes != 0 {
+		currentCount = workload.nodes
+	}
+
+	recommendation := &pkgCluster.NodePoolRecommendation{
+		CurrentInstanceType: nodePool.InstanceType,
+		CurrentCount:        currentCount,
+		InstanceType:        nodePool.InstanceType,
+		Count:               currentCount,
+		PendingPods:         workload.pendingPods,
+		Cpu:                 recommendationResource(workload.cpuRequest, workload.cpuAllocatable),
+		Memory:              recommendationResource(workload.memoryRequest, workload.memoryAllocatable),
+	}
+
+	cpuRatio, memoryRatio := defaultAllocatableRatio, defaultAllocatableRatio
+	currentSpec, ok := specs[nodePool.InstanceType]
+	if workload.nodes != 0 && workload.cpuCapacity != 0 && workload.memoryCapacity != 0 {
+		cpuRatio = float64(workload.cpuAllocatable) / float64(workload.cpuCapacity)
+		memoryRatio = float64(workload.memoryAllocatable) / float64(workload.memoryCapacity)
+		currentSpec = pkgCluster.MachineTypeSpec{
+			Cpu:    workload.cpuCapacity / int64(workload.nodes),
+			Memory: workload.memoryCapacity / int64(workload.nodes),
+		}
+		ok = true
+	}
+	if !ok {
+		recommendation.Message = fmt.Sprintf("the capacity of instance type %q is unknown", nodePool.InstanceType)
+		return recommendation
+	}
+
+	// usable resources of a node of the given instance type
+	usable := func(spec pkgCluster.MachineTypeSpec) (int64, int64) {
+		return int64(float64(spec.Cpu)*cpuRatio) - workload.cpuOverhead, int64(float64(spec.Memory)*memoryRatio) - workload.memoryOverhead
+	}
+	fits := func(spec pkgCluster.MachineTypeSpec) bool {
+		cpu, memory := usable(spec)
+		return cpu > 0 && memory > 0 && cpu >= workload.maxPodCpu && memory >= workload.maxPodMemory
+	}
+	count := func(spec pkgCluster.MachineTypeSpec) int {
+		cpu, memory := usable(spec)
+		n := ceilDiv(int64(float64(workload.cpuRequest)*(1+headroom)), cpu)
+		if m := ceilDiv(int64(float64(workload.memoryRequest)*(1+headroom)), memory); m > n {
+			n = m
+		}
+		if n < 1 {
+			n = 1
+		}
+		return int(n)
+	}
+
+	if fits(currentSpec) {
+		recommendation.Count = count(currentSpec)
+		recommendation.OverProvisioned = recommendation.Count < currentCount
+		return recommendation
+	}
+
+	var best *pkgCluster.MachineTypeSpec
+	for _, name := range candidates {
+		spec, ok := specs[name]
+		if !ok || !fits(spec) {
+			continue
+		}
+		if best == nil || spec.Cpu < best.Cpu || (spec.Cpu == best.Cpu && spec.Memory < best.Memory) {
+			best = &spec
+			recommendation.InstanceType = name
+		}
+	}
+
+	if best == nil {
+		recommendation.Message = "none of the instance types fits the largest pod of the node pool"
+		return recommendation
+	}
+
+	recommendation.Count = count(*best)
+	recommendation.Message = fmt.Sprintf("the largest pod of the node pool doesn't fit on a %s node", nodePool.InstanceType)
+
+	return recommendation
+}
+
+// recommendationUpdateRequest returns the request which resizes the node pools to the recommended counts,
+// the other node pool properties are kept as they are. The node pools recommended to change their instance type
+// keep their counts, as the recommended count is calculated for the other instance type.
+func recommendationUpdateRequest(commonCluster CommonCluster, status *pkgCluster.GetClusterStatusResponse, recommendations map[string]*pkgCluster.NodePoolRecommendation) *pkgCluster.UpdateClusterRequest {
+	settings := commonCluster.GetModel().NodePoolSettings
+	request := &pkgCluster.UpdateClusterRequest{Cloud: status.Cloud}
+
+	resize := func(nodePool *pkgCluster.NodePoolStatus, count int) (int, int, int) {
+		minCount, maxCount := nodePool.MinCount, nodePool.MaxCount
+		if nodePool.Autoscaling {
+			if count < minCount {
+				minCount = count
+			}
+			if count > maxCount {
+				maxCount = count
+			}
+		}
+		return count, minCount, maxCount
+	}
+	recommendedCount := func(name string, nodePool *pkgCluster.NodePoolStatus) int {
+		recommendation := recommendations[name]
+		if recommendation == nil || recommendation.InstanceType != nodePool.InstanceType {
+			return nodePool.Count
+		}
+		return recommendation.Count
+	}
+
+	switch commonCluster.(type) {
+	case *AWSCluster, *EKSCluster:
+		nodePools := make(map[string]*pkgAmazon.NodePool, len(status.NodePools))
+		for name, np := range status.NodePools {
+			nodePool := &pkgAmazon.NodePool{
+				InstanceType:     np.InstanceType,
+				SpotPrice:        np.SpotPrice,
+				Autoscaling:      np.Autoscaling,
+				Image:            np.Image,
+				NodePoolSettings: getNodePoolSettings(settings, name),
+			}
+			nodePool.Count, nodePool.MinCount, nodePool.MaxCount = resize(np, recommendedCount(name, np))
+			if !np.Autoscaling {
+				nodePool.MinCount, nodePool.MaxCount = nodePool.Count, nodePool.Count
+			}
+			nodePools[name] = nodePool
+		}
+		if _, ok := commonCluster.(*EKSCluster); ok {
+			request.Eks = &pkgEks.UpdateClusterAmazonEKS{NodePools: nodePools}
+		} else {
+			request.Amazon = &pkgAmazon.UpdateClusterAmazon{NodePools: nodePools}
+		}
+	case *AKSCluster:
+		nodePools := make(map[string]*pkgAzure.NodePoolUpdate, len(status.NodePools))
+		for name, np := range status.NodePools {
+			nodePool := &pkgAzure.NodePoolUpdate{
+				Autoscaling:      np.Autoscaling,
+				NodePoolSettings: getNodePoolSettings(settings, name),
+			}
+			nodePool.Count, nodePool.MinCount, nodePool.MaxCount = resize(np, recommendedCount(name, np))
+			nodePools[name] = nodePool
+		}
+		request.Azure = &pkgAzure.UpdateClusterAzure{NodePools: nodePools}
+	case *GKECluster:
+		nodePools := make(map[string]*pkgGoogle.NodePool, len(status.NodePools))
+		for name, np := range status.NodePools {
+			nodePool := &pkgGoogle.NodePool{
+				Autoscaling:      np.Autoscaling,
+				NodeInstanceType: np.InstanceType,
+				NodePoolSettings: getNodePoolSettings(settings, name),
+			}
+			nodePool.Count, nodePool.MinCount, nodePool.MaxCount = resize(np, recommendedCount(name, np))
+			nodePools[name] = nodePool
+		}
+		request.Google = &pkgGoogle.UpdateClusterGoogle{NodePools: nodePools}
+	default:
+		return nil
+	}
+
+	return request
+}
+
+func recommendationResource(request, allocatable int64) pkgCluster.RecommendationResource {
+	resource := pkgCluster.RecommendationResource{Request: request, Allocatable: allocatable}
+	if allocatable != 0 {
+		resource.Utilization = float64(request) / float64(allocatable)
+	}
+	return resource
+}
+
+// isPodUnschedulable returns true if the scheduler couldn't find a node for the pod
+func isPodUnschedulable(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionFalse && condition.Reason == v1.PodReasonUnschedulable {
+			return true
+		}
+	}
+	return false
+}
+
+func isDaemonSetPod(pod *v1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesNodeSelector(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func ceilDiv(a, b int64) int64 {
+	if a <= 0 {
+		return 0
+	}
+	return (a + b - 1) / b
+}
@@ -0,0 +1,120 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func recommendationTestPod(name, nodeName, cpu, memory string) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1.PodSpec{
+			NodeName: nodeName,
+			Containers: []v1.Container{{Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)},
+			}}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func TestNodePoolRecommendations(t *testing.T) {
+	nodes := make([]v1.Node, 0, 4)
+	for _, name := range []string{"n1", "n2", "n3", "n4"} {
+		nodes = append(nodes, v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{pkgCommon.LabelKey: "pool1"}},
+			Status: v1.NodeStatus{
+				Capacity:    v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("8Gi")},
+				Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("8Gi")},
+			},
+		})
+	}
+
+	daemon := recommendationTestPod("fluentd-n1", "n1", "200m", "256Mi")
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "fluentd"}}
+
+	pending := recommendationTestPod("batch", "", "1", "1Gi")
+	pending.Status = v1.PodStatus{Phase: v1.PodPending, Conditions: []v1.PodCondition{
+		{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: v1.PodReasonUnschedulable},
+	}}
+
+	gpu := recommendationTestPod("gpu", "", "1", "1Gi")
+	gpu.Status = pending.Status
+	gpu.Spec.NodeSelector = map[string]string{"accelerator": "nvidia"}
+
+	pods := []v1.Pod{
+		daemon,
+		recommendationTestPod("web-1", "n1", "500m", "1Gi"),
+		recommendationTestPod("web-2", "n2", "500m", "1Gi"),
+		pending,
+		gpu,
+	}
+
+	workloads, unassigned := calculateNodePoolWorkloads(map[string]map[string]string{
+		"pool1": {pkgCommon.LabelKey: "pool1"},
+	}, nodes, pods)
+
+	if len(unassigned) != 1 || unassigned[0] != "default/gpu" {
+		t.Errorf("expected the gpu pod to be unassigned, got %v", unassigned)
+	}
+
+	workload := workloads["pool1"]
+	if workload.nodes != 4 || workload.cpuRequest != 2000 || workload.pendingPods != 1 || workload.cpuOverhead != 200 || workload.maxPodCpu != 1000 {
+		t.Fatalf("unexpected workload: %+v", workload)
+	}
+
+	nodePool := &pkgCluster.NodePoolStatus{InstanceType: "m5.large", Count: 4}
+	specs := map[string]pkgCluster.MachineTypeSpec{
+		"m5.large":   {Cpu: 2000, Memory: 8 << 30},
+		"m5.xlarge":  {Cpu: 4000, Memory: 16 << 30},
+		"m5.2xlarge": {Cpu: 8000, Memory: 32 << 30},
+	}
+
+	// 2000m requested with 20% headroom needs 2 nodes with 1800m usable CPU each
+	recommendation := recommendNodePool(specs, nodePool, workload, nil, 0.2)
+	if recommendation.InstanceType != "m5.large" || recommendation.Count != 2 || !recommendation.OverProvisioned {
+		t.Errorf("unexpected recommendation: %+v", recommendation)
+	}
+
+	// a pod larger than a node needs a bigger instance type
+	workload.maxPodCpu = 3000
+	workload.cpuRequest = 4000
+	recommendation = recommendNodePool(specs, nodePool, workload, []string{"m5.large", "m5.2xlarge", "m5.xlarge"}, 0)
+	if recommendation.InstanceType != "m5.xlarge" || recommendation.Count != 2 || recommendation.OverProvisioned {
+		t.Errorf("unexpected recommendation for large pods: %+v", recommendation)
+	}
+}
+
+func TestRecommendationUpdateRequest(t *testing.T) {
+	commonCluster := &AWSCluster{modelCluster: &model.ClusterModel{}}
+	status := &pkgCluster.GetClusterStatusResponse{
+		Cloud: pkgCluster.Amazon,
+		NodePools: map[string]*pkgCluster.NodePoolStatus{
+			"pool1": {InstanceType: "m5.large", Count: 4, MinCount: 4, MaxCount: 4},
+			"pool2": {InstanceType: "m5.large", Count: 3, MinCount: 3, MaxCount: 3},
+		},
+	}
+	recommendations := map[string]*pkgCluster.NodePoolRecommendation{
+		"pool1": {InstanceType: "m5.large", Count: 2},
+		"pool2": {InstanceType: "m5.xlarge", Count: 1},
+	}
+
+	request := recommendationUpdateRequest(commonCluster, status, recommendations)
+	if request == nil || request.Amazon == nil {
+		t.Fatalf("expected an Amazon update request, got %+v", request)
+	}
+
+	// the count recommended for another instance type is not applied to the current one
+	for name, expected := range map[string]int{"pool1": 2, "pool2": 3} {
+		nodePool := request.Amazon.NodePools[name]
+		if nodePool.InstanceType != "m5.large" || nodePool.Count != expected || nodePool.MinCount != expected || nodePool.MaxCount != expected {
+			t.Errorf("%s: unexpected node pool: %+v", name, nodePool)
+		}
+	}
+}
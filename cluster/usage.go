@@ -0,0 +1,321 @@
+package cluster
+
+import (
+	"sort"
+	"time"
+
+	pipConfig "github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/helm"
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	resourceHelper "k8s.io/kubernetes/pkg/api/v1/resource"
+)
+
+// MaxUsagePoints is the maximum number of points returned in a usage time series
+const MaxUsagePoints = 1000
+
+// SampleClusterUsage stores the current resource summary of the cluster and its node pools
+func SampleClusterUsage(commonCluster CommonCluster, timestamp time.Time) error {
+	kubeConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		return errors.Wrap(err, "error getting cluster config")
+	}
+
+	client, err := helm.GetK8sConnection(kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "error listing nodes")
+	}
+
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "error listing pods")
+	}
+
+	samples := calculateUsageSamples(commonCluster.GetID(), timestamp, nodes.Items, pods.Items)
+
+	return errors.Wrap(model.SaveClusterUsageSamples(samples), "error saving usage samples")
+}
+
+// calculateUsageSamples sums up the capacity and allocatable of the nodes and the requests and limits of the pods
+// for each node pool and for the whole cluster, nodes without node pool label (eg. masters) and pods not scheduled
+// yet are counted only in the cluster summary
+func calculateUsageSamples(clusterID uint, timestamp time.Time, nodes []v1.Node, pods []v1.Pod) []*model.ClusterUsageSampleModel {
+	total := &model.ClusterUsageSampleModel{ClusterID: clusterID, Timestamp: timestamp}
+	nodePools := make(map[string]*model.ClusterUsageSampleModel)
+	nodePoolOfNode := make(map[string]string, len(nodes))
+
+	for _, node := range nodes {
+		capacity, allocatable := node.Status.Capacity, node.Status.Allocatable
+		addNodeToUsageSample(total, capacity, allocatable)
+
+		nodePool, ok := node.Labels[pkgCommon.LabelKey]
+		if !ok {
+			continue
+		}
+		nodePoolOfNode[node.Name] = nodePool
+
+		sample, ok := nodePools[nodePool]
+		if !ok {
+			sample = &model.ClusterUsageSampleModel{ClusterID: clusterID, NodePool: nodePool, Timestamp: timestamp}
+			nodePools[nodePool] = sample
+		}
+		addNodeToUsageSample(sample, capacity, allocatable)
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+
+		// finished pods don't hold resources anymore
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+
+		requests, limits := resourceHelper.PodRequestsAndLimits(pod)
+		addPodToUsageSample(total, requests, limits)
+
+		if sample, ok := nodePools[nodePoolOfNode[pod.Spec.NodeName]]; ok {
+			addPodToUsageSample(sample, requests, limits)
+		}
+	}
+
+	samples := []*model.ClusterUsageSampleModel{total}
+	for _, sample := range nodePools {
+		samples = append(samples, sample)
+	}
+
+	return samples
+}
+
+func addNodeToUsageSample(sample *model.ClusterUsageSampleModel, capacity, allocatable v1.ResourceList) {
+	sample.Nodes++
+	sample.CPUCapacity += capacity.Cpu().MilliValue()
+	sample.CPUAllocatable += allocatable.Cpu().MilliValue()
+	sample.MemoryCapacity += capacity.Memory().Value()
+	sample.MemoryAllocatable += allocatable.Memory().Value()
+}
+
+func addPodToUsageSample(sample *model.ClusterUsageSampleModel, requests, limits v1.ResourceList) {
+	sample.CPURequest += requests.Cpu().MilliValue()
+	sample.CPULimit += limits.Cpu().MilliValue()
+	sample.MemoryRequest += requests.Memory().Value()
+	sample.MemoryLimit += limits.Memory().Value()
+}
+
+// aggregateUsageSamples groups the samples into step long buckets starting from start by cluster and node pool,
+// the returned samples contain the average of the values and the highest node count of their bucket
+func aggregateUsageSamples(samples []*model.ClusterUsageSampleModel, start time.Time, step time.Duration) []*model.ClusterUsageSampleModel {
+	type bucketKey struct {
+		clusterID uint
+		nodePool  string
+		timestamp int64
+	}
+
+	type bucket struct {
+		sample *model.ClusterUsageSampleModel
+		count  int64
+	}
+
+	buckets := make(map[bucketKey]*bucket)
+	result := make([]*model.ClusterUsageSampleModel, 0)
+
+	for _, s := range samples {
+		timestamp := start.Add(s.Timestamp.Sub(start) / step * step)
+		key := bucketKey{clusterID: s.ClusterID, nodePool: s.NodePool, timestamp: timestamp.UnixNano()}
+
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{sample: &model.ClusterUsageSampleModel{
+				ClusterID:  s.ClusterID,
+				NodePool:   s.NodePool,
+				Timestamp:  timestamp,
+				Resolution: int64(step / time.Second),
+			}}
+			buckets[key] = b
+			result = append(result, b.sample)
+		}
+
+		b.count++
+		if s.Nodes > b.sample.Nodes {
+			b.sample.Nodes = s.Nodes
+		}
+		b.sample.CPUCapacity += s.CPUCapacity
+		b.sample.CPUAllocatable += s.CPUAllocatable
+		b.sample.CPURequest += s.CPURequest
+		b.sample.CPULimit += s.CPULimit
+		b.sample.MemoryCapacity += s.MemoryCapacity
+		b.sample.MemoryAllocatable += s.MemoryAllocatable
+		b.sample.MemoryRequest += s.MemoryRequest
+		b.sample.MemoryLimit += s.MemoryLimit
+	}
+
+	for _, b := range buckets {
+		b.sample.CPUCapacity /= b.count
+		b.sample.CPUAllocatable /= b.count
+		b.sample.CPURequest /= b.count
+		b.sample.CPULimit /= b.count
+		b.sample.MemoryCapacity /= b.count
+		b.sample.MemoryAllocatable /= b.count
+		b.sample.MemoryRequest /= b.count
+		b.sample.MemoryLimit /= b.count
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+
+	return result
+}
+
+// DefaultUsageStep returns the step used when the usage request doesn't specify one: the sample interval
+// or a longer step if the interval would result in too many points
+func DefaultUsageStep(from, to time.Time) time.Duration {
+	step := time.Duration(viper.GetInt(pipConfig.UsageSampleIntervalMinute)) * time.Minute
+	if minStep := to.Sub(from) / MaxUsagePoints; step < minStep {
+		step = (minStep + time.Minute - 1).Truncate(time.Minute)
+	}
+	return step
+}
+
+// GetClusterUsage returns the usage time series of the cluster and its node pools between from and to
+func GetClusterUsage(clusterID uint, from, to time.Time, step time.Duration) (*pkgCluster.UsageResponse, error) {
+	samples, err := model.QueryClusterUsageSamples(clusterID, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "error querying usage samples")
+	}
+
+	response := &pkgCluster.UsageResponse{
+		From:      from,
+		To:        to,
+		Step:      step.String(),
+		Total:     []*pkgCluster.UsagePoint{},
+		NodePools: make(map[string][]*pkgCluster.UsagePoint),
+	}
+
+	for _, s := range aggregateUsageSamples(samples, from, step) {
+		point := &pkgCluster.UsagePoint{
+			Timestamp: s.Timestamp,
+			Nodes:     s.Nodes,
+			Cpu: pkgCluster.UsageValue{
+				Capacity:    s.CPUCapacity,
+				Allocatable: s.CPUAllocatable,
+				Request:     s.CPURequest,
+				Limit:       s.CPULimit,
+			},
+			Memory: pkgCluster.UsageValue{
+				Capacity:    s.MemoryCapacity,
+				Allocatable: s.MemoryAllocatable,
+				Request:     s.MemoryRequest,
+				Limit:       s.MemoryLimit,
+			},
+		}
+
+		if len(s.NodePool) == 0 {
+			response.Total = append(response.Total, point)
+		} else {
+			response.NodePools[s.NodePool] = append(response.NodePools[s.NodePool], point)
+		}
+	}
+
+	return response, nil
+}
+
+// SampleUsage samples the resource summary of all running clusters, then downsamples the old samples
+// and deletes the ones older than the retention period
+func SampleUsage() {
+	now := time.Now().UTC()
+
+	clusters, err := model.QueryCluster(map[string]interface{}{"status": pkgCluster.Running})
+	if err != nil {
+		log.Errorf("Error listing running clusters: %s", err.Error())
+		return
+	}
+
+	for i := range clusters {
+		commonCluster, err := GetCommonClusterFromModel(&clusters[i])
+		if err != nil {
+			log.Errorf("Error getting cluster %d: %s", clusters[i].ID, err.Error())
+			continue
+		}
+
+		if err := SampleClusterUsage(commonCluster, now); err != nil {
+			log.Warnf("Error sampling usage of cluster %d: %s", clusters[i].ID, err.Error())
+		}
+	}
+
+	if err := downsampleUsage(now); err != nil {
+		log.Errorf("Error downsampling usage samples: %s", err.Error())
+	}
+
+	retention, err := time.ParseDuration(viper.GetString(pipConfig.UsageRetention))
+	if err != nil {
+		log.Errorf("Invalid usage retention: %s", err.Error())
+		return
+	}
+
+	if err := model.DeleteClusterUsageSamplesBefore(now.Add(-retention)); err != nil {
+		log.Errorf("Error deleting expired usage samples: %s", err.Error())
+	}
+}
+
+// downsampleUsage replaces the samples older than the configured age with their hourly (by default) averages
+func downsampleUsage(now time.Time) error {
+	after, err := time.ParseDuration(viper.GetString(pipConfig.UsageDownsampleAfter))
+	if err != nil {
+		return errors.Wrap(err, "invalid usage downsample age")
+	}
+
+	resolution, err := time.ParseDuration(viper.GetString(pipConfig.UsageDownsampleResolution))
+	if err != nil || resolution < time.Second {
+		return errors.Errorf("invalid usage downsample resolution %q", viper.GetString(pipConfig.UsageDownsampleResolution))
+	}
+
+	// only complete buckets are downsampled
+	epoch := time.Unix(0, 0).UTC()
+	before := epoch.Add(now.Add(-after).Sub(epoch) / resolution * resolution)
+
+	samples, err := model.QueryClusterUsageSamplesToDownsample(before, int64(resolution/time.Second))
+	if err != nil {
+		return err
+	}
+
+	if len(samples) == 0 {
+		return nil
+	}
+
+	return model.ReplaceClusterUsageSamples(samples, aggregateUsageSamples(samples, epoch, resolution))
+}
+
+// StartUsageSampler periodically samples the resource summary of running clusters.
+// Each period is claimed before sampling, so only one Pipeline instance samples and downsamples.
+func StartUsageSampler(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			claimed, err := model.ClaimClusterUsageSampling(time.Now().UTC(), interval)
+			if err != nil {
+				log.Errorf("Error claiming usage sampling: %s", err.Error())
+				continue
+			}
+			if !claimed {
+				log.Debug("Usage sampling has been run by another instance")
+				continue
+			}
+
+			log.Debug("Usage sampler running")
+			SampleUsage()
+		}
+	}()
+
+	return ticker
+}
@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/pipeline/model"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCalculateUsageSamples(t *testing.T) {
+	node := func(name, nodePool string) v1.Node {
+		n := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}},
+			Status: v1.NodeStatus{
+				Capacity: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("2"),
+					v1.ResourceMemory: resource.MustParse("4Gi"),
+				},
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("1900m"),
+					v1.ResourceMemory: resource.MustParse("3Gi"),
+				},
+			},
+		}
+		if len(nodePool) != 0 {
+			n.Labels[pkgCommon.LabelKey] = nodePool
+		}
+		return n
+	}
+	pod := func(nodeName string, phase v1.PodPhase) v1.Pod {
+		return v1.Pod{
+			Spec: v1.PodSpec{
+				NodeName: nodeName,
+				Containers: []v1.Container{{Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m"), v1.ResourceMemory: resource.MustParse("1Gi")},
+					Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+				}}},
+			},
+			Status: v1.PodStatus{Phase: phase},
+		}
+	}
+
+	nodes := []v1.Node{node("master", ""), node("n1", "pool1"), node("n2", "pool1")}
+	pods := []v1.Pod{
+		pod("n1", v1.PodRunning),
+		pod("n2", v1.PodRunning),
+		pod("n2", v1.PodSucceeded),
+		pod("", v1.PodPending),
+	}
+
+	samples := calculateUsageSamples(1, time.Now(), nodes, pods)
+	if len(samples) != 2 {
+		t.Fatalf("expected cluster and node pool samples, got %d", len(samples))
+	}
+
+	total, pool1 := samples[0], samples[1]
+	if total.NodePool != "" || total.Nodes != 3 || total.CPUCapacity != 6000 || total.CPURequest != 1500 || total.CPULimit != 3000 {
+		t.Errorf("unexpected cluster sample: %+v", total)
+	}
+	if pool1.NodePool != "pool1" || pool1.Nodes != 2 || pool1.CPUAllocatable != 3800 || pool1.CPURequest != 1000 {
+		t.Errorf("unexpected node pool sample: %+v", pool1)
+	}
+	if pool1.MemoryCapacity != 8<<30 || pool1.MemoryRequest != 2<<30 || pool1.MemoryLimit != 0 {
+		t.Errorf("unexpected node pool memory: %+v", pool1)
+	}
+}
+
+func TestAggregateUsageSamples(t *testing.T) {
+	start := time.Date(2018, 7, 10, 12, 0, 0, 0, time.UTC)
+	samples := []*model.ClusterUsageSampleModel{
+		{ClusterID: 1, Timestamp: start, Nodes: 2, CPURequest: 1000},
+		{ClusterID: 1, Timestamp: start.Add(5 * time.Minute), Nodes: 3, CPURequest: 2000},
+		{ClusterID: 1, NodePool: "pool1", Timestamp: start.Add(5 * time.Minute), Nodes: 1, CPURequest: 500},
+		{ClusterID: 1, Timestamp: start.Add(time.Hour), Nodes: 1, CPURequest: 300},
+	}
+
+	result := aggregateUsageSamples(samples, start, time.Hour)
+	if len(result) != 3 {
+		t.Fatalf("expected 3 buckets, got %d", len(result))
+	}
+
+	if r := result[0]; r.NodePool != "" || !r.Timestamp.Equal(start) || r.Nodes != 3 || r.CPURequest != 1500 || r.Resolution != 3600 {
+		t.Errorf("unexpected first bucket: %+v", r)
+	}
+	if r := result[1]; r.NodePool != "pool1" || r.CPURequest != 500 {
+		t.Errorf("unexpected node pool bucket: %+v", r)
+	}
+	if r := result[2]; !r.Timestamp.Equal(start.Add(time.Hour)) || r.CPURequest != 300 {
+		t.Errorf("unexpected last bucket: %+v", r)
+	}
+}
@@ -0,0 +1,342 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/helm"
+	pipelineKubernetes "github.com/banzaicloud/pipeline/kubernetes"
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/banzaicloud/pipeline/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	userCredentialManagedByLabel = "app.kubernetes.io/managed-by"
+	userCredentialUserLabel      = "pipeline.banzaicloud.com/user"
+	userCredentialExpiresAtKey   = "pipeline.banzaicloud.com/expires-at"
+
+	userCredentialRetryCount = 30
+	userCredentialRetrySleep = 2 * time.Second
+)
+
+var invalidNameCharacters = regexp.MustCompile("[^a-z0-9-]+")
+
+// ErrClusterRoleNotAllowed is returned when the organization role of the user doesn't allow the requested ClusterRole
+var ErrClusterRoleNotAllowed = errors.New("the ClusterRole is not allowed for the organization role of the user")
+
+// CreateUserCredential issues a short-lived credential to the user on the cluster, bound to the requested ClusterRole
+// if the organization role of the user allows it, and returns a kubeconfig using it. ServiceAccounts and ClusterRoleBindings are named after the user so the
+// Kubernetes audit logs show the real identity.
+func CreateUserCredential(commonCluster CommonCluster, user *auth.User, request *pkgCluster.CreateUserConfigRequest) ([]byte, *model.ClusterUserCredentialModel, error) {
+	credential, err := newUserCredential(commonCluster, user, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	adminConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error getting cluster config")
+	}
+
+	restConfig, err := pipelineKubernetes.GetK8sClientConfig(adminConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating kubernetes client config")
+	}
+
+	client, err := helm.GetK8sConnection(adminConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	if err := helm.CreateNamespaceIfNotExist(adminConfig, credential.Namespace); err != nil {
+		return nil, nil, errors.Wrap(err, "error creating namespace for service account")
+	}
+
+	token, ca, err := createServiceAccountToken(client, credential, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	authInfo := clientcmdapi.NewAuthInfo()
+	authInfo.Token = string(token)
+	caData := restConfig.CAData
+	if len(ca) != 0 {
+		caData = ca
+	}
+	subject := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: credential.Name, Namespace: credential.Namespace}
+
+	_, err = client.RbacV1().ClusterRoleBindings().Create(&rbacv1.ClusterRoleBinding{
+		ObjectMeta: userCredentialObjectMeta(credential, user),
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     credential.ClusterRole,
+		},
+		Subjects: []rbacv1.Subject{subject},
+	})
+	if err != nil {
+		deleteUserCredentialResources(client, credential)
+		return nil, nil, errors.Wrap(err, "error creating cluster role binding")
+	}
+
+	if err := credential.Save(); err != nil {
+		deleteUserCredentialResources(client, credential)
+		return nil, nil, errors.Wrap(err, "error saving user credential")
+	}
+
+	contextName := fmt.Sprintf("%s@%s", user.Login, commonCluster.GetName())
+
+	kubeConfig := clientcmdapi.NewConfig()
+	kubeConfig.Clusters[commonCluster.GetName()] = &clientcmdapi.Cluster{
+		Server:                   restConfig.Host,
+		CertificateAuthorityData: caData,
+		InsecureSkipTLSVerify:    restConfig.Insecure,
+	}
+	kubeConfig.AuthInfos[contextName] = authInfo
+	kubeConfig.Contexts[contextName] = &clientcmdapi.Context{
+		Cluster:  commonCluster.GetName(),
+		AuthInfo: contextName,
+	}
+	kubeConfig.CurrentContext = contextName
+
+	out, err := pipelineKubernetes.WriteKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Infof("Credential %s issued to user %s on cluster %s until %s", credential.Name, user.Login, commonCluster.GetName(), credential.ExpiresAt)
+
+	return out, credential, nil
+}
+
+// newUserCredential validates the request and fills in the defaults
+func newUserCredential(commonCluster CommonCluster, user *auth.User, request *pkgCluster.CreateUserConfigRequest) (*model.ClusterUserCredentialModel, error) {
+	credential := &model.ClusterUserCredentialModel{
+		ClusterID:      commonCluster.GetID(),
+		OrganizationID: commonCluster.GetOrganizationId(),
+		UserID:         user.ID,
+		Type:           request.Type,
+		ClusterRole:    request.ClusterRole,
+	}
+
+	if credential.Type == "" {
+		credential.Type = pkgCluster.UserCredentialServiceAccount
+	}
+	if credential.Type != pkgCluster.UserCredentialServiceAccount {
+		return nil, errors.Errorf("unsupported credential type: %s", credential.Type)
+	}
+	credential.Namespace = viper.GetString(config.UserCredentialsNamespace)
+
+	allowedClusterRoles, err := getAllowedClusterRoles(user, credential.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if credential.ClusterRole == "" {
+		credential.ClusterRole = viper.GetString(config.UserCredentialsClusterRole)
+		if !utils.Contains(allowedClusterRoles, credential.ClusterRole) && len(allowedClusterRoles) != 0 {
+			credential.ClusterRole = allowedClusterRoles[0]
+		}
+	}
+	if !utils.Contains(allowedClusterRoles, credential.ClusterRole) {
+		return nil, ErrClusterRoleNotAllowed
+	}
+
+	ttlParam := request.TTL
+	if ttlParam == "" {
+		ttlParam = viper.GetString(config.UserCredentialsTTL)
+	}
+	ttl, err := time.ParseDuration(ttlParam)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing ttl")
+	}
+	maxTTL := viper.GetDuration(config.UserCredentialsMaxTTL)
+	if ttl <= 0 || ttl > maxTTL {
+		return nil, errors.Errorf("ttl must be between 0 and %s", maxTTL)
+	}
+	credential.ExpiresAt = time.Now().Add(ttl).UTC()
+
+	suffix, err := secret.RandomString("randAlphaNum", 6)
+	if err != nil {
+		return nil, err
+	}
+	credential.Name = fmt.Sprintf("pipeline-user-%s-%s", userCredentialNamePart(user.Login), strings.ToLower(suffix))
+
+	return credential, nil
+}
+
+// getAllowedClusterRoles returns the ClusterRoles the organization role of the user allows to bind credentials to
+func getAllowedClusterRoles(user *auth.User, organizationID uint) ([]string, error) {
+	role, err := auth.GetUserOrganizationRole(user, organizationID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting organization role of the user")
+	}
+
+	return viper.GetStringMapStringSlice(config.UserCredentialsClusterRoles)[role], nil
+}
+
+// userCredentialNamePart converts the login name into a valid Kubernetes resource name part
+func userCredentialNamePart(login string) string {
+	name := strings.Trim(invalidNameCharacters.ReplaceAllString(strings.ToLower(login), "-"), "-")
+	if len(name) > 40 {
+		name = strings.TrimRight(name[:40], "-")
+	}
+	return name
+}
+
+func userCredentialObjectMeta(credential *model.ClusterUserCredentialModel, user *auth.User) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name: credential.Name,
+		Labels: map[string]string{
+			userCredentialManagedByLabel: "pipeline",
+			userCredentialUserLabel:      userCredentialNamePart(user.Login),
+		},
+		Annotations: map[string]string{
+			userCredentialExpiresAtKey: credential.ExpiresAt.Format(time.RFC3339),
+		},
+	}
+}
+
+// createServiceAccountToken creates a ServiceAccount for the credential and waits for its token to be populated
+func createServiceAccountToken(client *kubernetes.Clientset, credential *model.ClusterUserCredentialModel, user *auth.User) ([]byte, []byte, error) {
+	objectMeta := userCredentialObjectMeta(credential, user)
+	objectMeta.Namespace = credential.Namespace
+
+	_, err := client.CoreV1().ServiceAccounts(credential.Namespace).Create(&v1.ServiceAccount{ObjectMeta: objectMeta})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating service account")
+	}
+
+	for i := 0; i < userCredentialRetryCount; i++ {
+		serviceAccount, err := client.CoreV1().ServiceAccounts(credential.Namespace).Get(credential.Name, metav1.GetOptions{})
+		if err != nil {
+			deleteUserCredentialResources(client, credential)
+			return nil, nil, errors.Wrap(err, "error getting service account")
+		}
+
+		for _, ref := range serviceAccount.Secrets {
+			tokenSecret, err := client.CoreV1().Secrets(credential.Namespace).Get(ref.Name, metav1.GetOptions{})
+			if err != nil {
+				continue
+			}
+			if tokenSecret.Type == v1.SecretTypeServiceAccountToken && len(tokenSecret.Data[v1.ServiceAccountTokenKey]) != 0 {
+				return tokenSecret.Data[v1.ServiceAccountTokenKey], tokenSecret.Data[v1.ServiceAccountRootCAKey], nil
+			}
+		}
+
+		log.Debugf("Waiting for token of service account %s", credential.Name)
+		time.Sleep(userCredentialRetrySleep)
+	}
+
+	deleteUserCredentialResources(client, credential)
+	return nil, nil, errors.Errorf("timeout waiting for token of service account %s", credential.Name)
+}
+
+// deleteUserCredentialResources deletes the Kubernetes resources belonging to the credential
+func deleteUserCredentialResources(client *kubernetes.Clientset, credential *model.ClusterUserCredentialModel) error {
+	err := client.RbacV1().ClusterRoleBindings().Delete(credential.Name, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "error deleting cluster role binding")
+	}
+
+	if credential.Type == pkgCluster.UserCredentialServiceAccount {
+		// the token secret of the service account is garbage collected by Kubernetes
+		err = client.CoreV1().ServiceAccounts(credential.Namespace).Delete(credential.Name, &metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "error deleting service account")
+		}
+	}
+
+	return nil
+}
+
+// DeleteUserCredential revokes the credential on the cluster and deletes it from the database
+func DeleteUserCredential(credential *model.ClusterUserCredentialModel) error {
+	clusters, err := model.QueryCluster(map[string]interface{}{"id": credential.ClusterID})
+	if err != nil {
+		return err
+	}
+
+	// the cluster has been deleted along with the credential resources
+	if len(clusters) != 0 {
+		commonCluster, err := GetCommonClusterFromModel(&clusters[0])
+		if err != nil {
+			return err
+		}
+
+		kubeConfig, err := commonCluster.GetK8sConfig()
+		if err != nil {
+			return errors.Wrap(err, "error getting cluster config")
+		}
+
+		client, err := helm.GetK8sConnection(kubeConfig)
+		if err != nil {
+			return errors.Wrap(err, "error creating kubernetes client")
+		}
+
+		if err := deleteUserCredentialResources(client, credential); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Credential %s of user %d revoked", credential.Name, credential.UserID)
+
+	return credential.Delete()
+}
+
+// DeleteUserCredentials revokes all the credentials issued to the user in the organization
+func DeleteUserCredentials(organizationID, userID uint) {
+	credentials, err := model.QueryUserCredentials(organizationID, userID)
+	if err != nil {
+		log.Errorf("Error listing credentials of user %d: %s", userID, err.Error())
+		return
+	}
+
+	for i := range credentials {
+		if err := DeleteUserCredential(&credentials[i]); err != nil {
+			log.Errorf("Error revoking credential %s: %s", credentials[i].Name, err.Error())
+		}
+	}
+}
+
+// DeleteExpiredUserCredentials revokes all the expired credentials
+func DeleteExpiredUserCredentials() {
+	credentials, err := model.QueryExpiredUserCredentials(time.Now().UTC())
+	if err != nil {
+		log.Errorf("Error listing expired credentials: %s", err.Error())
+		return
+	}
+
+	for i := range credentials {
+		if err := DeleteUserCredential(&credentials[i]); err != nil {
+			log.Errorf("Error revoking expired credential %s: %s", credentials[i].Name, err.Error())
+		}
+	}
+}
+
+// StartUserCredentialsGarbageCollector periodically revokes the expired credentials
+func StartUserCredentialsGarbageCollector(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			log.Debug("User credentials garbage collector running")
+			DeleteExpiredUserCredentials()
+		}
+	}()
+
+	return ticker
+}
@@ -37,6 +37,313 @@ const (
 	// EksTemplateLocation is the configuration key the location to get EKS Cloud Formation templates from
 	// the location to get EKS Cloud Formation templates from
 	EksTemplateLocation = "eks.templateLocation"
+
+	// UserCredentialsClusterRole configuration key for the default ClusterRole bound to per-user cluster credentials
+	UserCredentialsClusterRole = "cluster.userCredentials.clusterRole"
+
+	// UserCredentialsClusterRoles configuration key for the ClusterRoles per-user cluster credentials can be bound to,
+	// by organization role
+	UserCredentialsClusterRoles = "cluster.userCredentials.clusterRoles"
+
+	// UserCredentialsNamespace configuration key for the K8s namespace the per-user ServiceAccounts are created in
+	UserCredentialsNamespace = "cluster.userCredentials.namespace"
+
+	// UserCredentialsTTL configuration key for the default lifetime of per-user cluster credentials
+	UserCredentialsTTL = "cluster.userCredentials.ttl"
+
+	// UserCredentialsMaxTTL configuration key for the maximum lifetime of per-user cluster credentials
+	UserCredentialsMaxTTL = "cluster.userCredentials.maxTtl"
+
+	// UserCredentialsGcIntervalMinute configuration key for the interval setting at which expired per-user
+	// cluster credentials are cleaned up
+	UserCredentialsGcIntervalMinute = "cluster.userCredentials.gcIntervalMinute"
+
+	// ProxyImpersonation configuration key for enabling user impersonation in the cluster API proxy
+	ProxyImpersonation = "cluster.proxy.impersonation"
+
+	// ProxyDefaultGroups configuration key for the default organization role to Kubernetes groups mapping
+	// used by the cluster API proxy when the organization has no mapping for the role
+	ProxyDefaultGroups = "cluster.proxy.defaultGroups"
+
+	// BackupObjectKeyPrefix configuration key for the object key prefix of cluster backups in the buckets
+	BackupObjectKeyPrefix = "cluster.backup.objectKeyPrefix"
+
+	// BackupScheduleIntervalMinute configuration key for the interval setting at which due backup schedules are run
+	BackupScheduleIntervalMinute = "cluster.backup.scheduleIntervalMinute"
+
+	// BackupMinScheduleInterval configuration key for the shortest allowed interval of backup schedules
+	BackupMinScheduleInterval = "cluster.backup.minScheduleInterval"
+
+	// NamespaceDefaultLabels configuration key for the labels added to the namespaces created through the API
+	NamespaceDefaultLabels = "cluster.namespace.defaultLabels"
+
+	// NamespaceDefaultAnnotations configuration key for the annotations added to the namespaces created through the API
+	NamespaceDefaultAnnotations = "cluster.namespace.defaultAnnotations"
+
+	// NodePoolLabelIntervalMinute configuration key for the interval setting at which node pool labels and taints
+	// are reapplied to the nodes of running clusters
+	NodePoolLabelIntervalMinute = "cluster.nodePools.labelIntervalMinute"
+
+	// UsageSampleIntervalMinute configuration key for the interval setting at which the resource summary
+	// of running clusters is sampled
+	UsageSampleIntervalMinute = "cluster.usage.sampleIntervalMinute"
+
+	// UsageDownsampleAfter configuration key for the age after which usage samples are downsampled
+	UsageDownsampleAfter = "cluster.usage.downsampleAfter"
+
+	// UsageDownsampleResolution configuration key for the period length of the downsampled usage samples
+	UsageDownsampleResolution = "cluster.usage.downsampleResolution"
+
+	// UsageRetention configuration key for the age after which usage samples are deleted
+	UsageRetention = "cluster.usage.retention"
+
+	// RecommendationHeadroom configuration key for the default extra capacity ratio kept over the requests
+	// of the pods when recommending node pool sizes
+	RecommendationHeadroom = "cluster.recommendation.headroom"
+
+	// RecommendationCloudInfoURL configuration key for the cloud info service listing the CPU and memory
+	// of the instance types considered when recommending node pool sizes
+	RecommendationCloudInfoURL = "cluster.recommendation.cloudInfoUrl"
+
+	// MonitorSDEnabled configuration key for discovering the clusters through the Prometheus HTTP service discovery
+	// endpoint, the Prometheus configuration isn't updated with the clusters when it's enabled. It's disabled by default,
+	// since it needs the Prometheus scrape config pointing to the discovery endpoint.
+	MonitorSDEnabled = "monitor.sd.enabled"
+
+	// MonitorSDAddress configuration key for the Pipeline address (host:port) put into the Prometheus
+	// service discovery targets, the host of the discovery request is used when it's empty
+	MonitorSDAddress = "monitor.sd.address"
+
+	// MonitorSDScheme configuration key for the scheme Prometheus uses to scrape the clusters through Pipeline,
+	// the scheme of the discovery request is used when it's empty
+	MonitorSDScheme = "monitor.sd.scheme"
+
+	// MonitorSDFederatePath configuration key for the path of the in-cluster Prometheus federation endpoint
+	// relative to the cluster API
+	MonitorSDFederatePath = "monitor.sd.federatePath"
+
+	// MonitorAlertingConfigMap configuration key for the name of the ConfigMap in the infra namespace managed by
+	// Pipeline the alert rules are synced into, it's mounted into the Prometheus server by the monitoring posthook
+	MonitorAlertingConfigMap = "monitor.alerting.configMap"
+
+	// MonitorAlertingRulesKey configuration key for the ConfigMap key of the rule file managed by Pipeline
+	MonitorAlertingRulesKey = "monitor.alerting.rulesKey"
+
+	// MonitorAlertingSyncIntervalMinute configuration key for the interval at which the alert rules are
+	// reconciled in the running clusters
+	MonitorAlertingSyncIntervalMinute = "monitor.alerting.syncIntervalMinute"
+
+	// MonitorAlertingPipelineURL configuration key for the Pipeline URL the Alertmanagers of the clusters
+	// send the alerts to, the receiver isn't configured when it's empty
+	MonitorAlertingPipelineURL = "monitor.alerting.pipelineUrl"
+
+	// NotifySecretExpiryCheckIntervalMinute configuration key for the interval of checking the certificates of the TLS secrets
+	NotifySecretExpiryCheckIntervalMinute = "notify.secretExpiry.checkIntervalMinute"
+
+	// NotifySecretExpiryThresholds configuration key for how long before the expiry of a certificate
+	// the secret.expiring events are sent, an event is sent once per threshold
+	NotifySecretExpiryThresholds = "notify.secretExpiry.thresholds"
+
+	// NotifyAllowPrivateNetworks configuration key for allowing the webhook URLs of the notification channels
+	// to point to loopback, private and link-local addresses
+	NotifyAllowPrivateNetworks = "notify.allowPrivateNetworks"
+
+	// WebhookDeliveryIntervalSecond configuration key for the interval of retrying the failed webhook deliveries
+	WebhookDeliveryIntervalSecond = "webhook.deliveryIntervalSecond"
+
+	// WebhookMaxAttempts configuration key for the number of attempts after which a webhook delivery fails
+	WebhookMaxAttempts = "webhook.maxAttempts"
+
+	// WebhookRetryBaseDelay configuration key for the delay after the first failed attempt, doubled after each attempt
+	WebhookRetryBaseDelay = "webhook.retryBaseDelay"
+
+	// WebhookRetryMaxDelay configuration key for the maximum delay between two attempts
+	WebhookRetryMaxDelay = "webhook.retryMaxDelay"
+
+	// WebhookTimeout configuration key for the timeout of the webhook requests
+	WebhookTimeout = "webhook.timeout"
+
+	// WebhookAllowPrivateNetworks configuration key for allowing the webhook URLs to point to loopback,
+	// private and link-local addresses, e.g. to the services of the internal network
+	WebhookAllowPrivateNetworks = "webhook.allowPrivateNetworks"
+
+	// WebhookDeliveryRetention configuration key for how long the finished webhook deliveries are kept
+	WebhookDeliveryRetention = "webhook.deliveryRetention"
+
+	// AuditRetentionMaxAge configuration key for how long the audit events are kept in the database, empty keeps them forever
+	AuditRetentionMaxAge = "audit.retention.maxAge"
+
+	// AuditRetentionMaxRows configuration key for the number of audit events kept in the database, 0 is unlimited
+	AuditRetentionMaxRows = "audit.retention.maxRows"
+
+	// AuditRetentionPruneIntervalMinute configuration key for the interval of pruning the audit events
+	AuditRetentionPruneIntervalMinute = "audit.retention.pruneIntervalMinute"
+
+	// AuditCheckpointIntervalMinute configuration key for the interval of storing signed checkpoints of the audit event chains
+	AuditCheckpointIntervalMinute = "audit.checkpoint.intervalMinute"
+
+	// AuditCheckpointKey configuration key for the key the checkpoints are signed with, checkpoints are not stored without it
+	AuditCheckpointKey = "audit.checkpoint.key"
+
+	// AuditCheckpointExportURL configuration key for the URL of the collector the checkpoints are exported to
+	AuditCheckpointExportURL = "audit.checkpoint.exportUrl"
+
+	// AuditCheckpointExportAuthorization configuration key for the Authorization header of the checkpoint export requests
+	AuditCheckpointExportAuthorization = "audit.checkpoint.exportAuthorization"
+
+	// AuditSinks configuration key for the sinks the audit events are sent to: db, file, syslog and http
+	AuditSinks = "audit.sinks"
+
+	// AuditSinkBufferSize configuration key for the number of audit events queued for each sink
+	AuditSinkBufferSize = "audit.sink.bufferSize"
+
+	// AuditSinkBatchSize configuration key for the maximum number of audit events written to a sink at once
+	AuditSinkBatchSize = "audit.sink.batchSize"
+
+	// AuditSinkFlushIntervalSecond configuration key for the interval of writing incomplete batches
+	AuditSinkFlushIntervalSecond = "audit.sink.flushIntervalSecond"
+
+	// AuditSinkOverflow configuration key for handling full sink queues: drop discards the events, block delays the requests
+	AuditSinkOverflow = "audit.sink.overflow"
+
+	// AuditFilePath configuration key for the path of the JSONL audit file
+	AuditFilePath = "audit.file.path"
+
+	// AuditFileMaxSizeMB configuration key for the size of the audit file at which it's rotated
+	AuditFileMaxSizeMB = "audit.file.maxSizeMB"
+
+	// AuditFileMaxBackups configuration key for the number of rotated audit files kept
+	AuditFileMaxBackups = "audit.file.maxBackups"
+
+	// AuditSyslogNetwork configuration key for the network of the syslog server: udp, tcp or unix
+	AuditSyslogNetwork = "audit.syslog.network"
+
+	// AuditSyslogAddress configuration key for the address of the syslog server
+	AuditSyslogAddress = "audit.syslog.address"
+
+	// AuditSyslogFacility configuration key for the numeric syslog facility of the audit events
+	AuditSyslogFacility = "audit.syslog.facility"
+
+	// AuditSyslogAppName configuration key for the APP-NAME of the syslog messages
+	AuditSyslogAppName = "audit.syslog.appName"
+
+	// AuditHTTPURL configuration key for the URL of the collector the audit events are posted to
+	AuditHTTPURL = "audit.http.url"
+
+	// AuditHTTPAuthorization configuration key for the Authorization header of the collector requests
+	AuditHTTPAuthorization = "audit.http.authorization"
+
+	// AuditHTTPTimeout configuration key for the timeout of the collector requests
+	AuditHTTPTimeout = "audit.http.timeout"
+
+	// InvitationExpiry configuration key for the lifetime of the organization member invitations
+	InvitationExpiry = "auth.invitation.expiry"
+
+	// GitlabEnabled configuration key for enabling the GitLab login provider
+	GitlabEnabled = "auth.gitlab.enabled"
+
+	// GitlabBaseURL configuration key for the URL of the GitLab instance, e.g. a self-hosted one
+	GitlabBaseURL = "auth.gitlab.baseURL"
+
+	// GitlabClientID configuration key for the application ID registered in GitLab
+	GitlabClientID = "auth.gitlab.clientID"
+
+	// GitlabClientSecret configuration key for the application secret registered in GitLab
+	GitlabClientSecret = "auth.gitlab.clientSecret"
+
+	// GitlabScopes configuration key for the scopes requested from GitLab
+	GitlabScopes = "auth.gitlab.scopes"
+
+	// OIDCEnabled configuration key for enabling the OpenID Connect login provider
+	OIDCEnabled = "auth.oidc.enabled"
+
+	// OIDCIssuerURL configuration key for the issuer of the OpenID Connect provider,
+	// its endpoints are discovered from /.well-known/openid-configuration under this URL
+	OIDCIssuerURL = "auth.oidc.issuerURL"
+
+	// OIDCClientID configuration key for the client ID registered at the OpenID Connect provider
+	OIDCClientID = "auth.oidc.clientID"
+
+	// OIDCClientSecret configuration key for the client secret registered at the OpenID Connect provider
+	OIDCClientSecret = "auth.oidc.clientSecret"
+
+	// OIDCScopes configuration key for the scopes requested from the OpenID Connect provider
+	OIDCScopes = "auth.oidc.scopes"
+
+	// OIDCLoginClaim configuration key for the claim used as the login of the users
+	OIDCLoginClaim = "auth.oidc.loginClaim"
+
+	// OIDCEmailClaim configuration key for the claim used as the email of the users
+	OIDCEmailClaim = "auth.oidc.emailClaim"
+
+	// OIDCNameClaim configuration key for the claim used as the name of the users
+	OIDCNameClaim = "auth.oidc.nameClaim"
+
+	// OIDCGroupsClaim configuration key for the claim listing the groups of the users, empty disables the organization import
+	OIDCGroupsClaim = "auth.oidc.groupsClaim"
+
+	// OIDCGroupPrefix configuration key for the prefix of the groups imported as organizations
+	OIDCGroupPrefix = "auth.oidc.groupPrefix"
+
+	// OIDCAdminGroupSuffix configuration key for the suffix of the groups granting the admin role in their organization
+	OIDCAdminGroupSuffix = "auth.oidc.adminGroupSuffix"
+
+	// LDAPEnabled configuration key for enabling the LDAP username and password login provider
+	LDAPEnabled = "auth.ldap.enabled"
+
+	// LDAPURL configuration key for the URL of the LDAP server, ldap://host:389 or ldaps://host:636
+	LDAPURL = "auth.ldap.url"
+
+	// LDAPStartTLS configuration key for upgrading ldap:// connections with StartTLS
+	LDAPStartTLS = "auth.ldap.startTLS"
+
+	// LDAPInsecureSkipVerify configuration key for skipping the verification of the LDAP server certificate
+	LDAPInsecureSkipVerify = "auth.ldap.insecureSkipVerify"
+
+	// LDAPCAFile configuration key for the PEM file of the CA certificates the LDAP server certificate is verified with
+	LDAPCAFile = "auth.ldap.caFile"
+
+	// LDAPTimeout configuration key for the timeout of the LDAP operations
+	LDAPTimeout = "auth.ldap.timeout"
+
+	// LDAPBindDN configuration key for the DN of the service account searching the users and groups, empty searches anonymously
+	LDAPBindDN = "auth.ldap.bindDN"
+
+	// LDAPBindPassword configuration key for the password of the service account
+	LDAPBindPassword = "auth.ldap.bindPassword"
+
+	// LDAPUserBaseDN configuration key for the base DN of the user search
+	LDAPUserBaseDN = "auth.ldap.userBaseDN"
+
+	// LDAPUserFilter configuration key for the filter of the user search, %s is replaced by the escaped login
+	LDAPUserFilter = "auth.ldap.userFilter"
+
+	// LDAPLoginAttribute configuration key for the attribute used as the login of the users
+	LDAPLoginAttribute = "auth.ldap.loginAttribute"
+
+	// LDAPEmailAttribute configuration key for the attribute used as the email of the users
+	LDAPEmailAttribute = "auth.ldap.emailAttribute"
+
+	// LDAPNameAttribute configuration key for the attribute used as the name of the users
+	LDAPNameAttribute = "auth.ldap.nameAttribute"
+
+	// LDAPGroupBaseDN configuration key for the base DN of the group search, empty disables the organization import
+	LDAPGroupBaseDN = "auth.ldap.groupBaseDN"
+
+	// LDAPGroupFilter configuration key for the filter of the group search, %s is replaced by the escaped DN of the user
+	LDAPGroupFilter = "auth.ldap.groupFilter"
+
+	// LDAPGroupNameAttribute configuration key for the attribute used as the name of the groups
+	LDAPGroupNameAttribute = "auth.ldap.groupNameAttribute"
+
+	// LDAPGroupPrefix configuration key for the prefix of the groups imported as organizations
+	LDAPGroupPrefix = "auth.ldap.groupPrefix"
+
+	// LDAPAdminGroupSuffix configuration key for the suffix of the groups granting the admin role in their organization
+	LDAPAdminGroupSuffix = "auth.ldap.adminGroupSuffix"
+
+	// LDAPGroupSyncIntervalMinute configuration key for the interval of synchronizing the organizations of the LDAP users with their groups
+	LDAPGroupSyncIntervalMinute = "auth.ldap.groupSyncIntervalMinute"
 )
 
 //Init initializes the configurations
@@ -73,6 +380,42 @@ func init() {
 
 	viper.SetDefault("auth.jwtissuer", "https://banzaicloud.com/")
 	viper.SetDefault("auth.jwtaudience", "https://pipeline.banzaicloud.com")
+	viper.SetDefault(InvitationExpiry, "168h")
+	viper.SetDefault(GitlabEnabled, false)
+	viper.SetDefault(GitlabBaseURL, "https://gitlab.com")
+	viper.SetDefault(GitlabClientID, "")
+	viper.SetDefault(GitlabClientSecret, "")
+	viper.SetDefault(GitlabScopes, []string{"read_user", "read_api"})
+	viper.SetDefault(OIDCEnabled, false)
+	viper.SetDefault(OIDCIssuerURL, "")
+	viper.SetDefault(OIDCClientID, "")
+	viper.SetDefault(OIDCClientSecret, "")
+	viper.SetDefault(OIDCScopes, []string{"openid", "profile", "email", "groups"})
+	viper.SetDefault(OIDCLoginClaim, "preferred_username")
+	viper.SetDefault(OIDCEmailClaim, "email")
+	viper.SetDefault(OIDCNameClaim, "name")
+	viper.SetDefault(OIDCGroupsClaim, "groups")
+	viper.SetDefault(OIDCGroupPrefix, "")
+	viper.SetDefault(OIDCAdminGroupSuffix, ":admin")
+	viper.SetDefault(LDAPEnabled, false)
+	viper.SetDefault(LDAPURL, "")
+	viper.SetDefault(LDAPStartTLS, false)
+	viper.SetDefault(LDAPInsecureSkipVerify, false)
+	viper.SetDefault(LDAPCAFile, "")
+	viper.SetDefault(LDAPTimeout, "10s")
+	viper.SetDefault(LDAPBindDN, "")
+	viper.SetDefault(LDAPBindPassword, "")
+	viper.SetDefault(LDAPUserBaseDN, "")
+	viper.SetDefault(LDAPUserFilter, "(uid=%s)")
+	viper.SetDefault(LDAPLoginAttribute, "uid")
+	viper.SetDefault(LDAPEmailAttribute, "mail")
+	viper.SetDefault(LDAPNameAttribute, "cn")
+	viper.SetDefault(LDAPGroupBaseDN, "")
+	viper.SetDefault(LDAPGroupFilter, "(member=%s)")
+	viper.SetDefault(LDAPGroupNameAttribute, "cn")
+	viper.SetDefault(LDAPGroupPrefix, "")
+	viper.SetDefault(LDAPAdminGroupSuffix, "-admins")
+	viper.SetDefault(LDAPGroupSyncIntervalMinute, 15)
 
 	viper.SetDefault("pipeline.listenport", 9090)
 	viper.SetDefault("pipeline.certfile", "")
@@ -92,6 +435,28 @@ func init() {
 	viper.SetDefault("audit.enabled", true)
 	viper.SetDefault("audit.headers", []string{"secretId"})
 	viper.SetDefault("audit.skippaths", []string{"/auth/github/callback", "/pipeline/api"})
+	viper.SetDefault(AuditRetentionMaxAge, "")
+	viper.SetDefault(AuditRetentionMaxRows, 0)
+	viper.SetDefault(AuditRetentionPruneIntervalMinute, 60)
+	viper.SetDefault(AuditCheckpointIntervalMinute, 60)
+	viper.SetDefault(AuditCheckpointKey, "")
+	viper.SetDefault(AuditCheckpointExportURL, "")
+	viper.SetDefault(AuditCheckpointExportAuthorization, "")
+	viper.SetDefault(AuditSinks, []string{"db"})
+	viper.SetDefault(AuditSinkBufferSize, 10000)
+	viper.SetDefault(AuditSinkBatchSize, 100)
+	viper.SetDefault(AuditSinkFlushIntervalSecond, 5)
+	viper.SetDefault(AuditSinkOverflow, "drop")
+	viper.SetDefault(AuditFilePath, "audit.jsonl")
+	viper.SetDefault(AuditFileMaxSizeMB, 100)
+	viper.SetDefault(AuditFileMaxBackups, 10)
+	viper.SetDefault(AuditSyslogNetwork, "udp")
+	viper.SetDefault(AuditSyslogAddress, "localhost:514")
+	viper.SetDefault(AuditSyslogFacility, 16)
+	viper.SetDefault(AuditSyslogAppName, "pipeline")
+	viper.SetDefault(AuditHTTPURL, "")
+	viper.SetDefault(AuditHTTPAuthorization, "")
+	viper.SetDefault(AuditHTTPTimeout, "10s")
 	viper.SetDefault("tls.validity", "8760h") // 1 year
 	viper.SetDefault(DNSBaseDomain, "banzaicloud.io")
 	viper.SetDefault(DNSSecretNamespace, "pipeline-infra")
@@ -110,6 +475,54 @@ func init() {
 
 	viper.SetDefault(PipelineMonitorNamespace, "pipeline-infra")
 	viper.SetDefault(EksTemplateLocation, filepath.Join(pwd, "templates", "eks"))
+	viper.SetDefault(UserCredentialsClusterRole, "edit")
+	viper.SetDefault(UserCredentialsClusterRoles, map[string][]string{
+		"admin":  {"cluster-admin", "admin", "edit", "view"},
+		"member": {"edit", "view"},
+		"viewer": {"view"},
+	})
+	viper.SetDefault(UserCredentialsNamespace, "pipeline-users")
+	viper.SetDefault(UserCredentialsTTL, "8h")
+	viper.SetDefault(UserCredentialsMaxTTL, "24h")
+	viper.SetDefault(UserCredentialsGcIntervalMinute, 5)
+	viper.SetDefault(ProxyImpersonation, true)
+	viper.SetDefault(ProxyDefaultGroups, map[string][]string{
+		"admin":  {"system:masters"},
+		"member": {"pipeline:member"},
+		"viewer": {"pipeline:viewer"},
+	})
+	viper.SetDefault(BackupObjectKeyPrefix, "pipeline-backups")
+	viper.SetDefault(BackupScheduleIntervalMinute, 1)
+	viper.SetDefault(BackupMinScheduleInterval, "1h")
+	viper.SetDefault(NamespaceDefaultLabels, map[string]string{
+		"app.kubernetes.io/managed-by": "pipeline",
+	})
+	viper.SetDefault(NamespaceDefaultAnnotations, map[string]string{})
+	viper.SetDefault(NodePoolLabelIntervalMinute, 5)
+	viper.SetDefault(UsageSampleIntervalMinute, 5)
+	viper.SetDefault(UsageDownsampleAfter, "168h")
+	viper.SetDefault(UsageDownsampleResolution, "1h")
+	viper.SetDefault(UsageRetention, "2160h")
+	viper.SetDefault(RecommendationHeadroom, 0.2)
+	viper.SetDefault(RecommendationCloudInfoURL, "https://beta.banzaicloud.io/cloudinfo/api/v1")
+	viper.SetDefault(MonitorSDEnabled, false)
+	viper.SetDefault(MonitorSDAddress, "")
+	viper.SetDefault(MonitorSDScheme, "")
+	viper.SetDefault(MonitorSDFederatePath, "/api/v1/namespaces/default/services/monitor-prometheus-server:80/proxy/prometheus/federate")
+	viper.SetDefault(MonitorAlertingConfigMap, "pipeline-alert-rules")
+	viper.SetDefault(MonitorAlertingRulesKey, "pipeline.rules")
+	viper.SetDefault(MonitorAlertingSyncIntervalMinute, 10)
+	viper.SetDefault(MonitorAlertingPipelineURL, "")
+	viper.SetDefault(NotifySecretExpiryCheckIntervalMinute, 1440)
+	viper.SetDefault(NotifySecretExpiryThresholds, []string{"336h", "72h", "24h"})
+	viper.SetDefault(NotifyAllowPrivateNetworks, false)
+	viper.SetDefault(WebhookDeliveryIntervalSecond, 10)
+	viper.SetDefault(WebhookMaxAttempts, 8)
+	viper.SetDefault(WebhookRetryBaseDelay, "30s")
+	viper.SetDefault(WebhookRetryMaxDelay, "1h")
+	viper.SetDefault(WebhookTimeout, "10s")
+	viper.SetDefault(WebhookAllowPrivateNetworks, false)
+	viper.SetDefault(WebhookDeliveryRetention, "720h")
 
 	// Find and read the config file
 	if err := viper.ReadInConfig(); err != nil {
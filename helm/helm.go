@@ -25,6 +25,7 @@ import (
 	"k8s.io/helm/pkg/helm"
 	helm_env "k8s.io/helm/pkg/helm/environment"
 	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
 	rls "k8s.io/helm/pkg/proto/hapi/services"
 	"k8s.io/helm/pkg/repo"
 	"time"
@@ -152,6 +153,15 @@ func ListDeployments(filter *string, kubeConfig []byte) (*rls.ListReleasesRespon
 	return resp, nil
 }
 
+// ListFailedDeployments lists the Helm deployments with failed status
+func ListFailedDeployments(kubeConfig []byte) (*rls.ListReleasesResponse, error) {
+	hClient, err := GetHelmClient(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return hClient.ListReleases(helm.ReleaseListStatuses([]release.Status_Code{release.Status_FAILED}))
+}
+
 //UpgradeDeployment upgrades a Helm deployment
 func UpgradeDeployment(releaseName, chartName, chartVersion string, values []byte, reuseValues bool, kubeConfig []byte, env helm_env.EnvSettings) (*rls.UpdateReleaseResponse, error) {
 	//Map chartName as
@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// MergeKubeConfigs merges the given kubeconfigs into a single kubeconfig.
+// Each kubeconfig contributes its current context (together with the referenced cluster and user)
+// under the name used as the map key. If namespace is not empty it's set as the default namespace of every context.
+func MergeKubeConfigs(kubeConfigs map[string][]byte, namespace string) ([]byte, error) {
+	merged := clientcmdapi.NewConfig()
+
+	names := make([]string, 0, len(kubeConfigs))
+	for name := range kubeConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		config, err := clientcmd.Load(kubeConfigs[name])
+		if err != nil {
+			return nil, errors.Wrapf(err, "error loading kubeconfig of %q", name)
+		}
+
+		context, err := getCurrentContext(config)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid kubeconfig of %q", name)
+		}
+
+		cluster, ok := config.Clusters[context.Cluster]
+		if !ok {
+			return nil, errors.Errorf("cluster %q of kubeconfig %q not found", context.Cluster, name)
+		}
+
+		authInfo, ok := config.AuthInfos[context.AuthInfo]
+		if !ok {
+			return nil, errors.Errorf("user %q of kubeconfig %q not found", context.AuthInfo, name)
+		}
+
+		mergedContext := clientcmdapi.NewContext()
+		mergedContext.Cluster = name
+		mergedContext.AuthInfo = name
+		mergedContext.Namespace = context.Namespace
+		if namespace != "" {
+			mergedContext.Namespace = namespace
+		}
+
+		merged.Clusters[name] = cluster
+		merged.AuthInfos[name] = authInfo
+		merged.Contexts[name] = mergedContext
+
+		if merged.CurrentContext == "" {
+			merged.CurrentContext = name
+		}
+	}
+
+	return WriteKubeConfig(merged)
+}
+
+// WriteKubeConfig serializes the given config into kubeconfig YAML format
+func WriteKubeConfig(config *clientcmdapi.Config) ([]byte, error) {
+	var out clientcmdapiv1.Config
+	if err := clientcmdlatest.Scheme.Convert(config, &out, nil); err != nil {
+		return nil, errors.Wrap(err, "error converting kubeconfig")
+	}
+	out.APIVersion = clientcmdlatest.Version
+	out.Kind = "Config"
+
+	return yaml.Marshal(out)
+}
+
+// getCurrentContext returns the current context of the config or the only context if current context is not set
+func getCurrentContext(config *clientcmdapi.Config) (*clientcmdapi.Context, error) {
+	if context, ok := config.Contexts[config.CurrentContext]; ok {
+		return context, nil
+	}
+
+	if config.CurrentContext == "" && len(config.Contexts) == 1 {
+		for _, context := range config.Contexts {
+			return context, nil
+		}
+	}
+
+	return nil, errors.New("current context not found")
+}
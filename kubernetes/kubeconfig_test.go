@@ -0,0 +1,86 @@
+package kubernetes_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/banzaicloud/pipeline/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: %[1]s
+  cluster:
+    server: https://%[1]s.example.com
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s
+    namespace: kube-system
+current-context: %[1]s
+users:
+- name: %[1]s
+  user:
+    token: %[1]s-token
+`
+
+func TestMergeKubeConfigs(t *testing.T) {
+	cases := []struct {
+		name              string
+		namespace         string
+		expectedNamespace string
+	}{
+		{name: "keep original namespace", namespace: "", expectedNamespace: "kube-system"},
+		{name: "override namespace", namespace: "default", expectedNamespace: "default"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			merged, err := kubernetes.MergeKubeConfigs(map[string][]byte{
+				"org/cluster-b": []byte(fmt.Sprintf(testKubeConfig, "admin-b")),
+				"org/cluster-a": []byte(fmt.Sprintf(testKubeConfig, "admin-a")),
+			}, tc.namespace)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			config, err := clientcmd.Load(merged)
+			if err != nil {
+				t.Fatalf("merged kubeconfig is invalid: %s", err.Error())
+			}
+
+			if config.CurrentContext != "org/cluster-a" {
+				t.Errorf("expected current context org/cluster-a, got %q", config.CurrentContext)
+			}
+
+			for name, server := range map[string]string{
+				"org/cluster-a": "https://admin-a.example.com",
+				"org/cluster-b": "https://admin-b.example.com",
+			} {
+				context, ok := config.Contexts[name]
+				if !ok {
+					t.Fatalf("context %q not found", name)
+				}
+				if context.Namespace != tc.expectedNamespace {
+					t.Errorf("expected namespace %q, got %q", tc.expectedNamespace, context.Namespace)
+				}
+				if config.Clusters[context.Cluster].Server != server {
+					t.Errorf("expected server %q, got %q", server, config.Clusters[context.Cluster].Server)
+				}
+				if config.AuthInfos[context.AuthInfo] == nil {
+					t.Errorf("user of context %q not found", name)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeKubeConfigsInvalid(t *testing.T) {
+	_, err := kubernetes.MergeKubeConfigs(map[string][]byte{"org/cluster": []byte("apiVersion: v1\nkind: Config\n")}, "")
+	if err == nil {
+		t.Error("expected error for kubeconfig without context")
+	}
+}
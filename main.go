@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"net/http"
 
@@ -10,6 +14,8 @@ import (
 	"github.com/banzaicloud/pipeline/api"
 	"github.com/banzaicloud/pipeline/audit"
 	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/backup"
+	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/config"
 	"github.com/banzaicloud/pipeline/database"
 	"github.com/banzaicloud/pipeline/dns"
@@ -18,6 +24,7 @@ import (
 	"github.com/banzaicloud/pipeline/model/defaults"
 	"github.com/banzaicloud/pipeline/notify"
 	"github.com/banzaicloud/pipeline/objectstore"
+	"github.com/banzaicloud/pipeline/webhook"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -89,11 +96,18 @@ func main() {
 		&model.KubernetesClusterModel{},
 		&model.Deployment{},
 		&model.Application{},
+		&model.ClusterUserCredentialModel{},
+		&model.KubernetesGroupMappingModel{},
 		&auth.AuthIdentity{},
 		&auth.User{},
 		&auth.UserOrganization{},
 		&auth.Organization{},
+		&auth.APIToken{},
+		&auth.TokenPolicy{},
+		&auth.Invitation{},
 		&audit.AuditEvent{},
+		&audit.ChainHead{},
+		&audit.Checkpoint{},
 		&defaults.AWSProfile{},
 		&defaults.AWSNodePoolProfile{},
 		&defaults.EKSProfile{},
@@ -105,16 +119,58 @@ func main() {
 		&objectstore.ManagedAzureBlobStore{},
 		&objectstore.ManagedGoogleBucket{},
 		&route53model.Route53Domain{},
+		&backup.ClusterBackupModel{},
+		&backup.ClusterBackupScheduleModel{},
+		&backup.ClusterBackupRestoreModel{},
+		&model.ClusterUsageSampleModel{},
+		&model.ClusterUsageSamplerModel{},
+		&model.AlertRuleGroupModel{},
+		&notify.ChannelModel{},
+		&notify.SubscriptionModel{},
+		&notify.SecretExpiryModel{},
+		&webhook.WebhookModel{},
+		&webhook.DeliveryModel{},
 	).Error; err != nil {
 
 		panic(err)
 	}
 
+	if err := defaults.MigrateOrganizationKeys(db); err != nil {
+		panic(err)
+	}
+
 	modelOracle.Init(logger)
 	modelOracleObjectstore.Init(logger)
 
 	defaults.SetDefaultValues()
 
+	// Revoke expired per-user cluster credentials, and the ones of users removed or demoted by a sync
+	auth.RevokeUserCredentials = cluster.DeleteUserCredentials
+	cluster.StartUserCredentialsGarbageCollector(time.Duration(viper.GetInt(config.UserCredentialsGcIntervalMinute)) * time.Minute)
+
+	// Run the due cluster backup schedules
+	backup.StartScheduler(time.Duration(viper.GetInt(config.BackupScheduleIntervalMinute)) * time.Minute)
+
+	// Reapply node pool labels and taints to the nodes of running clusters
+	cluster.StartNodePoolLabeler(time.Duration(viper.GetInt(config.NodePoolLabelIntervalMinute)) * time.Minute)
+
+	// Reconcile the alert rules of running clusters
+	cluster.StartAlertRuleReconciler(time.Duration(viper.GetInt(config.MonitorAlertingSyncIntervalMinute)) * time.Minute)
+
+	// Sample the resource usage of running clusters
+	cluster.StartUsageSampler(time.Duration(viper.GetInt(config.UsageSampleIntervalMinute)) * time.Minute)
+
+	// Notify the organizations about expiring TLS secrets
+	notify.StartSecretExpiryCheck(time.Duration(viper.GetInt(config.NotifySecretExpiryCheckIntervalMinute)) * time.Minute)
+
+	// Retry the failed webhook deliveries
+	webhook.StartDeliveryWorker(time.Duration(viper.GetInt(config.WebhookDeliveryIntervalSecond)) * time.Second)
+
+	// Synchronize the organizations of the LDAP users with their groups
+	if viper.GetBool(config.LDAPEnabled) {
+		auth.StartLDAPGroupSync(time.Duration(viper.GetInt(config.LDAPGroupSyncIntervalMinute)) * time.Minute)
+	}
+
 	// External DNS service
 	dnsSvc, err := dns.GetExternalDnsServiceClient()
 	if err != nil {
@@ -134,9 +190,20 @@ func main() {
 	router.Use(gin.LoggerWithWriter(gin.DefaultWriter, skipPaths...))
 	router.Use(gin.Recovery())
 	router.Use(cors.New(config.GetCORS()))
+	var auditDispatcher *audit.Dispatcher
 	if viper.GetBool("audit.enabled") {
 		log.Infoln("Audit enabled, installing Gin audit middleware")
-		router.Use(audit.LogWriter(skipPaths, viper.GetStringSlice("audit.headers")))
+		auditDispatcher, err = audit.NewDispatcherFromConfig()
+		if err != nil {
+			log.Fatalf("Error creating audit sinks: %s", err.Error())
+		}
+		router.Use(audit.LogWriter(skipPaths, viper.GetStringSlice("audit.headers"), auditDispatcher))
+		audit.StartRetentionPruner(time.Duration(viper.GetInt(config.AuditRetentionPruneIntervalMinute)) * time.Minute)
+		if len(viper.GetString(config.AuditCheckpointKey)) != 0 {
+			audit.StartCheckpointer(time.Duration(viper.GetInt(config.AuditCheckpointIntervalMinute)) * time.Minute)
+		} else {
+			log.Warnln("Audit checkpoint key is not configured, the audit event chains are not signed")
+		}
 	}
 
 	root := router.Group("/")
@@ -178,9 +245,62 @@ func main() {
 			orgs.POST("/:orgid/clusters", api.CreateClusterRequest)
 			//v1.GET("/status", api.Status)
 			orgs.GET("/:orgid/clusters", api.FetchClusters)
+			orgs.GET("/:orgid/kubeconfig", api.GetOrganizationKubeConfig)
+			orgs.GET("/:orgid/prometheus/targets", api.GetPrometheusTargets)
+			orgs.GET("/:orgid/alertrules", api.ListAlertRuleGroups)
+			orgs.POST("/:orgid/alertrules", api.CreateAlertRuleGroup)
+			orgs.GET("/:orgid/alertrules/:name", api.GetAlertRuleGroup)
+			orgs.PUT("/:orgid/alertrules/:name", api.UpdateAlertRuleGroup)
+			orgs.DELETE("/:orgid/alertrules/:name", api.DeleteAlertRuleGroup)
+			orgs.GET("/:orgid/notifications/channels", api.ListNotificationChannels)
+			orgs.POST("/:orgid/notifications/channels", api.CreateNotificationChannel)
+			orgs.GET("/:orgid/notifications/channels/:channelid", api.GetNotificationChannel)
+			orgs.PUT("/:orgid/notifications/channels/:channelid", api.UpdateNotificationChannel)
+			orgs.DELETE("/:orgid/notifications/channels/:channelid", api.DeleteNotificationChannel)
+			orgs.POST("/:orgid/notifications/channels/:channelid/test", api.TestNotificationChannel)
+			orgs.GET("/:orgid/notifications/subscriptions", api.ListNotificationSubscriptions)
+			orgs.POST("/:orgid/notifications/subscriptions", api.CreateNotificationSubscription)
+			orgs.DELETE("/:orgid/notifications/subscriptions/:subscriptionid", api.DeleteNotificationSubscription)
+			orgs.GET("/:orgid/webhooks", api.ListWebhooks)
+			orgs.POST("/:orgid/webhooks", api.CreateWebhook)
+			orgs.GET("/:orgid/webhooks/:webhookid", api.GetWebhook)
+			orgs.PUT("/:orgid/webhooks/:webhookid", api.UpdateWebhook)
+			orgs.DELETE("/:orgid/webhooks/:webhookid", api.DeleteWebhook)
+			orgs.POST("/:orgid/webhooks/:webhookid/ping", api.PingWebhook)
+			orgs.GET("/:orgid/webhooks/:webhookid/deliveries", api.ListWebhookDeliveries)
+			orgs.GET("/:orgid/webhooks/:webhookid/deliveries/:deliveryid", api.GetWebhookDelivery)
+			orgs.POST("/:orgid/webhooks/:webhookid/deliveries/:deliveryid/redeliver", api.RedeliverWebhookDelivery)
 			orgs.GET("/:orgid/clusters/:id", api.GetClusterStatus)
 			orgs.GET("/:orgid/clusters/:id/details", api.GetClusterDetails)
+			orgs.GET("/:orgid/clusters/:id/health", api.GetClusterHealth)
+			orgs.GET("/:orgid/clusters/:id/autoscaler", api.GetClusterAutoscalerStatus)
+			orgs.GET("/:orgid/clusters/:id/usage", api.GetClusterUsage)
+			orgs.GET("/:orgid/clusters/:id/recommendations", api.GetClusterRecommendations)
+			orgs.GET("/:orgid/clusters/:id/alertrules", api.ListAlertRuleGroups)
+			orgs.POST("/:orgid/clusters/:id/alertrules", api.CreateAlertRuleGroup)
+			orgs.GET("/:orgid/clusters/:id/alertrules/:name", api.GetAlertRuleGroup)
+			orgs.PUT("/:orgid/clusters/:id/alertrules/:name", api.UpdateAlertRuleGroup)
+			orgs.DELETE("/:orgid/clusters/:id/alertrules/:name", api.DeleteAlertRuleGroup)
+			orgs.POST("/:orgid/clusters/:id/alerts", api.ReceiveClusterAlerts)
+			orgs.POST("/:orgid/clusters/:id/backups", api.CreateClusterBackup)
+			orgs.GET("/:orgid/clusters/:id/backups", api.ListClusterBackups)
+			orgs.GET("/:orgid/clusters/:id/backups/:backupid", api.GetClusterBackup)
+			orgs.DELETE("/:orgid/clusters/:id/backups/:backupid", api.DeleteClusterBackup)
+			orgs.POST("/:orgid/clusters/:id/backups/:backupid/restore", api.RestoreClusterBackup)
+			orgs.GET("/:orgid/clusters/:id/restores", api.ListClusterRestores)
+			orgs.POST("/:orgid/clusters/:id/backupschedules", api.CreateClusterBackupSchedule)
+			orgs.GET("/:orgid/clusters/:id/backupschedules", api.ListClusterBackupSchedules)
+			orgs.DELETE("/:orgid/clusters/:id/backupschedules/:scheduleid", api.DeleteClusterBackupSchedule)
+			orgs.GET("/:orgid/backups", api.ListBackups)
+			orgs.GET("/:orgid/backups/:backupid", api.GetBackup)
+			orgs.POST("/:orgid/backups/:backupid/restore", api.RestoreBackup)
 			orgs.GET("/:orgid/clusters/:id/pods", api.GetPodDetails)
+			orgs.GET("/:orgid/clusters/:id/namespaces", api.ListNamespaces)
+			orgs.POST("/:orgid/clusters/:id/namespaces", api.CreateNamespace)
+			orgs.GET("/:orgid/clusters/:id/namespaces/:namespace", api.GetNamespace)
+			orgs.PUT("/:orgid/clusters/:id/namespaces/:namespace", api.UpdateNamespace)
+			orgs.DELETE("/:orgid/clusters/:id/namespaces/:namespace", api.DeleteNamespace)
+			orgs.GET("/:orgid/clusters/:id/namespaces/:namespace/pods/:pod/logs", api.GetPodLogs)
 			orgs.GET("/:orgid/clusters/:id/application", api.GetApplicationsByCluster)
 			orgs.PUT("/:orgid/clusters/:id", api.UpdateCluster)
 			orgs.PUT("/:orgid/clusters/:id/posthooks", api.ReRunPostHooks)
@@ -189,6 +309,7 @@ func main() {
 			orgs.DELETE("/:orgid/clusters/:id", api.DeleteCluster)
 			orgs.HEAD("/:orgid/clusters/:id", api.ClusterHEAD)
 			orgs.GET("/:orgid/clusters/:id/config", api.GetClusterConfig)
+			orgs.POST("/:orgid/clusters/:id/userconfig", api.CreateUserClusterConfig)
 			orgs.GET("/:orgid/clusters/:id/apiendpoint", api.GetApiEndpoint)
 			orgs.GET("/:orgid/clusters/:id/nodes", api.GetClusterNodes)
 			orgs.POST("/:orgid/clusters/:id/monitoring", api.UpdateMonitoring)
@@ -218,10 +339,22 @@ func main() {
 			orgs.PUT("/:orgid/secrets/:id", api.UpdateSecrets)
 			orgs.DELETE("/:orgid/secrets/:id", api.DeleteSecrets)
 			orgs.GET("/:orgid/secrets/:id/validate", api.ValidateSecret)
+			orgs.GET("/:orgid/kubernetes/groupmappings", api.GetKubernetesGroupMappings)
+			orgs.PUT("/:orgid/kubernetes/groupmappings", api.SetKubernetesGroupMappings)
+			orgs.GET("/:orgid/audit", api.ListAuditEvents)
+			orgs.GET("/:orgid/audit/verify", api.VerifyAuditChain)
+			orgs.GET("/:orgid/tokenpolicy", api.GetTokenPolicy)
+			orgs.PUT("/:orgid/tokenpolicy", api.SetTokenPolicy)
 			orgs.GET("/:orgid/users", api.GetUsers)
 			orgs.GET("/:orgid/users/:id", api.GetUsers)
 			orgs.POST("/:orgid/users/:id", api.AddUser)
 			orgs.DELETE("/:orgid/users/:id", api.RemoveUser)
+			orgs.GET("/:orgid/invitations", api.ListInvitations)
+			orgs.POST("/:orgid/invitations", api.CreateInvitation)
+			orgs.DELETE("/:orgid/invitations/:id", api.RevokeInvitation)
+			orgs.GET("/:orgid/roles", api.ListRoles)
+			orgs.PUT("/:orgid/roles/:role", api.SetRole)
+			orgs.DELETE("/:orgid/roles/:role", api.DeleteRole)
 
 			orgs.GET("/:orgid/buckets", api.ListObjectStoreBuckets)
 			orgs.POST("/:orgid/buckets", api.CreateObjectStoreBuckets)
@@ -241,6 +374,7 @@ func main() {
 		v1.GET("/tokens", auth.GetTokens)
 		v1.GET("/tokens/:id", auth.GetTokens)
 		v1.DELETE("/tokens/:id", auth.DeleteToken)
+		v1.POST("/invitations/accept", api.AcceptInvitation)
 
 		v1.GET("/allowed/secrets", api.ListAllowedSecretTypes)
 		v1.GET("/allowed/secrets/:type", api.ListAllowedSecretTypes)
@@ -256,10 +390,33 @@ func main() {
 		logger.Info("Pipeline API listening on port ", listenPort)
 	}
 
-	certFile, keyFile := viper.GetString("pipeline.certfile"), viper.GetString("pipeline.keyfile")
-	if certFile != "" && keyFile != "" {
-		router.RunTLS(listenPort, certFile, keyFile)
-	} else {
-		router.Run(listenPort)
+	server := &http.Server{Addr: listenPort, Handler: router}
+	go func() {
+		var err error
+		certFile, keyFile := viper.GetString("pipeline.certfile"), viper.GetString("pipeline.keyfile")
+		if certFile != "" && keyFile != "" {
+			err = server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Error serving the API: %s", err.Error())
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+	logger.Info("Shutting down Pipeline API")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Errorf("Error shutting down the API: %s", err.Error())
+	}
+
+	// write the queued audit events of the served requests before exiting
+	if auditDispatcher != nil {
+		auditDispatcher.Close()
 	}
 }
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+
+	"github.com/banzaicloud/pipeline/database"
+)
+
+// TableNameAlertRuleGroups is the table name of the alert rule groups
+const TableNameAlertRuleGroups = "alert_rule_groups"
+
+// AlertRuleGroupModel describes an alert rule group of an organization, groups with zero cluster id are applied
+// to every cluster of the organization. The rules are stored in JSON.
+type AlertRuleGroupModel struct {
+	ID             uint `gorm:"primary_key"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	OrganizationID uint   `gorm:"unique_index:idx_org_cluster_name"`
+	ClusterID      uint   `gorm:"unique_index:idx_org_cluster_name"`
+	Name           string `gorm:"unique_index:idx_org_cluster_name"`
+	Interval       string
+	Rules          string `sql:"type:text"`
+	CreatedBy      uint
+}
+
+// TableName sets AlertRuleGroupModel's table name
+func (AlertRuleGroupModel) TableName() string {
+	return TableNameAlertRuleGroups
+}
+
+// Save the alert rule group to DB
+func (m *AlertRuleGroupModel) Save() error {
+	return database.GetDB().Save(m).Error
+}
+
+// Delete the alert rule group from DB
+func (m *AlertRuleGroupModel) Delete() error {
+	return database.GetDB().Delete(m).Error
+}
+
+// GetAlertRuleGroup returns the named alert rule group, zero cluster id selects the organization level groups
+func GetAlertRuleGroup(organizationID, clusterID uint, name string) (*AlertRuleGroupModel, error) {
+	var group AlertRuleGroupModel
+	err := database.GetDB().
+		Where("organization_id = ? AND cluster_id = ? AND name = ?", organizationID, clusterID, name).
+		First(&group).Error
+	return &group, err
+}
+
+// QueryAlertRuleGroups returns the alert rule groups of the given scope ordered by name,
+// zero cluster id selects the organization level groups
+func QueryAlertRuleGroups(organizationID, clusterID uint) ([]*AlertRuleGroupModel, error) {
+	var groups []*AlertRuleGroupModel
+	err := database.GetDB().
+		Where("organization_id = ? AND cluster_id = ?", organizationID, clusterID).
+		Order("name").
+		Find(&groups).Error
+	return groups, err
+}
+
+// QueryAlertRuleGroupsOfCluster returns the organization level and the cluster level alert rule groups applied to the cluster
+func QueryAlertRuleGroupsOfCluster(organizationID, clusterID uint) ([]*AlertRuleGroupModel, error) {
+	var groups []*AlertRuleGroupModel
+	err := database.GetDB().
+		Where("organization_id = ? AND cluster_id IN (?)", organizationID, []uint{0, clusterID}).
+		Order("name, cluster_id").
+		Find(&groups).Error
+	return groups, err
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/banzaicloud/pipeline/database"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
 	modelOracle "github.com/banzaicloud/pipeline/pkg/providers/oracle/model"
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/banzaicloud/pipeline/utils"
@@ -56,6 +57,13 @@ type ClusterModel struct {
 	Oracle         modelOracle.Cluster
 	Applications   []Application `gorm:"foreignkey:ClusterID"`
 	CreatedBy      uint
+	Labels         map[string]string `gorm:"-"`
+	LabelsRaw      []byte
+
+	NodePoolSettings    map[string]*pkgCommon.NodePoolSettings `gorm:"-"`
+	NodePoolSettingsRaw []byte                                 `sql:"type:text"`
+	AutoscalerSettings  *pkgCluster.AutoscalerSettings         `gorm:"-"`
+	AutoscalerRaw       []byte                                 `sql:"type:text"`
 }
 
 //AmazonClusterModel describes the amazon cluster model
@@ -167,7 +175,7 @@ func (gc GoogleClusterModel) String() string {
 	return buffer.String()
 }
 
-// BeforeSave converts the metadata into a json string in case of Kubernetes
+// BeforeSave converts the metadata into a json string in case of Kubernetes and the labels, node pool and autoscaler settings into json strings
 func (cs *ClusterModel) BeforeSave() error {
 	log.Info("Before save convert meta data")
 
@@ -180,10 +188,37 @@ func (cs *ClusterModel) BeforeSave() error {
 		cs.Kubernetes.MetadataRaw = out
 	}
 
+	if cs.Labels != nil {
+		out, err := json.Marshal(cs.Labels)
+		if err != nil {
+			log.Errorf("Error during convert labels to json: %s", err.Error())
+			return err
+		}
+		cs.LabelsRaw = out
+	}
+
+	if cs.NodePoolSettings != nil {
+		out, err := json.Marshal(cs.NodePoolSettings)
+		if err != nil {
+			log.Errorf("Error during convert node pool settings to json: %s", err.Error())
+			return err
+		}
+		cs.NodePoolSettingsRaw = out
+	}
+
+	if cs.AutoscalerSettings != nil {
+		out, err := json.Marshal(cs.AutoscalerSettings)
+		if err != nil {
+			log.Errorf("Error during convert autoscaler settings to json: %s", err.Error())
+			return err
+		}
+		cs.AutoscalerRaw = out
+	}
+
 	return nil
 }
 
-// AfterFind converts metadata json string into map in case of Kubernetes and sets NodeInstanceType and/or Location field(s)
+// AfterFind converts metadata json string into map in case of Kubernetes, converts the labels json string into map and sets NodeInstanceType and/or Location field(s)
 // to unknown if they are empty
 func (cs *ClusterModel) AfterFind() error {
 
@@ -202,6 +237,29 @@ func (cs *ClusterModel) AfterFind() error {
 		cs.Kubernetes.Metadata = out
 	}
 
+	if len(cs.LabelsRaw) != 0 {
+		out, err := utils.ConvertJson2Map(cs.LabelsRaw)
+		if err != nil {
+			log.Errorf("Error during convert labels json to map: %s", err.Error())
+			return err
+		}
+		cs.Labels = out
+	}
+
+	if len(cs.NodePoolSettingsRaw) != 0 {
+		if err := json.Unmarshal(cs.NodePoolSettingsRaw, &cs.NodePoolSettings); err != nil {
+			log.Errorf("Error during convert node pool settings json to map: %s", err.Error())
+			return err
+		}
+	}
+
+	if len(cs.AutoscalerRaw) != 0 {
+		if err := json.Unmarshal(cs.AutoscalerRaw, &cs.AutoscalerSettings); err != nil {
+			log.Errorf("Error during convert autoscaler settings json: %s", err.Error())
+			return err
+		}
+	}
+
 	return nil
 }
 
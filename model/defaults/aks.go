@@ -28,6 +28,9 @@ type AKSNodePoolProfile struct {
 	NodeInstanceType string `gorm:"default:'Standard_D4_v2'"`
 	Name             string `gorm:"unique_index:idx_model_name"`
 	NodeName         string `gorm:"unique_index:idx_model_name"`
+	OrganizationID   uint   `gorm:"unique_index:idx_model_name"`
+	Labels           string `sql:"type:text"`
+	Taints           string `sql:"type:text"`
 }
 
 // TableName overrides AKSNodePoolProfile's table name
@@ -43,7 +46,7 @@ func (AKSProfile) TableName() string {
 // AfterFind loads nodepools to profile
 func (d *AKSProfile) AfterFind() error {
 	log.Info("AfterFind aks profile... load node pools")
-	return database.GetDB().Where(AKSNodePoolProfile{Name: d.Name}).Find(&d.NodePools).Error
+	return database.GetDB().Where(profileCondition(d.Name, d.OrganizationID)).Find(&d.NodePools).Error
 }
 
 // BeforeSave clears nodepools
@@ -52,13 +55,15 @@ func (d *AKSProfile) BeforeSave() error {
 
 	db := database.GetDB()
 	var nodePools []*AKSNodePoolProfile
-	err := db.Where(AKSNodePoolProfile{
-		Name: d.Name,
-	}).Find(&nodePools).Delete(&nodePools).Error
+	err := db.Where(profileCondition(d.Name, d.OrganizationID)).Find(&nodePools).Delete(&nodePools).Error
 	if err != nil {
 		log.Errorf("Error during deleting saved nodepools: %s", err.Error())
 	}
 
+	for _, np := range d.NodePools {
+		np.OrganizationID = d.OrganizationID
+	}
+
 	return nil
 }
 
@@ -67,9 +72,7 @@ func (d *AKSProfile) BeforeDelete() error {
 	log.Info("BeforeDelete aks profile... delete all nodepool")
 
 	var nodePools []*AKSNodePoolProfile
-	return database.GetDB().Where(AKSNodePoolProfile{
-		Name: d.Name,
-	}).Find(&nodePools).Delete(&nodePools).Error
+	return database.GetDB().Where(profileCondition(d.Name, d.OrganizationID)).Find(&nodePools).Delete(&nodePools).Error
 }
 
 // SaveInstance saves cluster profile into database
@@ -79,7 +82,7 @@ func (d *AKSProfile) SaveInstance() error {
 
 // IsDefinedBefore returns true if database contains en entry with profile name
 func (d *AKSProfile) IsDefinedBefore() bool {
-	return database.GetDB().First(&d).RowsAffected != int64(0)
+	return isProfileDefined(&AKSProfile{}, d.Name, d.OrganizationID)
 }
 
 // GetType returns profile's cloud type
@@ -99,14 +102,19 @@ func (d *AKSProfile) GetProfile() *pkgCluster.ClusterProfileResponse {
 				MaxCount:         np.MaxCount,
 				Count:            np.Count,
 				NodeInstanceType: np.NodeInstanceType,
+
+				NodePoolSettings: nodePoolSettingsFromColumns(np.Labels, np.Taints),
 			}
 		}
 	}
 
 	return &pkgCluster.ClusterProfileResponse{
-		Name:     d.DefaultModel.Name,
-		Location: d.Location,
-		Cloud:    pkgCluster.Azure,
+		Name:       d.DefaultModel.Name,
+		Location:   d.Location,
+		Cloud:      pkgCluster.Azure,
+		Extends:    d.Extends,
+		Global:     d.OrganizationID == GlobalOrganizationID,
+		Autoscaler: d.DefaultModel.getAutoscalerSettings(),
 		Properties: struct {
 			Amazon *amazon.ClusterProfileAmazon `json:"amazon,omitempty"`
 			Azure  *azure.ClusterProfileAzure   `json:"azure,omitempty"`
@@ -128,6 +136,8 @@ func (d *AKSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 		d.Location = r.Location
 	}
 
+	d.DefaultModel.setAutoscalerSettings(r.Autoscaler)
+
 	if r.Properties.Azure != nil {
 
 		if len(r.Properties.Azure.KubernetesVersion) != 0 {
@@ -138,6 +148,7 @@ func (d *AKSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 
 			var nodePools []*AKSNodePoolProfile
 			for name, np := range r.Properties.Azure.NodePools {
+				labels, taints := nodePoolSettingsToColumns(np.NodePoolSettings)
 				nodePools = append(nodePools, &AKSNodePoolProfile{
 					Autoscaling:      np.Autoscaling,
 					MinCount:         np.MinCount,
@@ -146,6 +157,8 @@ func (d *AKSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 					NodeInstanceType: np.NodeInstanceType,
 					Name:             d.Name,
 					NodeName:         name,
+					Labels:           labels,
+					Taints:           taints,
 				})
 			}
 
@@ -157,6 +170,8 @@ func (d *AKSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 		return d.SaveInstance()
 	}
 	d.Name = r.Name
+	d.OrganizationID = r.OrganizationId
+	d.Extends = r.Extends
 	return nil
 }
 
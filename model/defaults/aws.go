@@ -22,16 +22,19 @@ type AWSProfile struct {
 
 // AWSNodePoolProfile describes an Amazon cluster profile's nodepools
 type AWSNodePoolProfile struct {
-	ID           uint   `gorm:"primary_key"`
-	InstanceType string `gorm:"default:'m4.xlarge'"`
-	Name         string `gorm:"unique_index:idx_model_name"`
-	NodeName     string `gorm:"unique_index:idx_model_name"`
-	SpotPrice    string `gorm:"default:'0.2'"`
-	Autoscaling  bool   `gorm:"default:false"`
-	MinCount     int    `gorm:"default:1"`
-	MaxCount     int    `gorm:"default:2"`
-	Count        int    `gorm:"default:1"`
-	Image        string `gorm:"default:'ami-4d485ca7'"`
+	ID             uint   `gorm:"primary_key"`
+	InstanceType   string `gorm:"default:'m4.xlarge'"`
+	Name           string `gorm:"unique_index:idx_model_name"`
+	NodeName       string `gorm:"unique_index:idx_model_name"`
+	OrganizationID uint   `gorm:"unique_index:idx_model_name"`
+	SpotPrice      string `gorm:"default:'0.2'"`
+	Autoscaling    bool   `gorm:"default:false"`
+	MinCount       int    `gorm:"default:1"`
+	MaxCount       int    `gorm:"default:2"`
+	Count          int    `gorm:"default:1"`
+	Image          string `gorm:"default:'ami-4d485ca7'"`
+	Labels         string `sql:"type:text"`
+	Taints         string `sql:"type:text"`
 }
 
 // TableName overrides AWSNodePoolProfile's table name
@@ -56,13 +59,13 @@ func (d *AWSProfile) GetType() string {
 
 // IsDefinedBefore returns true if database contains en entry with profile name
 func (d *AWSProfile) IsDefinedBefore() bool {
-	return database.GetDB().First(&d).RowsAffected != int64(0)
+	return isProfileDefined(&AWSProfile{}, d.Name, d.OrganizationID)
 }
 
 // AfterFind loads nodepools to profile
 func (d *AWSProfile) AfterFind() error {
 	log.Info("AfterFind aws profile... load node pools")
-	return database.GetDB().Where(AWSNodePoolProfile{Name: d.Name}).Find(&d.NodePools).Error
+	return database.GetDB().Where(profileCondition(d.Name, d.OrganizationID)).Find(&d.NodePools).Error
 }
 
 // BeforeSave clears nodepools
@@ -71,13 +74,15 @@ func (d *AWSProfile) BeforeSave() error {
 
 	db := database.GetDB()
 	var nodePools []*AWSNodePoolProfile
-	err := db.Where(AWSNodePoolProfile{
-		Name: d.Name,
-	}).Find(&nodePools).Delete(&nodePools).Error
+	err := db.Where(profileCondition(d.Name, d.OrganizationID)).Find(&nodePools).Delete(&nodePools).Error
 	if err != nil {
 		log.Errorf("Error during deleting saved nodepools: %s", err.Error())
 	}
 
+	for _, np := range d.NodePools {
+		np.OrganizationID = d.OrganizationID
+	}
+
 	return nil
 }
 
@@ -86,9 +91,7 @@ func (d *AWSProfile) BeforeDelete() error {
 	log.Info("BeforeDelete aws profile... delete all nodepool")
 
 	var nodePools []*AWSNodePoolProfile
-	return database.GetDB().Where(AWSNodePoolProfile{
-		Name: d.Name,
-	}).Find(&nodePools).Delete(&nodePools).Error
+	return database.GetDB().Where(profileCondition(d.Name, d.OrganizationID)).Find(&nodePools).Delete(&nodePools).Error
 }
 
 // GetProfile load profile from database and converts ClusterProfileResponse
@@ -105,14 +108,19 @@ func (d *AWSProfile) GetProfile() *pkgCluster.ClusterProfileResponse {
 				MaxCount:     np.MaxCount,
 				Count:        np.Count,
 				Image:        np.Image,
+
+				NodePoolSettings: nodePoolSettingsFromColumns(np.Labels, np.Taints),
 			}
 		}
 	}
 
 	return &pkgCluster.ClusterProfileResponse{
-		Name:     d.DefaultModel.Name,
-		Location: d.Location,
-		Cloud:    pkgCluster.Amazon,
+		Name:       d.DefaultModel.Name,
+		Location:   d.Location,
+		Cloud:      pkgCluster.Amazon,
+		Extends:    d.Extends,
+		Global:     d.OrganizationID == GlobalOrganizationID,
+		Autoscaler: d.DefaultModel.getAutoscalerSettings(),
 		Properties: struct {
 			Amazon *pkgAmazon.ClusterProfileAmazon `json:"amazon,omitempty"`
 			Azure  *pkgAzure.ClusterProfileAzure   `json:"azure,omitempty"`
@@ -139,6 +147,8 @@ func (d *AWSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 		d.Location = r.Location
 	}
 
+	d.DefaultModel.setAutoscalerSettings(r.Autoscaler)
+
 	if r.Properties.Amazon != nil {
 
 		if len(r.Properties.Amazon.NodePools) != 0 {
@@ -181,6 +191,8 @@ func (d *AWSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 					image = nodePool.Image
 				}
 
+				labels, taints := nodePoolSettingsToColumns(nodePool.NodePoolSettings)
+
 				nodePools = append(nodePools, &AWSNodePoolProfile{
 					InstanceType: instanceType,
 					Name:         d.Name,
@@ -191,6 +203,8 @@ func (d *AWSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 					MaxCount:     maxCount,
 					Count:        count,
 					Image:        image,
+					Labels:       labels,
+					Taints:       taints,
 				})
 
 			}
@@ -212,6 +226,8 @@ func (d *AWSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 		return d.SaveInstance()
 	}
 	d.Name = r.Name
+	d.OrganizationID = r.OrganizationId
+	d.Extends = r.Extends
 	return nil
 }
 
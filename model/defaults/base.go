@@ -1,12 +1,15 @@
 package defaults
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/banzaicloud/pipeline/config"
 	"github.com/banzaicloud/pipeline/database"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
 	pkgErrors "github.com/banzaicloud/pipeline/pkg/errors"
 	oracle "github.com/banzaicloud/pipeline/pkg/providers/oracle/model"
 	"github.com/sirupsen/logrus"
@@ -64,16 +67,61 @@ type ClusterProfile interface {
 	IsDefinedBefore() bool
 	SaveInstance() error
 	GetType() string
+	GetName() string
+	GetOrganizationID() uint
 	GetProfile() *pkgCluster.ClusterProfileResponse
 	UpdateProfile(*pkgCluster.ClusterProfileRequest, bool) error
 	DeleteProfile() error
+	GetOverrides() *pkgCluster.ClusterProfileRequest
+	SetOverrides(*pkgCluster.ClusterProfileRequest)
 }
 
 // DefaultModel describes the common variables all types of clouds
 type DefaultModel struct {
-	Name      string `gorm:"primary_key"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Name           string `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"primary_key;auto_increment:false"`
+	Extends        string
+	// Overrides are the profile's own values, which are applied on the extended profile when the profile is read
+	Overrides  string `sql:"type:text"`
+	Autoscaler string `sql:"type:text"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// GlobalOrganizationID is the organization id of the profiles which are visible for all organizations
+const GlobalOrganizationID = pkgCluster.GlobalProfileOrganizationID
+
+// maxExtendsDepth limits the chain of extended profiles, which protects reads against cyclic chains
+const maxExtendsDepth = 10
+
+// GetName returns the profile's name
+func (d *DefaultModel) GetName() string {
+	return d.Name
+}
+
+// GetOrganizationID returns the id of the organization which owns the profile
+func (d *DefaultModel) GetOrganizationID() uint {
+	return d.OrganizationID
+}
+
+// GetOverrides returns the profile's own values if it extends another profile
+func (d *DefaultModel) GetOverrides() *pkgCluster.ClusterProfileRequest {
+	if len(d.Overrides) == 0 {
+		return nil
+	}
+	var overrides pkgCluster.ClusterProfileRequest
+	if err := json.Unmarshal([]byte(d.Overrides), &overrides); err != nil {
+		log.Errorf("Error during convert profile overrides: %s", err.Error())
+		return nil
+	}
+	return &overrides
+}
+
+// SetOverrides stores the profile's own values
+func (d *DefaultModel) SetOverrides(overrides *pkgCluster.ClusterProfileRequest) {
+	if out, err := json.Marshal(overrides); err == nil {
+		d.Overrides = string(out)
+	}
 }
 
 // save saves the given data into database
@@ -87,6 +135,69 @@ func loadFirst(output interface{}) error {
 	return database.GetDB().First(output).Error
 }
 
+// getAutoscalerSettings returns the cluster autoscaler settings of the profile
+func (d *DefaultModel) getAutoscalerSettings() *pkgCluster.AutoscalerSettings {
+	if len(d.Autoscaler) == 0 {
+		return nil
+	}
+	var settings pkgCluster.AutoscalerSettings
+	if err := json.Unmarshal([]byte(d.Autoscaler), &settings); err != nil {
+		log.Errorf("Error during convert profile autoscaler settings: %s", err.Error())
+		return nil
+	}
+	return &settings
+}
+
+// setAutoscalerSettings stores the cluster autoscaler settings in the profile
+func (d *DefaultModel) setAutoscalerSettings(settings *pkgCluster.AutoscalerSettings) {
+	if settings == nil {
+		return
+	}
+	if out, err := json.Marshal(settings); err == nil {
+		d.Autoscaler = string(out)
+	}
+}
+
+// profileCondition returns the where condition which selects a profile (or its node pools) of an organization by name
+func profileCondition(name string, organizationID uint) map[string]interface{} {
+	return map[string]interface{}{"name": name, "organization_id": organizationID}
+}
+
+// nodePoolSettingsToColumns converts node pool labels and taints into the json columns of a node pool profile
+func nodePoolSettingsToColumns(settings pkgCommon.NodePoolSettings) (labels string, taints string) {
+	if len(settings.Labels) != 0 {
+		if out, err := json.Marshal(settings.Labels); err == nil {
+			labels = string(out)
+		}
+	}
+	if len(settings.Taints) != 0 {
+		if out, err := json.Marshal(settings.Taints); err == nil {
+			taints = string(out)
+		}
+	}
+	return
+}
+
+// nodePoolSettingsFromColumns converts the json columns of a node pool profile into node pool labels and taints
+func nodePoolSettingsFromColumns(labels string, taints string) (settings pkgCommon.NodePoolSettings) {
+	if len(labels) != 0 {
+		if err := json.Unmarshal([]byte(labels), &settings.Labels); err != nil {
+			log.Errorf("Error during convert node pool profile labels: %s", err.Error())
+		}
+	}
+	if len(taints) != 0 {
+		if err := json.Unmarshal([]byte(taints), &settings.Taints); err != nil {
+			log.Errorf("Error during convert node pool profile taints: %s", err.Error())
+		}
+	}
+	return
+}
+
+// isProfileDefined returns true if the given profile table contains an entry with the given name in the organization
+func isProfileDefined(out interface{}, name string, organizationID uint) bool {
+	return !database.GetDB().Where(profileCondition(name, organizationID)).First(out).RecordNotFound()
+}
+
 // GetDefaultProfiles create all types of clouds with default profile name
 func GetDefaultProfiles() []ClusterProfile {
 	var defaults []ClusterProfile
@@ -122,11 +233,12 @@ func GetDefaultProfiles() []ClusterProfile {
 	return defaults
 }
 
-// GetAllProfiles loads all saved cluster profile from database by given cloud type
-func GetAllProfiles(cloudType string) ([]ClusterProfile, error) {
+// GetAllProfiles loads all saved cluster profile from database by given cloud type.
+// The global profiles are listed as well, except the ones which are overridden in the organization
+func GetAllProfiles(organizationID uint, cloudType string) ([]ClusterProfile, error) {
 
 	var defaults []ClusterProfile
-	db := database.GetDB()
+	db := database.GetDB().Where("organization_id IN (?)", []uint{GlobalOrganizationID, organizationID})
 
 	switch cloudType {
 
@@ -159,7 +271,7 @@ func GetAllProfiles(cloudType string) ([]ClusterProfile, error) {
 		}
 
 	case pkgCluster.Oracle:
-		okeProfiles := oracle.GetProfiles()
+		okeProfiles := oracle.GetProfiles(organizationID)
 		for i := range okeProfiles {
 			defaults = append(defaults, &okeProfiles[i])
 		}
@@ -168,39 +280,158 @@ func GetAllProfiles(cloudType string) ([]ClusterProfile, error) {
 		return nil, pkgErrors.ErrorNotSupportedCloudType
 	}
 
-	return defaults, nil
+	for _, profile := range defaults {
+		resolveExtends(profile, cloudType, 0)
+	}
+
+	return filterOverriddenProfiles(defaults), nil
 
 }
 
-// GetProfile finds cluster profile from database by given name and cloud type
-func GetProfile(cloudType string, name string) (ClusterProfile, error) {
-	db := database.GetDB()
+// filterOverriddenProfiles drops the global profiles which have an organization specific profile with the same name
+func filterOverriddenProfiles(profiles []ClusterProfile) []ClusterProfile {
+
+	overridden := make(map[string]bool)
+	for _, p := range profiles {
+		if p.GetOrganizationID() != GlobalOrganizationID {
+			overridden[p.GetName()] = true
+		}
+	}
+
+	var filtered []ClusterProfile
+	for _, p := range profiles {
+		if p.GetOrganizationID() == GlobalOrganizationID && overridden[p.GetName()] {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+
+	return filtered
+}
+
+// GetProfile finds cluster profile from database by given name and cloud type.
+// The organization's own profile is returned if exists, otherwise the global one.
+// The values of the extended profile are resolved on every read, so the changes of the extended profile are inherited
+func GetProfile(organizationID uint, cloudType string, name string) (ClusterProfile, error) {
+	return getResolvedProfile(organizationID, cloudType, name, 0)
+}
+
+func getResolvedProfile(organizationID uint, cloudType string, name string, depth int) (ClusterProfile, error) {
+
+	profile, err := LoadProfile(organizationID, cloudType, name)
+	if err != nil {
+		return nil, err
+	}
+
+	resolveExtends(profile, cloudType, depth)
+
+	return profile, nil
+}
+
+// LoadProfile finds cluster profile from database like GetProfile, but it returns the profile as it's saved,
+// without resolving the extended profile, e.g. to update it
+func LoadProfile(organizationID uint, cloudType string, name string) (ClusterProfile, error) {
+
+	profile, err := getProfile(organizationID, cloudType, name)
+	if database.IsErrorGormNotFound(err) && organizationID != GlobalOrganizationID {
+		log.Infof("Profile %s[%s] not found in organization [%d], fall back to global profile", name, cloudType, organizationID)
+		return getProfile(GlobalOrganizationID, cloudType, name)
+	}
+
+	return profile, err
+}
+
+// ResolveExtends applies the profile's own values on the current values of the profile it extends
+func ResolveExtends(profile ClusterProfile, cloudType string) {
+	resolveExtends(profile, cloudType, 0)
+}
+
+// resolveExtends applies the profile's own values on the current values of the profile it extends.
+// The values saved last time are kept if the extended profile can't be resolved
+func resolveExtends(profile ClusterProfile, cloudType string, depth int) {
+
+	overrides := profile.GetOverrides()
+	if overrides == nil || len(overrides.Extends) == 0 {
+		return
+	}
+	overrides.Name = profile.GetName()
+	overrides.OrganizationId = profile.GetOrganizationID()
+
+	if depth >= maxExtendsDepth {
+		log.Warnf("Profile %s[%s] extends too many profiles, using its saved values", overrides.Name, cloudType)
+		return
+	}
+
+	extended, err := getResolvedProfile(overrides.OrganizationId, cloudType, overrides.Extends, depth+1)
+	if err != nil {
+		log.Warnf("Error during getting extended profile %s of %s[%s], using its saved values: %s", overrides.Extends, overrides.Name, cloudType, err.Error())
+		return
+	}
+
+	extendedRequest := extended.GetProfile().ClusterProfileRequest()
+	extendedRequest.Name = overrides.Name
+	extendedRequest.OrganizationId = overrides.OrganizationId
+	extendedRequest.Extends = overrides.Extends
+
+	profile.UpdateProfile(extendedRequest, false)
+	profile.UpdateProfile(overrides, false)
+}
+
+// UpdateOverrides applies the request on the profile's own values, which are applied on the extended profile when it's read
+func UpdateOverrides(profile ClusterProfile, request *pkgCluster.ClusterProfileRequest) {
+
+	// a profile of the same type without values collects the values set by the requests
+	own := reflect.New(reflect.TypeOf(profile).Elem()).Interface().(ClusterProfile)
+	extends := request.Extends
+	if overrides := profile.GetOverrides(); overrides != nil {
+		own.UpdateProfile(overrides, false)
+		if extends == "" {
+			extends = overrides.Extends
+		}
+	}
+	own.UpdateProfile(request, false)
+
+	overrides := own.GetProfile().ClusterProfileRequest()
+	overrides.Extends = extends
+	profile.SetOverrides(overrides)
+}
+
+// getProfile finds cluster profile of the given organization by name and cloud type
+func getProfile(organizationID uint, cloudType string, name string) (ClusterProfile, error) {
+	db := database.GetDB().Where(profileCondition(name, organizationID))
 
 	switch cloudType {
 	case pkgCluster.Amazon:
 		var awsProfile AWSProfile
-		if err := db.Where(GKEProfile{DefaultModel: DefaultModel{Name: name}}).First(&awsProfile).Error; err != nil {
+		if err := db.First(&awsProfile).Error; err != nil {
 			return nil, err
 		}
 		return &awsProfile, nil
 
+	case pkgCluster.AmazonEKS:
+		var eksProfile EKSProfile
+		if err := db.First(&eksProfile).Error; err != nil {
+			return nil, err
+		}
+		return &eksProfile, nil
+
 	case pkgCluster.Azure:
 		var aksProfile AKSProfile
-		if err := db.Where(GKEProfile{DefaultModel: DefaultModel{Name: name}}).First(&aksProfile).Error; err != nil {
+		if err := db.First(&aksProfile).Error; err != nil {
 			return nil, err
 		}
 		return &aksProfile, nil
 
 	case pkgCluster.Google:
 		var gkeProfile GKEProfile
-		if err := db.Where(GKEProfile{DefaultModel: DefaultModel{Name: name}}).First(&gkeProfile).Error; err != nil {
+		if err := db.First(&gkeProfile).Error; err != nil {
 			return nil, err
 		}
 		return &gkeProfile, nil
 
 	case pkgCluster.Oracle:
 		var okeProfile oracle.Profile
-		okeProfile, err := oracle.GetProfileByName(name)
+		okeProfile, err := oracle.GetProfileByName(organizationID, name)
 		return &okeProfile, err
 
 	default:
@@ -86,6 +86,50 @@ func TestUpdateWithoutSave(t *testing.T) {
 
 }
 
+func TestExtendProfile(t *testing.T) {
+
+	const (
+		extendedName     = "TestExtendedProfile"
+		extendedLocation = "TestExtendedLocation"
+		organizationID   = 1
+	)
+
+	extended := fullGKE
+	extendedRequest := extended.GetProfile().ClusterProfileRequest()
+
+	profile := &defaults.GKEProfile{}
+	profile.UpdateProfile(extendedRequest, false)
+	profile.UpdateProfile(&pkgCluster.ClusterProfileRequest{
+		OrganizationId: organizationID,
+		Name:           extendedName,
+		Location:       extendedLocation,
+		Cloud:          pkgCluster.Google,
+		Extends:        name,
+	}, false)
+
+	if profile.Name != extendedName || profile.OrganizationID != organizationID || profile.Extends != name {
+		t.Errorf("Expected profile %s extending %s in organization %d, got: %#v", extendedName, name, organizationID, profile.DefaultModel)
+	}
+
+	if profile.Location != extendedLocation {
+		t.Errorf("Expected location: %s, got: %s", extendedLocation, profile.Location)
+	}
+
+	if profile.NodeVersion != version || profile.MasterVersion != version {
+		t.Errorf("Expected inherited versions: %s, got: %s, %s", version, profile.NodeVersion, profile.MasterVersion)
+	}
+
+	if len(profile.NodePools) != 1 || profile.NodePools[0].NodeName != agentName || profile.NodePools[0].NodeInstanceType != nodeInstanceType {
+		t.Errorf("Expected inherited node pools: %#v, got: %#v", extended.NodePools, profile.NodePools)
+	}
+
+	response := profile.GetProfile()
+	if response.Extends != name || response.Global {
+		t.Errorf("Expected organization profile extending %s, got: extends %s, global %t", name, response.Extends, response.Global)
+	}
+
+}
+
 const (
 	name               = "TestProfile"
 	location           = "TestLocation"
@@ -400,3 +444,38 @@ var (
 		Location:     location,
 	}
 )
+
+func TestUpdateOverrides(t *testing.T) {
+
+	profile := &defaults.AWSProfile{}
+	defaults.UpdateOverrides(profile, &pkgCluster.ClusterProfileRequest{
+		Name:       name,
+		Cloud:      pkgCluster.Amazon,
+		Extends:    "TestExtendedProfile",
+		Properties: masterRequestAWS.Properties,
+	})
+	defaults.UpdateOverrides(profile, &pkgCluster.ClusterProfileRequest{
+		Name:     name,
+		Location: location,
+		Cloud:    pkgCluster.Amazon,
+	})
+
+	overrides := profile.GetOverrides()
+	if overrides == nil {
+		t.Fatal("Expected overrides, got: <nil>")
+	}
+
+	if overrides.Extends != "TestExtendedProfile" || overrides.Location != location {
+		t.Errorf("Expected overrides extending %s with location %s, got: %#v", "TestExtendedProfile", location, overrides)
+	}
+
+	amazonOverrides := overrides.Properties.Amazon
+	if amazonOverrides == nil || amazonOverrides.Master == nil || amazonOverrides.Master.InstanceType != masterInstanceType {
+		t.Errorf("Expected master instance type %s kept in overrides, got: %#v", masterInstanceType, amazonOverrides)
+	}
+
+	if amazonOverrides != nil && len(amazonOverrides.NodePools) != 0 {
+		t.Errorf("Expected no node pools in overrides, got: %#v", amazonOverrides.NodePools)
+	}
+
+}
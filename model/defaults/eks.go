@@ -31,6 +31,17 @@ func (d *EKSProfile) SaveInstance() error {
 	return save(d)
 }
 
+// BeforeSave sets the organization of nodepools
+func (d *EKSProfile) BeforeSave() error {
+	log.Info("BeforeSave eks profile...")
+
+	for _, np := range d.NodePools {
+		np.OrganizationID = d.OrganizationID
+	}
+
+	return nil
+}
+
 // GetType returns profile's cloud type
 func (d *EKSProfile) GetType() string {
 	return pkgCluster.Amazon
@@ -38,7 +49,7 @@ func (d *EKSProfile) GetType() string {
 
 // IsDefinedBefore returns true if database contains en entry with profile name
 func (d *EKSProfile) IsDefinedBefore() bool {
-	return database.GetDB().First(&d).RowsAffected != 0
+	return isProfileDefined(&EKSProfile{}, d.Name, d.OrganizationID)
 }
 
 // GetProfile load profile from database and converts ClusterProfileResponse
@@ -55,14 +66,19 @@ func (d *EKSProfile) GetProfile() *pkgCluster.ClusterProfileResponse {
 				MaxCount:     np.MaxCount,
 				Count:        np.Count,
 				Image:        np.Image,
+
+				NodePoolSettings: nodePoolSettingsFromColumns(np.Labels, np.Taints),
 			}
 		}
 	}
 
 	return &pkgCluster.ClusterProfileResponse{
-		Name:     d.DefaultModel.Name,
-		Location: d.Region,
-		Cloud:    pkgCluster.Amazon,
+		Name:       d.DefaultModel.Name,
+		Location:   d.Region,
+		Cloud:      pkgCluster.Amazon,
+		Extends:    d.Extends,
+		Global:     d.OrganizationID == GlobalOrganizationID,
+		Autoscaler: d.DefaultModel.getAutoscalerSettings(),
 		Properties: struct {
 			Amazon *amazon.ClusterProfileAmazon `json:"amazon,omitempty"`
 			Azure  *azure.ClusterProfileAzure   `json:"azure,omitempty"`
@@ -85,6 +101,8 @@ func (d *EKSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 		d.Region = r.Location
 	}
 
+	d.DefaultModel.setAutoscalerSettings(r.Autoscaler)
+
 	if r.Properties.Eks != nil {
 
 		if len(r.Properties.Eks.Version) != 0 {
@@ -131,6 +149,8 @@ func (d *EKSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 					image = nodePool.Image
 				}
 
+				labels, taints := nodePoolSettingsToColumns(nodePool.NodePoolSettings)
+
 				nodePools = append(nodePools, &AWSNodePoolProfile{
 					InstanceType: instanceType,
 					Name:         d.Name,
@@ -141,6 +161,8 @@ func (d *EKSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 					MaxCount:     maxCount,
 					Count:        count,
 					Image:        image,
+					Labels:       labels,
+					Taints:       taints,
 				})
 
 			}
@@ -152,6 +174,8 @@ func (d *EKSProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 		return d.SaveInstance()
 	}
 	d.Name = r.Name
+	d.OrganizationID = r.OrganizationId
+	d.Extends = r.Extends
 	return nil
 }
 
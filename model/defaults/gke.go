@@ -29,6 +29,9 @@ type GKENodePoolProfile struct {
 	NodeInstanceType string `gorm:"default:'n1-standard-1'"`
 	Name             string `gorm:"unique_index:idx_model_name"`
 	NodeName         string `gorm:"unique_index:idx_model_name"`
+	OrganizationID   uint   `gorm:"unique_index:idx_model_name"`
+	Labels           string `sql:"type:text"`
+	Taints           string `sql:"type:text"`
 }
 
 // TableName overrides GKEProfile's table name
@@ -44,7 +47,7 @@ func (GKENodePoolProfile) TableName() string {
 // AfterFind loads nodepools to profile
 func (d *GKEProfile) AfterFind() error {
 	log.Info("AfterFind gke profile... load node pools")
-	return database.GetDB().Where(GKENodePoolProfile{Name: d.Name}).Find(&d.NodePools).Error
+	return database.GetDB().Where(profileCondition(d.Name, d.OrganizationID)).Find(&d.NodePools).Error
 }
 
 // BeforeSave clears nodepools
@@ -52,13 +55,15 @@ func (d *GKEProfile) BeforeSave() error {
 	log.Info("BeforeSave gke profile...")
 
 	var nodePools []*GKENodePoolProfile
-	err := database.GetDB().Where(GKENodePoolProfile{
-		Name: d.Name,
-	}).Find(&nodePools).Delete(&nodePools).Error
+	err := database.GetDB().Where(profileCondition(d.Name, d.OrganizationID)).Find(&nodePools).Delete(&nodePools).Error
 	if err != nil {
 		log.Errorf("Error during deleting saved nodepools: %s", err.Error())
 	}
 
+	for _, np := range d.NodePools {
+		np.OrganizationID = d.OrganizationID
+	}
+
 	return nil
 }
 
@@ -67,9 +72,7 @@ func (d *GKEProfile) BeforeDelete() error {
 	log.Info("BeforeDelete gke profile... delete all nodepool")
 
 	var nodePools []*GKENodePoolProfile
-	return database.GetDB().Where(GKENodePoolProfile{
-		Name: d.Name,
-	}).Find(&nodePools).Delete(&nodePools).Error
+	return database.GetDB().Where(profileCondition(d.Name, d.OrganizationID)).Find(&nodePools).Delete(&nodePools).Error
 }
 
 // SaveInstance saves cluster profile into database
@@ -79,7 +82,7 @@ func (d *GKEProfile) SaveInstance() error {
 
 // IsDefinedBefore returns true if database contains en entry with profile name
 func (d *GKEProfile) IsDefinedBefore() bool {
-	return database.GetDB().First(&d).RowsAffected != int64(0)
+	return isProfileDefined(&GKEProfile{}, d.Name, d.OrganizationID)
 }
 
 // GetType returns profile's cloud type
@@ -99,6 +102,8 @@ func (d *GKEProfile) GetProfile() *pkgCluster.ClusterProfileResponse {
 				MaxCount:         np.MaxCount,
 				Count:            np.Count,
 				NodeInstanceType: np.NodeInstanceType,
+
+				NodePoolSettings: nodePoolSettingsFromColumns(np.Labels, np.Taints),
 			}
 		}
 	}
@@ -107,6 +112,8 @@ func (d *GKEProfile) GetProfile() *pkgCluster.ClusterProfileResponse {
 		Name:     d.DefaultModel.Name,
 		Location: d.Location,
 		Cloud:    pkgCluster.Google,
+		Extends:  d.Extends,
+		Global:   d.OrganizationID == GlobalOrganizationID,
 		Properties: struct {
 			Amazon *amazon.ClusterProfileAmazon `json:"amazon,omitempty"`
 			Azure  *azure.ClusterProfileAzure   `json:"azure,omitempty"`
@@ -142,6 +149,7 @@ func (d *GKEProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 
 			var nodePools []*GKENodePoolProfile
 			for name, np := range r.Properties.Google.NodePools {
+				labels, taints := nodePoolSettingsToColumns(np.NodePoolSettings)
 				nodePools = append(nodePools, &GKENodePoolProfile{
 					Autoscaling:      np.Autoscaling,
 					MinCount:         np.MinCount,
@@ -150,6 +158,8 @@ func (d *GKEProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 					NodeInstanceType: np.NodeInstanceType,
 					Name:             d.Name,
 					NodeName:         name,
+					Labels:           labels,
+					Taints:           taints,
 				})
 			}
 
@@ -165,6 +175,8 @@ func (d *GKEProfile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave
 		return d.SaveInstance()
 	}
 	d.Name = r.Name
+	d.OrganizationID = r.OrganizationId
+	d.Extends = r.Extends
 	return nil
 }
 
@@ -0,0 +1,86 @@
+package defaults
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MigrateOrganizationKeys migrates the keys of the profile tables created before profiles were scoped to organizations.
+// AutoMigrate adds the organization_id columns, but it doesn't change existing primary keys and indexes, so without
+// this the old keys on the profile name would still prevent organizations from having profiles with the same name.
+func MigrateOrganizationKeys(db *gorm.DB) error {
+
+	for _, table := range []string{
+		DefaultAmazonProfileTablaName,
+		DefaultAmazonEksProfileTablaName,
+		DefaultAzureProfileTablaName,
+		DefaultGoogleProfileTablaName,
+	} {
+		if err := migratePrimaryKey(db, table); err != nil {
+			return fmt.Errorf("error migrating primary key of %s: %s", table, err.Error())
+		}
+	}
+
+	for _, nodePool := range []interface{}{&AWSNodePoolProfile{}, &AKSNodePoolProfile{}, &GKENodePoolProfile{}} {
+		if err := migrateModelNameIndex(db, nodePool); err != nil {
+			return fmt.Errorf("error migrating index of %s: %s", db.NewScope(nodePool).TableName(), err.Error())
+		}
+	}
+
+	return nil
+}
+
+// keyHasColumn tells whether the index of the table contains the column, the primary key's index is PRIMARY
+func keyHasColumn(db *gorm.DB, table, index, column string) (bool, error) {
+	var count int
+	err := db.Raw("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ? AND column_name = ?",
+		table, index, column).Row().Scan(&count)
+	return count > 0, err
+}
+
+// migrateOrganizationIDs moves the profiles created before the organization_id column existed to the global organization
+func migrateOrganizationIDs(db *gorm.DB, table string) error {
+	return db.Table(table).Where("organization_id IS NULL").UpdateColumn("organization_id", GlobalOrganizationID).Error
+}
+
+// migratePrimaryKey extends the primary key of a profile table with the organization
+func migratePrimaryKey(db *gorm.DB, table string) error {
+	if !db.HasTable(table) {
+		return nil
+	}
+	migrated, err := keyHasColumn(db, table, "PRIMARY", "organization_id")
+	if err != nil || migrated {
+		return err
+	}
+
+	log.Infof("Migrate primary key of %s to (name, organization_id)", table)
+	if err := migrateOrganizationIDs(db, table); err != nil {
+		return err
+	}
+	return db.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY organization_id int unsigned NOT NULL DEFAULT 0, DROP PRIMARY KEY, ADD PRIMARY KEY (name, organization_id)", table)).Error
+}
+
+// migrateModelNameIndex extends the unique idx_model_name index of a node pool profile table with the organization
+func migrateModelNameIndex(db *gorm.DB, nodePool interface{}) error {
+	const index = "idx_model_name"
+
+	table := db.NewScope(nodePool).TableName()
+	if !db.HasTable(table) {
+		return nil
+	}
+	if err := migrateOrganizationIDs(db, table); err != nil {
+		return err
+	}
+	if db.Dialect().HasIndex(table, index) {
+		migrated, err := keyHasColumn(db, table, index, "organization_id")
+		if err != nil || migrated {
+			return err
+		}
+		log.Infof("Migrate index %s of %s to (name, node_name, organization_id)", index, table)
+		if err := db.Model(nodePool).RemoveIndex(index).Error; err != nil {
+			return err
+		}
+	}
+	return db.Model(nodePool).AddUniqueIndex(index, "name", "node_name", "organization_id").Error
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/banzaicloud/pipeline/database"
+)
+
+// TableNameKubernetesGroupMappings is the table name of the organization role to Kubernetes group mappings
+const TableNameKubernetesGroupMappings = "kubernetes_group_mappings"
+
+// KubernetesGroupMappingModel maps a Pipeline organization role to Kubernetes groups
+type KubernetesGroupMappingModel struct {
+	ID             uint   `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"unique_index:idx_org_role"`
+	Role           string `gorm:"unique_index:idx_org_role"`
+	Groups         string
+}
+
+// TableName sets KubernetesGroupMappingModel's table name
+func (KubernetesGroupMappingModel) TableName() string {
+	return TableNameKubernetesGroupMappings
+}
+
+// GetKubernetesGroupMappings returns the Kubernetes groups by role configured for the organization
+func GetKubernetesGroupMappings(organizationID uint) (map[string][]string, error) {
+	var mappings []KubernetesGroupMappingModel
+	err := database.GetDB().Where(&KubernetesGroupMappingModel{OrganizationID: organizationID}).Find(&mappings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string, len(mappings))
+	for _, mapping := range mappings {
+		groups[mapping.Role] = []string{}
+		if mapping.Groups != "" {
+			groups[mapping.Role] = strings.Split(mapping.Groups, ",")
+		}
+	}
+	return groups, nil
+}
+
+// SetKubernetesGroupMappings replaces the Kubernetes groups by role configured for the organization
+func SetKubernetesGroupMappings(organizationID uint, groups map[string][]string) error {
+	tx := database.GetDB().Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	err := tx.Where(&KubernetesGroupMappingModel{OrganizationID: organizationID}).Delete(&KubernetesGroupMappingModel{}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for role, roleGroups := range groups {
+		err = tx.Create(&KubernetesGroupMappingModel{
+			OrganizationID: organizationID,
+			Role:           role,
+			Groups:         strings.Join(roleGroups, ","),
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
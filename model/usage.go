@@ -0,0 +1,136 @@
+package model
+
+import (
+	"time"
+
+	"github.com/banzaicloud/pipeline/database"
+)
+
+// TableNameClusterUsageSamples is the table name of the cluster resource usage samples
+const TableNameClusterUsageSamples = "cluster_usage_samples"
+
+// TableNameClusterUsageSampler is the table name of the cluster usage sampler state
+const TableNameClusterUsageSampler = "cluster_usage_sampler"
+
+// clusterUsageSamplerID is the ID of the single row of the sampler state
+const clusterUsageSamplerID = 1
+
+// ClusterUsageSampleModel describes the resource summary of a node pool at a point in time,
+// the sample with empty node pool name contains the summary of the whole cluster.
+// CPU values are stored in millicores, memory values in bytes.
+type ClusterUsageSampleModel struct {
+	ID                uint      `gorm:"primary_key"`
+	ClusterID         uint      `gorm:"index:idx_cluster_timestamp"`
+	NodePool          string    `gorm:"size:255"`
+	Timestamp         time.Time `gorm:"index:idx_cluster_timestamp"`
+	Resolution        int64     // length of the period covered by the sample in seconds, 0 for raw samples
+	Nodes             int
+	CPUCapacity       int64
+	CPUAllocatable    int64
+	CPURequest        int64
+	CPULimit          int64
+	MemoryCapacity    int64
+	MemoryAllocatable int64
+	MemoryRequest     int64
+	MemoryLimit       int64
+}
+
+// TableName sets ClusterUsageSampleModel's table name
+func (ClusterUsageSampleModel) TableName() string {
+	return TableNameClusterUsageSamples
+}
+
+// ClusterUsageSamplerModel records the last sampling run, so only one Pipeline instance samples the clusters in a period
+type ClusterUsageSamplerModel struct {
+	ID        uint `gorm:"primary_key"`
+	LastRunAt time.Time
+}
+
+// TableName sets ClusterUsageSamplerModel's table name
+func (ClusterUsageSamplerModel) TableName() string {
+	return TableNameClusterUsageSampler
+}
+
+// ClaimClusterUsageSampling records a sampling run at the given time, it returns false if another
+// Pipeline instance has run the sampling in the last half interval
+func ClaimClusterUsageSampling(now time.Time, interval time.Duration) (bool, error) {
+	db := database.GetDB()
+
+	if err := db.FirstOrCreate(&ClusterUsageSamplerModel{}, ClusterUsageSamplerModel{ID: clusterUsageSamplerID}).Error; err != nil {
+		return false, err
+	}
+
+	result := db.Model(ClusterUsageSamplerModel{}).
+		Where("id = ? AND last_run_at <= ?", clusterUsageSamplerID, now.Add(-interval/2)).
+		Update("last_run_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// SaveClusterUsageSamples saves the samples to DB
+func SaveClusterUsageSamples(samples []*ClusterUsageSampleModel) error {
+	tx := database.GetDB().Begin()
+	for _, sample := range samples {
+		if err := tx.Create(sample).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// QueryClusterUsageSamples returns the samples of the cluster taken in the [from, to) interval ordered by time
+func QueryClusterUsageSamples(clusterID uint, from, to time.Time) ([]*ClusterUsageSampleModel, error) {
+	var samples []*ClusterUsageSampleModel
+	err := database.GetDB().
+		Where("cluster_id = ? AND timestamp >= ? AND timestamp < ?", clusterID, from, to).
+		Order("timestamp").
+		Find(&samples).Error
+	return samples, err
+}
+
+// QueryClusterUsageSamplesToDownsample returns the samples taken before the given time with a finer resolution
+func QueryClusterUsageSamplesToDownsample(before time.Time, resolution int64) ([]*ClusterUsageSampleModel, error) {
+	var samples []*ClusterUsageSampleModel
+	err := database.GetDB().
+		Where("timestamp < ? AND resolution < ?", before, resolution).
+		Order("timestamp").
+		Find(&samples).Error
+	return samples, err
+}
+
+// ReplaceClusterUsageSamples replaces the given samples with their downsampled versions
+func ReplaceClusterUsageSamples(old []*ClusterUsageSampleModel, downsampled []*ClusterUsageSampleModel) error {
+	ids := make([]uint, 0, len(old))
+	for _, sample := range old {
+		ids = append(ids, sample.ID)
+	}
+
+	tx := database.GetDB().Begin()
+	for _, sample := range downsampled {
+		if err := tx.Create(sample).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	// delete in batches to keep the size of the statements reasonable
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > 1000 {
+			n = 1000
+		}
+		if err := tx.Where("id IN (?)", ids[:n]).Delete(&ClusterUsageSampleModel{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		ids = ids[n:]
+	}
+	return tx.Commit().Error
+}
+
+// DeleteClusterUsageSamplesBefore deletes the samples taken before the given time
+func DeleteClusterUsageSamplesBefore(before time.Time) error {
+	return database.GetDB().Where("timestamp < ?", before).Delete(&ClusterUsageSampleModel{}).Error
+}
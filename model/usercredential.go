@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/banzaicloud/pipeline/database"
+)
+
+// TableNameClusterUserCredentials is the table name of the per-user cluster credentials
+const TableNameClusterUserCredentials = "cluster_user_credentials"
+
+// ClusterUserCredentialModel describes a short-lived credential issued to a user on a cluster
+type ClusterUserCredentialModel struct {
+	ID             uint      `json:"id" gorm:"primary_key"`
+	CreatedAt      time.Time `json:"createdAt"`
+	ClusterID      uint      `json:"clusterId" gorm:"index"`
+	OrganizationID uint      `json:"organizationId" gorm:"index:idx_org_user"`
+	UserID         uint      `json:"userId" gorm:"index:idx_org_user"`
+	Type           string    `json:"type"`
+	Name           string    `json:"name"`
+	Namespace      string    `json:"namespace,omitempty"`
+	ClusterRole    string    `json:"clusterRole"`
+	ExpiresAt      time.Time `json:"expiresAt" gorm:"index"`
+}
+
+// TableName sets ClusterUserCredentialModel's table name
+func (ClusterUserCredentialModel) TableName() string {
+	return TableNameClusterUserCredentials
+}
+
+// Save saves the credential to DB
+func (uc *ClusterUserCredentialModel) Save() error {
+	return database.GetDB().Save(uc).Error
+}
+
+// Delete deletes the credential from DB
+func (uc *ClusterUserCredentialModel) Delete() error {
+	return database.GetDB().Delete(uc).Error
+}
+
+// QueryExpiredUserCredentials returns the credentials expired before the given time
+func QueryExpiredUserCredentials(before time.Time) ([]ClusterUserCredentialModel, error) {
+	var credentials []ClusterUserCredentialModel
+	err := database.GetDB().Where("expires_at < ?", before).Find(&credentials).Error
+	return credentials, err
+}
+
+// QueryUserCredentials returns the credentials issued to the user in the organization
+func QueryUserCredentials(organizationID, userID uint) ([]ClusterUserCredentialModel, error) {
+	var credentials []ClusterUserCredentialModel
+	err := database.GetDB().Where(&ClusterUserCredentialModel{OrganizationID: organizationID, UserID: userID}).Find(&credentials).Error
+	return credentials, err
+}
@@ -0,0 +1,212 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	pkgNotify "github.com/banzaicloud/pipeline/pkg/notify"
+	"github.com/banzaicloud/pipeline/pkg/outbound"
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"gopkg.in/gomail.v2"
+)
+
+// httpTimeout is the timeout of the requests to the webhooks of the channels
+const httpTimeout = 10 * time.Second
+
+// sender delivers events to a notification channel
+type sender interface {
+	send(event *Event) error
+}
+
+// ChannelSecretType returns the secret type holding the credentials of the channel type
+func ChannelSecretType(channelType string) string {
+	if channelType == pkgNotify.ChannelEmail {
+		return pkgSecret.SMTPSecretType
+	}
+	return pkgSecret.WebhookSecretType
+}
+
+// ValidateChannelSecret checks the type of the secret holding the credentials of the channel, and the webhook URL
+// in it. Unless allowed, the URL must resolve to public addresses.
+func ValidateChannelSecret(channelType string, channelSecret *secret.SecretItemResponse) error {
+	if err := channelSecret.ValidateSecretType(ChannelSecretType(channelType)); err != nil {
+		return err
+	}
+	if channelType == pkgNotify.ChannelEmail {
+		return nil
+	}
+
+	return errors.Wrap(
+		outbound.ValidateURL(channelSecret.GetValue(pkgSecret.WebhookURL), viper.GetBool(config.NotifyAllowPrivateNetworks)),
+		"invalid webhook URL",
+	)
+}
+
+// newSender creates the sender of the channel with the credentials read from the secret
+func newSender(channel *ChannelModel, channelSecret *secret.SecretItemResponse) (sender, error) {
+	if err := channelSecret.ValidateSecretType(ChannelSecretType(channel.Type)); err != nil {
+		return nil, err
+	}
+
+	switch channel.Type {
+	case pkgNotify.ChannelSlack:
+		return &slackSender{
+			webhookURL: channelSecret.GetValue(pkgSecret.WebhookURL),
+			channel:    channel.SlackChannel,
+		}, nil
+	case pkgNotify.ChannelWebhook:
+		return &webhookSender{
+			url:           channelSecret.GetValue(pkgSecret.WebhookURL),
+			authorization: channelSecret.GetValue(pkgSecret.WebhookAuthorization),
+		}, nil
+	case pkgNotify.ChannelTeams:
+		return &teamsSender{
+			webhookURL: channelSecret.GetValue(pkgSecret.WebhookURL),
+		}, nil
+	case pkgNotify.ChannelEmail:
+		port, err := strconv.Atoi(channelSecret.GetValue(pkgSecret.SMTPPort))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid SMTP port")
+		}
+		return &emailSender{
+			host:       channelSecret.GetValue(pkgSecret.SMTPHost),
+			port:       port,
+			username:   channelSecret.GetValue(pkgSecret.Username),
+			password:   channelSecret.GetValue(pkgSecret.Password),
+			from:       channelSecret.GetValue(pkgSecret.SMTPFrom),
+			recipients: strings.Split(channel.Recipients, ","),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported channel type %q", channel.Type)
+}
+
+// slackSender posts the events to a Slack incoming webhook
+type slackSender struct {
+	webhookURL string
+	channel    string
+}
+
+func (s *slackSender) send(event *Event) error {
+	body, err := json.Marshal(Slack{
+		Text:      fmt.Sprintf("*%s*\n%s", event.Title(), event.Message),
+		Username:  "banzaicloud",
+		IconEmoji: ":cloud:",
+		Channel:   s.channel,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error marshalling message")
+	}
+
+	form := url.Values{"payload": {string(body)}}
+	request, err := http.NewRequest(http.MethodPost, s.webhookURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.Wrap(err, "error creating webhook request")
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return doRequest(request)
+}
+
+// webhookSender posts the events in JSON to a generic webhook
+type webhookSender struct {
+	url           string
+	authorization string
+}
+
+func (s *webhookSender) send(event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling event")
+	}
+
+	request, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error creating webhook request")
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(s.authorization) != 0 {
+		request.Header.Set("Authorization", s.authorization)
+	}
+
+	return doRequest(request)
+}
+
+// teamsSender posts the events as message cards to a Microsoft Teams compatible incoming webhook
+type teamsSender struct {
+	webhookURL string
+}
+
+func (s *teamsSender) send(event *Event) error {
+	body, err := json.Marshal(map[string]string{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  event.Title(),
+		"title":    event.Title(),
+		"text":     event.Message,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error marshalling message card")
+	}
+
+	request, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error creating webhook request")
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	return doRequest(request)
+}
+
+// emailSender sends the events in email through an SMTP server
+type emailSender struct {
+	host       string
+	port       int
+	username   string
+	password   string
+	from       string
+	recipients []string
+}
+
+func (s *emailSender) send(event *Event) error {
+	message := gomail.NewMessage()
+	message.SetHeader("From", s.from)
+	message.SetHeader("To", s.recipients...)
+	message.SetHeader("Subject", "[Pipeline] "+event.Title())
+	message.SetBody("text/plain", fmt.Sprintf("%s\n\nOrganization: %d\nTime: %s\n",
+		event.Message, event.OrganizationID, event.Time.Format(time.RFC3339)))
+
+	dialer := &gomail.Dialer{Host: s.host, Port: s.port, SSL: s.port == 465}
+	if len(s.username) != 0 {
+		dialer = gomail.NewPlainDialer(s.host, s.port, s.username, s.password)
+	}
+
+	return errors.Wrap(dialer.DialAndSend(message), "error sending email")
+}
+
+// doRequest posts to the webhook of a channel, the addresses are checked again when connecting,
+// and redirects are not followed
+func doRequest(request *http.Request) error {
+	client := outbound.NewClient(httpTimeout, viper.GetBool(config.NotifyAllowPrivateNetworks))
+	response, err := client.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "error posting to webhook")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with %s", response.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package notify
+
+import (
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/database"
+	pkgNotify "github.com/banzaicloud/pipeline/pkg/notify"
+)
+
+// Table names of the notification models
+const (
+	TableNameChannels       = "notification_channels"
+	TableNameSubscriptions  = "notification_subscriptions"
+	TableNameSecretExpiries = "notification_secret_expiries"
+)
+
+// ChannelModel describes a notification channel of an organization, the credentials are stored in the secret store
+type ChannelModel struct {
+	ID             uint `gorm:"primary_key"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	OrganizationID uint   `gorm:"unique_index:idx_org_name"`
+	Name           string `gorm:"unique_index:idx_org_name"`
+	Type           string
+	SecretID       string
+	SlackChannel   string
+	Recipients     string
+	CreatedBy      uint
+}
+
+// SubscriptionModel describes the event types sent to a notification channel
+type SubscriptionModel struct {
+	ID             uint `gorm:"primary_key"`
+	CreatedAt      time.Time
+	OrganizationID uint `gorm:"index"`
+	ChannelID      uint `gorm:"index"`
+	Events         string
+	CreatedBy      uint
+}
+
+// SecretExpiryModel records the last threshold the expiry of the certificates of a TLS secret was notified at
+type SecretExpiryModel struct {
+	ID             uint   `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"unique_index:idx_org_secret"`
+	SecretID       string `gorm:"unique_index:idx_org_secret"`
+	ExpiresAt      time.Time
+	Threshold      time.Duration
+	NotifiedAt     time.Time
+}
+
+// TableName sets ChannelModel's table name
+func (ChannelModel) TableName() string {
+	return TableNameChannels
+}
+
+// TableName sets SubscriptionModel's table name
+func (SubscriptionModel) TableName() string {
+	return TableNameSubscriptions
+}
+
+// TableName sets SecretExpiryModel's table name
+func (SecretExpiryModel) TableName() string {
+	return TableNameSecretExpiries
+}
+
+// Save the channel to DB
+func (m *ChannelModel) Save() error {
+	return database.GetDB().Save(m).Error
+}
+
+// Delete the channel and its subscriptions from DB
+func (m *ChannelModel) Delete() error {
+	tx := database.GetDB().Begin()
+	if err := tx.Where("channel_id = ?", m.ID).Delete(SubscriptionModel{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(m).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// Save the subscription to DB
+func (m *SubscriptionModel) Save() error {
+	return database.GetDB().Save(m).Error
+}
+
+// Delete the subscription from DB
+func (m *SubscriptionModel) Delete() error {
+	return database.GetDB().Delete(m).Error
+}
+
+// HasEvent returns true if the subscription covers the event type
+func (m *SubscriptionModel) HasEvent(eventType string) bool {
+	for _, event := range strings.Split(m.Events, ",") {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// ToResponse converts the model to API response
+func (m *ChannelModel) ToResponse() *pkgNotify.ChannelResponse {
+	response := &pkgNotify.ChannelResponse{
+		ID:           m.ID,
+		Name:         m.Name,
+		Type:         m.Type,
+		SecretID:     m.SecretID,
+		SlackChannel: m.SlackChannel,
+		CreatedBy:    m.CreatedBy,
+		CreatedAt:    m.CreatedAt,
+	}
+	if len(m.Recipients) != 0 {
+		response.Recipients = strings.Split(m.Recipients, ",")
+	}
+	return response
+}
+
+// ToResponse converts the model to API response
+func (m *SubscriptionModel) ToResponse() *pkgNotify.SubscriptionResponse {
+	return &pkgNotify.SubscriptionResponse{
+		ID:        m.ID,
+		ChannelID: m.ChannelID,
+		Events:    strings.Split(m.Events, ","),
+		CreatedBy: m.CreatedBy,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// GetChannel returns a notification channel of the organization
+func GetChannel(organizationID, channelID uint) (*ChannelModel, error) {
+	var channel ChannelModel
+	err := database.GetDB().Where("organization_id = ? AND id = ?", organizationID, channelID).First(&channel).Error
+	return &channel, err
+}
+
+// ListChannels returns the notification channels of the organization
+func ListChannels(organizationID uint) ([]*ChannelModel, error) {
+	var channels []*ChannelModel
+	err := database.GetDB().Where("organization_id = ?", organizationID).Order("name").Find(&channels).Error
+	return channels, err
+}
+
+// GetSubscription returns a notification subscription of the organization
+func GetSubscription(organizationID, subscriptionID uint) (*SubscriptionModel, error) {
+	var subscription SubscriptionModel
+	err := database.GetDB().Where("organization_id = ? AND id = ?", organizationID, subscriptionID).First(&subscription).Error
+	return &subscription, err
+}
+
+// ListSubscriptions returns the notification subscriptions of the organization
+func ListSubscriptions(organizationID uint) ([]*SubscriptionModel, error) {
+	var subscriptions []*SubscriptionModel
+	err := database.GetDB().Where("organization_id = ?", organizationID).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// claimSecretExpiry records that the expiry of the secret is notified at the threshold, it returns false if
+// it has already been notified at the threshold or a smaller one, e.g. by another instance in the meantime
+func claimSecretExpiry(organizationID uint, secretID string, expiresAt time.Time, threshold time.Duration) (bool, error) {
+	db := database.GetDB()
+	now := time.Now()
+
+	result := db.Model(SecretExpiryModel{}).
+		Where("organization_id = ? AND secret_id = ? AND (expires_at <> ? OR threshold > ?)", organizationID, secretID, expiresAt, threshold).
+		Updates(map[string]interface{}{"expires_at": expiresAt, "threshold": threshold, "notified_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	var count int
+	if err := db.Model(SecretExpiryModel{}).Where("organization_id = ? AND secret_id = ?", organizationID, secretID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count != 0 {
+		return false, nil
+	}
+
+	err := db.Create(&SecretExpiryModel{
+		OrganizationID: organizationID,
+		SecretID:       secretID,
+		ExpiresAt:      expiresAt,
+		Threshold:      threshold,
+		NotifiedAt:     now,
+	}).Error
+	if err != nil {
+		// the unique index rejects the record if another instance has created it in the meantime
+		if db.Model(SecretExpiryModel{}).Where("organization_id = ? AND secret_id = ?", organizationID, secretID).Count(&count).Error == nil && count != 0 {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
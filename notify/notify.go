@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	pkgNotify "github.com/banzaicloud/pipeline/pkg/notify"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/pkg/errors"
+)
+
+// eventTitles contains the human readable titles of the event types
+var eventTitles = map[string]string{
+	pkgNotify.EventClusterCreated:   "Cluster created",
+	pkgNotify.EventClusterFailed:    "Cluster failed",
+	pkgNotify.EventClusterDeleted:   "Cluster deleted",
+	pkgNotify.EventPosthookFailed:   "Cluster posthook failed",
+	pkgNotify.EventClusterAlert:     "Cluster alert",
+	pkgNotify.EventDeploymentFailed: "Deployment failed",
+	pkgNotify.EventSecretExpiring:   "Secret expiring",
+}
+
+// Event describes an event of an organization which is sent to the subscribed notification channels
+type Event struct {
+	Type           string    `json:"type"`
+	OrganizationID uint      `json:"organizationId"`
+	ClusterID      uint      `json:"clusterId,omitempty"`
+	ClusterName    string    `json:"clusterName,omitempty"`
+	Message        string    `json:"message"`
+	Time           time.Time `json:"time"`
+}
+
+// Title returns the short description of the event
+func (e *Event) Title() string {
+	title, ok := eventTitles[e.Type]
+	if !ok {
+		title = e.Type
+	}
+	if len(e.ClusterName) != 0 {
+		title = fmt.Sprintf("%s: %s", title, e.ClusterName)
+	}
+	return title
+}
+
+// Publish sends the event to the notification channels of the organization subscribed to its type in the background
+func Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	go dispatch(&event)
+}
+
+// dispatch sends the event to every subscribed channel once
+func dispatch(event *Event) {
+	subscriptions, err := ListSubscriptions(event.OrganizationID)
+	if err != nil {
+		log.Errorf("Error listing notification subscriptions: %s", err.Error())
+		return
+	}
+
+	sent := make(map[uint]bool)
+	for _, subscription := range subscriptions {
+		if sent[subscription.ChannelID] || !subscription.HasEvent(event.Type) {
+			continue
+		}
+		sent[subscription.ChannelID] = true
+
+		channel, err := GetChannel(event.OrganizationID, subscription.ChannelID)
+		if err != nil {
+			log.Errorf("Error getting notification channel %d: %s", subscription.ChannelID, err.Error())
+			continue
+		}
+
+		if err := Send(channel, event); err != nil {
+			log.Warnf("Error sending %s notification to channel %s: %s", event.Type, channel.Name, err.Error())
+		}
+	}
+}
+
+// Send delivers the event to the channel
+func Send(channel *ChannelModel, event *Event) error {
+	channelSecret, err := secret.Store.Get(channel.OrganizationID, channel.SecretID)
+	if err != nil {
+		return errors.Wrap(err, "error getting channel secret")
+	}
+
+	sender, err := newSender(channel, channelSecret)
+	if err != nil {
+		return err
+	}
+
+	return sender.send(event)
+}
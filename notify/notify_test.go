@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	pkgNotify "github.com/banzaicloud/pipeline/pkg/notify"
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/spf13/viper"
+)
+
+func TestWebhookSender(t *testing.T) {
+	allowPrivate := viper.GetBool(config.NotifyAllowPrivateNetworks)
+	viper.Set(config.NotifyAllowPrivateNetworks, true)
+	defer viper.Set(config.NotifyAllowPrivateNetworks, allowPrivate)
+
+	var received Event
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	event := &Event{Type: pkgNotify.EventClusterFailed, OrganizationID: 1, ClusterID: 2, ClusterName: "mycluster", Message: "failed"}
+	sender := &webhookSender{url: server.URL, authorization: "Bearer token"}
+	if err := sender.send(event); err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Type != event.Type || received.ClusterName != event.ClusterName || authorization != "Bearer token" {
+		t.Errorf("unexpected webhook request: %+v, authorization: %q", received, authorization)
+	}
+	if title := event.Title(); title != "Cluster failed: mycluster" {
+		t.Errorf("unexpected title: %q", title)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+
+	if err := (&teamsSender{webhookURL: failing.URL}).send(event); err == nil {
+		t.Error("expected error for failed webhook response")
+	}
+}
+
+func TestSubscriptionHasEvent(t *testing.T) {
+	subscription := &SubscriptionModel{Events: pkgNotify.EventClusterCreated + "," + pkgNotify.EventClusterFailed}
+
+	if !subscription.HasEvent(pkgNotify.EventClusterFailed) || subscription.HasEvent(pkgNotify.EventClusterDeleted) {
+		t.Errorf("unexpected subscription match for %q", subscription.Events)
+	}
+}
+
+func TestCertificateExpiry(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificate := func(notAfter time.Time) string {
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "test"},
+			NotBefore:    notAfter.Add(-time.Hour),
+			NotAfter:     notAfter,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	}
+
+	soon := time.Now().Add(48 * time.Hour).Truncate(time.Second).UTC()
+	expiry, ok := certificateExpiry(map[string]string{
+		pkgSecret.CACert:     certificate(soon.Add(365 * 24 * time.Hour)),
+		pkgSecret.ServerCert: certificate(soon),
+		pkgSecret.TLSHosts:   "localhost",
+	})
+	if !ok || !expiry.Equal(soon) {
+		t.Errorf("expected the earliest expiry %s, got %s", soon, expiry)
+	}
+
+	if _, ok := certificateExpiry(map[string]string{pkgSecret.TLSHosts: "localhost"}); ok {
+		t.Error("expected no expiry without certificates")
+	}
+}
+
+func TestExpiryThreshold(t *testing.T) {
+	thresholds, err := parseThresholds([]string{"336h", "72h", "24h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for remaining, expected := range map[time.Duration]time.Duration{
+		-time.Hour:      24 * time.Hour,
+		12 * time.Hour:  24 * time.Hour,
+		48 * time.Hour:  72 * time.Hour,
+		100 * time.Hour: 336 * time.Hour,
+	} {
+		if actual, ok := expiryThreshold(thresholds, remaining); !ok || actual != expected {
+			t.Errorf("%s: expected threshold %s, got %s", remaining, expected, actual)
+		}
+	}
+
+	if _, ok := expiryThreshold(thresholds, 500*time.Hour); ok {
+		t.Error("expected no threshold for a far expiry")
+	}
+	if _, err := parseThresholds([]string{"2 weeks"}); err == nil {
+		t.Error("expected an error for an invalid threshold")
+	}
+}
+
+func TestValidateChannelSecret(t *testing.T) {
+	allowPrivate := viper.GetBool(config.NotifyAllowPrivateNetworks)
+	viper.Set(config.NotifyAllowPrivateNetworks, false)
+	defer viper.Set(config.NotifyAllowPrivateNetworks, allowPrivate)
+
+	webhookSecret := func(url string) *secret.SecretItemResponse {
+		return &secret.SecretItemResponse{Type: pkgSecret.WebhookSecretType, Values: map[string]string{pkgSecret.WebhookURL: url}}
+	}
+
+	if err := ValidateChannelSecret(pkgNotify.ChannelWebhook, webhookSecret("https://8.8.8.8/hook")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, url := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest/meta-data/", "file:///etc/passwd"} {
+		if err := ValidateChannelSecret(pkgNotify.ChannelSlack, webhookSecret(url)); err == nil {
+			t.Errorf("%s: expected an error", url)
+		}
+	}
+}
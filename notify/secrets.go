@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/database"
+	pkgNotify "github.com/banzaicloud/pipeline/pkg/notify"
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/spf13/viper"
+)
+
+// certificateKeys are the TLS secret values holding certificates
+var certificateKeys = []string{pkgSecret.CACert, pkgSecret.ServerCert, pkgSecret.ClientCert}
+
+// CheckExpiringSecrets publishes an event for every TLS secret with a certificate expiring within one of the
+// configured thresholds. The notified thresholds are recorded, so an event is published only once per threshold,
+// even if the check runs on multiple instances.
+func CheckExpiringSecrets() {
+	thresholds, err := parseThresholds(viper.GetStringSlice(config.NotifySecretExpiryThresholds))
+	if err != nil {
+		log.Errorf("Invalid secret expiry threshold: %s", err.Error())
+		return
+	}
+
+	var organizations []auth.Organization
+	if err := database.GetDB().Find(&organizations).Error; err != nil {
+		log.Errorf("Error listing organizations: %s", err.Error())
+		return
+	}
+
+	for _, organization := range organizations {
+		secrets, err := secret.Store.List(organization.ID, &pkgSecret.ListSecretsQuery{Type: pkgSecret.TLSSecretType, Values: true})
+		if err != nil {
+			log.Errorf("Error listing secrets of organization %d: %s", organization.ID, err.Error())
+			continue
+		}
+
+		for _, tlsSecret := range secrets {
+			expiry, ok := certificateExpiry(tlsSecret.Values)
+			if !ok {
+				continue
+			}
+
+			threshold, ok := expiryThreshold(thresholds, time.Until(expiry))
+			if !ok {
+				continue
+			}
+
+			claimed, err := claimSecretExpiry(organization.ID, tlsSecret.ID, expiry, threshold)
+			if err != nil {
+				log.Errorf("Error recording expiry notification of secret %s: %s", tlsSecret.ID, err.Error())
+				continue
+			}
+			if !claimed {
+				continue
+			}
+
+			Publish(Event{
+				Type:           pkgNotify.EventSecretExpiring,
+				OrganizationID: organization.ID,
+				Message:        fmt.Sprintf("A certificate of secret %s (%s) expires at %s", tlsSecret.Name, tlsSecret.ID, expiry.Format(time.RFC3339)),
+			})
+		}
+	}
+}
+
+// parseThresholds parses the expiry thresholds
+func parseThresholds(values []string) ([]time.Duration, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no thresholds configured")
+	}
+
+	thresholds := make([]time.Duration, 0, len(values))
+	for _, value := range values {
+		threshold, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, threshold)
+	}
+
+	return thresholds, nil
+}
+
+// expiryThreshold returns the smallest threshold the remaining time until the expiry is within
+func expiryThreshold(thresholds []time.Duration, remaining time.Duration) (time.Duration, bool) {
+	var threshold time.Duration
+	found := false
+
+	for _, t := range thresholds {
+		if remaining <= t && (!found || t < threshold) {
+			threshold = t
+			found = true
+		}
+	}
+
+	return threshold, found
+}
+
+// certificateExpiry returns the earliest expiry of the certificates in the TLS secret values
+func certificateExpiry(values map[string]string) (time.Time, bool) {
+	var expiry time.Time
+	found := false
+
+	for _, key := range certificateKeys {
+		block, _ := pem.Decode([]byte(values[key]))
+		if block == nil {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			log.Debugf("Error parsing certificate %s: %s", key, err.Error())
+			continue
+		}
+
+		if !found || certificate.NotAfter.Before(expiry) {
+			expiry = certificate.NotAfter
+			found = true
+		}
+	}
+
+	return expiry, found
+}
+
+// StartSecretExpiryCheck periodically checks the certificates of the TLS secrets
+func StartSecretExpiryCheck(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			log.Debug("Secret expiry check running")
+			CheckExpiringSecrets()
+		}
+	}()
+
+	return ticker
+}
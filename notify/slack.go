@@ -46,6 +46,11 @@ func SlackNotify(message string) error {
 	content.Username = "banzaicloud"
 	content.Text = message
 
+	return postSlackMessage(webhookUrl, content)
+}
+
+// postSlackMessage posts the message to the Slack incoming webhook
+func postSlackMessage(webhookUrl string, content Slack) error {
 	params, marsErr := json.Marshal(content)
 	if marsErr != nil {
 		log.Debug(marsErr)
@@ -65,5 +70,8 @@ func SlackNotify(message string) error {
 		return fmt.Errorf("http response failed: %s", respErr)
 	}
 	log.Debug("Slack API Response:", string(body))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack responded with %s: %s", resp.Status, string(body))
+	}
 	return nil
 }
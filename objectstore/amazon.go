@@ -1,7 +1,9 @@
 package objectstore
 
 import (
+	"bytes"
 	"errors"
+	"io/ioutil"
 	"sort"
 	"strings"
 
@@ -237,3 +239,61 @@ func (b *AmazonObjectStore) newManagedBucketSearchCriteria(bucketName string) *M
 		Name:  bucketName,
 	}
 }
+
+// PutObject uploads an object into the given managed S3 bucket
+func (b *AmazonObjectStore) PutObject(bucketName, key string, body []byte) error {
+	svc, err := b.newManagedBucketClient(bucketName)
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	})
+	return err
+}
+
+// GetObject downloads an object from the given managed S3 bucket
+func (b *AmazonObjectStore) GetObject(bucketName, key string) ([]byte, error) {
+	svc, err := b.newManagedBucketClient(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	return ioutil.ReadAll(output.Body)
+}
+
+// DeleteObject deletes an object from the given managed S3 bucket
+func (b *AmazonObjectStore) DeleteObject(bucketName, key string) error {
+	svc, err := b.newManagedBucketClient(bucketName)
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// newManagedBucketClient creates an S3 client for the region of the given managed bucket
+func (b *AmazonObjectStore) newManagedBucketClient(bucketName string) (*s3.S3, error) {
+	managedBucket := &ManagedAmazonBucket{}
+	if err := getManagedBucket(b.newManagedBucketSearchCriteria(bucketName), managedBucket); err != nil {
+		return nil, err
+	}
+
+	return createS3Client(managedBucket.Region, b.secret)
+}
@@ -1,8 +1,10 @@
 package objectstore
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"sort"
@@ -513,3 +515,66 @@ func (b *AzureObjectStore) newManagedBucketSearchCriteria(bucketName string) *Ma
 func generateResourceGroupName(location string) string {
 	return fmt.Sprintf("pipeline-auto-%s", location)
 }
+
+// PutObject uploads a block blob into the given managed storage container
+func (b *AzureObjectStore) PutObject(bucketName, key string, body []byte) error {
+	containerURL, err := b.newManagedContainerURL(bucketName)
+	if err != nil {
+		return err
+	}
+
+	_, err = containerURL.NewBlockBlobURL(key).PutBlob(context.Background(), bytes.NewReader(body),
+		azblob.BlobHTTPHeaders{}, azblob.Metadata{}, azblob.BlobAccessConditions{})
+	return err
+}
+
+// GetObject downloads a blob from the given managed storage container
+func (b *AzureObjectStore) GetObject(bucketName, key string) ([]byte, error) {
+	containerURL, err := b.newManagedContainerURL(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := containerURL.NewBlobURL(key).GetBlob(context.Background(), azblob.BlobRange{}, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		return nil, err
+	}
+	body := response.Body()
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
+
+// DeleteObject deletes a blob from the given managed storage container
+func (b *AzureObjectStore) DeleteObject(bucketName, key string) error {
+	containerURL, err := b.newManagedContainerURL(bucketName)
+	if err != nil {
+		return err
+	}
+
+	_, err = containerURL.NewBlobURL(key).Delete(context.Background(), azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{})
+	return err
+}
+
+// newManagedContainerURL returns the URL of the given managed storage container under the current
+// resource group and storage account
+func (b *AzureObjectStore) newManagedContainerURL(bucketName string) (*azblob.ContainerURL, error) {
+	managedBucket := &ManagedAzureBlobStore{}
+	if err := getManagedBucket(b.newManagedBucketSearchCriteria(bucketName), managedBucket); err != nil {
+		return nil, err
+	}
+
+	key, err := getStorageAccountKey(b.secret, b.resourceGroup, b.storageAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	p := azblob.NewPipeline(azblob.NewSharedKeyCredential(b.storageAccount, key), azblob.PipelineOptions{})
+	URL, err := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", b.storageAccount, bucketName))
+	if err != nil {
+		return nil, err
+	}
+	containerURL := azblob.NewContainerURL(*URL, p)
+
+	return &containerURL, nil
+}
@@ -37,6 +37,10 @@ type ObjectStore interface {
 	DeleteBucket(string) error
 	CheckBucket(string) error
 
+	PutObject(bucketName, key string, body []byte) error
+	GetObject(bucketName, key string) ([]byte, error)
+	DeleteObject(bucketName, key string) error
+
 	WithResourceGroup(string) error
 	WithStorageAccount(string) error
 	WithRegion(string) error
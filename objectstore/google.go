@@ -12,6 +12,7 @@ import (
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	apiStorage "google.golang.org/api/storage/v1"
+	"io/ioutil"
 	"sort"
 	"strings"
 )
@@ -277,3 +278,65 @@ func (b *GoogleObjectStore) newManagedBucketSearchCriteria(bucketName string) *M
 		Name:  bucketName,
 	}
 }
+
+// PutObject uploads an object into the given managed GS bucket
+func (b *GoogleObjectStore) PutObject(bucketName, key string, body []byte) error {
+	ctx := context.Background()
+	client, err := b.newManagedBucketClient(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	writer := client.Bucket(bucketName).Object(key).NewWriter(ctx)
+	if _, err := writer.Write(body); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+// GetObject downloads an object from the given managed GS bucket
+func (b *GoogleObjectStore) GetObject(bucketName, key string) ([]byte, error) {
+	ctx := context.Background()
+	client, err := b.newManagedBucketClient(ctx, bucketName)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	reader, err := client.Bucket(bucketName).Object(key).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+// DeleteObject deletes an object from the given managed GS bucket
+func (b *GoogleObjectStore) DeleteObject(bucketName, key string) error {
+	ctx := context.Background()
+	client, err := b.newManagedBucketClient(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.Bucket(bucketName).Object(key).Delete(ctx)
+}
+
+// newManagedBucketClient creates a storage client after checking that the given bucket is managed
+func (b *GoogleObjectStore) newManagedBucketClient(ctx context.Context, bucketName string) (*storage.Client, error) {
+	managedBucket := &ManagedGoogleBucket{}
+	if err := getManagedBucket(b.newManagedBucketSearchCriteria(bucketName), managedBucket); err != nil {
+		return nil, err
+	}
+
+	credentials, err := newGoogleCredentials(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.NewClient(ctx, option.WithCredentials(credentials))
+}
@@ -265,3 +265,53 @@ func (o *OCIObjectStore) GetManagedBuckets() ([]model.ManagedOracleBucket, error
 
 	return managedBuckets, nil
 }
+
+// PutObject uploads an object into the given managed Oracle object store bucket
+func (o *OCIObjectStore) PutObject(bucketName, key string, body []byte) error {
+	client, err := o.newManagedBucketClient(bucketName)
+	if err != nil {
+		return err
+	}
+
+	return client.PutObject(bucketName, key, body)
+}
+
+// GetObject downloads an object from the given managed Oracle object store bucket
+func (o *OCIObjectStore) GetObject(bucketName, key string) ([]byte, error) {
+	client, err := o.newManagedBucketClient(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetObject(bucketName, key)
+}
+
+// DeleteObject deletes an object from the given managed Oracle object store bucket
+func (o *OCIObjectStore) DeleteObject(bucketName, key string) error {
+	client, err := o.newManagedBucketClient(bucketName)
+	if err != nil {
+		return err
+	}
+
+	return client.DeleteObject(bucketName, key)
+}
+
+// newManagedBucketClient creates an object storage client in the region of the given managed bucket
+func (o *OCIObjectStore) newManagedBucketClient(bucketName string) (*oci.ObjectStorage, error) {
+	oci, err := oci.NewOCI(verify.CreateOCICredential(o.secret.Values))
+	if err != nil {
+		return nil, err
+	}
+
+	managedBucket := &model.ManagedOracleBucket{}
+	searchCriteria := o.newManagedBucketSearchCriteria(bucketName, o.location, oci.CompartmentOCID)
+	if err := getManagedBucket(searchCriteria, managedBucket); err != nil {
+		return nil, err
+	}
+
+	if err := oci.ChangeRegion(managedBucket.Location); err != nil {
+		return nil, err
+	}
+
+	return oci.NewObjectStorageClient()
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Export formats of the audit events
+const (
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// EventResponse describes an audited API request
+type EventResponse struct {
+	ID             uint            `json:"id"`
+	Time           time.Time       `json:"time"`
+	UserID         uint            `json:"userId"`
+	OrganizationID uint            `json:"organizationId"`
+	Method         string          `json:"method"`
+	Path           string          `json:"path"`
+	StatusCode     int             `json:"statusCode"`
+	LatencyMs      int64           `json:"latencyMs"`
+	ResourceType   string          `json:"resourceType,omitempty"`
+	ResourceID     string          `json:"resourceId,omitempty"`
+	ClientIP       string          `json:"clientIp"`
+	UserAgent      string          `json:"userAgent"`
+	Body           *string         `json:"body,omitempty"`
+	Headers        json.RawMessage `json:"headers,omitempty"`
+	Hash           string          `json:"hash,omitempty"`
+}
+
+// CSVHeader contains the columns of the CSV export, request bodies and headers are exported only in JSON formats
+var CSVHeader = []string{
+	"id", "time", "userId", "organizationId", "method", "path", "statusCode", "latencyMs",
+	"resourceType", "resourceId", "clientIp", "userAgent",
+}
+
+// ChainVerification describes the result of verifying the audit event chain of an organization
+type ChainVerification struct {
+	OrganizationID     uint        `json:"organizationId"`
+	Verified           bool        `json:"verified"`
+	EventsChecked      int         `json:"eventsChecked"`
+	CheckpointsChecked int         `json:"checkpointsChecked"`
+	FirstEventID       uint        `json:"firstEventId,omitempty"`
+	LastEventID        uint        `json:"lastEventId,omitempty"`
+	BrokenLink         *BrokenLink `json:"brokenLink,omitempty"`
+}
+
+// CheckpointResponse describes a signed checkpoint of the audit event chain of an organization
+type CheckpointResponse struct {
+	OrganizationID uint      `json:"organizationId"`
+	Sequence       uint      `json:"sequence"`
+	EventID        uint      `json:"eventId"`
+	Hash           string    `json:"hash"`
+	PrevSignature  string    `json:"prevSignature,omitempty"`
+	Signature      string    `json:"signature"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// BrokenLink describes the first event at which the audit event chain is broken
+type BrokenLink struct {
+	EventID  uint   `json:"eventId"`
+	Reason   string `json:"reason"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// Break marks the chain as broken at the given event
+func (v *ChainVerification) Break(eventID uint, reason, expected, actual string) {
+	v.Verified = false
+	v.BrokenLink = &BrokenLink{EventID: eventID, Reason: reason, Expected: expected, Actual: actual}
+}
@@ -0,0 +1,91 @@
+package backup
+
+import "time"
+
+// Backup and restore statuses
+const (
+	StatusCreating  = "CREATING"
+	StatusRestoring = "RESTORING"
+	StatusCompleted = "COMPLETED"
+	StatusFailed    = "FAILED"
+)
+
+// Bucket describes the managed object store bucket a backup is stored in
+type Bucket struct {
+	Cloud          string `json:"cloud" binding:"required"`
+	SecretID       string `json:"secretId" binding:"required"`
+	Name           string `json:"name" binding:"required"`
+	Location       string `json:"location,omitempty"`
+	ResourceGroup  string `json:"resourceGroup,omitempty"`
+	StorageAccount string `json:"storageAccount,omitempty"`
+}
+
+// CreateBackupRequest describes a cluster backup creation request
+type CreateBackupRequest struct {
+	Name          string   `json:"name,omitempty"`
+	Namespaces    []string `json:"namespaces" binding:"required"`
+	LabelSelector string   `json:"labelSelector,omitempty"`
+	Bucket        Bucket   `json:"bucket" binding:"required"`
+}
+
+// RestoreBackupRequest describes a backup restore request. The backup is restored into the
+// cluster it was taken from if no target cluster is given.
+type RestoreBackupRequest struct {
+	TargetClusterID uint `json:"targetClusterId,omitempty"`
+}
+
+// CreateScheduleRequest describes a periodic cluster backup
+type CreateScheduleRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Interval      string   `json:"interval" binding:"required"`
+	Retention     int      `json:"retention,omitempty"`
+	Namespaces    []string `json:"namespaces" binding:"required"`
+	LabelSelector string   `json:"labelSelector,omitempty"`
+	Bucket        Bucket   `json:"bucket" binding:"required"`
+}
+
+// BackupResponse describes a cluster backup
+type BackupResponse struct {
+	ID            uint      `json:"id"`
+	Name          string    `json:"name"`
+	ClusterID     uint      `json:"clusterId"`
+	ScheduleID    uint      `json:"scheduleId,omitempty"`
+	Namespaces    []string  `json:"namespaces"`
+	LabelSelector string    `json:"labelSelector,omitempty"`
+	Bucket        Bucket    `json:"bucket"`
+	ObjectKey     string    `json:"objectKey"`
+	Status        string    `json:"status"`
+	StatusMessage string    `json:"statusMessage,omitempty"`
+	ResourceCount int       `json:"resourceCount"`
+	Size          int64     `json:"size"`
+	CreatedBy     uint      `json:"createdBy,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// RestoreResponse describes a backup restore
+type RestoreResponse struct {
+	ID              uint      `json:"id"`
+	BackupID        uint      `json:"backupId"`
+	TargetClusterID uint      `json:"targetClusterId"`
+	Status          string    `json:"status"`
+	StatusMessage   string    `json:"statusMessage,omitempty"`
+	Created         int       `json:"created"`
+	Skipped         int       `json:"skipped"`
+	Failed          []string  `json:"failed,omitempty"`
+	CreatedBy       uint      `json:"createdBy,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// ScheduleResponse describes a periodic cluster backup
+type ScheduleResponse struct {
+	ID            uint       `json:"id"`
+	Name          string     `json:"name"`
+	ClusterID     uint       `json:"clusterId"`
+	Interval      string     `json:"interval"`
+	Retention     int        `json:"retention"`
+	Namespaces    []string   `json:"namespaces"`
+	LabelSelector string     `json:"labelSelector,omitempty"`
+	Bucket        Bucket     `json:"bucket"`
+	LastRunAt     *time.Time `json:"lastRunAt,omitempty"`
+	NextRunAt     time.Time  `json:"nextRunAt"`
+}
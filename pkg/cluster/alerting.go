@@ -0,0 +1,141 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	promModel "github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql"
+)
+
+// Scopes of the alert rule groups
+const (
+	AlertRuleScopeOrganization = "organization"
+	AlertRuleScopeCluster      = "cluster"
+)
+
+// AlertNotifyLabel is the label of the alert rules whose alerts are sent to the notification channels of Pipeline
+const AlertNotifyLabel = "pipeline_notify"
+
+// alertTemplateDefs are the variables available in the annotation templates, the same as in Prometheus
+const alertTemplateDefs = "{{$labels := .Labels}}{{$externalLabels := .ExternalLabels}}{{$value := .Value}}"
+
+var alertRuleGroupNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([-_.a-zA-Z0-9]*[a-zA-Z0-9])?$`)
+
+// AlertRuleGroup describes a group of Prometheus alerting rules evaluated together
+type AlertRuleGroup struct {
+	Name     string      `json:"name" binding:"required"`
+	Interval string      `json:"interval,omitempty"`
+	Rules    []AlertRule `json:"rules" binding:"required"`
+}
+
+// AlertRule describes a Prometheus alerting rule
+type AlertRule struct {
+	Alert       string            `json:"alert" binding:"required"`
+	Expr        string            `json:"expr" binding:"required"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Notify      bool              `json:"notify,omitempty"`
+}
+
+// AlertRuleGroupResponse describes an alert rule group stored in Pipeline
+type AlertRuleGroupResponse struct {
+	AlertRuleGroup
+	Scope     string `json:"scope"`
+	ClusterID uint   `json:"clusterId,omitempty"`
+}
+
+// Validate checks the names, durations, PromQL expressions, labels and annotation templates of the group
+func (g *AlertRuleGroup) Validate() error {
+	if !alertRuleGroupNameRegexp.MatchString(g.Name) {
+		return fmt.Errorf("invalid group name %q", g.Name)
+	}
+	if len(g.Interval) != 0 {
+		if _, err := promModel.ParseDuration(g.Interval); err != nil {
+			return fmt.Errorf("invalid interval in group %q: %s", g.Name, err.Error())
+		}
+	}
+	if len(g.Rules) == 0 {
+		return fmt.Errorf("group %q has no rules", g.Name)
+	}
+
+	for i := range g.Rules {
+		if err := g.Rules[i].validate(); err != nil {
+			return fmt.Errorf("invalid rule in group %q: %s", g.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (r *AlertRule) validate() error {
+	if !promModel.IsValidMetricName(promModel.LabelValue(r.Alert)) {
+		return fmt.Errorf("invalid alert name %q", r.Alert)
+	}
+	if len(strings.TrimSpace(r.Expr)) == 0 {
+		return fmt.Errorf("expression of alert %q is empty", r.Alert)
+	}
+	if _, err := promql.ParseExpr(r.Expr); err != nil {
+		return fmt.Errorf("invalid expression of alert %q: %s", r.Alert, err.Error())
+	}
+	if len(r.For) != 0 {
+		if _, err := promModel.ParseDuration(r.For); err != nil {
+			return fmt.Errorf("invalid for duration of alert %q: %s", r.Alert, err.Error())
+		}
+	}
+
+	for name, value := range r.Labels {
+		if !promModel.LabelName(name).IsValid() || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid label name %q in alert %q", name, r.Alert)
+		}
+		if name == AlertNotifyLabel {
+			return fmt.Errorf("label %q of alert %q is reserved, use the notify field instead", name, r.Alert)
+		}
+		if _, err := template.New(name).Parse(alertTemplateDefs + value); err != nil {
+			return fmt.Errorf("invalid template in label %q of alert %q: %s", name, r.Alert, err.Error())
+		}
+	}
+
+	for name, value := range r.Annotations {
+		if !promModel.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid annotation name %q in alert %q", name, r.Alert)
+		}
+		if _, err := template.New(name).Parse(alertTemplateDefs + value); err != nil {
+			return fmt.Errorf("invalid template in annotation %q of alert %q: %s", name, r.Alert, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// AlertmanagerMessage is the payload of the Alertmanager webhook receiver
+type AlertmanagerMessage struct {
+	Version           string              `json:"version"`
+	Status            string              `json:"status"`
+	Receiver          string              `json:"receiver"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []AlertmanagerAlert `json:"alerts"`
+}
+
+// AlertmanagerAlert describes an alert sent by the Alertmanager webhook receiver
+type AlertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+}
+
+// AlertNotifyResponse describes the result of forwarding the alerts to the notification channels
+type AlertNotifyResponse struct {
+	Received int `json:"received"`
+	Notified int `json:"notified"`
+}
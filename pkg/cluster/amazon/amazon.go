@@ -26,6 +26,8 @@ type NodePool struct {
 	MaxCount     int    `json:"maxCount"`
 	Count        int    `json:"count"`
 	Image        string `json:"image"`
+
+	pkgCommon.NodePoolSettings
 }
 
 // UpdateClusterAmazon describes Amazon's node fields of an UpdateCluster request
@@ -0,0 +1,149 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	pkgErrors "github.com/banzaicloud/pipeline/pkg/errors"
+)
+
+// ### [ Cluster autoscaler expanders ] ### //
+const (
+	AutoscalerExpanderRandom     = "random"
+	AutoscalerExpanderMostPods   = "most-pods"
+	AutoscalerExpanderLeastWaste = "least-waste"
+	AutoscalerExpanderPrice      = "price"
+)
+
+// AutoscalerSettings describes the cluster-autoscaler settings of a cluster
+type AutoscalerSettings struct {
+	Expander                      string   `json:"expander,omitempty"`
+	ScaleDownEnabled              *bool    `json:"scaleDownEnabled,omitempty"`
+	ScaleDownDelayAfterAdd        string   `json:"scaleDownDelayAfterAdd,omitempty"`
+	ScaleDownDelayAfterDelete     string   `json:"scaleDownDelayAfterDelete,omitempty"`
+	ScaleDownDelayAfterFailure    string   `json:"scaleDownDelayAfterFailure,omitempty"`
+	ScaleDownUnneededTime         string   `json:"scaleDownUnneededTime,omitempty"`
+	ScaleDownUtilizationThreshold *float64 `json:"scaleDownUtilizationThreshold,omitempty"`
+	MaxNodeProvisionTime          string   `json:"maxNodeProvisionTime,omitempty"`
+	SkipNodesWithLocalStorage     *bool    `json:"skipNodesWithLocalStorage,omitempty"`
+	SkipNodesWithSystemPods       *bool    `json:"skipNodesWithSystemPods,omitempty"`
+	BalanceSimilarNodeGroups      *bool    `json:"balanceSimilarNodeGroups,omitempty"`
+}
+
+// Validate checks the autoscaler settings
+func (s *AutoscalerSettings) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	switch s.Expander {
+	case "", AutoscalerExpanderRandom, AutoscalerExpanderMostPods, AutoscalerExpanderLeastWaste, AutoscalerExpanderPrice:
+	default:
+		return fmt.Errorf("invalid autoscaler expander %q, must be one of %s, %s, %s, %s", s.Expander,
+			AutoscalerExpanderRandom, AutoscalerExpanderMostPods, AutoscalerExpanderLeastWaste, AutoscalerExpanderPrice)
+	}
+
+	durations := map[string]string{
+		"scaleDownDelayAfterAdd":     s.ScaleDownDelayAfterAdd,
+		"scaleDownDelayAfterDelete":  s.ScaleDownDelayAfterDelete,
+		"scaleDownDelayAfterFailure": s.ScaleDownDelayAfterFailure,
+		"scaleDownUnneededTime":      s.ScaleDownUnneededTime,
+		"maxNodeProvisionTime":       s.MaxNodeProvisionTime,
+	}
+	for field, value := range durations {
+		if len(value) == 0 {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("invalid autoscaler %s %q, must be a positive duration", field, value)
+		}
+	}
+
+	if t := s.ScaleDownUtilizationThreshold; t != nil && (*t <= 0 || *t > 1) {
+		return pkgErrors.ErrorAutoscalerUtilizationThreshold
+	}
+
+	return nil
+}
+
+// ExtraArgs returns the cluster-autoscaler command line arguments of the settings
+func (s *AutoscalerSettings) ExtraArgs() map[string]string {
+	args := make(map[string]string)
+	if s == nil {
+		return args
+	}
+
+	setString := func(name, value string) {
+		if len(value) != 0 {
+			args[name] = value
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			args[name] = strconv.FormatBool(*value)
+		}
+	}
+
+	setString("expander", s.Expander)
+	setBool("scale-down-enabled", s.ScaleDownEnabled)
+	setString("scale-down-delay-after-add", s.ScaleDownDelayAfterAdd)
+	setString("scale-down-delay-after-delete", s.ScaleDownDelayAfterDelete)
+	setString("scale-down-delay-after-failure", s.ScaleDownDelayAfterFailure)
+	setString("scale-down-unneeded-time", s.ScaleDownUnneededTime)
+	if s.ScaleDownUtilizationThreshold != nil {
+		args["scale-down-utilization-threshold"] = strconv.FormatFloat(*s.ScaleDownUtilizationThreshold, 'f', -1, 64)
+	}
+	setString("max-node-provision-time", s.MaxNodeProvisionTime)
+	setBool("skip-nodes-with-local-storage", s.SkipNodesWithLocalStorage)
+	setBool("skip-nodes-with-system-pods", s.SkipNodesWithSystemPods)
+	setBool("balance-similar-node-groups", s.BalanceSimilarNodeGroups)
+
+	return args
+}
+
+// IsAutoscalerSupported returns true if Pipeline deploys the cluster-autoscaler to the clusters of the cloud
+func IsAutoscalerSupported(cloud string) bool {
+	return cloud == Amazon || cloud == Azure
+}
+
+// AutoscalerStatusResponse describes Pipeline's GetClusterAutoscalerStatus API response
+type AutoscalerStatusResponse struct {
+	Deployed    bool                         `json:"deployed"`
+	Settings    *AutoscalerSettings          `json:"settings,omitempty"`
+	LastUpdated string                       `json:"lastUpdated,omitempty"`
+	ClusterWide *AutoscalerConditions        `json:"clusterWide,omitempty"`
+	NodeGroups  []*AutoscalerNodeGroupStatus `json:"nodeGroups,omitempty"`
+	Events      []*AutoscalerEvent           `json:"events,omitempty"`
+}
+
+// AutoscalerConditions describes the health, scale up and scale down conditions reported by the cluster-autoscaler
+type AutoscalerConditions struct {
+	Health    *AutoscalerCondition `json:"health,omitempty"`
+	ScaleUp   *AutoscalerCondition `json:"scaleUp,omitempty"`
+	ScaleDown *AutoscalerCondition `json:"scaleDown,omitempty"`
+}
+
+// AutoscalerCondition describes a condition reported by the cluster-autoscaler
+type AutoscalerCondition struct {
+	Status             string `json:"status"`
+	Details            string `json:"details,omitempty"`
+	LastProbeTime      string `json:"lastProbeTime,omitempty"`
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+}
+
+// AutoscalerNodeGroupStatus describes the conditions of a node group reported by the cluster-autoscaler
+type AutoscalerNodeGroupStatus struct {
+	Name string `json:"name"`
+	AutoscalerConditions
+}
+
+// AutoscalerEvent describes an event emitted by the cluster-autoscaler
+type AutoscalerEvent struct {
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+	Object    string    `json:"object"`
+	Count     int32     `json:"count"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -29,6 +29,8 @@ type NodePoolCreate struct {
 	MaxCount         int    `json:"maxCount"`
 	Count            int    `json:"count"`
 	NodeInstanceType string `json:"instanceType"`
+
+	pkgCommon.NodePoolSettings
 }
 
 // NodePoolUpdate describes Azure's node count of a UpdateCluster request
@@ -37,6 +39,8 @@ type NodePoolUpdate struct {
 	MinCount    int  `json:"minCount"`
 	MaxCount    int  `json:"maxCount"`
 	Count       int  `json:"count"`
+
+	pkgCommon.NodePoolSettings
 }
 
 // UpdateClusterAzure describes Azure's node fields of an UpdateCluster request
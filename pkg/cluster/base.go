@@ -3,6 +3,7 @@ package cluster
 import (
 	"bytes"
 	"fmt"
+	"time"
 
 	"github.com/banzaicloud/pipeline/pkg/cluster/amazon"
 	"github.com/banzaicloud/pipeline/pkg/cluster/azure"
@@ -41,6 +42,9 @@ const (
 	Oracle     = "oracle"
 )
 
+// GlobalProfileOrganizationID is the organization id of the cluster profiles which are visible for all organizations
+const GlobalProfileOrganizationID uint = 0
+
 // constants for posthooks
 const (
 	StoreKubeConfig                    = "StoreKubeConfig"
@@ -73,12 +77,14 @@ const (
 
 // CreateClusterRequest describes a create cluster request
 type CreateClusterRequest struct {
-	Name        string    `json:"name" binding:"required"`
-	Location    string    `json:"location"`
-	Cloud       string    `json:"cloud" binding:"required"`
-	SecretId    string    `json:"secretId" binding:"required"`
-	ProfileName string    `json:"profileName"`
-	PostHooks   PostHooks `json:"postHooks"`
+	Name        string              `json:"name" binding:"required"`
+	Location    string              `json:"location"`
+	Cloud       string              `json:"cloud" binding:"required"`
+	SecretId    string              `json:"secretId" binding:"required"`
+	ProfileName string              `json:"profileName"`
+	PostHooks   PostHooks           `json:"postHooks"`
+	Labels      map[string]string   `json:"labels,omitempty"`
+	Autoscaler  *AutoscalerSettings `json:"autoscaler,omitempty"`
 	Properties  struct {
 		CreateClusterAmazon *amazon.CreateClusterAmazon  `json:"amazon,omitempty"`
 		CreateClusterEks    *eks.CreateClusterEks        `json:"eks,omitempty"`
@@ -148,6 +154,25 @@ type GetClusterConfigResponse struct {
 	Data   string `json:"data"`
 }
 
+// Per-user cluster credential types, client certificates are not issued because they can't be revoked before they expire
+const (
+	UserCredentialServiceAccount = "serviceaccount"
+)
+
+// CreateUserConfigRequest describes Pipeline's CreateUserClusterConfig API request
+type CreateUserConfigRequest struct {
+	Type        string `json:"type,omitempty"`
+	ClusterRole string `json:"clusterRole,omitempty"`
+	TTL         string `json:"ttl,omitempty"`
+}
+
+// CreateUserConfigResponse describes Pipeline's CreateUserClusterConfig API response
+type CreateUserConfigResponse struct {
+	Status    int       `json:"status"`
+	Data      string    `json:"data"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
 // UpdateClusterResponse describes Pipeline's UpdateCluster API response
 type UpdateClusterResponse struct {
 	Status int `json:"status"`
@@ -155,7 +180,8 @@ type UpdateClusterResponse struct {
 
 // UpdateClusterRequest describes an update cluster request
 type UpdateClusterRequest struct {
-	Cloud            string `json:"cloud" binding:"required"`
+	Cloud            string              `json:"cloud" binding:"required"`
+	Autoscaler       *AutoscalerSettings `json:"autoscaler,omitempty"`
 	UpdateProperties `json:"properties"`
 }
 
@@ -239,6 +265,14 @@ func (r *CreateClusterRequest) Validate() error {
 		return err
 	}
 
+	if err := validateNodePoolSettings(r.GetNodePoolSettings()); err != nil {
+		return err
+	}
+
+	if err := validateAutoscalerSettings(r.Cloud, r.Autoscaler); err != nil {
+		return err
+	}
+
 	switch r.Cloud {
 	case Amazon:
 		// amazon validate
@@ -283,6 +317,14 @@ func (r *UpdateClusterRequest) Validate() error {
 
 	r.preValidate()
 
+	if err := validateNodePoolSettings(r.GetNodePoolSettings()); err != nil {
+		return err
+	}
+
+	if err := validateAutoscalerSettings(r.Cloud, r.Autoscaler); err != nil {
+		return err
+	}
+
 	switch r.Cloud {
 	case Amazon:
 		// amazon validate
@@ -337,11 +379,147 @@ func (r *UpdateClusterRequest) preValidate() {
 	}
 }
 
+// GetNodePoolSettings returns the labels and taints of the requested node pools by node pool name,
+// nil is returned if none of the node pools has labels or taints
+func (r *CreateClusterRequest) GetNodePoolSettings() map[string]*pkgCommon.NodePoolSettings {
+	settings := make(map[string]*pkgCommon.NodePoolSettings)
+
+	switch r.Cloud {
+	case Amazon:
+		if r.Properties.CreateClusterAmazon != nil {
+			for name, np := range r.Properties.CreateClusterAmazon.NodePools {
+				if np != nil {
+					settings[name] = &np.NodePoolSettings
+				}
+			}
+		} else if r.Properties.CreateClusterEks != nil {
+			for name, np := range r.Properties.CreateClusterEks.NodePools {
+				if np != nil {
+					settings[name] = &np.NodePoolSettings
+				}
+			}
+		}
+	case Azure:
+		if r.Properties.CreateClusterAzure != nil {
+			for name, np := range r.Properties.CreateClusterAzure.NodePools {
+				if np != nil {
+					settings[name] = &np.NodePoolSettings
+				}
+			}
+		}
+	case Google:
+		if r.Properties.CreateClusterGoogle != nil {
+			for name, np := range r.Properties.CreateClusterGoogle.NodePools {
+				if np != nil {
+					settings[name] = &np.NodePoolSettings
+				}
+			}
+		}
+	case Oracle:
+		if r.Properties.CreateClusterOracle != nil {
+			for name, np := range r.Properties.CreateClusterOracle.NodePools {
+				if np != nil {
+					settings[name] = &pkgCommon.NodePoolSettings{Labels: np.Labels, Taints: np.Taints}
+				}
+			}
+		}
+	}
+
+	for name, s := range settings {
+		if s.IsEmpty() {
+			delete(settings, name)
+		}
+	}
+
+	if len(settings) == 0 {
+		return nil
+	}
+
+	return settings
+}
+
+// GetNodePoolSettings returns the labels and taints of the updated node pools by node pool name,
+// nil is returned if the request doesn't contain node pools
+func (r *UpdateClusterRequest) GetNodePoolSettings() map[string]*pkgCommon.NodePoolSettings {
+	settings := make(map[string]*pkgCommon.NodePoolSettings)
+
+	switch r.Cloud {
+	case Amazon:
+		if r.Amazon != nil {
+			for name, np := range r.Amazon.NodePools {
+				if np != nil {
+					settings[name] = &np.NodePoolSettings
+				}
+			}
+		} else if r.Eks != nil {
+			for name, np := range r.Eks.NodePools {
+				if np != nil {
+					settings[name] = &np.NodePoolSettings
+				}
+			}
+		}
+	case Azure:
+		if r.Azure != nil {
+			for name, np := range r.Azure.NodePools {
+				if np != nil {
+					settings[name] = &np.NodePoolSettings
+				}
+			}
+		}
+	case Google:
+		if r.Google != nil {
+			for name, np := range r.Google.NodePools {
+				if np != nil {
+					settings[name] = &np.NodePoolSettings
+				}
+			}
+		}
+	case Oracle:
+		if r.Oracle != nil {
+			for name, np := range r.Oracle.NodePools {
+				if np != nil {
+					settings[name] = &pkgCommon.NodePoolSettings{Labels: np.Labels, Taints: np.Taints}
+				}
+			}
+		}
+	}
+
+	if len(settings) == 0 {
+		return nil
+	}
+
+	return settings
+}
+
+// validateAutoscalerSettings checks the autoscaler settings and whether the cloud supports them
+func validateAutoscalerSettings(cloud string, settings *AutoscalerSettings) error {
+	if settings == nil {
+		return nil
+	}
+	if !IsAutoscalerSupported(cloud) {
+		return pkgErrors.ErrorAutoscalerNotSupported
+	}
+	return settings.Validate()
+}
+
+// validateNodePoolSettings checks the labels and taints of all node pools
+func validateNodePoolSettings(settings map[string]*pkgCommon.NodePoolSettings) error {
+	for name, s := range settings {
+		if err := s.Validate(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ClusterProfileResponse describes Pipeline's ClusterProfile API responses
 type ClusterProfileResponse struct {
-	Name       string `json:"name" binding:"required"`
-	Location   string `json:"location" binding:"required"`
-	Cloud      string `json:"cloud" binding:"required"`
+	Name       string              `json:"name" binding:"required"`
+	Location   string              `json:"location" binding:"required"`
+	Cloud      string              `json:"cloud" binding:"required"`
+	Extends    string              `json:"extends,omitempty"`
+	Global     bool                `json:"global"`
+	Autoscaler *AutoscalerSettings `json:"autoscaler,omitempty"`
 	Properties struct {
 		Amazon *amazon.ClusterProfileAmazon `json:"amazon,omitempty"`
 		Azure  *azure.ClusterProfileAzure   `json:"azure,omitempty"`
@@ -353,10 +531,14 @@ type ClusterProfileResponse struct {
 
 // ClusterProfileRequest describes CreateClusterProfile request
 type ClusterProfileRequest struct {
-	Name       string `json:"name" binding:"required"`
-	Location   string `json:"location" binding:"required"`
-	Cloud      string `json:"cloud" binding:"required"`
-	Properties struct {
+	OrganizationId uint                `json:"-"`
+	Name           string              `json:"name" binding:"required"`
+	Location       string              `json:"location" binding:"required"`
+	Cloud          string              `json:"cloud" binding:"required"`
+	Extends        string              `json:"extends,omitempty"`
+	SecretId       string              `json:"secretId,omitempty"`
+	Autoscaler     *AutoscalerSettings `json:"autoscaler,omitempty"`
+	Properties     struct {
 		Amazon *amazon.ClusterProfileAmazon `json:"amazon,omitempty"`
 		Azure  *azure.ClusterProfileAzure   `json:"azure,omitempty"`
 		Eks    *eks.ClusterProfileEks       `json:"eks,omitempty"`
@@ -365,6 +547,17 @@ type ClusterProfileRequest struct {
 	} `json:"properties" binding:"required"`
 }
 
+// ClusterProfileRequest creates a ClusterProfileRequest from the profile, it's used to inherit values of an extended profile
+func (p *ClusterProfileResponse) ClusterProfileRequest() *ClusterProfileRequest {
+	return &ClusterProfileRequest{
+		Name:       p.Name,
+		Location:   p.Location,
+		Cloud:      p.Cloud,
+		Autoscaler: p.Autoscaler,
+		Properties: p.Properties,
+	}
+}
+
 // CloudInfoRequest describes Cloud info requests
 type CloudInfoRequest struct {
 	OrganizationId uint             `json:"-"`
@@ -547,5 +740,11 @@ func (p *ClusterProfileResponse) CreateClusterRequest(createRequest *CreateClust
 		}
 	}
 
+	// the autoscaler settings of the request override the profile's ones
+	response.Autoscaler = p.Autoscaler
+	if createRequest.Autoscaler != nil {
+		response.Autoscaler = createRequest.Autoscaler
+	}
+
 	return response, nil
 }
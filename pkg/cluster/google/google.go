@@ -31,6 +31,8 @@ type NodePool struct {
 	MaxCount         int    `json:"maxCount"`
 	Count            int    `json:"count,omitempty"`
 	NodeInstanceType string `json:"instanceType,omitempty"`
+
+	pkgCommon.NodePoolSettings
 }
 
 // UpdateClusterGoogle describes Google's node fields of an UpdateCluster request
@@ -0,0 +1,41 @@
+package cluster
+
+// ### [ Cluster health statuses ] ### //
+const (
+	HealthGreen  = "green"
+	HealthYellow = "yellow"
+	HealthRed    = "red"
+)
+
+// HealthResponse describes Pipeline's GetClusterHealth API response
+type HealthResponse struct {
+	Status string         `json:"status"`
+	Checks []*HealthCheck `json:"checks"`
+}
+
+// HealthCheck describes the result of a single cluster health check
+type HealthCheck struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Message string   `json:"message,omitempty"`
+	Items   []string `json:"items,omitempty"`
+}
+
+// AddCheck appends the check to the report and degrades the overall status if needed
+func (r *HealthResponse) AddCheck(check *HealthCheck) {
+	r.Checks = append(r.Checks, check)
+	if healthSeverity(check.Status) > healthSeverity(r.Status) {
+		r.Status = check.Status
+	}
+}
+
+func healthSeverity(status string) int {
+	switch status {
+	case HealthYellow:
+		return 1
+	case HealthRed:
+		return 2
+	default:
+		return 0
+	}
+}
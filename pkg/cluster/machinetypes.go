@@ -0,0 +1,7 @@
+package cluster
+
+// MachineTypeSpec describes the CPU (in millicores) and memory (in bytes) capacity of a machine type
+type MachineTypeSpec struct {
+	Cpu    int64 `json:"cpu"`
+	Memory int64 `json:"memory"`
+}
@@ -0,0 +1,39 @@
+package namespace
+
+import (
+	"time"
+
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+)
+
+// Request describes a namespace creation or update request. Resources are given
+// in Kubernetes quantity format, e.g. {"requests.cpu": "2", "limits.memory": "4Gi"}.
+type Request struct {
+	Name          string                        `json:"name,omitempty"`
+	Labels        map[string]string             `json:"labels,omitempty"`
+	Annotations   map[string]string             `json:"annotations,omitempty"`
+	ResourceQuota map[string]string             `json:"resourceQuota,omitempty"`
+	LimitRange    *LimitRange                   `json:"limitRange,omitempty"`
+	Secrets       *secretTypes.ListSecretsQuery `json:"secrets,omitempty"`
+}
+
+// LimitRange describes the resource constraints of the containers in a namespace
+type LimitRange struct {
+	Default        map[string]string `json:"default,omitempty"`
+	DefaultRequest map[string]string `json:"defaultRequest,omitempty"`
+	Max            map[string]string `json:"max,omitempty"`
+	Min            map[string]string `json:"min,omitempty"`
+}
+
+// Response describes a namespace with its resource quota and limit range
+type Response struct {
+	Name              string                      `json:"name"`
+	Status            string                      `json:"status"`
+	Labels            map[string]string           `json:"labels,omitempty"`
+	Annotations       map[string]string           `json:"annotations,omitempty"`
+	ResourceQuota     map[string]string           `json:"resourceQuota,omitempty"`
+	ResourceQuotaUsed map[string]string           `json:"resourceQuotaUsed,omitempty"`
+	LimitRange        *LimitRange                 `json:"limitRange,omitempty"`
+	Secrets           []secretTypes.K8SSourceMeta `json:"secrets,omitempty"`
+	CreatedAt         time.Time                   `json:"createdAt"`
+}
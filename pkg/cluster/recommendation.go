@@ -0,0 +1,31 @@
+package cluster
+
+// RecommendationResponse describes Pipeline's GetClusterRecommendations API response
+type RecommendationResponse struct {
+	Headroom             float64                            `json:"headroom"`
+	NodePools            map[string]*NodePoolRecommendation `json:"nodePools"`
+	UnassignedPods       []string                           `json:"unassignedPods,omitempty"`
+	UpdateClusterRequest *UpdateClusterRequest              `json:"updateClusterRequest,omitempty"`
+}
+
+// NodePoolRecommendation describes the instance type and node count recommended for a node pool,
+// OverProvisioned is set when the workload fits on fewer nodes of the current instance type
+type NodePoolRecommendation struct {
+	CurrentInstanceType string                 `json:"currentInstanceType"`
+	CurrentCount        int                    `json:"currentCount"`
+	InstanceType        string                 `json:"instanceType"`
+	Count               int                    `json:"count"`
+	OverProvisioned     bool                   `json:"overProvisioned"`
+	PendingPods         int                    `json:"pendingPods"`
+	Cpu                 RecommendationResource `json:"cpu"`
+	Memory              RecommendationResource `json:"memory"`
+	Message             string                 `json:"message,omitempty"`
+}
+
+// RecommendationResource describes the requests of the running and pending pods of a node pool
+// compared to its current allocatable, CPU is measured in millicores, memory in bytes
+type RecommendationResource struct {
+	Request     int64   `json:"request"`
+	Allocatable int64   `json:"allocatable"`
+	Utilization float64 `json:"utilization"`
+}
@@ -0,0 +1,30 @@
+package cluster
+
+import "time"
+
+// UsageResponse describes Pipeline's GetClusterUsage API response
+type UsageResponse struct {
+	From      time.Time                `json:"from"`
+	To        time.Time                `json:"to"`
+	Step      string                   `json:"step"`
+	Total     []*UsagePoint            `json:"total"`
+	NodePools map[string][]*UsagePoint `json:"nodePools"`
+}
+
+// UsagePoint describes the average resource summary of a cluster or node pool in a step of the usage time series,
+// Nodes is the highest node count seen in the step
+type UsagePoint struct {
+	Timestamp time.Time  `json:"timestamp"`
+	Nodes     int        `json:"nodes"`
+	Cpu       UsageValue `json:"cpu"`
+	Memory    UsageValue `json:"memory"`
+}
+
+// UsageValue describes the capacity, allocatable, requests and limits of a resource,
+// CPU is measured in millicores, memory in bytes
+type UsageValue struct {
+	Capacity    int64 `json:"capacity"`
+	Allocatable int64 `json:"allocatable"`
+	Request     int64 `json:"request"`
+	Limit       int64 `json:"limit"`
+}
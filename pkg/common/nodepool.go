@@ -0,0 +1,77 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Taint effects accepted on node pools
+const (
+	TaintEffectNoSchedule       = "NoSchedule"
+	TaintEffectPreferNoSchedule = "PreferNoSchedule"
+	TaintEffectNoExecute        = "NoExecute"
+)
+
+// NodeTaint describes a taint which is applied to every node of a node pool
+type NodeTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+// NodePoolSettings describes the labels and taints Pipeline applies to the nodes of a node pool
+type NodePoolSettings struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Taints []NodeTaint       `json:"taints,omitempty"`
+}
+
+// IsEmpty returns true if neither labels nor taints are set
+func (s *NodePoolSettings) IsEmpty() bool {
+	return s == nil || (len(s.Labels) == 0 && len(s.Taints) == 0)
+}
+
+// Validate checks the labels and taints of the given node pool
+func (s *NodePoolSettings) Validate(nodePoolName string) error {
+	if s == nil {
+		return nil
+	}
+
+	for key, value := range s.Labels {
+		if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+			return fmt.Errorf("invalid label key %q in node pool %q: %s", key, nodePoolName, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) != 0 {
+			return fmt.Errorf("invalid label value %q in node pool %q: %s", value, nodePoolName, strings.Join(errs, ", "))
+		}
+		if key == LabelKey && value != nodePoolName {
+			return fmt.Errorf("label %q is reserved for the node pool name", LabelKey)
+		}
+	}
+
+	taints := make(map[string]bool, len(s.Taints))
+	for _, taint := range s.Taints {
+		if errs := validation.IsQualifiedName(taint.Key); len(errs) != 0 {
+			return fmt.Errorf("invalid taint key %q in node pool %q: %s", taint.Key, nodePoolName, strings.Join(errs, ", "))
+		}
+		if len(taint.Value) != 0 {
+			if errs := validation.IsValidLabelValue(taint.Value); len(errs) != 0 {
+				return fmt.Errorf("invalid taint value %q in node pool %q: %s", taint.Value, nodePoolName, strings.Join(errs, ", "))
+			}
+		}
+		switch taint.Effect {
+		case TaintEffectNoSchedule, TaintEffectPreferNoSchedule, TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("invalid taint effect %q in node pool %q, must be one of %s, %s, %s",
+				taint.Effect, nodePoolName, TaintEffectNoSchedule, TaintEffectPreferNoSchedule, TaintEffectNoExecute)
+		}
+		id := taint.Key + ":" + taint.Effect
+		if taints[id] {
+			return fmt.Errorf("duplicated taint %q in node pool %q", id, nodePoolName)
+		}
+		taints[id] = true
+	}
+
+	return nil
+}
@@ -57,4 +57,7 @@ var (
 	ErrorNodePoolNotFoundByName     = errors.New("nodepool not found by name")
 	ErrorNoInfrastructureRG         = errors.New("no infrastructure resource group found")
 	ErrStateStorePathEmpty          = errors.New("statestore path cannot be empty")
+
+	ErrorAutoscalerNotSupported         = errors.New("autoscaler settings are supported only for amazon and azure clusters")
+	ErrorAutoscalerUtilizationThreshold = errors.New("'scaleDownUtilizationThreshold' must be greater than 0 and lower than or equal to 1")
 )
@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+)
+
+// Notification channel types
+const (
+	ChannelSlack   = "slack"
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+	ChannelTeams   = "teams"
+)
+
+// Event types the notification subscriptions can filter on
+const (
+	EventClusterCreated   = "cluster.created"
+	EventClusterFailed    = "cluster.failed"
+	EventClusterDeleted   = "cluster.deleted"
+	EventPosthookFailed   = "cluster.posthook.failed"
+	EventClusterAlert     = "cluster.alert"
+	EventDeploymentFailed = "deployment.failed"
+	EventSecretExpiring   = "secret.expiring"
+)
+
+// EventTypes contains the supported event types
+var EventTypes = []string{
+	EventClusterCreated,
+	EventClusterFailed,
+	EventClusterDeleted,
+	EventPosthookFailed,
+	EventClusterAlert,
+	EventDeploymentFailed,
+	EventSecretExpiring,
+}
+
+// ChannelRequest describes a notification channel creation or update request. The webhook URL of Slack, Teams
+// and generic webhook channels and the SMTP server of email channels are read from the referenced secret.
+type ChannelRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Type         string   `json:"type" binding:"required"`
+	SecretID     string   `json:"secretId" binding:"required"`
+	SlackChannel string   `json:"slackChannel,omitempty"`
+	Recipients   []string `json:"recipients,omitempty"`
+}
+
+// Validate checks the type specific settings of the channel
+func (r *ChannelRequest) Validate() error {
+	switch r.Type {
+	case ChannelSlack, ChannelWebhook, ChannelTeams:
+		if len(r.Recipients) != 0 {
+			return fmt.Errorf("recipients are supported only by %s channels", ChannelEmail)
+		}
+	case ChannelEmail:
+		if len(r.Recipients) == 0 {
+			return fmt.Errorf("%s channels need at least one recipient", ChannelEmail)
+		}
+	default:
+		return fmt.Errorf("unsupported channel type %q, must be one of %s, %s, %s, %s",
+			r.Type, ChannelSlack, ChannelWebhook, ChannelEmail, ChannelTeams)
+	}
+
+	if len(r.SlackChannel) != 0 && r.Type != ChannelSlack {
+		return fmt.Errorf("slackChannel is supported only by %s channels", ChannelSlack)
+	}
+
+	return nil
+}
+
+// ChannelResponse describes a notification channel of the organization
+type ChannelResponse struct {
+	ID           uint      `json:"id"`
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	SecretID     string    `json:"secretId"`
+	SlackChannel string    `json:"slackChannel,omitempty"`
+	Recipients   []string  `json:"recipients,omitempty"`
+	CreatedBy    uint      `json:"createdBy,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// SubscriptionRequest describes a subscription of a channel to event types
+type SubscriptionRequest struct {
+	ChannelID uint     `json:"channelId" binding:"required"`
+	Events    []string `json:"events" binding:"required"`
+}
+
+// Validate checks the event types of the subscription
+func (r *SubscriptionRequest) Validate() error {
+	if len(r.Events) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+
+	for _, event := range r.Events {
+		if !IsEventType(event) {
+			return fmt.Errorf("unsupported event type %q", event)
+		}
+	}
+
+	return nil
+}
+
+// SubscriptionResponse describes a subscription of a channel to event types
+type SubscriptionResponse struct {
+	ID        uint      `json:"id"`
+	ChannelID uint      `json:"channelId"`
+	Events    []string  `json:"events"`
+	CreatedBy uint      `json:"createdBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// IsEventType returns true if the event type is supported
+func IsEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
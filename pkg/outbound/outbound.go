@@ -0,0 +1,176 @@
+package outbound
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// deniedNetworks are the networks of the loopback, private, link-local and other special purpose addresses,
+// requests to user supplied URLs must not reach the services of the internal network through them
+var deniedNetworks = parseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// IsPublicIP returns false for the loopback, private, link-local and other special purpose addresses
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range deniedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateURL checks that the URL is an absolute http or https URL, and unless private addresses are allowed,
+// that its host resolves to public addresses only. The addresses are checked again when connecting,
+// as the host may resolve to other addresses by then.
+func ValidateURL(rawURL string, allowPrivate bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if len(u.Hostname()) == 0 {
+		return fmt.Errorf("URL %q has no host", rawURL)
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	_, err = lookupPublicIPs(context.Background(), u.Hostname())
+	return err
+}
+
+// transportKey identifies the shared transports by their settings
+type transportKey struct {
+	timeout      time.Duration
+	allowPrivate bool
+}
+
+// transports are shared by the clients, so their idle connections are reused instead of leaking with every client
+var transports = struct {
+	sync.Mutex
+	byKey map[transportKey]*http.Transport
+}{byKey: map[transportKey]*http.Transport{}}
+
+// NewClient returns an HTTP client for requests to user supplied URLs. Unless private addresses are allowed,
+// it only connects to public addresses, which are checked after resolving the host, right before connecting.
+// Redirects are not followed, and the proxy settings of the environment are ignored.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: getTransport(timeout, allowPrivate),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// getTransport returns the shared transport with the given settings, creating it on first use
+func getTransport(timeout time.Duration, allowPrivate bool) *http.Transport {
+	key := transportKey{timeout: timeout, allowPrivate: allowPrivate}
+
+	transports.Lock()
+	defer transports.Unlock()
+
+	transport, ok := transports.byKey[key]
+	if !ok {
+		transport = newTransport(timeout, allowPrivate)
+		transports.byKey[key] = transport
+	}
+	return transport
+}
+
+// newTransport creates a transport which, unless private addresses are allowed, dials the checked public addresses only
+func newTransport(timeout time.Duration, allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+
+	dial := dialer.DialContext
+	if !allowPrivate {
+		dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+
+			ips, err := lookupPublicIPs(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+
+			// the checked address is dialed, so the host can't be resolved to another one in the meantime
+			return dialer.DialContext(ctx, network, net.JoinHostPort(ips[0].String(), port))
+		}
+	}
+
+	return &http.Transport{
+		DialContext:         dial,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// lookupPublicIPs resolves the host and returns its addresses, it fails if any of them is not public
+func lookupPublicIPs(ctx context.Context, host string) ([]net.IP, error) {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("host %q has no addresses", host)
+	}
+	for _, ip := range ips {
+		if !IsPublicIP(ip) {
+			return nil, fmt.Errorf("address %s of host %q is not public", ip, host)
+		}
+	}
+
+	return ips, nil
+}
@@ -0,0 +1,63 @@
+package outbound
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	for address, expected := range map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.20.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"::ffff:127.0.0.1": false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+	} {
+		if actual := IsPublicIP(net.ParseIP(address)); actual != expected {
+			t.Errorf("%s: expected public to be %t, got %t", address, expected, actual)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	cases := []struct {
+		url          string
+		allowPrivate bool
+		valid        bool
+	}{
+		{url: "https://8.8.8.8/hook", valid: true},
+		{url: "http://127.0.0.1:9090/hook", allowPrivate: true, valid: true},
+		{url: "http://127.0.0.1:9090/hook"},
+		{url: "http://169.254.169.254/latest/meta-data/"},
+		{url: "http://[::1]/hook"},
+		{url: "ftp://8.8.8.8/hook"},
+		{url: "/hook"},
+	}
+
+	for _, tc := range cases {
+		err := ValidateURL(tc.url, tc.allowPrivate)
+		if tc.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.url, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("%s: expected an error", tc.url)
+		}
+	}
+}
+
+func TestNewClientSharesTransport(t *testing.T) {
+	if NewClient(time.Second, false).Transport != NewClient(time.Second, false).Transport {
+		t.Error("clients with the same settings should share the transport")
+	}
+	if NewClient(time.Second, false).Transport == NewClient(time.Second, true).Transport {
+		t.Error("clients allowing private addresses should have their own transport")
+	}
+}
@@ -19,11 +19,12 @@ type Cluster struct {
 
 // NodePool describes Oracle's node fields of a Create/Update request
 type NodePool struct {
-	Version string            `json:"version,omitempty"`
-	Count   uint              `json:"count,omitempty"`
-	Labels  map[string]string `json:"labels,omitempty"`
-	Image   string            `json:"image,omitempty"`
-	Shape   string            `json:"shape,omitempty"`
+	Version string                `json:"version,omitempty"`
+	Count   uint                  `json:"count,omitempty"`
+	Labels  map[string]string     `json:"labels,omitempty"`
+	Image   string                `json:"image,omitempty"`
+	Shape   string                `json:"shape,omitempty"`
+	Taints  []pkgCommon.NodeTaint `json:"taints,omitempty"`
 
 	subnetIds         []string
 	quantityPerSubnet uint
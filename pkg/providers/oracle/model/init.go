@@ -2,6 +2,8 @@ package model
 
 import (
 	"github.com/banzaicloud/pipeline/database"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,7 +17,8 @@ func Init(logger *logrus.Entry) error {
 		NodePoolLabel.TableName(NodePoolLabel{}),
 	)
 
-	return database.GetDB().AutoMigrate(
+	db := database.GetDB()
+	err := db.AutoMigrate(
 		&Cluster{},
 		&NodePool{},
 		&NodePoolSubnet{},
@@ -23,5 +26,25 @@ func Init(logger *logrus.Entry) error {
 		&Profile{},
 		&ProfileNodePool{},
 		&ProfileNodePoolLabel{},
+		&ProfileNodePoolTaint{},
 	).Error
+	if err != nil {
+		return err
+	}
+
+	return migrateProfileOrganizations(db)
+}
+
+// migrateProfileOrganizations moves the profiles created before profiles were scoped to organizations to the
+// global organization, and drops their unique index on the name, which AutoMigrate doesn't remove
+func migrateProfileOrganizations(db *gorm.DB) error {
+	err := db.Model(&Profile{}).Where("organization_id IS NULL").UpdateColumn("organization_id", pkgCluster.GlobalProfileOrganizationID).Error
+	if err != nil {
+		return err
+	}
+
+	if db.Dialect().HasIndex(ProfileTableName, "idx_modelid_name") {
+		return db.Model(&Profile{}).RemoveIndex("idx_modelid_name").Error
+	}
+	return nil
 }
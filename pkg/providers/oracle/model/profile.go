@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/banzaicloud/pipeline/config"
@@ -10,6 +11,7 @@ import (
 	"github.com/banzaicloud/pipeline/pkg/cluster/azure"
 	"github.com/banzaicloud/pipeline/pkg/cluster/eks"
 	"github.com/banzaicloud/pipeline/pkg/cluster/google"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
 	oracle "github.com/banzaicloud/pipeline/pkg/providers/oracle/cluster"
 	"github.com/sirupsen/logrus"
 )
@@ -26,12 +28,17 @@ const (
 	ProfileTableName              = "oracle_profiles"
 	ProfileNodePoolTableName      = "oracle_profiles_nodepools"
 	ProfileNodePoolLabelTableName = "oracle_profiles_nodepools_labels"
+	ProfileNodePoolTaintTableName = "oracle_profiles_nodepools_taints"
 )
 
 // Profile describes the Oracle cluster profile model
 type Profile struct {
-	ID        uint   `gorm:"primary_key"`
-	Name      string `gorm:"unique_index:idx_modelid_name"`
+	ID             uint   `gorm:"primary_key"`
+	Name           string `gorm:"unique_index:idx_organizationid_name"`
+	OrganizationID uint   `gorm:"unique_index:idx_organizationid_name"`
+	Extends        string
+	// Overrides are the profile's own values, which are applied on the extended profile when the profile is read
+	Overrides string `sql:"type:text"`
 	Location  string `gorm:"default:'eu-frankfurt-1'"`
 	Version   string `gorm:"default:'v1.10.3'"`
 	NodePools []*ProfileNodePool
@@ -48,6 +55,7 @@ type ProfileNodePool struct {
 	Shape     string `gorm:"default:'VM.Standard1.1'"`
 	Version   string `gorm:"default:'v1.10.3'"`
 	Labels    []*ProfileNodePoolLabel
+	Taints    []*ProfileNodePoolTaint
 	ProfileID uint `gorm:"unique_index:idx_modelid_name; foreignKey"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -63,6 +71,17 @@ type ProfileNodePoolLabel struct {
 	UpdatedAt         time.Time
 }
 
+// ProfileNodePoolTaint stores taints for node pools
+type ProfileNodePoolTaint struct {
+	ID                uint   `gorm:"primary_key"`
+	Key               string `gorm:"unique_index:idx_key_effect"`
+	Value             string
+	Effect            string `gorm:"unique_index:idx_key_effect"`
+	ProfileNodePoolID uint   `gorm:"unique_index:idx_key_effect"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
 // TableName overrides Profile table name
 func (Profile) TableName() string {
 	return ProfileTableName
@@ -78,20 +97,25 @@ func (ProfileNodePoolLabel) TableName() string {
 	return ProfileNodePoolLabelTableName
 }
 
-// GetProfiles gets Profiles from database and eager loads node pools
-func GetProfiles() []Profile {
+// TableName overrides ProfileNodePoolTaint table name
+func (ProfileNodePoolTaint) TableName() string {
+	return ProfileNodePoolTaintTableName
+}
+
+// GetProfiles gets the global and the given organization's Profiles from database and eager loads node pools
+func GetProfiles(organizationID uint) []Profile {
 
 	var Profiles []Profile
-	database.GetDB().Preload("NodePools.Labels").Find(&Profiles)
+	database.GetDB().Where("organization_id IN (?)", []uint{pkgCluster.GlobalProfileOrganizationID, organizationID}).Preload("NodePools.Labels").Preload("NodePools.Taints").Find(&Profiles)
 
 	return Profiles
 }
 
-// GetProfileByName load a Profile from database by it's name and eager load node pools
-func GetProfileByName(name string) (Profile, error) {
+// GetProfileByName load a Profile of the organization from database by it's name and eager load node pools
+func GetProfileByName(organizationID uint, name string) (Profile, error) {
 
 	var profile Profile
-	err := database.GetDB().Where(Profile{Name: name}).Preload("NodePools.Labels").First(&profile).Error
+	err := database.GetDB().Where(map[string]interface{}{"name": name, "organization_id": organizationID}).Preload("NodePools.Labels").Preload("NodePools.Taints").First(&profile).Error
 
 	return profile, err
 }
@@ -103,7 +127,7 @@ func (d *Profile) SaveInstance() error {
 
 // IsDefinedBefore returns true if database contains en entry with profile name
 func (d *Profile) IsDefinedBefore() bool {
-	return database.GetDB().First(&d, Profile{Name: d.Name}).RowsAffected != int64(0)
+	return !database.GetDB().Where(map[string]interface{}{"name": d.Name, "organization_id": d.OrganizationID}).First(&Profile{}).RecordNotFound()
 }
 
 // GetType returns profile's cloud type
@@ -111,6 +135,16 @@ func (d *Profile) GetType() string {
 	return pkgCluster.Oracle
 }
 
+// GetName returns the profile's name
+func (d *Profile) GetName() string {
+	return d.Name
+}
+
+// GetOrganizationID returns the id of the organization which owns the profile
+func (d *Profile) GetOrganizationID() uint {
+	return d.OrganizationID
+}
+
 // GetProfile load profile from database and converts ClusterProfileResponse
 func (d *Profile) GetProfile() *pkgCluster.ClusterProfileResponse {
 
@@ -127,6 +161,13 @@ func (d *Profile) GetProfile() *pkgCluster.ClusterProfileResponse {
 			for _, l := range np.Labels {
 				nodePools[np.Name].Labels[l.Name] = l.Value
 			}
+			for _, t := range np.Taints {
+				nodePools[np.Name].Taints = append(nodePools[np.Name].Taints, pkgCommon.NodeTaint{
+					Key:    t.Key,
+					Value:  t.Value,
+					Effect: t.Effect,
+				})
+			}
 		}
 	}
 
@@ -134,6 +175,8 @@ func (d *Profile) GetProfile() *pkgCluster.ClusterProfileResponse {
 		Name:     d.Name,
 		Location: d.Location,
 		Cloud:    pkgCluster.Oracle,
+		Extends:  d.Extends,
+		Global:   d.OrganizationID == pkgCluster.GlobalProfileOrganizationID,
 		Properties: struct {
 			Amazon *amazon.ClusterProfileAmazon `json:"amazon,omitempty"`
 			Azure  *azure.ClusterProfileAzure   `json:"azure,omitempty"`
@@ -152,12 +195,19 @@ func (d *Profile) GetProfile() *pkgCluster.ClusterProfileResponse {
 // UpdateProfile update profile's data with ClusterProfileRequest's data and if bool is true then update in the database
 func (d *Profile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave bool) error {
 
-	if r != nil {
-
-		s := r.Properties.Oracle
+	if r == nil {
+		return nil
+	}
 
-		d.Version = s.Version
+	if len(r.Location) != 0 {
 		d.Location = r.Location
+	}
+
+	if s := r.Properties.Oracle; s != nil {
+
+		if len(s.Version) != 0 {
+			d.Version = s.Version
+		}
 
 		if len(s.NodePools) != 0 {
 			var nodePools []*ProfileNodePool
@@ -175,6 +225,13 @@ func (d *Profile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave bo
 						Value: value,
 					})
 				}
+				for _, taint := range np.Taints {
+					nodePool.Taints = append(nodePool.Taints, &ProfileNodePoolTaint{
+						Key:    taint.Key,
+						Value:  taint.Value,
+						Effect: taint.Effect,
+					})
+				}
 				nodePools = append(nodePools, nodePool)
 			}
 
@@ -187,10 +244,32 @@ func (d *Profile) UpdateProfile(r *pkgCluster.ClusterProfileRequest, withSave bo
 	}
 
 	d.Name = r.Name
+	d.OrganizationID = r.OrganizationId
+	d.Extends = r.Extends
 
 	return nil
 }
 
+// GetOverrides returns the profile's own values if it extends another profile
+func (d *Profile) GetOverrides() *pkgCluster.ClusterProfileRequest {
+	if len(d.Overrides) == 0 {
+		return nil
+	}
+	var overrides pkgCluster.ClusterProfileRequest
+	if err := json.Unmarshal([]byte(d.Overrides), &overrides); err != nil {
+		log.Errorf("Error during convert profile overrides: %s", err.Error())
+		return nil
+	}
+	return &overrides
+}
+
+// SetOverrides stores the profile's own values
+func (d *Profile) SetOverrides(overrides *pkgCluster.ClusterProfileRequest) {
+	if out, err := json.Marshal(overrides); err == nil {
+		d.Overrides = string(out)
+	}
+}
+
 // DeleteProfile deletes cluster profile from database
 func (d *Profile) DeleteProfile() error {
 	return database.GetDB().Delete(&d).Error
@@ -206,15 +285,24 @@ func (d *Profile) BeforeDelete() error {
 	}).Find(&nodePools).Delete(&nodePools).Error
 }
 
-// BeforeDelete deletes all labels belongs to the nodepool
+// BeforeDelete deletes all labels and taints belongs to the nodepool
 func (d *ProfileNodePool) BeforeDelete() error {
-	log.Info("BeforeDelete oke nodepool... delete all labels")
+	log.Info("BeforeDelete oke nodepool... delete all labels and taints")
 
 	var nodePoolLabels []*ProfileNodePoolLabel
 
-	return database.GetDB().Where(ProfileNodePoolLabel{
+	err := database.GetDB().Where(ProfileNodePoolLabel{
 		ProfileNodePoolID: d.ID,
 	}).Find(&nodePoolLabels).Delete(&nodePoolLabels).Error
+	if err != nil {
+		return err
+	}
+
+	var nodePoolTaints []*ProfileNodePoolTaint
+
+	return database.GetDB().Where(ProfileNodePoolTaint{
+		ProfileNodePoolID: d.ID,
+	}).Find(&nodePoolTaints).Delete(&nodePoolTaints).Error
 }
 
 // BeforeSave clears nodepools
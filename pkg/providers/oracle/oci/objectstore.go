@@ -1,8 +1,10 @@
 package oci
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 
 	"github.com/oracle/oci-go-sdk/common"
 	"github.com/oracle/oci-go-sdk/objectstorage"
@@ -123,3 +125,46 @@ func (os *ObjectStorage) GetBuckets() (buckets []objectstorage.BucketSummary, er
 
 	return buckets, err
 }
+
+// PutObject uploads an object into the given bucket
+func (os *ObjectStorage) PutObject(bucketName, objectName string, body []byte) error {
+
+	contentLength := int64(len(body))
+	_, err := os.client.PutObject(context.Background(), objectstorage.PutObjectRequest{
+		NamespaceName: &os.Namespace,
+		BucketName:    &bucketName,
+		ObjectName:    &objectName,
+		ContentLength: &contentLength,
+		PutObjectBody: ioutil.NopCloser(bytes.NewReader(body)),
+	})
+
+	return err
+}
+
+// GetObject downloads an object from the given bucket
+func (os *ObjectStorage) GetObject(bucketName, objectName string) ([]byte, error) {
+
+	response, err := os.client.GetObject(context.Background(), objectstorage.GetObjectRequest{
+		NamespaceName: &os.Namespace,
+		BucketName:    &bucketName,
+		ObjectName:    &objectName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer response.Content.Close()
+
+	return ioutil.ReadAll(response.Content)
+}
+
+// DeleteObject deletes an object from the given bucket
+func (os *ObjectStorage) DeleteObject(bucketName, objectName string) error {
+
+	_, err := os.client.DeleteObject(context.Background(), objectstorage.DeleteObjectRequest{
+		NamespaceName: &os.Namespace,
+		BucketName:    &bucketName,
+		ObjectName:    &objectName,
+	})
+
+	return err
+}
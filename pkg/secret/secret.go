@@ -84,6 +84,19 @@ const (
 	Password = "password"
 )
 
+// Webhook keys
+const (
+	WebhookURL           = "url"
+	WebhookAuthorization = "authorization"
+)
+
+// SMTP keys
+const (
+	SMTPHost = "host"
+	SMTPPort = "port"
+	SMTPFrom = "from"
+)
+
 // Internal usage
 const (
 	TagKubeConfig   = "KubeConfig"
@@ -108,6 +121,10 @@ const (
 	FnSecretType = "fn"
 	// PasswordSecretType marks secrets as of type "password"
 	PasswordSecretType = "password"
+	// WebhookSecretType marks secrets as of type "webhook"
+	WebhookSecretType = "webhook"
+	// SMTPSecretType marks secrets as of type "smtp"
+	SMTPSecretType = "smtp"
 )
 
 // DefaultRules key matching for types
@@ -200,6 +217,23 @@ var DefaultRules = map[string]Meta{
 		},
 		Sourcing: EnvVar,
 	},
+	WebhookSecretType: {
+		Fields: []FieldMeta{
+			{Name: WebhookURL, Required: true, Description: "Incoming webhook URL"},
+			{Name: WebhookAuthorization, Required: false, Description: "Authorization header sent to generic webhooks"},
+		},
+		Sourcing: EnvVar,
+	},
+	SMTPSecretType: {
+		Fields: []FieldMeta{
+			{Name: SMTPHost, Required: true},
+			{Name: SMTPPort, Required: true},
+			{Name: SMTPFrom, Required: true, Description: "Sender address of the notification emails"},
+			{Name: Username, Required: false},
+			{Name: Password, Required: false},
+		},
+		Sourcing: EnvVar,
+	},
 }
 
 // ListSecretsQuery represent a secret listing filter
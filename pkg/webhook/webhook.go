@@ -0,0 +1,198 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/banzaicloud/pipeline/pkg/outbound"
+)
+
+// EnvelopeVersion is the version of the event envelope format
+const EnvelopeVersion = "v1"
+
+// HTTP headers of the webhook requests
+const (
+	HeaderSignature = "X-Pipeline-Signature"
+	HeaderTimestamp = "X-Pipeline-Timestamp"
+	HeaderEvent     = "X-Pipeline-Event"
+	HeaderEventID   = "X-Pipeline-Event-Id"
+	HeaderDelivery  = "X-Pipeline-Delivery"
+)
+
+// Resource types of the events
+const (
+	ResourceCluster    = "cluster"
+	ResourceDeployment = "deployment"
+	ResourceBucket     = "bucket"
+	ResourceSecret     = "secret"
+	ResourceWebhook    = "webhook"
+)
+
+// Event types sent to the webhooks
+const (
+	EventPing              = "ping"
+	EventClusterCreated    = "cluster.created"
+	EventClusterUpdated    = "cluster.updated"
+	EventClusterDeleted    = "cluster.deleted"
+	EventDeploymentCreated = "deployment.created"
+	EventDeploymentUpdated = "deployment.updated"
+	EventDeploymentDeleted = "deployment.deleted"
+	EventBucketCreated     = "bucket.created"
+	EventBucketDeleted     = "bucket.deleted"
+	EventSecretCreated     = "secret.created"
+	EventSecretUpdated     = "secret.updated"
+	EventSecretDeleted     = "secret.deleted"
+)
+
+// EventTypes contains the event types webhooks can subscribe to, ping events are always sent
+var EventTypes = []string{
+	EventClusterCreated,
+	EventClusterUpdated,
+	EventClusterDeleted,
+	EventDeploymentCreated,
+	EventDeploymentUpdated,
+	EventDeploymentDeleted,
+	EventBucketCreated,
+	EventBucketDeleted,
+	EventSecretCreated,
+	EventSecretUpdated,
+	EventSecretDeleted,
+}
+
+// Delivery statuses
+const (
+	DeliveryPending   = "PENDING"
+	DeliverySucceeded = "SUCCEEDED"
+	DeliveryFailed    = "FAILED"
+)
+
+// Envelope is the versioned JSON body of the webhook requests
+type Envelope struct {
+	Version      string          `json:"version"`
+	ID           string          `json:"id"`
+	Type         string          `json:"type"`
+	Organization Organization    `json:"organization"`
+	Resource     Resource        `json:"resource"`
+	Actor        *Actor          `json:"actor,omitempty"`
+	Timestamp    time.Time       `json:"timestamp"`
+	Payload      json.RawMessage `json:"payload,omitempty"`
+}
+
+// Organization identifies the organization of the event
+type Organization struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// Resource identifies the resource the event is about
+type Resource struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// Actor identifies the user who triggered the event
+type Actor struct {
+	ID    uint   `json:"id"`
+	Login string `json:"login,omitempty"`
+}
+
+// ClusterPayload is the payload of the cluster events
+type ClusterPayload struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Cloud    string `json:"cloud"`
+	Location string `json:"location"`
+	Status   string `json:"status"`
+}
+
+// DeploymentPayload is the payload of the deployment events
+type DeploymentPayload struct {
+	ClusterID   uint   `json:"clusterId"`
+	ClusterName string `json:"clusterName"`
+	ReleaseName string `json:"releaseName"`
+	Chart       string `json:"chart,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+}
+
+// BucketPayload is the payload of the bucket events
+type BucketPayload struct {
+	Name     string `json:"name"`
+	Cloud    string `json:"cloud"`
+	Location string `json:"location,omitempty"`
+	SecretID string `json:"secretId,omitempty"`
+}
+
+// SecretPayload is the payload of the secret events, secret values are never sent
+type SecretPayload struct {
+	ID   string   `json:"id"`
+	Name string   `json:"name"`
+	Type string   `json:"type"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// WebhookRequest describes a webhook subscription creation or update request. A signing secret is generated
+// on creation when it's not given, and the current one is kept on update.
+type WebhookRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	URL    string   `json:"url" binding:"required"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+// Validate checks the URL and the event types of the webhook, the URL must resolve to public addresses
+// unless private networks are allowed
+func (r *WebhookRequest) Validate(allowPrivateNetworks bool) error {
+	if err := outbound.ValidateURL(r.URL, allowPrivateNetworks); err != nil {
+		return fmt.Errorf("invalid url: %s", err.Error())
+	}
+
+	for _, event := range r.Events {
+		if !IsEventType(event) {
+			return fmt.Errorf("unsupported event type %q", event)
+		}
+	}
+
+	return nil
+}
+
+// WebhookResponse describes a webhook subscription of the organization, the signing secret is returned only on creation
+type WebhookResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedBy uint      `json:"createdBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DeliveryResponse describes a delivery of an event to a webhook
+type DeliveryResponse struct {
+	ID            uint            `json:"id"`
+	EventID       string          `json:"eventId"`
+	EventType     string          `json:"eventType"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	ResponseCode  int             `json:"responseCode,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	NextAttemptAt *time.Time      `json:"nextAttemptAt,omitempty"`
+	DeliveredAt   *time.Time      `json:"deliveredAt,omitempty"`
+	RedeliveryOf  uint            `json:"redeliveryOf,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	Request       json.RawMessage `json:"request,omitempty"`
+}
+
+// IsEventType returns true if webhooks can subscribe to the event type
+func IsEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
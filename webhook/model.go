@@ -0,0 +1,193 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/database"
+	pkgWebhook "github.com/banzaicloud/pipeline/pkg/webhook"
+)
+
+// Table names of the webhook models
+const (
+	TableNameWebhooks          = "webhooks"
+	TableNameWebhookDeliveries = "webhook_deliveries"
+)
+
+// WebhookModel describes a webhook subscription of an organization, the signing secret is stored in the secret store
+type WebhookModel struct {
+	ID             uint `gorm:"primary_key"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	OrganizationID uint   `gorm:"unique_index:idx_org_name"`
+	Name           string `gorm:"unique_index:idx_org_name"`
+	URL            string `sql:"type:text"`
+	Events         string `sql:"type:text"`
+	Active         bool
+	SecretID       string
+	CreatedBy      uint
+}
+
+// DeliveryModel describes a delivery of an event to a webhook with the result of the last attempt
+type DeliveryModel struct {
+	ID             uint `gorm:"primary_key"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	OrganizationID uint `gorm:"index"`
+	WebhookID      uint `gorm:"index"`
+	EventID        string
+	EventType      string
+	Payload        string `sql:"type:mediumtext"`
+	Status         string
+	Attempts       int
+	NextAttemptAt  *time.Time `gorm:"index"`
+	ResponseCode   int
+	Error          string `sql:"type:text"`
+	DeliveredAt    *time.Time
+	RedeliveryOf   uint
+}
+
+// TableName sets WebhookModel's table name
+func (WebhookModel) TableName() string {
+	return TableNameWebhooks
+}
+
+// TableName sets DeliveryModel's table name
+func (DeliveryModel) TableName() string {
+	return TableNameWebhookDeliveries
+}
+
+// Save the webhook to DB
+func (m *WebhookModel) Save() error {
+	return database.GetDB().Save(m).Error
+}
+
+// Delete the webhook and its delivery log from DB
+func (m *WebhookModel) Delete() error {
+	tx := database.GetDB().Begin()
+	if err := tx.Where("webhook_id = ?", m.ID).Delete(DeliveryModel{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(m).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// Subscribed returns true if the webhook is active and subscribed to the event type,
+// a webhook without event types is subscribed to every event
+func (m *WebhookModel) Subscribed(eventType string) bool {
+	if !m.Active {
+		return false
+	}
+	if eventType == pkgWebhook.EventPing || len(m.Events) == 0 {
+		return true
+	}
+	for _, event := range strings.Split(m.Events, ",") {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// ToResponse converts the model to API response
+func (m *WebhookModel) ToResponse() *pkgWebhook.WebhookResponse {
+	response := &pkgWebhook.WebhookResponse{
+		ID:        m.ID,
+		Name:      m.Name,
+		URL:       m.URL,
+		Events:    []string{},
+		Active:    m.Active,
+		CreatedBy: m.CreatedBy,
+		CreatedAt: m.CreatedAt,
+	}
+	if len(m.Events) != 0 {
+		response.Events = strings.Split(m.Events, ",")
+	}
+	return response
+}
+
+// ToResponse converts the model to API response, the request is included on demand
+func (m *DeliveryModel) ToResponse(details bool) *pkgWebhook.DeliveryResponse {
+	response := &pkgWebhook.DeliveryResponse{
+		ID:           m.ID,
+		EventID:      m.EventID,
+		EventType:    m.EventType,
+		Status:       m.Status,
+		Attempts:     m.Attempts,
+		ResponseCode: m.ResponseCode,
+		Error:        m.Error,
+		DeliveredAt:  m.DeliveredAt,
+		RedeliveryOf: m.RedeliveryOf,
+		CreatedAt:    m.CreatedAt,
+	}
+	if m.Status == pkgWebhook.DeliveryPending {
+		response.NextAttemptAt = m.NextAttemptAt
+	}
+	if details {
+		response.Request = json.RawMessage(m.Payload)
+	}
+	return response
+}
+
+// GetWebhook returns a webhook of the organization
+func GetWebhook(organizationID, webhookID uint) (*WebhookModel, error) {
+	var webhook WebhookModel
+	err := database.GetDB().Where("organization_id = ? AND id = ?", organizationID, webhookID).First(&webhook).Error
+	return &webhook, err
+}
+
+// ListWebhooks returns the webhooks of the organization
+func ListWebhooks(organizationID uint) ([]*WebhookModel, error) {
+	var webhooks []*WebhookModel
+	err := database.GetDB().Where("organization_id = ?", organizationID).Order("name").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// GetDelivery returns a delivery of the webhook
+func GetDelivery(webhookID, deliveryID uint) (*DeliveryModel, error) {
+	var delivery DeliveryModel
+	err := database.GetDB().Where("webhook_id = ? AND id = ?", webhookID, deliveryID).First(&delivery).Error
+	return &delivery, err
+}
+
+// ListDeliveries returns the latest deliveries of the webhook
+func ListDeliveries(webhookID uint, limit int) ([]*DeliveryModel, error) {
+	var deliveries []*DeliveryModel
+	err := database.GetDB().Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// queryDueDeliveries returns the pending deliveries whose next attempt is due
+func queryDueDeliveries(now time.Time) ([]*DeliveryModel, error) {
+	var deliveries []*DeliveryModel
+	err := database.GetDB().
+		Where("status = ? AND next_attempt_at <= ?", pkgWebhook.DeliveryPending, now).
+		Order("next_attempt_at").
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// claimDelivery postpones the next attempt of the delivery while it's being attempted, it returns false
+// if the delivery has been claimed by someone else in the meantime
+func claimDelivery(delivery *DeliveryModel, until time.Time) (bool, error) {
+	result := database.GetDB().Model(DeliveryModel{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, pkgWebhook.DeliveryPending, delivery.NextAttemptAt).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	delivery.NextAttemptAt = &until
+	return result.RowsAffected == 1, nil
+}
+
+// deleteDeliveriesBefore deletes the deliveries finished before the given time
+func deleteDeliveriesBefore(before time.Time) error {
+	return database.GetDB().
+		Where("status <> ? AND updated_at < ?", pkgWebhook.DeliveryPending, before).
+		Delete(DeliveryModel{}).Error
+}
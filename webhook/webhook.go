@@ -0,0 +1,363 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/database"
+	"github.com/banzaicloud/pipeline/pkg/outbound"
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	pkgWebhook "github.com/banzaicloud/pipeline/pkg/webhook"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// signingKey is the key of the signing secret value in the secret store
+const signingKey = "signingKey"
+
+var log *logrus.Logger
+
+// Simple init for logging
+func init() {
+	log = config.Logger()
+}
+
+// Event describes a change of a resource of an organization
+type Event struct {
+	Type           string
+	OrganizationID uint
+	Resource       pkgWebhook.Resource
+	Actor          *pkgWebhook.Actor
+	Payload        interface{}
+}
+
+// ActorFromUser returns the actor of the events triggered by the user
+func ActorFromUser(user *auth.User) *pkgWebhook.Actor {
+	if user == nil {
+		return nil
+	}
+	return &pkgWebhook.Actor{ID: user.ID, Login: user.Login}
+}
+
+// ActorFromUserID returns the actor of the events triggered by the user with the given id, e.g. in the background
+func ActorFromUserID(userID uint) *pkgWebhook.Actor {
+	if userID == 0 {
+		return nil
+	}
+	user, err := auth.GetUserById(userID)
+	if err != nil {
+		return &pkgWebhook.Actor{ID: userID}
+	}
+	return ActorFromUser(user)
+}
+
+// Publish queues the event for delivery to the subscribed webhooks of the organization in the background
+func Publish(event Event) {
+	go func() {
+		webhooks, err := ListWebhooks(event.OrganizationID)
+		if err != nil {
+			log.Errorf("Error listing webhooks: %s", err.Error())
+			return
+		}
+
+		subscribed := make([]*WebhookModel, 0, len(webhooks))
+		for _, webhook := range webhooks {
+			if webhook.Subscribed(event.Type) {
+				subscribed = append(subscribed, webhook)
+			}
+		}
+		if len(subscribed) == 0 {
+			return
+		}
+
+		deliveries, err := createDeliveries(&event, subscribed)
+		if err != nil {
+			log.Errorf("Error creating %s webhook deliveries: %s", event.Type, err.Error())
+			return
+		}
+
+		for i, delivery := range deliveries {
+			attempt(subscribed[i], delivery)
+		}
+	}()
+}
+
+// Ping sends a ping event to the webhook and returns the result of the first attempt
+func Ping(webhook *WebhookModel, actor *pkgWebhook.Actor) (*DeliveryModel, error) {
+	event := &Event{
+		Type:           pkgWebhook.EventPing,
+		OrganizationID: webhook.OrganizationID,
+		Resource:       pkgWebhook.Resource{Type: pkgWebhook.ResourceWebhook, ID: fmt.Sprint(webhook.ID), Name: webhook.Name},
+		Actor:          actor,
+		Payload:        webhook.ToResponse(),
+	}
+
+	deliveries, err := createDeliveries(event, []*WebhookModel{webhook})
+	if err != nil {
+		return nil, err
+	}
+
+	attempt(webhook, deliveries[0])
+
+	return deliveries[0], nil
+}
+
+// Redeliver sends the event of the delivery again as a new delivery and returns the result of its first attempt
+func Redeliver(webhook *WebhookModel, delivery *DeliveryModel) (*DeliveryModel, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	redelivery := &DeliveryModel{
+		OrganizationID: delivery.OrganizationID,
+		WebhookID:      delivery.WebhookID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         pkgWebhook.DeliveryPending,
+		NextAttemptAt:  &now,
+		RedeliveryOf:   delivery.ID,
+	}
+	if err := database.GetDB().Create(redelivery).Error; err != nil {
+		return nil, errors.Wrap(err, "error saving delivery")
+	}
+
+	attempt(webhook, redelivery)
+
+	return redelivery, nil
+}
+
+// createDeliveries stores a pending delivery of the event for each webhook
+func createDeliveries(event *Event, webhooks []*WebhookModel) ([]*DeliveryModel, error) {
+	organization, err := auth.GetOrganizationById(event.OrganizationID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting organization")
+	}
+
+	envelope := pkgWebhook.Envelope{
+		Version:      pkgWebhook.EnvelopeVersion,
+		ID:           uuid.NewV4().String(),
+		Type:         event.Type,
+		Organization: pkgWebhook.Organization{ID: organization.ID, Name: organization.Name},
+		Resource:     event.Resource,
+		Actor:        event.Actor,
+		Timestamp:    time.Now().UTC(),
+	}
+	if event.Payload != nil {
+		if envelope.Payload, err = json.Marshal(event.Payload); err != nil {
+			return nil, errors.Wrap(err, "error marshalling event payload")
+		}
+	}
+
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling event")
+	}
+
+	// the next attempt is stored with second precision, as it's compared when the delivery is claimed
+	nextAttemptAt := envelope.Timestamp.Truncate(time.Second)
+	deliveries := make([]*DeliveryModel, 0, len(webhooks))
+	tx := database.GetDB().Begin()
+	for _, webhook := range webhooks {
+		delivery := &DeliveryModel{
+			OrganizationID: event.OrganizationID,
+			WebhookID:      webhook.ID,
+			EventID:        envelope.ID,
+			EventType:      event.Type,
+			Payload:        string(body),
+			Status:         pkgWebhook.DeliveryPending,
+			NextAttemptAt:  &nextAttemptAt,
+		}
+		if err := tx.Create(delivery).Error; err != nil {
+			tx.Rollback()
+			return nil, errors.Wrap(err, "error saving delivery")
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, tx.Commit().Error
+}
+
+// attempt sends the delivery to the webhook unless it's being attempted already,
+// failed attempts are retried with exponential backoff until the attempts run out
+func attempt(webhook *WebhookModel, delivery *DeliveryModel) {
+	timeout := viper.GetDuration(config.WebhookTimeout)
+
+	claimed, err := claimDelivery(delivery, time.Now().UTC().Add(2*timeout).Truncate(time.Second))
+	if err != nil {
+		log.Errorf("Error claiming webhook delivery %d: %s", delivery.ID, err.Error())
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	delivery.Attempts++
+	var signingSecret *secret.SecretItemResponse
+	if signingSecret, err = secret.Store.Get(webhook.OrganizationID, webhook.SecretID); err == nil {
+		key := []byte(signingSecret.GetValue(signingKey))
+		delivery.ResponseCode, err = send(webhook.URL, key, delivery, timeout)
+	} else {
+		err = errors.Wrap(err, "error getting signing secret")
+	}
+
+	now := time.Now().UTC()
+	if err == nil {
+		delivery.Status = pkgWebhook.DeliverySucceeded
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	} else {
+		log.Debugf("Webhook delivery %d attempt %d failed: %s", delivery.ID, delivery.Attempts, err.Error())
+		delivery.Error = err.Error()
+		if delivery.Attempts >= viper.GetInt(config.WebhookMaxAttempts) {
+			delivery.Status = pkgWebhook.DeliveryFailed
+			delivery.NextAttemptAt = nil
+		} else {
+			next := now.Add(backoff(delivery.Attempts)).Truncate(time.Second)
+			delivery.NextAttemptAt = &next
+		}
+	}
+
+	if err := database.GetDB().Save(delivery).Error; err != nil {
+		log.Errorf("Error saving webhook delivery %d: %s", delivery.ID, err.Error())
+	}
+}
+
+// skipDelivery fails the pending delivery of an inactive webhook, it can be redelivered once the webhook is active again
+func skipDelivery(delivery *DeliveryModel) {
+	delivery.Status = pkgWebhook.DeliveryFailed
+	delivery.Error = "webhook is inactive"
+	delivery.NextAttemptAt = nil
+
+	if err := database.GetDB().Save(delivery).Error; err != nil {
+		log.Errorf("Error saving webhook delivery %d: %s", delivery.ID, err.Error())
+	}
+}
+
+// send posts the event of the delivery signed with the key to the URL, non-2xx responses are errors.
+// Redirects are not followed, and the response body is discarded, so the webhooks can't be used
+// to read the responses of internal services.
+func send(url string, key []byte, delivery *DeliveryModel, timeout time.Duration) (int, error) {
+	body := []byte(delivery.Payload)
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, errors.Wrap(err, "error creating request")
+	}
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Pipeline-Webhook/"+pkgWebhook.EnvelopeVersion)
+	request.Header.Set(pkgWebhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(pkgWebhook.HeaderSignature, Sign(key, timestamp, body))
+	request.Header.Set(pkgWebhook.HeaderEvent, delivery.EventType)
+	request.Header.Set(pkgWebhook.HeaderEventID, delivery.EventID)
+	request.Header.Set(pkgWebhook.HeaderDelivery, fmt.Sprint(delivery.ID))
+
+	client := outbound.NewClient(timeout, viper.GetBool(config.WebhookAllowPrivateNetworks))
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, errors.Wrap(err, "error posting event")
+	}
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("webhook responded with %s", response.Status)
+	}
+
+	return response.StatusCode, nil
+}
+
+// Sign returns the value of the signature header, the hex encoded HMAC-SHA256 of the timestamp header value
+// and the body joined by a dot. The receivers verify the timestamp as well, and reject the old requests to
+// prevent them from being replayed.
+func Sign(key []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay before the next attempt after the given number of failed attempts
+func backoff(attempts int) time.Duration {
+	maxDelay := viper.GetDuration(config.WebhookRetryMaxDelay)
+
+	delay := viper.GetDuration(config.WebhookRetryBaseDelay)
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return delay
+}
+
+// StoreSigningSecret stores the signing secret of a webhook in the secret store and returns its id
+func StoreSigningSecret(organizationID uint, signingSecret string) (string, error) {
+	return secret.Store.Store(organizationID, &secret.CreateSecretRequest{
+		Name:   "webhook-signing-" + strings.ToLower(uuid.NewV4().String()),
+		Type:   pkgSecret.GenericSecret,
+		Values: map[string]string{signingKey: signingSecret},
+		Tags:   []string{pkgSecret.TagBanzaiHidden, "webhook"},
+	})
+}
+
+// RunDueDeliveries retries the pending deliveries whose next attempt is due and deletes the expired delivery log
+func RunDueDeliveries() {
+	deliveries, err := queryDueDeliveries(time.Now().UTC())
+	if err != nil {
+		log.Errorf("Error listing due webhook deliveries: %s", err.Error())
+		return
+	}
+
+	webhooks := make(map[uint]*WebhookModel)
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			if webhook, err = GetWebhook(delivery.OrganizationID, delivery.WebhookID); err != nil {
+				log.Errorf("Error getting webhook %d: %s", delivery.WebhookID, err.Error())
+				continue
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		if !webhook.Active {
+			skipDelivery(delivery)
+			continue
+		}
+
+		attempt(webhook, delivery)
+	}
+
+	retention, err := time.ParseDuration(viper.GetString(config.WebhookDeliveryRetention))
+	if err != nil {
+		log.Errorf("Invalid webhook delivery retention: %s", err.Error())
+		return
+	}
+	if err := deleteDeliveriesBefore(time.Now().UTC().Add(-retention)); err != nil {
+		log.Errorf("Error deleting expired webhook deliveries: %s", err.Error())
+	}
+}
+
+// StartDeliveryWorker periodically retries the failed webhook deliveries
+func StartDeliveryWorker(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			RunDueDeliveries()
+		}
+	}()
+
+	return ticker
+}
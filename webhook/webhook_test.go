@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	pkgWebhook "github.com/banzaicloud/pipeline/pkg/webhook"
+	"github.com/spf13/viper"
+)
+
+func TestSign(t *testing.T) {
+	expected := "sha256=8aa54731458d2ae876dcc4c9712d4bfeb7b0b3f6b70d6f8835005b58ead58d57"
+	actual := Sign([]byte("secret"), 1500000000, []byte(`{"type":"ping"}`))
+	if actual != expected {
+		t.Errorf("unexpected signature: %q", actual)
+	}
+	if Sign([]byte("secret"), 1500000001, []byte(`{"type":"ping"}`)) == actual {
+		t.Error("expected the signature to depend on the timestamp")
+	}
+}
+
+func TestSend(t *testing.T) {
+	allowPrivate := viper.GetBool(config.WebhookAllowPrivateNetworks)
+	viper.Set(config.WebhookAllowPrivateNetworks, true)
+	defer viper.Set(config.WebhookAllowPrivateNetworks, allowPrivate)
+
+	key := []byte("secret")
+	delivery := &DeliveryModel{ID: 42, EventID: "event", EventType: pkgWebhook.EventPing, Payload: `{"type":"ping"}`}
+
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	code, err := send(server.URL, key, delivery, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusOK {
+		t.Errorf("unexpected response code: %d", code)
+	}
+	if string(body) != delivery.Payload {
+		t.Errorf("unexpected request body: %q", body)
+	}
+
+	timestamp, err := strconv.ParseInt(header.Get(pkgWebhook.HeaderTimestamp), 10, 64)
+	if err != nil || time.Since(time.Unix(timestamp, 0)) > time.Minute {
+		t.Errorf("unexpected timestamp: %q", header.Get(pkgWebhook.HeaderTimestamp))
+	}
+	if signature := header.Get(pkgWebhook.HeaderSignature); signature != Sign(key, timestamp, body) {
+		t.Errorf("unexpected signature: %q", signature)
+	}
+	if header.Get(pkgWebhook.HeaderEvent) != pkgWebhook.EventPing || header.Get(pkgWebhook.HeaderDelivery) != "42" {
+		t.Errorf("unexpected headers: %v", header)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	if code, err := send(failing.URL, key, delivery, time.Second); err == nil || code != http.StatusServiceUnavailable {
+		t.Errorf("expected error for failed webhook response, got %d", code)
+	}
+
+	redirecting := httptest.NewServer(http.RedirectHandler(server.URL, http.StatusFound))
+	defer redirecting.Close()
+
+	header = nil
+	if code, err := send(redirecting.URL, key, delivery, time.Second); err == nil || code != http.StatusFound || header != nil {
+		t.Errorf("expected redirect not to be followed, got %d", code)
+	}
+}
+
+func TestSendPrivateAddress(t *testing.T) {
+	allowPrivate := viper.GetBool(config.WebhookAllowPrivateNetworks)
+	viper.Set(config.WebhookAllowPrivateNetworks, false)
+	defer viper.Set(config.WebhookAllowPrivateNetworks, allowPrivate)
+
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	delivery := &DeliveryModel{ID: 42, EventID: "event", EventType: pkgWebhook.EventPing, Payload: `{"type":"ping"}`}
+	if _, err := send(server.URL, []byte("secret"), delivery, time.Second); err == nil || called {
+		t.Error("expected webhook on loopback address to be rejected")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	viper.Set(config.WebhookRetryBaseDelay, "30s")
+	viper.Set(config.WebhookRetryMaxDelay, "5m")
+
+	expected := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, delay := range expected {
+		if actual := backoff(i + 1); actual != delay {
+			t.Errorf("expected %s delay after %d attempts, got %s", delay, i+1, actual)
+		}
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	webhook := &WebhookModel{Active: true, Events: pkgWebhook.EventClusterCreated + "," + pkgWebhook.EventClusterDeleted}
+
+	if !webhook.Subscribed(pkgWebhook.EventClusterDeleted) || webhook.Subscribed(pkgWebhook.EventSecretCreated) {
+		t.Errorf("unexpected subscription match for %q", webhook.Events)
+	}
+	if !webhook.Subscribed(pkgWebhook.EventPing) {
+		t.Error("expected ping events to be sent to every webhook")
+	}
+
+	webhook.Active = false
+	if webhook.Subscribed(pkgWebhook.EventClusterCreated) {
+		t.Error("expected no events for inactive webhook")
+	}
+}